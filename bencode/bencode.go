@@ -20,7 +20,13 @@ const (
 	DictionaryKeyPieceLength  = "piece length"
 	DictionaryKeyPieces       = "pieces"
 	DictionaryKeyUrlList      = "url-list"
+	DictionaryKeyHttpSeeds    = "httpseeds"
 	DictionaryKeyFiles        = "files"
+	DictionaryKeyAttr         = "attr"
+	DictionaryKeySimilar      = "similar"
+	DictionaryKeyCollections  = "collections"
+	DictionaryKeyFileTree     = "file tree"   // http://www.bittorrent.org/beps/bep_0052.html
+	DictionaryKeyPiecesRoot   = "pieces root" // http://www.bittorrent.org/beps/bep_0052.html
 )
 
 var (
@@ -42,18 +48,36 @@ var (
 
 type Piece [20]byte
 
+// File is a file of a torrent, its generated MarshalBencode and UnmarshalBencode
+// methods only read and write the raw fields: length, path (RawPath, or DecomposedPath without it) and attr
+//
+// The display path of a decoded file is its raw path, the CompletePath is set by the Info holding it
+//
+//go:generate go run github.com/trixky/gobencode/cmd/bencodegen -type File,infoWire -output file_bencode.go
 type File struct {
-	Length         int
-	Path           string
-	DecomposedPath []string
+	Length         int      `bencode:"length,required"`
+	Path           string   // display path: path.utf-8, or path decoded from the declared encoding
+	DecomposedPath []string // display path components
 	CompletePath   string
+	RawPath        []string `bencode:"path,required"`  // path components as stored in the torrent
+	Attributes     string   `bencode:"attr,omitempty"` // http://www.bittorrent.org/beps/bep_0047.html (p: padding, x: executable, h: hidden, l: symlink)
 }
 
+// Info is the info section of a torrent
+//
+// The file offsets are cached by UnmarshallInfo, they are computed again once the file lengths changed.
+// Its MarshalBencode and UnmarshalBencode methods read and write the keys of its fields only (see infoWire),
+// a single file is the length and the attr of the info dictionary, several files are its files list
 type Info struct {
 	Files         []File
 	PieceLength   int
 	Pieces        []Piece
-	DirectoryName string
+	DirectoryName string     // display name: name.utf-8, or name decoded from the declared encoding
+	RawName       string     // name as stored in the torrent
+	Similar       [][20]byte // info hashes of torrents sharing files with this one (BEP 38)
+	Collections   []string   // names of the collections of the torrent (BEP 38)
+
+	file_offsets []int // offsets of the files and total length, cached by UnmarshallInfo
 }
 
 type Bencode struct {
@@ -64,9 +88,34 @@ type Bencode struct {
 	Comment                string
 	CreatedBy              string
 	CreationDate           int
+	Encoding               string
 	Info                   Info
 	InfoHash               [20]byte
 	UrlList                []string
+	HttpSeeds              []string // http://www.bittorrent.org/beps/bep_0017.html
+}
+
+// IsMultiFile checks if the files of the torrent are in a root directory, the info dictionary has a files list
+//
+// Without parsed data, the files with a path tell it
+func (b *Bencode) IsMultiFile() bool {
+	dictionary, ok := b.Data.(map[string]interface{})
+
+	if !ok {
+		for _, file := range b.Info.Files {
+			if len(file.RawPath) > 0 || len(file.DecomposedPath) > 0 {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	info_dictionary, _ := dictionary[DictionaryKeyInfo].(map[string]interface{})
+
+	_, ok = info_dictionary[DictionaryKeyFiles]
+
+	return ok
 }
 
 // RandomizeAnnounceList generates a Randomized Announce List from the initial announce list
@@ -79,7 +128,7 @@ func (b *Bencode) RandomizeAnnounceList() error {
 	rand.Seed(time.Now().UnixNano())
 
 	for _, sub_announce_list := range b.AnnounceList {
-		sub_announce_list_copy := sub_announce_list
+		sub_announce_list_copy := append([]string{}, sub_announce_list...) // keep the tiers untouched
 
 		for l := len(sub_announce_list_copy); l > 0; l-- {
 			random_index := rand.Intn(l)
@@ -90,3 +139,22 @@ func (b *Bencode) RandomizeAnnounceList() error {
 
 	return nil
 }
+
+// Trackers returns the announce urls of all the tiers and the announce, without repetition
+func (b *Bencode) Trackers() []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	tiers := make([][]string, 0, len(b.AnnounceList)+1)
+	tiers = append(append(tiers, b.AnnounceList...), []string{b.Announce})
+
+	for _, tier := range tiers {
+		for _, url := range tier {
+			if len(url) > 0 && !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+
+	return urls
+}
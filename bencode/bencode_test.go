@@ -2,6 +2,7 @@ package bencode
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -104,6 +105,10 @@ func TestRandomizeAnnounceList(t *testing.T) {
 		bencode.RandomizeAnnounceList()
 
 		check_sub_randomized_announce_list(bencode.RandomizedAnnounceList, first_chars, second_chars)
+
+		if !reflect.DeepEqual(bencode.AnnounceList, generate_announce_list(first_chars, second_chars)) {
+			t.Fatalf("announce list modified: %v", bencode.AnnounceList)
+		}
 	}
 
 	for _, test := range tests {
@@ -112,3 +117,49 @@ func TestRandomizeAnnounceList(t *testing.T) {
 		}
 	}
 }
+
+func TestTrackers(t *testing.T) {
+	tests := []struct {
+		input    Bencode
+		expected []string
+	}{
+		{Bencode{}, []string{}},
+		{Bencode{Announce: "a"}, []string{"a"}},
+		{Bencode{Announce: "a", AnnounceList: [][]string{{"b", "a"}, {"c", "b"}}}, []string{"b", "a", "c"}},
+	}
+
+	for index, test := range tests {
+		if output := test.input.Trackers(); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+
+	// the spare capacity of the announce list is not written
+	announce_list := make([][]string, 1, 2)
+	announce_list[0] = []string{"b"}
+	bc := Bencode{Announce: "a", AnnounceList: announce_list}
+	bc.Trackers()
+
+	if spare := announce_list[:2][1]; spare != nil {
+		t.Errorf("expected [[]] | %v spare tier", spare)
+	}
+}
+
+func TestIsMultiFile(t *testing.T) {
+	tests := []struct {
+		input    Bencode
+		expected bool
+	}{
+		{Bencode{Data: map[string]interface{}{"info": map[string]interface{}{"files": []interface{}{}}}}, true},
+		{Bencode{Data: map[string]interface{}{"info": map[string]interface{}{"length": 1}}}, false},
+		{Bencode{Data: map[string]interface{}{}}, false},
+		{Bencode{Info: Info{Files: []File{{Length: 1, RawPath: []string{"a"}}}}}, true},
+		{Bencode{Info: Info{Files: []File{{Length: 1, Path: "a"}}}}, false},
+	}
+
+	for index, test := range tests {
+		if output := test.input.IsMultiFile(); output != test.expected {
+			t.Errorf("test %d: expected [%t] | [%t] output", index, test.expected, output)
+		}
+	}
+}
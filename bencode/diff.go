@@ -0,0 +1,266 @@
+package bencode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+type ChangeType int
+
+const (
+	ChangeAdded ChangeType = iota
+	ChangeRemoved
+	ChangeModified
+)
+
+// String returns the marker of the change type used by the text output
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	case ChangeModified:
+		return "~"
+	}
+
+	return "?"
+}
+
+// Change is a difference at a key path, Old is nil for an added element and New for a removed one
+type Change struct {
+	Type            ChangeType
+	Path            KeyPath
+	Old             interface{}
+	New             interface{}
+	AffectsInfoHash bool // the change is in the info dictionary
+}
+
+// Diff is the list of the differences between two bencoded documents, in the order they are walked:
+// by sorted key for the maps, in document order for the *parser.OrderedDict
+type Diff struct {
+	Changes []Change
+}
+
+// formatCompact shows an element on a single line
+func formatCompact(element interface{}) string {
+	switch element := element.(type) {
+	case string:
+		return formatString(element)
+	case int:
+		return strconv.Itoa(element)
+	case []interface{}:
+		return fmt.Sprintf("<list of %d>", len(element))
+	case map[string]interface{}:
+		return fmt.Sprintf("<dictionary of %d keys>", len(element))
+	case *parser.OrderedDict:
+		return fmt.Sprintf("<dictionary of %d keys>", element.Len())
+	}
+
+	return fmt.Sprintf("<%T>", element)
+}
+
+// String returns the change as a single line
+func (c Change) String() string {
+	line := ""
+
+	switch c.Type {
+	case ChangeAdded:
+		line = fmt.Sprintf("%s %s: %s", c.Type, c.Path, formatCompact(c.New))
+	case ChangeRemoved:
+		line = fmt.Sprintf("%s %s: %s", c.Type, c.Path, formatCompact(c.Old))
+	default:
+		line = fmt.Sprintf("%s %s: %s -> %s", c.Type, c.Path, formatCompact(c.Old), formatCompact(c.New))
+	}
+
+	if c.AffectsInfoHash {
+		line += " (info hash)"
+	}
+
+	return line
+}
+
+// add appends a change
+func (d *Diff) add(change_type ChangeType, path KeyPath, old interface{}, new interface{}) {
+	d.Changes = append(d.Changes, Change{
+		Type:            change_type,
+		Path:            path,
+		Old:             old,
+		New:             new,
+		AffectsInfoHash: path.HasPrefix(DictionaryKeyInfo),
+	})
+}
+
+// compare walks two elements, containers of the same type are compared element by element
+//
+// a map compared to an *parser.OrderedDict is compared as an ordered dictionary with sorted keys
+func (d *Diff) compare(path KeyPath, old interface{}, new interface{}) {
+	if old_element, ok := old.(map[string]interface{}); ok {
+		if _, ok := new.(*parser.OrderedDict); ok {
+			old = parser.NewOrderedDict(old_element)
+		}
+	} else if new_element, ok := new.(map[string]interface{}); ok {
+		if _, ok := old.(*parser.OrderedDict); ok {
+			new = parser.NewOrderedDict(new_element)
+		}
+	}
+
+	switch old_element := old.(type) {
+	case *parser.OrderedDict:
+		new_element, ok := new.(*parser.OrderedDict)
+
+		if !ok {
+			break
+		}
+
+		d.compareEntries(path, old_element.Entries, new_element.Entries)
+
+		return
+	case map[string]interface{}:
+		new_element, ok := new.(map[string]interface{})
+
+		if !ok {
+			break
+		}
+
+		keys := []string{}
+
+		for key := range old_element {
+			keys = append(keys, key)
+		}
+
+		for key := range new_element {
+			if _, ok := old_element[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			old_value, old_ok := old_element[key]
+			new_value, new_ok := new_element[key]
+
+			switch {
+			case !old_ok:
+				d.add(ChangeAdded, path.Key(key), nil, new_value)
+			case !new_ok:
+				d.add(ChangeRemoved, path.Key(key), old_value, nil)
+			default:
+				d.compare(path.Key(key), old_value, new_value)
+			}
+		}
+
+		return
+	case []interface{}:
+		new_element, ok := new.([]interface{})
+
+		if !ok {
+			break
+		}
+
+		for index := 0; index < len(old_element) || index < len(new_element); index++ {
+			switch {
+			case index >= len(old_element):
+				d.add(ChangeAdded, path.Index(index), nil, new_element[index])
+			case index >= len(new_element):
+				d.add(ChangeRemoved, path.Index(index), old_element[index], nil)
+			default:
+				d.compare(path.Index(index), old_element[index], new_element[index])
+			}
+		}
+
+		return
+	case string, int:
+		if old == new {
+			return
+		}
+	}
+
+	d.add(ChangeModified, path, old, new)
+}
+
+// compareEntries walks the entries of two ordered dictionaries in their order
+//
+// an entry missing on one side is added or removed, a moved key is removed then added
+func (d *Diff) compareEntries(path KeyPath, old []parser.DictionaryEntry, new []parser.DictionaryEntry) {
+	remaining := map[string]int{} // occurrences of the keys in the new entries not walked yet
+
+	for _, entry := range new {
+		remaining[entry.Key]++
+	}
+
+	old_index, new_index := 0, 0
+
+	for old_index < len(old) || new_index < len(new) {
+		switch {
+		case old_index < len(old) && new_index < len(new) && old[old_index].Key == new[new_index].Key:
+			d.compare(path.Key(old[old_index].Key), old[old_index].Value, new[new_index].Value)
+			remaining[new[new_index].Key]--
+			old_index++
+			new_index++
+		case old_index >= len(old) || new_index < len(new) && remaining[old[old_index].Key] > 0:
+			d.add(ChangeAdded, path.Key(new[new_index].Key), nil, new[new_index].Value)
+			remaining[new[new_index].Key]--
+			new_index++
+		default:
+			d.add(ChangeRemoved, path.Key(old[old_index].Key), old[old_index].Value, nil)
+			old_index++
+		}
+	}
+}
+
+// DiffElements compares two parsed bencode elements (see parser.ParseElement), the dictionaries can be *parser.OrderedDict
+func DiffElements(old interface{}, new interface{}) Diff {
+	d := Diff{}
+
+	d.compare(KeyPath{}, old, new)
+
+	return d
+}
+
+// Diff compares the parsed data of two torrents
+func (b *Bencode) Diff(other *Bencode) Diff {
+	return DiffElements(b.Data, other.Data)
+}
+
+// InfoHashChanged checks if some changes are in the info dictionary, the torrents are then different for the peers
+func (d Diff) InfoHashChanged() bool {
+	for _, change := range d.Changes {
+		if change.AffectsInfoHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the changes one per line, followed by a summary
+func (d Diff) String() string {
+	if len(d.Changes) == 0 {
+		return "no changes\n"
+	}
+
+	builder := strings.Builder{}
+	info_changes := 0
+
+	for _, change := range d.Changes {
+		builder.WriteString(change.String() + "\n")
+
+		if change.AffectsInfoHash {
+			info_changes++
+		}
+	}
+
+	if info_changes > 0 {
+		builder.WriteString(fmt.Sprintf("%d changes, %d in the info dictionary: the info hash changed\n", len(d.Changes), info_changes))
+	} else {
+		builder.WriteString(fmt.Sprintf("%d changes, none in the info dictionary: the info hash is unchanged\n", len(d.Changes)))
+	}
+
+	return builder.String()
+}
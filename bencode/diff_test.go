@@ -0,0 +1,163 @@
+package bencode
+
+import (
+	"testing"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+func TestDiffElements(t *testing.T) {
+	old := map[string]interface{}{
+		"announce": "http://a/announce",
+		"comment":  "old",
+		"announce-list": []interface{}{
+			[]interface{}{"http://a/announce"},
+		},
+		"info": map[string]interface{}{
+			"name":         "file",
+			"piece length": 16384,
+		},
+	}
+	new := map[string]interface{}{
+		"announce": "http://b/announce",
+		"announce-list": []interface{}{
+			[]interface{}{"http://b/announce"},
+			[]interface{}{"udp://c/announce"},
+		},
+		"created by": "me",
+		"info": map[string]interface{}{
+			"name":         "file",
+			"piece length": 32768,
+		},
+	}
+
+	tests := []struct {
+		old      interface{}
+		new      interface{}
+		expected []string
+		info     bool
+	}{
+		{old, old, []string{}, false},
+		{"a", "a", []string{}, false},
+		{1, 2, []string{`~ .: 1 -> 2`}, false},
+		{1, "1", []string{`~ .: 1 -> "1"`}, false},
+		{[]interface{}{1}, map[string]interface{}{}, []string{`~ .: <list of 1> -> <dictionary of 0 keys>`}, false},
+		{old, new, []string{
+			`~ announce: "http://a/announce" -> "http://b/announce"`,
+			`~ announce-list[0][0]: "http://a/announce" -> "http://b/announce"`,
+			`+ announce-list[1]: <list of 1>`,
+			`- comment: "old"`,
+			`+ ["created by"]: "me"`,
+			`~ info["piece length"]: 16384 -> 32768 (info hash)`,
+		}, true},
+	}
+
+	for index, test := range tests {
+		diff := DiffElements(test.old, test.new)
+
+		if len(diff.Changes) != len(test.expected) {
+			t.Errorf("test %d: expected [%d] | [%d] output changes", index, len(test.expected), len(diff.Changes))
+			continue
+		}
+
+		for change_index, change := range diff.Changes {
+			if output := change.String(); output != test.expected[change_index] {
+				t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected[change_index], output)
+			}
+		}
+
+		if diff.InfoHashChanged() != test.info {
+			t.Errorf("test %d: expected [%t] | [%t] output info hash changed", index, test.info, diff.InfoHashChanged())
+		}
+	}
+}
+
+func TestDiffOrderedDictionaries(t *testing.T) {
+	ordered := func(entries ...interface{}) *parser.OrderedDict {
+		dictionary := &parser.OrderedDict{}
+
+		for index := 0; index < len(entries); index += 2 {
+			dictionary.Entries = append(dictionary.Entries, parser.DictionaryEntry{Key: entries[index].(string), Value: entries[index+1]})
+		}
+
+		return dictionary
+	}
+
+	old := ordered("announce", "a", "info", ordered("name", "file", "piece length", 16384))
+
+	tests := []struct {
+		old      interface{}
+		new      interface{}
+		expected []string
+	}{
+		{old, ordered("announce", "a", "info", ordered("name", "file", "piece length", 16384)), []string{}},
+		{old, map[string]interface{}{"announce": "a", "info": map[string]interface{}{"name": "file", "piece length": 16384}}, []string{}},
+		{map[string]interface{}{"announce": "a", "info": map[string]interface{}{"name": "file", "piece length": 16384}}, old, []string{}},
+		{old, ordered("announce", "b", "info", ordered("name", "file", "piece length", 32768)), []string{
+			`~ announce: "a" -> "b"`,
+			`~ info["piece length"]: 16384 -> 32768 (info hash)`,
+		}},
+		{old, ordered("announce", "a", "comment", "c", "info", ordered("name", "file")), []string{
+			`+ comment: "c"`,
+			`- info["piece length"]: 16384 (info hash)`,
+		}},
+		{old, ordered("info", ordered("name", "file", "piece length", 16384), "announce", "a"), []string{
+			`+ info: <dictionary of 2 keys> (info hash)`,
+			`- info: <dictionary of 2 keys> (info hash)`,
+		}},
+		{ordered("a", 1), ordered("a", 1, "a", 2), []string{`+ a: 2`}},
+		{ordered("a", 1), []interface{}{}, []string{`~ .: <dictionary of 1 keys> -> <list of 0>`}},
+	}
+
+	for index, test := range tests {
+		diff := DiffElements(test.old, test.new)
+
+		if len(diff.Changes) != len(test.expected) {
+			t.Errorf("test %d: expected [%d] | [%d] output changes: %v", index, len(test.expected), len(diff.Changes), diff.Changes)
+			continue
+		}
+
+		for change_index, change := range diff.Changes {
+			if output := change.String(); output != test.expected[change_index] {
+				t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected[change_index], output)
+			}
+		}
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	tests := []struct {
+		old      interface{}
+		new      interface{}
+		expected string
+	}{
+		{1, 1, "no changes\n"},
+		{
+			map[string]interface{}{"announce": "a"},
+			map[string]interface{}{"announce": "b"},
+			"~ announce: \"a\" -> \"b\"\n1 changes, none in the info dictionary: the info hash is unchanged\n",
+		},
+		{
+			map[string]interface{}{"info": map[string]interface{}{"private": 1}},
+			map[string]interface{}{"info": map[string]interface{}{}},
+			"- info.private: 1 (info hash)\n1 changes, 1 in the info dictionary: the info hash changed\n",
+		},
+	}
+
+	for index, test := range tests {
+		if output := DiffElements(test.old, test.new).String(); output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestBencodeDiff(t *testing.T) {
+	old := Bencode{Data: map[string]interface{}{"info": map[string]interface{}{"name": "a"}}}
+	new := Bencode{Data: map[string]interface{}{"info": map[string]interface{}{"name": "a"}, "comment": "b"}}
+
+	diff := old.Diff(&new)
+
+	if len(diff.Changes) != 1 || diff.Changes[0].Type != ChangeAdded || diff.Changes[0].New != "b" || diff.InfoHashChanged() {
+		t.Errorf("expected [+ comment] | [%s] output", diff)
+	}
+}
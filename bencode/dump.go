@@ -0,0 +1,210 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	dump_bytes_per_line = 16
+)
+
+var (
+	ErrorDumpCorrupted = errors.New("bencode corrupted")
+)
+
+// dumper walks raw bencoded bytes and writes the byte ranges of each element
+type dumper struct {
+	data    []byte
+	offset  int
+	builder strings.Builder
+}
+
+// line writes a byte range with its key path and a description
+func (d *dumper) line(start int, end int, path KeyPath, description string) {
+	for line_start := start; line_start < end; line_start += dump_bytes_per_line {
+		line_end := line_start + dump_bytes_per_line
+
+		if line_end > end {
+			line_end = end
+		}
+
+		hex_bytes := make([]string, 0, dump_bytes_per_line)
+		ascii := make([]byte, 0, dump_bytes_per_line)
+
+		for _, b := range d.data[line_start:line_end] {
+			hex_bytes = append(hex_bytes, fmt.Sprintf("%02x", b))
+
+			if b >= 0x20 && b < 0x7f {
+				ascii = append(ascii, b)
+			} else {
+				ascii = append(ascii, '.')
+			}
+		}
+
+		line := fmt.Sprintf("%08x  %-47s  %-16s", line_start, strings.Join(hex_bytes, " "), ascii)
+
+		// the annotation is only written on the first line of a range
+		if line_start == start {
+			line += "  " + path.String() + " " + description
+		}
+
+		d.builder.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+}
+
+// errorf returns an ErrorDumpCorrupted located at the current offset
+func (d *dumper) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrorDumpCorrupted, d.offset, fmt.Sprintf(format, a...))
+}
+
+// readUntil returns the bytes before a delimiter and moves after it
+func (d *dumper) readUntil(delimiter byte) (string, error) {
+	end := d.offset
+
+	for end < len(d.data) && d.data[end] != delimiter {
+		end++
+	}
+
+	if end == len(d.data) {
+		return "", d.errorf("missing [%c]", delimiter)
+	}
+
+	value := string(d.data[d.offset:end])
+	d.offset = end + 1
+
+	return value, nil
+}
+
+// string reads a string without writing it, it returns its start and its value
+func (d *dumper) string() (int, string, error) {
+	start := d.offset
+	length_string, err := d.readUntil(':')
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	length, err := strconv.Atoi(length_string)
+
+	if err != nil || length < 0 {
+		d.offset = start
+		return 0, "", d.errorf("invalid string length [%s]", length_string)
+	}
+
+	if length > len(d.data)-d.offset {
+		return 0, "", d.errorf("string of %d bytes truncated", length)
+	}
+
+	value := string(d.data[d.offset : d.offset+length])
+	d.offset += length
+
+	return start, value, nil
+}
+
+// element writes an element and its sub elements
+func (d *dumper) element(path KeyPath, key string) error {
+	if d.offset >= len(d.data) {
+		return d.errorf("unexpected end")
+	}
+
+	start := d.offset
+
+	switch d.data[d.offset] {
+	case 'i':
+		d.offset++
+		integer, err := d.readUntil('e')
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := strconv.Atoi(integer); err != nil {
+			d.offset = start
+			return d.errorf("invalid integer [%s]", integer)
+		}
+
+		d.line(start, d.offset, path, "integer "+integer)
+	case 'l':
+		d.offset++
+		d.line(start, d.offset, path, "list")
+
+		for index := 0; d.offset < len(d.data) && d.data[d.offset] != 'e'; index++ {
+			if err := d.element(path.Index(index), ""); err != nil {
+				return err
+			}
+		}
+
+		if d.offset >= len(d.data) {
+			return d.errorf("unterminated list")
+		}
+
+		d.offset++
+		d.line(d.offset-1, d.offset, path, "end of list")
+	case 'd':
+		d.offset++
+		d.line(start, d.offset, path, "dictionary")
+
+		for d.offset < len(d.data) && d.data[d.offset] != 'e' {
+			key_start, sub_key, err := d.string()
+
+			if err != nil {
+				return err
+			}
+
+			d.line(key_start, d.offset, path.Key(sub_key), "key")
+
+			if err := d.element(path.Key(sub_key), sub_key); err != nil {
+				return err
+			}
+		}
+
+		if d.offset >= len(d.data) {
+			return d.errorf("unterminated dictionary")
+		}
+
+		d.offset++
+		d.line(d.offset-1, d.offset, path, "end of dictionary")
+	default:
+		if d.data[d.offset] < '0' || d.data[d.offset] > '9' {
+			return d.errorf("unexpected [%c]", d.data[d.offset])
+		}
+
+		_, value, err := d.string()
+
+		if err != nil {
+			return err
+		}
+
+		description := "string " + formatString(value)
+
+		if key == DictionaryKeyPieces && len(value)%20 == 0 && !isText(value) {
+			description = fmt.Sprintf("string <%d SHA-1 hashes>", len(value)/20)
+		}
+
+		d.line(start, d.offset, path, description)
+	}
+
+	return nil
+}
+
+// HexDump renders raw bencoded bytes as an hexadecimal dump, annotated with the key path of each byte range
+//
+// The dump is returned up to the first error
+func HexDump(data []byte) (string, error) {
+	d := dumper{
+		data: data,
+	}
+
+	if err := d.element(KeyPath{}, ""); err != nil {
+		return d.builder.String(), err
+	}
+
+	if d.offset < len(data) {
+		d.line(d.offset, len(data), KeyPath{}, "trailing data")
+	}
+
+	return d.builder.String(), nil
+}
@@ -0,0 +1,60 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHexDump(t *testing.T) {
+	output, err := HexDump([]byte("d4:infod6:lengthi3e6:pieces20:" + strings.Repeat("\x01", 20) + "e1:ll1:aee"))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"00000000  64                                               d                 . dictionary",
+		"00000001  34 3a 69 6e 66 6f                                4:info            info key",
+		"00000007  64                                               d                 info dictionary",
+		"00000008  36 3a 6c 65 6e 67 74 68                          6:length          info.length key",
+		"00000010  69 33 65                                         i3e               info.length integer 3",
+		"00000013  36 3a 70 69 65 63 65 73                          6:pieces          info.pieces key",
+		"0000001b  32 30 3a 01 01 01 01 01 01 01 01 01 01 01 01 01  20:.............  info.pieces string <1 SHA-1 hashes>",
+		"0000002b  01 01 01 01 01 01 01                             .......",
+		"00000032  65                                               e                 info end of dictionary",
+		"00000033  31 3a 6c                                         1:l               l key",
+		"00000036  6c                                               l                 l list",
+		"00000037  31 3a 61                                         1:a               l[0] string \"a\"",
+		"0000003a  65                                               e                 l end of list",
+		"0000003b  65                                               e                 . end of dictionary",
+	}
+
+	if lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\n| output\n%s", strings.Join(expected, "\n"), output)
+	}
+
+	errors_tests := []string{
+		"",
+		"d4:info",
+		"i12",
+		"ixe",
+		"l5:abce",
+		"x",
+		"d1:ai1e",
+		"9223372036854775807:abc", // the end offset would overflow
+		"d4:info9223372036854775807:abce",
+	}
+
+	for index, test := range errors_tests {
+		if _, err := HexDump([]byte(test)); !errors.Is(err, ErrorDumpCorrupted) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, ErrorDumpCorrupted, err)
+		}
+	}
+
+	output, err = HexDump([]byte("i1eXX"))
+
+	if err != nil || !strings.Contains(output, "trailing data") {
+		t.Errorf("expected trailing data | [%s] [%v] output", output, err)
+	}
+}
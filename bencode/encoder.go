@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
+
+	"github.com/trixky/gobencode/codec"
+	"github.com/trixky/gobencode/parser"
 )
 
 var (
@@ -63,6 +67,23 @@ func encodeDictionary(dictionary map[string]interface{}) (string, error) {
 	return encoded_dictionary + "e", nil
 }
 
+// encodeOrderedDictionary encodes an ordered dictionary in the bencode format, its keys are written in their order
+func encodeOrderedDictionary(dictionary *parser.OrderedDict) (string, error) {
+	encoded_dictionary := "d"
+
+	for _, entry := range dictionary.Entries {
+		encoded_dictionary += encodeString(entry.Key)
+
+		if encoded_element, err := encodeElement(entry.Value); err != nil {
+			return "", err
+		} else {
+			encoded_dictionary += encoded_element
+		}
+	}
+
+	return encoded_dictionary + "e", nil
+}
+
 // encodePieces encodes Pieces in the bencode format
 func encodePieces(pieces []Piece) string {
 	encoded_pieces := strconv.Itoa(len(pieces)*20) + ":"
@@ -82,10 +103,22 @@ func encodeFiles(files []File) (string, error) {
 			return "", ErrorFilePathIsMissing
 		}
 
-		encodedFiles += "d" + encodeString(DictionaryKeyLength) + encodeInteger(file.Length)
+		encodedFiles += "d"
+
+		if len(file.Attributes) > 0 {
+			encodedFiles += encodeString(DictionaryKeyAttr) + encodeString(file.Attributes)
+		}
+
+		encodedFiles += encodeString(DictionaryKeyLength) + encodeInteger(file.Length)
 		encodedFiles += encodeString(DictionaryKeyPath) + "l"
 
-		for _, path := range file.DecomposedPath {
+		path_components := file.DecomposedPath
+
+		if len(file.RawPath) > 0 {
+			path_components = file.RawPath
+		}
+
+		for _, path := range path_components {
 			if len(path) == 0 {
 				return "", ErrorFilePathIsMissing
 
@@ -98,17 +131,204 @@ func encodeFiles(files []File) (string, error) {
 	return encodedFiles + "e", nil
 }
 
+// rawName returns the name as stored in the torrent
+func (i *Info) rawName() string {
+	if len(i.RawName) > 0 {
+		return i.RawName
+	}
+
+	if i.isSingleFile() {
+		return i.Files[0].Path
+	}
+
+	return i.DirectoryName
+}
+
+// isSingleFile checks if the files are in the single file form: one file without a path
+func (i *Info) isSingleFile() bool {
+	return len(i.Files) == 1 && len(i.Files[0].RawPath) == 0 && len(i.Files[0].DecomposedPath) == 0
+}
+
+// encodingBencode returns the file encoded by AppendBencode, its display path is the raw one without a raw path
+func (f *File) encodingBencode() *File {
+	if len(f.RawPath) > 0 {
+		return f
+	}
+
+	file := *f
+	file.RawPath = f.DecomposedPath
+
+	return &file
+}
+
+// decodedBencode sets the display path of a file decoded by DecodeBencode
+func (f *File) decodedBencode() error {
+	if len(f.RawPath) == 0 {
+		return ErrorFilePathIsMissing
+	}
+
+	f.DecomposedPath = displayPath(f.RawPath, nil, "")
+	f.Path = strings.Join(f.DecomposedPath, "/")
+	f.CompletePath = ""
+
+	return nil
+}
+
+// pieceList is the pieces of an info dictionary, written as a single string of their hashes
+type pieceList []Piece
+
+// AppendBencode appends the hashes of the pieces as a single string
+func (p pieceList) AppendBencode(dst []byte) []byte {
+	dst = strconv.AppendInt(dst, int64(len(p)*20), 10)
+	dst = append(dst, ':')
+
+	for _, piece := range p {
+		dst = append(dst, piece[:]...)
+	}
+
+	return dst
+}
+
+// DecodeBencode reads the hashes of the pieces from a single string
+func (p *pieceList) DecodeBencode(d *codec.Decoder) error {
+	raw, err := d.ReadBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if len(raw)%20 != 0 {
+		return fmt.Errorf("%w: %v", ErrorLengthIsNotMultipleOf20, DictionaryKeyPieces)
+	}
+
+	*p = make(pieceList, len(raw)/20)
+
+	for index := range *p {
+		copy((*p)[index][:], raw[index*20:])
+	}
+
+	return nil
+}
+
+// infoWire is the dictionary form of an Info, its methods are generated
+//
+// A single file is the length and the attr of the dictionary, several files are its files list
+type infoWire struct {
+	Attr        string     `bencode:"attr,omitempty"`
+	Collections []string   `bencode:"collections,omitempty"`
+	Files       []File     `bencode:"files,omitempty"`
+	Length      *int       `bencode:"length"`
+	Name        string     `bencode:"name,required"`
+	PieceLength int        `bencode:"piece length,required"`
+	Pieces      pieceList  `bencode:"pieces,required"`
+	Similar     [][20]byte `bencode:"similar,omitempty"`
+}
+
+// wire returns the dictionary form of an info, it shares the files and the pieces of the info
+func (i *Info) wire() infoWire {
+	wire := infoWire{
+		Collections: i.Collections,
+		Name:        i.rawName(),
+		PieceLength: i.PieceLength,
+		Pieces:      pieceList(i.Pieces),
+		Similar:     i.Similar,
+	}
+
+	if i.isSingleFile() {
+		wire.Length, wire.Attr = &i.Files[0].Length, i.Files[0].Attributes
+	} else {
+		wire.Files = i.Files
+	}
+
+	return wire
+}
+
+// MarshalBencode encodes an Info as a bencoded dictionary
+func (i *Info) MarshalBencode() ([]byte, error) {
+	return i.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of an Info
+func (i *Info) AppendBencode(dst []byte) []byte {
+	wire := i.wire()
+
+	return wire.AppendBencode(dst)
+}
+
+// UnmarshalBencode decodes an Info from a bencoded dictionary
+func (i *Info) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := i.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads an Info from the dictionary at the position of a decoder
+//
+// The info is replaced, its display names are the raw ones
+func (i *Info) DecodeBencode(d *codec.Decoder) error {
+	wire := infoWire{}
+
+	if err := wire.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	info := Info{
+		PieceLength:   wire.PieceLength,
+		Pieces:        []Piece(wire.Pieces),
+		DirectoryName: displayString(wire.Name, nil, ""),
+		RawName:       wire.Name,
+		Similar:       wire.Similar,
+		Collections:   wire.Collections,
+	}
+
+	if len(wire.Files) > 0 {
+		info.Files = wire.Files
+
+		for index := range info.Files {
+			info.Files[index].CompletePath = info.DirectoryName + "/" + info.Files[index].Path
+		}
+	} else if wire.Length != nil {
+		info.Files = []File{{
+			Length:       *wire.Length,
+			Path:         info.DirectoryName,
+			CompletePath: info.DirectoryName,
+			Attributes:   wire.Attr,
+		}}
+	} else {
+		return fmt.Errorf("%w: %v", ErrorIntegerElementMissingInDictionary, DictionaryKeyLength)
+	}
+
+	info.file_offsets = computeOffsets(info.Files)
+	*i = info
+
+	return nil
+}
+
 // encodeInfo encodes an Info section in the bencode format
 func encodeInfo(info Info) (string, error) {
 	encoded_info := "d"
 
+	if len(info.Collections) > 0 {
+		encoded_info += encodeString(DictionaryKeyCollections) + "l"
+
+		for _, collection := range info.Collections {
+			encoded_info += encodeString(collection)
+		}
+
+		encoded_info += "e"
+	}
+
 	if len(info.Files) == 1 {
 		if len(info.DirectoryName) == 0 {
 			return "", ErrorFileNameIsMissing
 		}
 
 		encoded_info += encodeString(DictionaryKeyLength) + encodeInteger(info.Files[0].Length)
-		encoded_info += encodeString(DictionaryKeyName) + encodeString(info.Files[0].Path)
+		encoded_info += encodeString(DictionaryKeyName) + encodeString(info.rawName())
 	} else if len(info.Files) > 2 {
 		if len(info.DirectoryName) == 0 {
 			return "", ErrorDirectoryNameIsMissing
@@ -121,18 +341,35 @@ func encodeInfo(info Info) (string, error) {
 		}
 
 		encoded_info += encodedFiles
-		encoded_info += encodeString(DictionaryKeyName) + encodeString(info.DirectoryName)
+		encoded_info += encodeString(DictionaryKeyName) + encodeString(info.rawName())
 	}
 
 	encoded_info += encodeString(DictionaryKeyPieceLength) + encodeInteger(info.PieceLength)
 	encoded_info += encodeString(DictionaryKeyPieces) + encodePieces(info.Pieces)
 
+	if len(info.Similar) > 0 {
+		encoded_info += encodeString(DictionaryKeySimilar) + "l"
+
+		for _, info_hash := range info.Similar {
+			encoded_info += encodeString(string(info_hash[:]))
+		}
+
+		encoded_info += "e"
+	}
+
 	return encoded_info + "e", nil
 }
 
+// RawElement is an element already in the bencode format, it is written as it is
+//
+// It keeps the bytes of an element which must not change, like the info dictionary of a torrent
+type RawElement []byte
+
 // encodeElement encodes any type of element in the bencode format
 func encodeElement(element interface{}) (string, error) {
 	switch element.(type) {
+	case RawElement:
+		return string(element.(RawElement)), nil
 	case string:
 		return encodeString(element.(string)), nil
 	case int:
@@ -141,6 +378,8 @@ func encodeElement(element interface{}) (string, error) {
 		return encodeList(element.([]interface{}))
 	case map[string]interface{}:
 		return encodeDictionary(element.(map[string]interface{}))
+	case *parser.OrderedDict:
+		return encodeOrderedDictionary(element.(*parser.OrderedDict))
 	case []Piece:
 		return encodePieces(element.([]Piece)), nil
 	case Info:
@@ -149,3 +388,8 @@ func encodeElement(element interface{}) (string, error) {
 		return "", fmt.Errorf("%w: %T", ErrorTypeNotEncodable, element)
 	}
 }
+
+// EncodeElement encodes any type of element in the bencode format
+func EncodeElement(element interface{}) (string, error) {
+	return encodeElement(element)
+}
@@ -1,7 +1,15 @@
 package bencode
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/trixky/gobencode/codec"
+	"github.com/trixky/gobencode/parser"
 )
 
 func TestEncodeString(t *testing.T) {
@@ -146,6 +154,44 @@ func TestEncodeDictionary(t *testing.T) {
 	}
 }
 
+func TestEncodeOrderedDictionary(t *testing.T) {
+	tests := []struct {
+		input    *parser.OrderedDict
+		expected string
+	}{
+		{
+			input:    &parser.OrderedDict{},
+			expected: "de",
+		},
+		{
+			input:    &parser.OrderedDict{Entries: []parser.DictionaryEntry{{Key: "second", Value: 2}, {Key: "first", Value: 1}}},
+			expected: "d6:secondi2e5:firsti1ee",
+		},
+		{
+			input:    &parser.OrderedDict{Entries: []parser.DictionaryEntry{{Key: "b", Value: 1}, {Key: "b", Value: 2}}},
+			expected: "d1:bi1e1:bi2ee",
+		},
+		{
+			input:    parser.NewOrderedDict(map[string]interface{}{"test": 1, "list": []interface{}{map[string]interface{}{"chat": 8}}}),
+			expected: "d4:listld4:chati8eee4:testi1ee",
+		},
+	}
+
+	for index, test := range tests {
+		output, err := encodeElement(test.input)
+
+		if err != nil {
+			t.Errorf("failed to encode ordered dictionary %d: %v", index, err)
+			continue
+		}
+
+		if test.expected != output {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+			continue
+		}
+	}
+}
+
 func TestEncodePieces(t *testing.T) {
 	tests := []struct {
 		input    []Piece
@@ -205,6 +251,16 @@ func TestEncodeFiles(t *testing.T) {
 				},
 			},
 			expected: "5:filesld6:lengthi3400e4:pathl4:chat9:nooon.txteed6:lengthi12e4:pathl9:ouiii.txteee",
+		}, {
+			input: []File{
+				{
+					Length:         5,
+					Path:           ".pad/5",
+					DecomposedPath: []string{".pad", "5"},
+					Attributes:     "p",
+				},
+			},
+			expected: "5:filesld4:attr1:p6:lengthi5e4:pathl4:.pad1:5eee",
 		},
 	}
 
@@ -223,6 +279,185 @@ func TestEncodeFiles(t *testing.T) {
 	}
 }
 
+func TestFileMarshalBencode(t *testing.T) {
+	tests := []File{
+		{Length: 12, RawPath: []string{"ouiii.txt"}, DecomposedPath: []string{"ouiii.txt"}},
+		{Length: 3400, RawPath: []string{"chat", "nooon.txt"}, DecomposedPath: []string{"chat", "nooon.txt"}},
+		{Length: 5, RawPath: []string{".pad", "5"}, DecomposedPath: []string{".pad", "5"}, Attributes: "p"},
+	}
+
+	for index, test := range tests {
+		output, err := test.MarshalBencode()
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		expected, err := encodeFiles([]File{test})
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		// the dictionary of the file inside "5:filesl" ... "e"
+		if expected = expected[len("5:filesl") : len(expected)-1]; expected != string(output) {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, expected, output)
+		}
+
+		file := File{}
+
+		if err := file.UnmarshalBencode(output); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		if file.Length != test.Length || file.Attributes != test.Attributes || strings.Join(file.RawPath, "/") != strings.Join(test.RawPath, "/") {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, test, file)
+		}
+	}
+}
+
+func TestFileBencodeDerivedFields(t *testing.T) {
+	// the display path is encoded without a raw path
+	file := File{Length: 5, Path: "dir/a", DecomposedPath: []string{"dir", "a"}}
+
+	if output, _ := file.MarshalBencode(); string(output) != "d6:lengthi5e4:pathl3:dir1:aee" {
+		t.Errorf("expected [%s] | [%s] output", "d6:lengthi5e4:pathl3:dir1:aee", output)
+	}
+
+	if len(file.RawPath) > 0 {
+		t.Errorf("expected the file to be unchanged | [%+v] output", file)
+	}
+
+	// the display fields of a reused file are replaced
+	file = File{Path: "old", DecomposedPath: []string{"old"}, CompletePath: "dir/old", Attributes: "p"}
+	expected := File{Length: 3, Path: "b/c", DecomposedPath: []string{"b", "c"}, RawPath: []string{"b", "c"}, Attributes: "p"}
+
+	if err := file.UnmarshalBencode([]byte("d6:lengthi3e4:pathl1:b1:cee")); err != nil || !reflect.DeepEqual(file, expected) {
+		t.Errorf("expected [%+v] | [%+v] [%v] output", expected, file, err)
+	}
+
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"de", codec.ErrorMissingKey},
+		{"d6:lengthi3ee", codec.ErrorMissingKey},
+		{"d4:pathl1:aee", codec.ErrorMissingKey},
+		{"d6:lengthi3e4:pathlee", ErrorFilePathIsMissing},
+		{"d6:lengthi03e4:pathl1:aee", parser.ErrorNotCanonical},
+	}
+
+	for index, test := range tests {
+		if err := (&File{}).UnmarshalBencode([]byte(test.input)); !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+		}
+	}
+}
+
+func TestInfoBencodeTestFiles(t *testing.T) {
+	files := []string{
+		"../.test_files/arch.torrent",
+		"../.test_files/minecraft.torrent",
+		"../.test_files/ubuntu.torrent",
+	}
+
+	for index, file := range files {
+		data, err := os.ReadFile(file)
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		raw_info, err := parser.RawValue(data, DictionaryKeyInfo)
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		element, _, err := parser.ParseBytes(data, parser.Options{})
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		bc := Bencode{Data: element}
+
+		if err := bc.UnmarshallInfo(); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		info := Info{}
+
+		if err := info.UnmarshalBencode(raw_info); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		if !reflect.DeepEqual(info, bc.Info) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, bc.Info.Files, info.Files)
+		}
+
+		// the test files have no key without a field, the info hash is kept
+		if output, _ := info.MarshalBencode(); !bytes.Equal(output, raw_info) {
+			t.Errorf("test %d: expected the raw info | [%.80s] output", index, output)
+		}
+	}
+}
+
+func TestInfoBencode(t *testing.T) {
+	pieces := "d12:piece lengthi4e6:pieces20:" + strings.Repeat("h", 20)
+	piece := Piece{}
+	copy(piece[:], strings.Repeat("h", 20))
+
+	tests := []struct {
+		input    string
+		expected Info
+		err      error
+	}{
+		{
+			input: "d4:attr1:x6:lengthi0e4:name1:a" + pieces[1:] + "e",
+			expected: Info{
+				Files:         []File{{Length: 0, Path: "a", CompletePath: "a", Attributes: "x"}},
+				PieceLength:   4,
+				Pieces:        []Piece{piece},
+				DirectoryName: "a",
+				RawName:       "a",
+				file_offsets:  []int{0, 0},
+			},
+		},
+		{input: "d4:name1:a" + pieces[1:] + "e", err: ErrorIntegerElementMissingInDictionary},
+		{input: "d6:lengthi1e4:name1:a12:piece lengthi4e6:pieces3:abce", err: ErrorLengthIsNotMultipleOf20},
+		{input: "d6:lengthi1e4:name1:a12:piece lengthi4ee", err: codec.ErrorMissingKey},
+		{input: "d6:lengthi1e" + pieces[1:] + "e", err: codec.ErrorMissingKey},
+	}
+
+	for index, test := range tests {
+		output := Info{}
+		err := output.UnmarshalBencode([]byte(test.input))
+
+		if !errors.Is(err, test.err) || (err == nil && !reflect.DeepEqual(output, test.expected)) {
+			t.Errorf("test %d: expected [%+v] [%v] | [%+v] [%v] output", index, test.expected, test.err, output, err)
+			continue
+		}
+
+		if err == nil {
+			if data, _ := output.MarshalBencode(); string(data) != test.input {
+				t.Errorf("test %d: expected [%s] | [%s] output", index, test.input, data)
+			}
+		}
+	}
+
+	// a single file with a path is in the files list
+	info := Info{
+		Files:         []File{{Length: 1, Path: "b", DecomposedPath: []string{"b"}}},
+		PieceLength:   4,
+		DirectoryName: "a",
+	}
+
+	if data, _ := info.MarshalBencode(); string(data) != "d5:filesld6:lengthi1e4:pathl1:beee4:name1:a12:piece lengthi4e6:pieces0:e" {
+		t.Errorf("expected a files list | [%s] output", data)
+	}
+}
+
 func TestEncodeInfo(t *testing.T) {
 	tests := []struct {
 		Bc       Bencode
@@ -280,6 +515,28 @@ func TestEncodeInfo(t *testing.T) {
 			},
 			expected: "d5:filesld6:lengthi19710976e4:pathl13:resource1.bineed6:lengthi9050674e4:pathl12:resource.bineed6:lengthi1056768e4:pathl9:setup.exeeee4:name16:Minecraft 1.15.212:piece lengthi16384e6:pieces20:0123456789abcdefghije",
 		},
+		{
+			Bc: Bencode{
+				Info: Info{
+					Files: []File{
+						{
+							Length:         12,
+							Path:           "ouiii.txt",
+							DecomposedPath: []string{"ouiii.txt"},
+							CompletePath:   "ouiii.txt",
+						},
+					},
+					PieceLength: 233,
+					Pieces: []Piece{
+						{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j'},
+					},
+					DirectoryName: "ouiii.txt",
+					Similar:       [][20]byte{{'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z', 'A', 'B', 'C', 'D'}},
+					Collections:   []string{"series"},
+				},
+			},
+			expected: "d11:collectionsl6:seriese6:lengthi12e4:name9:ouiii.txt12:piece lengthi233e6:pieces20:0123456789abcdefghij7:similarl20:klmnopqrstuvwxyzABCDee",
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,135 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/trixky/gobencode/utils"
+)
+
+const (
+	DictionaryKeyEncoding = "encoding"
+	DictionaryKeyNameUTF8 = "name.utf-8"
+	DictionaryKeyPathUTF8 = "path.utf-8"
+)
+
+var (
+	ErrorUnknownEncoding = errors.New("unknown encoding")
+	ErrorInvalidEncoding = errors.New("invalid bytes for the encoding")
+)
+
+// Decoder converts a string of a legacy encoding (GBK, Shift_JIS...) to UTF-8
+type Decoder func(raw string) (string, error)
+
+var (
+	decoders_mutex sync.RWMutex
+	decoders       = map[string]Decoder{
+		"utf8":     decodeUTF8,
+		"usascii":  decodeUTF8,
+		"ascii":    decodeUTF8,
+		"iso88591": decodeLatin1,
+		"latin1":   decodeLatin1,
+	}
+)
+
+// normalizeEncoding returns the registry key of an encoding name: "Shift_JIS", "shift-jis" and "SHIFTJIS" are the same
+func normalizeEncoding(encoding string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToLower(encoding))
+}
+
+// decodeUTF8 checks that the string already is UTF-8
+func decodeUTF8(raw string) (string, error) {
+	if !utf8.ValidString(raw) {
+		return "", ErrorInvalidEncoding
+	}
+
+	return raw, nil
+}
+
+// decodeLatin1 converts ISO-8859-1, each byte is a code point
+func decodeLatin1(raw string) (string, error) {
+	runes := make([]rune, len(raw))
+
+	for index := 0; index < len(raw); index++ {
+		runes[index] = rune(raw[index])
+	}
+
+	return string(runes), nil
+}
+
+// RegisterDecoder adds or replaces the decoder of an encoding
+//
+// Only UTF-8, ASCII and Latin-1 are built in, other encodings can be plugged with golang.org/x/text:
+//
+//	bencode.RegisterDecoder("GBK", func(raw string) (string, error) {
+//		return simplifiedchinese.GBK.NewDecoder().String(raw)
+//	})
+func RegisterDecoder(encoding string, decoder Decoder) {
+	decoders_mutex.Lock()
+	defer decoders_mutex.Unlock()
+
+	decoders[normalizeEncoding(encoding)] = decoder
+}
+
+// Decode converts a string of an encoding to UTF-8 with the registered decoders
+func Decode(encoding string, raw string) (string, error) {
+	decoders_mutex.RLock()
+	decoder, ok := decoders[normalizeEncoding(encoding)]
+	decoders_mutex.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrorUnknownEncoding, encoding)
+	}
+
+	return decoder(raw)
+}
+
+// displayString chooses the text shown for a raw string: its UTF-8 alternate,
+// the raw string decoded with the declared encoding, or the raw string as is
+func displayString(raw string, alternate interface{}, encoding string) string {
+	if alternate, ok := alternate.(string); ok && utf8.ValidString(alternate) {
+		return alternate
+	}
+
+	if len(encoding) == 0 || utf8.ValidString(raw) && normalizeEncoding(encoding) == "utf8" {
+		return raw
+	}
+
+	if decoded, err := Decode(encoding, raw); err == nil {
+		return decoded
+	}
+
+	return raw
+}
+
+// displayPath chooses the path components shown, see displayString
+func displayPath(raw []string, alternate interface{}, encoding string) []string {
+	if alternate, err := utils.ToStringList(alternate); err == nil && len(alternate) == len(raw) {
+		valid := true
+
+		for _, component := range alternate {
+			valid = valid && utf8.ValidString(component)
+		}
+
+		if valid {
+			return alternate
+		}
+	}
+
+	display := make([]string, len(raw))
+
+	for index, component := range raw {
+		display[index] = displayString(component, nil, encoding)
+	}
+
+	return display
+}
@@ -0,0 +1,157 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	RegisterDecoder("X-Upper_Test", func(raw string) (string, error) {
+		return strings.ToUpper(raw), nil
+	})
+
+	tests := []struct {
+		encoding string
+		input    string
+		expected string
+		err      error
+	}{
+		{"UTF-8", "été", "été", nil},
+		{"utf8", "\xe9t\xe9", "", ErrorInvalidEncoding},
+		{"ISO-8859-1", "\xe9t\xe9", "été", nil},
+		{"latin1", "abc", "abc", nil},
+		{"x-upper-test", "abc", "ABC", nil},
+		{"KOI8-R", "abc", "", ErrorUnknownEncoding},
+	}
+
+	for index, test := range tests {
+		output, err := Decode(test.encoding, test.input)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, test.err, err)
+			continue
+		}
+
+		if output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestUnmarshallEncodedNames(t *testing.T) {
+	tests := []struct {
+		data          map[string]interface{}
+		name          string
+		raw_name      string
+		paths         []string
+		raw_paths     [][]string
+		complete_path string
+	}{
+		{
+			// name.utf-8 and path.utf-8 are preferred
+			data: map[string]interface{}{
+				DictionaryKeyEncoding: "GBK",
+				DictionaryKeyInfo: map[string]interface{}{
+					DictionaryKeyName:     "\xd6\xd0\xce\xc4",
+					DictionaryKeyNameUTF8: "中文",
+					DictionaryKeyFiles: []interface{}{
+						map[string]interface{}{
+							DictionaryKeyLength:   1,
+							DictionaryKeyPath:     []interface{}{"\xce\xc4", "a.txt"},
+							DictionaryKeyPathUTF8: []interface{}{"文", "a.txt"},
+						},
+					},
+				},
+			},
+			name:          "中文",
+			raw_name:      "\xd6\xd0\xce\xc4",
+			paths:         []string{"文/a.txt"},
+			raw_paths:     [][]string{{"\xce\xc4", "a.txt"}},
+			complete_path: "中文/文/a.txt",
+		},
+		{
+			// the declared encoding is decoded
+			data: map[string]interface{}{
+				DictionaryKeyEncoding: "ISO-8859-1",
+				DictionaryKeyInfo: map[string]interface{}{
+					DictionaryKeyName: "caf\xe9",
+					DictionaryKeyFiles: []interface{}{
+						map[string]interface{}{
+							DictionaryKeyLength: 1,
+							DictionaryKeyPath:   []interface{}{"cr\xe8me.txt"},
+						},
+					},
+				},
+			},
+			name:          "café",
+			raw_name:      "caf\xe9",
+			paths:         []string{"crème.txt"},
+			raw_paths:     [][]string{{"cr\xe8me.txt"}},
+			complete_path: "café/crème.txt",
+		},
+		{
+			// unknown encodings and invalid alternates keep the raw names
+			data: map[string]interface{}{
+				DictionaryKeyEncoding: "Shift_JIS",
+				DictionaryKeyInfo: map[string]interface{}{
+					DictionaryKeyName:     "\x93\xfa\x96\x7b.txt",
+					DictionaryKeyNameUTF8: "\xff",
+					DictionaryKeyLength:   1,
+				},
+			},
+			name:          "\x93\xfa\x96\x7b.txt",
+			raw_name:      "\x93\xfa\x96\x7b.txt",
+			paths:         []string{"\x93\xfa\x96\x7b.txt"},
+			raw_paths:     [][]string{nil},
+			complete_path: "\x93\xfa\x96\x7b.txt",
+		},
+	}
+
+	for index, test := range tests {
+		test.data[DictionaryKeyInfo].(map[string]interface{})[DictionaryKeyPieceLength] = 16384
+		test.data[DictionaryKeyInfo].(map[string]interface{})[DictionaryKeyPieces] = string(make([]byte, 20))
+
+		bc := Bencode{
+			Data: test.data,
+		}
+
+		if err := bc.UnmarshallInfo(); err != nil {
+			t.Errorf("test %d: failed to unmarshall info: %v", index, err)
+			continue
+		}
+
+		if bc.Info.DirectoryName != test.name || bc.Info.RawName != test.raw_name {
+			t.Errorf("test %d: expected [%s] [%q] | [%s] [%q] name", index, test.name, test.raw_name, bc.Info.DirectoryName, bc.Info.RawName)
+		}
+
+		paths := []string{}
+		raw_paths := [][]string{}
+
+		for _, file := range bc.Info.Files {
+			paths = append(paths, file.Path)
+			raw_paths = append(raw_paths, file.RawPath)
+		}
+
+		if !reflect.DeepEqual(paths, test.paths) || !reflect.DeepEqual(raw_paths, test.raw_paths) {
+			t.Errorf("test %d: expected %q %q | %q %q paths", index, test.paths, test.raw_paths, paths, raw_paths)
+		}
+
+		if bc.Info.Files[0].CompletePath != test.complete_path {
+			t.Errorf("test %d: expected [%s] | [%s] complete path", index, test.complete_path, bc.Info.Files[0].CompletePath)
+		}
+
+		// the encoder writes the raw names back
+		encoded, err := encodeInfo(bc.Info)
+
+		if err != nil {
+			t.Errorf("test %d: failed to encode: %v", index, err)
+			continue
+		}
+
+		if !strings.Contains(encoded, encodeString(DictionaryKeyName)+encodeString(test.raw_name)) {
+			t.Errorf("test %d: expected the raw name in [%q]", index, encoded)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// Code generated by bencodegen. DO NOT EDIT.
+
+package bencode
+
+import (
+	"github.com/trixky/gobencode/codec"
+)
+
+// MarshalBencode encodes a File as a bencoded dictionary
+func (x *File) MarshalBencode() ([]byte, error) {
+	return x.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of a File
+func (x *File) AppendBencode(dst []byte) []byte {
+	x = x.encodingBencode()
+	dst = codec.AppendDictionaryStart(dst)
+
+	if len(x.Attributes) > 0 {
+		dst = codec.AppendString(dst, "attr")
+		dst = codec.AppendString(dst, x.Attributes)
+	}
+
+	dst = codec.AppendString(dst, "length")
+	dst = codec.AppendInt(dst, int64(x.Length))
+
+	dst = codec.AppendString(dst, "path")
+	dst = codec.AppendListStart(dst)
+
+	for _, v1 := range x.RawPath {
+		dst = codec.AppendString(dst, v1)
+	}
+
+	dst = codec.AppendEnd(dst)
+
+	return codec.AppendEnd(dst)
+}
+
+// UnmarshalBencode decodes a File from a bencoded dictionary
+func (x *File) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := x.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads a File from the dictionary at the position of a decoder
+//
+// The unknown keys are skipped, the fields without a bencode tag are set by decodedBencode
+func (x *File) DecodeBencode(d *codec.Decoder) error {
+	start := d.Offset()
+	found := [2]bool{}
+
+	if err := d.ReadDictionaryStart(); err != nil {
+		return err
+	}
+
+	var previous []byte
+
+	for d.More() {
+		key, err := d.ReadKey(previous)
+
+		if err != nil {
+			return err
+		}
+
+		previous = key
+
+		switch string(key) {
+		case "attr":
+			v0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			x.Attributes = v0
+		case "length":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int(r0)
+			x.Length = v0
+			found[0] = true
+		case "path":
+			var v0 []string
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				v1, err := d.ReadString()
+
+				if err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.RawPath = v0
+			found[1] = true
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.ReadEnd(); err != nil {
+		return err
+	}
+
+	if !found[0] {
+		return codec.MissingKey(start, "length")
+	}
+
+	if !found[1] {
+		return codec.MissingKey(start, "path")
+	}
+
+	return x.decodedBencode()
+}
+
+// MarshalBencode encodes a infoWire as a bencoded dictionary
+func (x *infoWire) MarshalBencode() ([]byte, error) {
+	return x.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of a infoWire
+func (x *infoWire) AppendBencode(dst []byte) []byte {
+	dst = codec.AppendDictionaryStart(dst)
+
+	if len(x.Attr) > 0 {
+		dst = codec.AppendString(dst, "attr")
+		dst = codec.AppendString(dst, x.Attr)
+	}
+
+	if len(x.Collections) > 0 {
+		dst = codec.AppendString(dst, "collections")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Collections {
+			dst = codec.AppendString(dst, v1)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	if len(x.Files) > 0 {
+		dst = codec.AppendString(dst, "files")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Files {
+			dst = v1.AppendBencode(dst)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	if x.Length != nil {
+		dst = codec.AppendString(dst, "length")
+		dst = codec.AppendInt(dst, int64(*x.Length))
+	}
+
+	dst = codec.AppendString(dst, "name")
+	dst = codec.AppendString(dst, x.Name)
+
+	dst = codec.AppendString(dst, "piece length")
+	dst = codec.AppendInt(dst, int64(x.PieceLength))
+
+	dst = codec.AppendString(dst, "pieces")
+	dst = x.Pieces.AppendBencode(dst)
+
+	if len(x.Similar) > 0 {
+		dst = codec.AppendString(dst, "similar")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Similar {
+			dst = codec.AppendBytes(dst, v1[:])
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	return codec.AppendEnd(dst)
+}
+
+// UnmarshalBencode decodes a infoWire from a bencoded dictionary
+func (x *infoWire) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := x.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads a infoWire from the dictionary at the position of a decoder
+//
+// The unknown keys are skipped, the fields without a bencode tag are kept
+func (x *infoWire) DecodeBencode(d *codec.Decoder) error {
+	start := d.Offset()
+	found := [3]bool{}
+
+	if err := d.ReadDictionaryStart(); err != nil {
+		return err
+	}
+
+	var previous []byte
+
+	for d.More() {
+		key, err := d.ReadKey(previous)
+
+		if err != nil {
+			return err
+		}
+
+		previous = key
+
+		switch string(key) {
+		case "attr":
+			v0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			x.Attr = v0
+		case "collections":
+			var v0 []string
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				v1, err := d.ReadString()
+
+				if err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Collections = v0
+		case "files":
+			var v0 []File
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				var v1 File
+
+				if err := v1.DecodeBencode(d); err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Files = v0
+		case "length":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			p0 := int(r0)
+			v0 := &p0
+			x.Length = v0
+		case "name":
+			v0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			x.Name = v0
+			found[0] = true
+		case "piece length":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int(r0)
+			x.PieceLength = v0
+			found[1] = true
+		case "pieces":
+			var v0 pieceList
+
+			if err := v0.DecodeBencode(d); err != nil {
+				return err
+			}
+
+			x.Pieces = v0
+			found[2] = true
+		case "similar":
+			var v0 [][20]byte
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				var v1 [20]byte
+
+				if err := d.ReadFixedBytes(v1[:]); err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Similar = v0
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.ReadEnd(); err != nil {
+		return err
+	}
+
+	if !found[0] {
+		return codec.MissingKey(start, "name")
+	}
+
+	if !found[1] {
+		return codec.MissingKey(start, "piece length")
+	}
+
+	if !found[2] {
+		return codec.MissingKey(start, "pieces")
+	}
+
+	return nil
+}
@@ -5,8 +5,19 @@ import (
 )
 
 // GetInfoHash encodes the info section an generate his hash
+//
+// The parsed info dictionary is preferred to the Info structure when available,
+// so keys unknown to Info (private, source...) are part of the hash
 func (b *Bencode) GetInfoHash() error {
-	encoded_info, err := encodeElement(b.Info)
+	var info interface{} = b.Info
+
+	if dictionary, ok := b.Data.(map[string]interface{}); ok {
+		if info_dictionary, ok := dictionary[DictionaryKeyInfo].(map[string]interface{}); ok {
+			info = info_dictionary
+		}
+	}
+
+	encoded_info, err := encodeElement(info)
 
 	if err != nil {
 		return err
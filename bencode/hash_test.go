@@ -2,6 +2,7 @@ package bencode
 
 import (
 	"bufio"
+	"crypto/sha1"
 	"os"
 	"testing"
 
@@ -70,3 +71,31 @@ func TestGetInfoHash(t *testing.T) {
 		}
 	}
 }
+
+func TestGetInfoHashUnknownKeys(t *testing.T) {
+	bc := Bencode{
+		Data: map[string]interface{}{
+			DictionaryKeyInfo: map[string]interface{}{
+				DictionaryKeyLength:      12,
+				DictionaryKeyName:        "ouiii.txt",
+				DictionaryKeyPieceLength: 16384,
+				DictionaryKeyPieces:      "0123456789abcdefghij",
+				"private":                1,
+			},
+		},
+	}
+
+	if err := bc.UnmarshallInfo(); err != nil {
+		t.Fatalf("failed to unmarshall info: %v", err)
+	}
+
+	if err := bc.GetInfoHash(); err != nil {
+		t.Fatalf("failed to get info hash: %v", err)
+	}
+
+	expected := sha1.Sum([]byte("d6:lengthi12e4:name9:ouiii.txt12:piece lengthi16384e6:pieces20:0123456789abcdefghij7:privatei1ee"))
+
+	if expected != bc.InfoHash {
+		t.Errorf("expected %v | %v output", expected, bc.InfoHash)
+	}
+}
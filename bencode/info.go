@@ -0,0 +1,226 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	FileAttributePadding    = 'p'
+	FileAttributeExecutable = 'x'
+	FileAttributeHidden     = 'h'
+	FileAttributeSymlink    = 'l'
+)
+
+var (
+	ErrorPieceIndexOutOfRange = errors.New("piece index out of range")
+	ErrorFileIndexOutOfRange  = errors.New("file index out of range")
+	ErrorInvalidPieceLength   = errors.New("invalid piece length")
+	ErrorPieceAfterEnd        = errors.New("piece after the end of the files")
+)
+
+// FileSpan is the part of a file covered by a piece
+type FileSpan struct {
+	FileIndex int
+	Offset    int // offset in the file
+	Length    int
+}
+
+// IsPadding checks if the file is a padding file, its content is made of zeros and is not written on disk
+//
+// http://www.bittorrent.org/beps/bep_0047.html
+func (f *File) IsPadding() bool {
+	return strings.ContainsRune(f.Attributes, FileAttributePadding)
+}
+
+// TotalLength returns the length of all the files, padding files included
+func (i *Info) TotalLength() int {
+	offsets := i.offsets()
+
+	return offsets[len(offsets)-1]
+}
+
+// PieceCount returns the number of pieces
+func (i *Info) PieceCount() int {
+	return len(i.Pieces)
+}
+
+// computeOffsets returns the offset of each file in the concatenation of all the files, followed by the total length
+func computeOffsets(files []File) []int {
+	offsets := make([]int, len(files)+1)
+
+	for index, file := range files {
+		offsets[index+1] = offsets[index] + file.Length
+	}
+
+	return offsets
+}
+
+// offsets returns the offsets of the files and the total length, cached by UnmarshallInfo
+//
+// the cache is used only if it still matches the file lengths, an info built or changed by hand gets fresh offsets
+func (i *Info) offsets() []int {
+	if len(i.file_offsets) != len(i.Files)+1 {
+		return computeOffsets(i.Files)
+	}
+
+	for index, file := range i.Files {
+		if i.file_offsets[index+1]-i.file_offsets[index] != file.Length {
+			return computeOffsets(i.Files)
+		}
+	}
+
+	return i.file_offsets
+}
+
+// FileOffsets returns the offset of each file in the concatenation of all the files
+func (i *Info) FileOffsets() []int {
+	return append([]int{}, i.offsets()[:len(i.Files)]...)
+}
+
+// FileOffset returns the offset of a file in the concatenation of all the files
+func (i *Info) FileOffset(file_index int) (int, error) {
+	if file_index < 0 || file_index >= len(i.Files) {
+		return 0, fmt.Errorf("%w: %d out of %d files", ErrorFileIndexOutOfRange, file_index, len(i.Files))
+	}
+
+	return i.offsets()[file_index], nil
+}
+
+// PieceLengthAt returns the length of a piece, only the last one can be shorter than PieceLength
+//
+// a piece starting after the end of the files (more pieces than data) is an error
+func (i *Info) PieceLengthAt(piece_index int) (int, error) {
+	if piece_index < 0 || piece_index >= len(i.Pieces) {
+		return 0, fmt.Errorf("%w: %d out of %d pieces", ErrorPieceIndexOutOfRange, piece_index, len(i.Pieces))
+	}
+
+	if i.PieceLength <= 0 {
+		return 0, fmt.Errorf("%w: %d", ErrorInvalidPieceLength, i.PieceLength)
+	}
+
+	start := piece_index * i.PieceLength
+	total_length := i.TotalLength()
+
+	if start >= total_length {
+		return 0, fmt.Errorf("%w: piece %d starts at %d, the files end at %d", ErrorPieceAfterEnd, piece_index, start, total_length)
+	}
+
+	if end := start + i.PieceLength; end > total_length {
+		return total_length - start, nil
+	}
+
+	return i.PieceLength, nil
+}
+
+// LastPieceLength returns the length of the last piece
+func (i *Info) LastPieceLength() int {
+	length, _ := i.PieceLengthAt(len(i.Pieces) - 1)
+
+	return length
+}
+
+// PieceSpans returns the parts of the files covered by a piece, in order
+//
+// zero-length files are not part of any piece, padding files are
+func (i *Info) PieceSpans(piece_index int) ([]FileSpan, error) {
+	piece_length, err := i.PieceLengthAt(piece_index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := piece_index * i.PieceLength
+	end := start + piece_length
+	spans := []FileSpan{}
+	offsets := i.offsets()
+
+	// first file ending after the start of the piece
+	first := sort.Search(len(i.Files), func(file_index int) bool {
+		return offsets[file_index+1] > start
+	})
+
+	for file_index := first; file_index < len(i.Files) && offsets[file_index] < end; file_index++ {
+		file_offset, file_end := offsets[file_index], offsets[file_index+1]
+
+		if file_end == file_offset {
+			continue
+		}
+
+		span_start := file_offset
+
+		if span_start < start {
+			span_start = start
+		}
+
+		span_end := file_end
+
+		if span_end > end {
+			span_end = end
+		}
+
+		spans = append(spans, FileSpan{
+			FileIndex: file_index,
+			Offset:    span_start - file_offset,
+			Length:    span_end - span_start,
+		})
+	}
+
+	return spans, nil
+}
+
+// FilePieces returns the first and the last piece overlapping a file
+//
+// a zero-length file overlaps no piece, last is then first - 1 so ranging from first to last does nothing
+func (i *Info) FilePieces(file_index int) (first int, last int, err error) {
+	if file_index < 0 || file_index >= len(i.Files) {
+		return 0, 0, fmt.Errorf("%w: %d out of %d files", ErrorFileIndexOutOfRange, file_index, len(i.Files))
+	}
+
+	if i.PieceLength <= 0 {
+		return 0, 0, fmt.Errorf("%w: %d", ErrorInvalidPieceLength, i.PieceLength)
+	}
+
+	offset := i.offsets()[file_index]
+	first = offset / i.PieceLength
+
+	if i.Files[file_index].Length == 0 {
+		return first, first - 1, nil
+	}
+
+	return first, (offset + i.Files[file_index].Length - 1) / i.PieceLength, nil
+}
+
+// FileWholePieces returns the first and the last piece lying wholly inside a file
+//
+// the last piece of the torrent can be shorter, last is first - 1 if the file contains no whole piece
+func (i *Info) FileWholePieces(file_index int) (first int, last int, err error) {
+	if file_index < 0 || file_index >= len(i.Files) {
+		return 0, 0, fmt.Errorf("%w: %d out of %d files", ErrorFileIndexOutOfRange, file_index, len(i.Files))
+	}
+
+	if i.PieceLength <= 0 {
+		return 0, 0, fmt.Errorf("%w: %d", ErrorInvalidPieceLength, i.PieceLength)
+	}
+
+	offset := i.offsets()[file_index]
+	end := offset + i.Files[file_index].Length
+	first = (offset + i.PieceLength - 1) / i.PieceLength
+	last = end/i.PieceLength - 1
+
+	if end == i.TotalLength() && end%i.PieceLength != 0 {
+		last = end / i.PieceLength
+	}
+
+	if last >= len(i.Pieces) {
+		last = len(i.Pieces) - 1
+	}
+
+	if last < first {
+		return first, first - 1, nil
+	}
+
+	return first, last, nil
+}
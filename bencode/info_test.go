@@ -0,0 +1,317 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testInfo returns an info of 3 pieces of 4 bytes: a(5) empty(0) padding(3) b(4)
+func testInfo() Info {
+	return Info{
+		PieceLength: 4,
+		Pieces:      make([]Piece, 3),
+		Files: []File{
+			{Length: 5, Path: "a"},
+			{Length: 0, Path: "empty"},
+			{Length: 3, Path: ".pad/3", Attributes: "p"},
+			{Length: 4, Path: "b"},
+		},
+	}
+}
+
+func TestInfoLengths(t *testing.T) {
+	info := testInfo()
+
+	if info.TotalLength() != 12 {
+		t.Errorf("expected [12] | [%d] total length", info.TotalLength())
+	}
+
+	if info.PieceCount() != 3 {
+		t.Errorf("expected [3] | [%d] piece count", info.PieceCount())
+	}
+
+	if expected := []int{0, 5, 5, 8}; !reflect.DeepEqual(info.FileOffsets(), expected) {
+		t.Errorf("expected %v | %v file offsets", expected, info.FileOffsets())
+	}
+
+	if info.LastPieceLength() != 4 {
+		t.Errorf("expected [4] | [%d] last piece length", info.LastPieceLength())
+	}
+
+	info.Files[3].Length = 1
+
+	if info.LastPieceLength() != 1 {
+		t.Errorf("expected [1] | [%d] last piece length", info.LastPieceLength())
+	}
+
+	if !info.Files[2].IsPadding() || info.Files[0].IsPadding() {
+		t.Errorf("expected only the third file to be a padding file")
+	}
+}
+
+func TestPieceSpans(t *testing.T) {
+	info := testInfo()
+
+	tests := []struct {
+		input    int
+		expected []FileSpan
+	}{
+		{
+			input:    0,
+			expected: []FileSpan{{FileIndex: 0, Offset: 0, Length: 4}},
+		},
+		{
+			input:    1,
+			expected: []FileSpan{{FileIndex: 0, Offset: 4, Length: 1}, {FileIndex: 2, Offset: 0, Length: 3}},
+		},
+		{
+			input:    2,
+			expected: []FileSpan{{FileIndex: 3, Offset: 0, Length: 4}},
+		},
+	}
+
+	for index, test := range tests {
+		output, err := info.PieceSpans(test.input)
+
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+
+	for _, piece_index := range []int{-1, 3} {
+		if _, err := info.PieceSpans(piece_index); !errors.Is(err, ErrorPieceIndexOutOfRange) {
+			t.Errorf("piece %d: expected [%v] | [%v] error", piece_index, ErrorPieceIndexOutOfRange, err)
+		}
+	}
+}
+
+func TestPieceSpansCachedOffsets(t *testing.T) {
+	info := testInfo()
+	expected := [][]FileSpan{}
+
+	for piece_index := range info.Pieces {
+		spans, _ := info.PieceSpans(piece_index)
+		expected = append(expected, spans)
+	}
+
+	info.file_offsets = computeOffsets(info.Files)
+
+	for piece_index := range info.Pieces {
+		if output, err := info.PieceSpans(piece_index); err != nil || !reflect.DeepEqual(output, expected[piece_index]) {
+			t.Errorf("piece %d: expected %v | %v output (%v)", piece_index, expected[piece_index], output, err)
+		}
+	}
+
+	if offset, err := info.FileOffset(3); err != nil || offset != 8 {
+		t.Errorf("expected [8] | [%d] offset (%v)", offset, err)
+	}
+
+	// a file appended by hand is not missed by the cache
+	info.Files = append(info.Files, File{Length: 4, Path: "c"})
+	info.Pieces = append(info.Pieces, Piece{})
+
+	if output, err := info.PieceSpans(3); err != nil || !reflect.DeepEqual(output, []FileSpan{{FileIndex: 4, Offset: 0, Length: 4}}) {
+		t.Errorf("expected the appended file | %v output (%v)", output, err)
+	}
+
+	// nor a file length changed in place
+	info.Files[0].Length = 2
+
+	if offset, err := info.FileOffset(3); err != nil || offset != 5 {
+		t.Errorf("expected [5] | [%d] offset (%v)", offset, err)
+	}
+
+	if total_length := info.TotalLength(); total_length != 13 {
+		t.Errorf("expected [13] | [%d] total length", total_length)
+	}
+}
+
+func TestPieceLengthAtErrors(t *testing.T) {
+	tests := []struct {
+		piece_length int
+		pieces       int
+		piece_index  int
+		expected     error
+	}{
+		{piece_length: 4, pieces: 3, piece_index: 3, expected: ErrorPieceIndexOutOfRange},
+		{piece_length: 0, pieces: 3, piece_index: 0, expected: ErrorInvalidPieceLength},
+		{piece_length: -4, pieces: 3, piece_index: 0, expected: ErrorInvalidPieceLength},
+		{piece_length: 4, pieces: 5, piece_index: 3, expected: ErrorPieceAfterEnd}, // more pieces than data
+		{piece_length: 4, pieces: 5, piece_index: 2},
+	}
+
+	for index, test := range tests {
+		info := testInfo()
+		info.PieceLength = test.piece_length
+		info.Pieces = make([]Piece, test.pieces)
+
+		length, err := info.PieceLengthAt(test.piece_index)
+
+		if !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+
+		if length < 0 {
+			t.Errorf("test %d: negative length %d", index, length)
+		}
+	}
+}
+
+func TestFilePieces(t *testing.T) {
+	info := testInfo()
+
+	tests := []struct {
+		input int
+		first int
+		last  int
+	}{
+		{input: 0, first: 0, last: 1},
+		{input: 1, first: 1, last: 0}, // zero-length file
+		{input: 2, first: 1, last: 1},
+		{input: 3, first: 2, last: 2},
+	}
+
+	for index, test := range tests {
+		first, last, err := info.FilePieces(test.input)
+
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if first != test.first || last != test.last {
+			t.Errorf("test %d: expected [%d %d] | [%d %d] output", index, test.first, test.last, first, last)
+		}
+	}
+
+	if _, _, err := info.FilePieces(4); !errors.Is(err, ErrorFileIndexOutOfRange) {
+		t.Errorf("expected [%v] | [%v] error", ErrorFileIndexOutOfRange, err)
+	}
+
+	info.PieceLength = 0
+
+	if _, _, err := info.FilePieces(0); !errors.Is(err, ErrorInvalidPieceLength) {
+		t.Errorf("expected [%v] | [%v] error", ErrorInvalidPieceLength, err)
+	}
+}
+
+func TestFileWholePieces(t *testing.T) {
+	short_last_piece := Info{
+		PieceLength: 4,
+		Pieces:      make([]Piece, 3),
+		Files:       []File{{Length: 6, Path: "a"}, {Length: 4, Path: "b"}},
+	}
+
+	tests := []struct {
+		info  Info
+		input int
+		first int
+		last  int
+	}{
+		{info: testInfo(), input: 0, first: 0, last: 0},
+		{info: testInfo(), input: 1, first: 2, last: 1}, // zero-length file
+		{info: testInfo(), input: 2, first: 2, last: 1},
+		{info: testInfo(), input: 3, first: 2, last: 2},
+		{info: short_last_piece, input: 0, first: 0, last: 0},
+		{info: short_last_piece, input: 1, first: 2, last: 2},
+	}
+
+	for index, test := range tests {
+		first, last, err := test.info.FileWholePieces(test.input)
+
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if first != test.first || last != test.last {
+			t.Errorf("test %d: expected [%d %d] | [%d %d] output", index, test.first, test.last, first, last)
+		}
+	}
+
+	info := testInfo()
+
+	if _, _, err := info.FileWholePieces(4); !errors.Is(err, ErrorFileIndexOutOfRange) {
+		t.Errorf("expected [%v] | [%v] error", ErrorFileIndexOutOfRange, err)
+	}
+}
+
+func TestUnmarshallAttributes(t *testing.T) {
+	bc := Bencode{
+		Data: map[string]interface{}{
+			DictionaryKeyInfo: map[string]interface{}{
+				DictionaryKeyName:        "dir",
+				DictionaryKeyPieceLength: 4,
+				DictionaryKeyPieces:      string(make([]byte, 40)),
+				DictionaryKeyFiles: []interface{}{
+					map[string]interface{}{DictionaryKeyLength: 3, DictionaryKeyPath: []interface{}{"a"}, DictionaryKeyAttr: "x"},
+					map[string]interface{}{DictionaryKeyLength: 1, DictionaryKeyPath: []interface{}{".pad", "1"}, DictionaryKeyAttr: "p"},
+					map[string]interface{}{DictionaryKeyLength: 4, DictionaryKeyPath: []interface{}{"b"}},
+				},
+			},
+		},
+	}
+
+	if err := bc.UnmarshallInfo(); err != nil {
+		t.Fatalf("failed to unmarshall info: %v", err)
+	}
+
+	attributes := []string{}
+
+	for _, file := range bc.Info.Files {
+		attributes = append(attributes, file.Attributes)
+	}
+
+	if expected := []string{"x", "p", ""}; !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("expected %v | %v attributes", expected, attributes)
+	}
+}
+
+func TestUnmarshallSimilar(t *testing.T) {
+	info_hash := "0123456789abcdefghij"
+
+	tests := []struct {
+		similar     interface{}
+		collections interface{}
+		expected    Info
+	}{
+		{
+			similar:     []interface{}{info_hash, "too short"},
+			collections: []interface{}{"series", "season 1"},
+			expected:    Info{Similar: [][20]byte{{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j'}}, Collections: []string{"series", "season 1"}},
+		},
+		{
+			similar:     info_hash,
+			collections: []interface{}{1},
+			expected:    Info{},
+		},
+		{
+			expected: Info{},
+		},
+	}
+
+	for index, test := range tests {
+		info_dictionary := map[string]interface{}{}
+
+		if test.similar != nil {
+			info_dictionary[DictionaryKeySimilar] = test.similar
+		}
+
+		if test.collections != nil {
+			info_dictionary[DictionaryKeyCollections] = test.collections
+		}
+
+		info := Info{}
+		info.unmarshallSimilar(info_dictionary)
+
+		if !reflect.DeepEqual(info, test.expected) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.expected, info)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package bencode
+
+import (
+	"github.com/trixky/gobencode/parser"
+)
+
+// KeyPath locates an element in a bencoded tree, it is made of dictionary keys (string) and list indexes (int)
+type KeyPath []interface{}
+
+// Key returns a copy of the path followed by a dictionary key
+func (p KeyPath) Key(key string) KeyPath {
+	return append(append(KeyPath{}, p...), key)
+}
+
+// Index returns a copy of the path followed by a list index
+func (p KeyPath) Index(index int) KeyPath {
+	return append(append(KeyPath{}, p...), index)
+}
+
+// String returns the path as info.files[0].path or info["piece length"], the root is "."
+func (p KeyPath) String() string {
+	return parser.FormatPath(p)
+}
+
+// HasPrefix checks if the path starts with some segments
+func (p KeyPath) HasPrefix(prefix ...interface{}) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+
+	for index, segment := range prefix {
+		if p[index] != segment {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,39 @@
+package bencode
+
+import (
+	"testing"
+)
+
+func TestKeyPathString(t *testing.T) {
+	tests := []struct {
+		input    KeyPath
+		expected string
+	}{
+		{KeyPath{}, "."},
+		{KeyPath{"announce"}, "announce"},
+		{KeyPath{"info", "files", 0, "path", 1}, "info.files[0].path[1]"},
+		{KeyPath{"info", "piece length"}, `info["piece length"]`},
+		{KeyPath{"announce-list", 0, 0}, "announce-list[0][0]"},
+		{KeyPath{0, "a.b"}, `[0]["a.b"]`},
+	}
+
+	for index, test := range tests {
+		if output := test.input.String(); output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestKeyPathCopies(t *testing.T) {
+	root := make(KeyPath, 0, 4)
+	first := root.Key("first")
+	second := root.Key("second")
+
+	if first.String() != "first" || second.String() != "second" {
+		t.Errorf("expected [first] [second] | [%s] [%s] output", first, second)
+	}
+
+	if !first.Index(3).HasPrefix("first") || first.HasPrefix("first", 3) {
+		t.Errorf("bad HasPrefix output")
+	}
+}
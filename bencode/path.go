@@ -0,0 +1,185 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// PathPolicy tells how the unsafe path components of a torrent are handled
+type PathPolicy int
+
+const (
+	PathPolicyReject    PathPolicy = iota // unsafe components are errors
+	PathPolicyNormalize                   // unsafe components are rewritten
+)
+
+const (
+	MaxPathComponentLength = 255 // bytes, the limit of most file systems
+
+	path_replacement = "_"
+)
+
+var (
+	ErrorUnsafePath = errors.New("unsafe path")
+)
+
+// windows_reserved_names can not be used as file names on Windows, even with an extension
+var windows_reserved_names = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isUnsafeRune checks if a character can not be part of a path component on Linux, macOS or Windows
+func isUnsafeRune(r rune) bool {
+	return r < 0x20 || r == 0x7f || strings.ContainsRune(`/\<>:"|?*`, r)
+}
+
+// isWindowsReservedName checks if a path component is a reserved device name of Windows
+func isWindowsReservedName(component string) bool {
+	base := component
+
+	if dot := strings.IndexByte(base, '.'); dot >= 0 {
+		base = base[:dot]
+	}
+
+	return windows_reserved_names[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+// CheckPathComponent checks that a path component of a torrent can be written on disk
+// without escaping its directory, on any operating system
+func CheckPathComponent(component string) error {
+	switch {
+	case len(component) == 0:
+		return fmt.Errorf("%w: empty component", ErrorUnsafePath)
+	case component == "." || component == "..":
+		return fmt.Errorf("%w: [%s] component", ErrorUnsafePath, component)
+	case len(component) > MaxPathComponentLength:
+		return fmt.Errorf("%w: component of %d bytes", ErrorUnsafePath, len(component))
+	case strings.IndexFunc(component, isUnsafeRune) >= 0:
+		return fmt.Errorf("%w: forbidden character in [%q]", ErrorUnsafePath, component)
+	case strings.HasSuffix(component, ".") || strings.HasSuffix(component, " "):
+		return fmt.Errorf("%w: trailing dot or space in [%s]", ErrorUnsafePath, component)
+	case isWindowsReservedName(component):
+		return fmt.Errorf("%w: reserved name [%s]", ErrorUnsafePath, component)
+	}
+
+	return nil
+}
+
+// normalizePathComponent rewrites an unsafe path component, an empty result means the component is dropped
+func normalizePathComponent(component string) string {
+	if component == "." {
+		return ""
+	}
+
+	if component == ".." {
+		return path_replacement
+	}
+
+	component = strings.Map(func(r rune) rune {
+		if isUnsafeRune(r) {
+			return '_'
+		}
+
+		return r
+	}, component)
+
+	if len(component) > MaxPathComponentLength {
+		extension := filepath.Ext(component)
+
+		if len(extension) > 16 {
+			extension = ""
+		}
+
+		component = component[:MaxPathComponentLength-len(extension)]
+
+		// do not cut a character in the middle
+		for len(component) > 0 && !utf8.ValidString(component) {
+			component = component[:len(component)-1]
+		}
+
+		component += extension
+	}
+
+	component = strings.TrimRight(component, ". ")
+
+	if isWindowsReservedName(component) {
+		component = path_replacement + component
+	}
+
+	return component
+}
+
+// SanitizePath checks or normalizes the path components of a file according to a policy
+func SanitizePath(components []string, policy PathPolicy) ([]string, error) {
+	sanitized := []string{}
+
+	for _, component := range components {
+		if err := CheckPathComponent(component); err == nil {
+			sanitized = append(sanitized, component)
+			continue
+		} else if policy == PathPolicyReject {
+			return nil, err
+		}
+
+		if component = normalizePathComponent(component); len(component) > 0 {
+			sanitized = append(sanitized, component)
+		}
+	}
+
+	if len(sanitized) == 0 {
+		return nil, fmt.Errorf("%w: empty path", ErrorUnsafePath)
+	}
+
+	return sanitized, nil
+}
+
+// JoinLocalPath joins sanitized path components to a root directory
+//
+// The result is checked to be inside the root directory
+func JoinLocalPath(root string, components []string, policy PathPolicy) (string, error) {
+	sanitized, err := SanitizePath(components, policy)
+
+	if err != nil {
+		return "", err
+	}
+
+	local_path := filepath.Join(append([]string{root}, sanitized...)...)
+	relative_path, err := filepath.Rel(filepath.Clean(root), local_path)
+
+	if err != nil || relative_path == ".." || strings.HasPrefix(relative_path, ".."+string(filepath.Separator)) || filepath.IsAbs(relative_path) {
+		return "", fmt.Errorf("%w: [%s] escapes [%s]", ErrorUnsafePath, local_path, root)
+	}
+
+	return local_path, nil
+}
+
+// PathComponents returns the path components of the file, including the root directory of multi file torrents
+func (f *File) PathComponents() []string {
+	if len(f.DecomposedPath) == 0 {
+		return []string{f.Path}
+	}
+
+	components := []string{}
+
+	// CompletePath is the root directory followed by the path of the file
+	if directory := strings.TrimSuffix(f.CompletePath, "/"+f.Path); len(directory) < len(f.CompletePath) {
+		components = append(components, directory)
+	}
+
+	return append(components, f.DecomposedPath...)
+}
+
+// LocalPath returns the path of the file in a download directory, unsafe paths are rejected
+func (f *File) LocalPath(root string) (string, error) {
+	return JoinLocalPath(root, f.PathComponents(), PathPolicyReject)
+}
+
+// LocalPathWithPolicy returns the path of the file in a download directory, unsafe paths are handled according to a policy
+func (f *File) LocalPathWithPolicy(root string, policy PathPolicy) (string, error) {
+	return JoinLocalPath(root, f.PathComponents(), policy)
+}
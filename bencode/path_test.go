@@ -0,0 +1,147 @@
+package bencode
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckPathComponent(t *testing.T) {
+	tests := []struct {
+		input string
+		safe  bool
+	}{
+		{"ubuntu.iso", true},
+		{"Minecraft 1.15.2", true},
+		{".pad", true},
+		{"été", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{"/etc", false},
+		{"..\\windows", false},
+		{"C:", false},
+		{"nul\x00byte", false},
+		{"new\nline", false},
+		{"trailing.", false},
+		{"trailing ", false},
+		{"CON", false},
+		{"com1.txt", false},
+		{"Lpt9 .log", false},
+		{"CONSOLE", true},
+		{strings.Repeat("a", 255), true},
+		{strings.Repeat("a", 256), false},
+	}
+
+	for index, test := range tests {
+		err := CheckPathComponent(test.input)
+
+		if test.safe != (err == nil) {
+			t.Errorf("test %d: expected safe [%t] | [%v] output for [%q]", index, test.safe, err, test.input)
+		}
+
+		if err != nil && !errors.Is(err, ErrorUnsafePath) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, ErrorUnsafePath, err)
+		}
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected []string
+	}{
+		{[]string{"dir", "file.txt"}, []string{"dir", "file.txt"}},
+		{[]string{"..", "..", "etc", "passwd"}, []string{"_", "_", "etc", "passwd"}},
+		{[]string{".", "a/b", "c\\d"}, []string{"a_b", "c_d"}},
+		{[]string{"aux.txt", "end. "}, []string{"_aux.txt", "end"}},
+		{[]string{"nul\x00byte"}, []string{"nul_byte"}},
+		{[]string{strings.Repeat("é", 200) + ".mkv"}, []string{strings.Repeat("é", 125) + ".mkv"}},
+	}
+
+	for index, test := range tests {
+		output, err := SanitizePath(test.input, PathPolicyNormalize)
+
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %q | %q output", index, test.expected, output)
+		}
+
+		for _, component := range output {
+			if err := CheckPathComponent(component); err != nil {
+				t.Errorf("test %d: normalized component is unsafe: %v", index, err)
+			}
+		}
+
+		_, err = SanitizePath(test.input, PathPolicyReject)
+
+		if safe := index == 0; safe != (err == nil) {
+			t.Errorf("test %d: unexpected reject output: %v", index, err)
+		}
+	}
+
+	if _, err := SanitizePath([]string{".", "."}, PathPolicyNormalize); !errors.Is(err, ErrorUnsafePath) {
+		t.Errorf("expected [%v] | [%v] error for an empty path", ErrorUnsafePath, err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	root := filepath.FromSlash("/downloads")
+
+	tests := []struct {
+		input    File
+		expected string
+	}{
+		{
+			input:    File{Path: "ubuntu.iso", CompletePath: "ubuntu.iso"},
+			expected: "/downloads/ubuntu.iso",
+		},
+		{
+			input:    File{Path: "sub/a.txt", DecomposedPath: []string{"sub", "a.txt"}, CompletePath: "dir/sub/a.txt"},
+			expected: "/downloads/dir/sub/a.txt",
+		},
+		{
+			input:    File{Path: "a.txt", DecomposedPath: []string{"a.txt"}},
+			expected: "/downloads/a.txt",
+		},
+		{
+			input: File{Path: "../../etc/passwd", DecomposedPath: []string{"..", "..", "etc", "passwd"}, CompletePath: "dir/../../etc/passwd"},
+		},
+		{
+			input: File{Path: "/etc/passwd", CompletePath: "/etc/passwd"},
+		},
+	}
+
+	for index, test := range tests {
+		output, err := test.input.LocalPath(root)
+
+		if len(test.expected) == 0 {
+			if !errors.Is(err, ErrorUnsafePath) {
+				t.Errorf("test %d: expected [%v] | [%v] error", index, ErrorUnsafePath, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if expected := filepath.FromSlash(test.expected); output != expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, expected, output)
+		}
+	}
+
+	output, err := tests[3].input.LocalPathWithPolicy(root, PathPolicyNormalize)
+
+	if expected := filepath.FromSlash("/downloads/dir/_/_/etc/passwd"); err != nil || output != expected {
+		t.Errorf("expected [%s] | [%s] [%v] normalized output", expected, output, err)
+	}
+}
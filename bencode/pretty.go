@@ -0,0 +1,134 @@
+package bencode
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+const (
+	pretty_indent        = "  "
+	pretty_binary_prefix = 16 // bytes shown of the binary strings
+)
+
+// isText checks if a string can be shown as text
+func isText(str string) bool {
+	if !utf8.ValidString(str) {
+		return false
+	}
+
+	for _, r := range str {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatString shows a string as quoted text, or as a shortened hexadecimal for binary data
+func formatString(str string) string {
+	if isText(str) {
+		return strconv.Quote(str)
+	}
+
+	if len(str) <= pretty_binary_prefix {
+		return fmt.Sprintf("<%d bytes %s>", len(str), hex.EncodeToString([]byte(str)))
+	}
+
+	return fmt.Sprintf("<%d bytes %s…>", len(str), hex.EncodeToString([]byte(str[:pretty_binary_prefix])))
+}
+
+// formatElement writes an element at a depth of indentation
+func formatElement(builder *strings.Builder, element interface{}, key string, depth int) {
+	indent := strings.Repeat(pretty_indent, depth)
+
+	switch element := element.(type) {
+	case string:
+		if key == DictionaryKeyPieces && len(element)%20 == 0 && !isText(element) {
+			builder.WriteString(fmt.Sprintf("<%d SHA-1 hashes>", len(element)/20))
+		} else {
+			builder.WriteString(formatString(element))
+		}
+	case int:
+		builder.WriteString(strconv.Itoa(element))
+	case []interface{}:
+		if len(element) == 0 {
+			builder.WriteString("[]")
+			return
+		}
+
+		builder.WriteString("[\n")
+
+		for _, sub_element := range element {
+			builder.WriteString(indent + pretty_indent)
+			formatElement(builder, sub_element, "", depth+1)
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(indent + "]")
+	case map[string]interface{}:
+		if len(element) == 0 {
+			builder.WriteString("{}")
+			return
+		}
+
+		keys := make([]string, 0, len(element))
+
+		for key := range element {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		builder.WriteString("{\n")
+
+		for _, key := range keys {
+			builder.WriteString(indent + pretty_indent + formatString(key) + ": ")
+			formatElement(builder, element[key], key, depth+1)
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(indent + "}")
+	case *parser.OrderedDict:
+		if element.Len() == 0 {
+			builder.WriteString("{}")
+			return
+		}
+
+		builder.WriteString("{\n")
+
+		for _, entry := range element.Entries {
+			builder.WriteString(indent + pretty_indent + formatString(entry.Key) + ": ")
+			formatElement(builder, entry.Value, entry.Key, depth+1)
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(indent + "}")
+	default:
+		builder.WriteString(fmt.Sprintf("<%T>", element))
+	}
+}
+
+// Format renders a parsed bencode element (see parser.ParseElement) as indented text
+//
+// Binary strings are shortened and the pieces are shown as a count of hashes,
+// the keys of a *parser.OrderedDict are kept in their order
+func Format(element interface{}) string {
+	builder := strings.Builder{}
+
+	formatElement(&builder, element, "", 0)
+
+	return builder.String()
+}
+
+// String renders the parsed data of the torrent, see Format
+func (b *Bencode) String() string {
+	return Format(b.Data)
+}
@@ -0,0 +1,77 @@
+package bencode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{
+			input:    42,
+			expected: "42",
+		},
+		{
+			input:    "été",
+			expected: `"été"`,
+		},
+		{
+			input:    "\x00\x01\x02",
+			expected: "<3 bytes 000102>",
+		},
+		{
+			input:    strings.Repeat("\xff", 20),
+			expected: "<20 bytes ffffffffffffffffffffffffffffffff…>",
+		},
+		{
+			input:    []interface{}{},
+			expected: "[]",
+		},
+		{
+			input:    map[string]interface{}{},
+			expected: "{}",
+		},
+		{
+			input: map[string]interface{}{
+				"announce": "http://tracker.example.org/announce",
+				"info": map[string]interface{}{
+					"name":         "ubuntu.iso",
+					"piece length": 262144,
+					"pieces":       strings.Repeat("\x01", 60),
+				},
+				"url-list": []interface{}{"http://a/", []interface{}{1}},
+			},
+			expected: `{
+  "announce": "http://tracker.example.org/announce"
+  "info": {
+    "name": "ubuntu.iso"
+    "piece length": 262144
+    "pieces": <3 SHA-1 hashes>
+  }
+  "url-list": [
+    "http://a/"
+    [
+      1
+    ]
+  ]
+}`,
+		},
+	}
+
+	for index, test := range tests {
+		if output := Format(test.input); output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+
+	bc := Bencode{
+		Data: map[string]interface{}{"comment": "hello"},
+	}
+
+	if expected := "{\n  \"comment\": \"hello\"\n}"; bc.String() != expected {
+		t.Errorf("expected [%s] | [%s] String output", expected, bc.String())
+	}
+}
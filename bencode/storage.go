@@ -0,0 +1,185 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrorPieceHashMismatch = errors.New("piece hash mismatch")
+	ErrorPieceLength       = errors.New("bad piece length")
+)
+
+// VerifyPiece checks the data of a piece against its hash
+func (i *Info) VerifyPiece(piece_index int, data []byte) error {
+	piece_length, err := i.PieceLengthAt(piece_index)
+
+	if err != nil {
+		return err
+	}
+
+	if len(data) != piece_length {
+		return fmt.Errorf("%w: %d bytes for piece %d of %d bytes", ErrorPieceLength, len(data), piece_index, piece_length)
+	}
+
+	if Piece(sha1.Sum(data)) != i.Pieces[piece_index] {
+		return fmt.Errorf("%w: piece %d", ErrorPieceHashMismatch, piece_index)
+	}
+
+	return nil
+}
+
+// ReadPiece reads a piece from the files of a download directory, the padding files are read as zeros
+func (i *Info) ReadPiece(root string, piece_index int) ([]byte, error) {
+	spans, err := i.PieceSpans(piece_index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	piece := []byte{}
+
+	for _, span := range spans {
+		data := make([]byte, span.Length)
+		file := &i.Files[span.FileIndex]
+
+		if !file.IsPadding() {
+			local_path, err := file.LocalPath(root)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err := readAt(local_path, data, span.Offset); err != nil {
+				return nil, err
+			}
+		}
+
+		piece = append(piece, data...)
+	}
+
+	return piece, nil
+}
+
+// WritePiece writes a piece to the files of a download directory, the padding files are not written
+//
+// The directories and the files are created if needed
+func (i *Info) WritePiece(root string, piece_index int, data []byte) error {
+	spans, err := i.PieceSpans(piece_index)
+
+	if err != nil {
+		return err
+	}
+
+	if piece_length, _ := i.PieceLengthAt(piece_index); len(data) != piece_length {
+		return fmt.Errorf("%w: %d bytes for piece %d of %d bytes", ErrorPieceLength, len(data), piece_index, piece_length)
+	}
+
+	for _, span := range spans {
+		file := &i.Files[span.FileIndex]
+
+		if !file.IsPadding() {
+			local_path, err := file.LocalPath(root)
+
+			if err != nil {
+				return err
+			}
+
+			if err := writeAt(local_path, data[:span.Length], span.Offset); err != nil {
+				return err
+			}
+		}
+
+		data = data[span.Length:]
+	}
+
+	return nil
+}
+
+// CreateFiles creates the files of a download directory at their length, the padding files are not created
+//
+// The existing files are extended if needed, their content is kept
+func (i *Info) CreateFiles(root string) error {
+	for index := range i.Files {
+		file := &i.Files[index]
+
+		if file.IsPadding() {
+			continue
+		}
+
+		local_path, err := file.LocalPath(root)
+
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(local_path), 0755); err != nil {
+			return err
+		}
+
+		local_file, err := os.OpenFile(local_path, os.O_CREATE|os.O_WRONLY, 0644)
+
+		if err != nil {
+			return err
+		}
+
+		stat, err := local_file.Stat()
+
+		if err == nil && stat.Size() < int64(file.Length) {
+			err = local_file.Truncate(int64(file.Length))
+		}
+
+		local_file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readAt reads a part of a file
+func readAt(path string, data []byte, offset int) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if _, err := file.ReadAt(data, int64(offset)); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// writeAt writes a part of a file
+func writeAt(path string, data []byte, offset int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteAt(data, int64(offset)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
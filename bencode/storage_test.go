@@ -0,0 +1,128 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newStorageInfo returns the info of some file contents
+func newStorageInfo(piece_length int, files []File, contents []string) Info {
+	info := Info{
+		DirectoryName: "dir",
+		PieceLength:   piece_length,
+		Files:         files,
+	}
+
+	content := ""
+
+	for _, file_content := range contents {
+		content += file_content
+	}
+
+	for start := 0; start < len(content); start += piece_length {
+		end := start + piece_length
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		info.Pieces = append(info.Pieces, Piece(sha1.Sum([]byte(content[start:end]))))
+	}
+
+	return info
+}
+
+func TestWriteReadPiece(t *testing.T) {
+	info := newStorageInfo(4, []File{
+		{Length: 6, Path: "a", DecomposedPath: []string{"a"}, CompletePath: "dir/a"},
+		{Length: 2, Path: "pad", DecomposedPath: []string{"pad"}, CompletePath: "dir/pad", Attributes: "p"},
+		{Length: 3, Path: "b/c", DecomposedPath: []string{"b", "c"}, CompletePath: "dir/b/c"},
+	}, []string{"abcdef", "\x00\x00", "ghi"})
+
+	tests := []struct {
+		piece    int
+		expected string
+	}{
+		{0, "abcd"},
+		{1, "ef\x00\x00"},
+		{2, "ghi"},
+	}
+
+	root := t.TempDir()
+
+	for index, test := range tests {
+		if err := info.VerifyPiece(test.piece, []byte(test.expected)); err != nil {
+			t.Errorf("test %d: failed to verify piece: %v", index, err)
+		}
+
+		if err := info.WritePiece(root, test.piece, []byte(test.expected)); err != nil {
+			t.Errorf("test %d: failed to write piece: %v", index, err)
+		}
+	}
+
+	for index, test := range tests {
+		if output, err := info.ReadPiece(root, test.piece); err != nil || string(output) != test.expected {
+			t.Errorf("test %d: expected [%q] | [%q] output: %v", index, test.expected, output, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "dir", "pad")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the padding file not to be written: %v", err)
+	}
+
+	if err := info.VerifyPiece(0, []byte("abcD")); !errors.Is(err, ErrorPieceHashMismatch) {
+		t.Errorf("expected [%v] | [%v] output", ErrorPieceHashMismatch, err)
+	}
+
+	if err := info.WritePiece(root, 0, []byte("abc")); !errors.Is(err, ErrorPieceLength) {
+		t.Errorf("expected [%v] | [%v] output", ErrorPieceLength, err)
+	}
+
+	if _, err := info.ReadPiece(t.TempDir(), 0); err == nil {
+		t.Errorf("expected an error for missing files")
+	}
+}
+
+func TestCreateFiles(t *testing.T) {
+	info := newStorageInfo(4, []File{
+		{Length: 6, Path: "a", DecomposedPath: []string{"a"}, CompletePath: "dir/a"},
+		{Length: 2, Path: "pad", DecomposedPath: []string{"pad"}, CompletePath: "dir/pad", Attributes: "p"},
+		{Length: 3, Path: "b/c", DecomposedPath: []string{"b", "c"}, CompletePath: "dir/b/c"},
+	}, []string{"abcdef", "\x00\x00", "ghi"})
+
+	root := t.TempDir()
+
+	// an existing file keeps its content
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "dir", "a"), []byte("ab"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := info.CreateFiles(root); err != nil {
+		t.Fatalf("failed to create files: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{filepath.Join(root, "dir", "a"), "ab\x00\x00\x00\x00"},
+		{filepath.Join(root, "dir", "b", "c"), "\x00\x00\x00"},
+	}
+
+	for index, test := range tests {
+		if content, err := os.ReadFile(test.path); err != nil || string(content) != test.expected {
+			t.Errorf("test %d: expected [%q] | [%q] output: %v", index, test.expected, content, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "dir", "pad")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected [%v] | [%v] output", os.ErrNotExist, err)
+	}
+}
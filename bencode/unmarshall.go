@@ -40,9 +40,10 @@ func (i *Info) unmarshallPieceLength(info_dictionary map[string]interface{}) err
 }
 
 // unmarshallName unmarshall the Name attribute from a bencode info section
-func (i *Info) unmarshallName(info_dictionary map[string]interface{}) error {
+func (i *Info) unmarshallName(info_dictionary map[string]interface{}, encoding string) error {
 	if name, ok := info_dictionary[DictionaryKeyName].(string); ok {
-		i.DirectoryName = name
+		i.RawName = name
+		i.DirectoryName = displayString(name, info_dictionary[DictionaryKeyNameUTF8], encoding)
 		return nil
 	}
 
@@ -76,8 +77,27 @@ func (i *Info) unmarshallPieces(info_dictionary map[string]interface{}) error {
 	return fmt.Errorf("%v: %v", ErrorIntegerElementMissingInDictionary, DictionaryKeyPieces)
 }
 
+// unmarshallSimilar unmarshall the Similar and Collections attributes from a bencode info section,
+// they are optional and ignored if malformed
+//
+// http://www.bittorrent.org/beps/bep_0038.html
+func (i *Info) unmarshallSimilar(info_dictionary map[string]interface{}) {
+	if similar, err := utils.ToStringList(info_dictionary[DictionaryKeySimilar]); err == nil {
+		for _, info_hash := range similar {
+			if len(info_hash) == 20 {
+				i.Similar = append(i.Similar, [20]byte{})
+				copy(i.Similar[len(i.Similar)-1][:], info_hash)
+			}
+		}
+	}
+
+	if collections, err := utils.ToStringList(info_dictionary[DictionaryKeyCollections]); err == nil {
+		i.Collections = collections
+	}
+}
+
 // unmarshallFiles unmarshall the Files attribute from a bencode info section
-func (i *Info) unmarshallFiles(info_dictionary map[string]interface{}) error {
+func (i *Info) unmarshallFiles(info_dictionary map[string]interface{}, encoding string) error {
 	info_files, ok := info_dictionary[DictionaryKeyFiles]
 
 	if ok {
@@ -94,6 +114,14 @@ func (i *Info) unmarshallFiles(info_dictionary map[string]interface{}) error {
 								return fmt.Errorf("file corrupted: %w", err)
 							}
 
+							file.RawPath = file.DecomposedPath
+							file.DecomposedPath = displayPath(file.RawPath, file_dictionary[DictionaryKeyPathUTF8], encoding)
+							file.Path = strings.Join(file.DecomposedPath, "/")
+
+							if attributes, ok := file_dictionary[DictionaryKeyAttr].(string); ok {
+								file.Attributes = attributes
+							}
+
 							if len(i.DirectoryName) > 0 {
 								file.CompletePath = i.DirectoryName + "/" + file.Path
 							}
@@ -114,10 +142,13 @@ func (i *Info) unmarshallFiles(info_dictionary map[string]interface{}) error {
 		}
 	} else {
 		if file_length, ok := info_dictionary[DictionaryKeyLength].(int); ok {
+			attributes, _ := info_dictionary[DictionaryKeyAttr].(string)
+
 			i.Files = append(i.Files, File{
 				Length:       file_length,
 				Path:         i.DirectoryName,
 				CompletePath: i.DirectoryName,
+				Attributes:   attributes,
 			})
 		} else {
 			return fmt.Errorf("%w: %v", ErrorIntegerElementMissingInDictionary, DictionaryKeyLength)
@@ -266,21 +297,42 @@ func (b *Bencode) UnmarshallInfo() error {
 		return err
 	}
 
+	// ---------- encoding
+	encoding, _ := dictionary[DictionaryKeyEncoding].(string)
+
 	// ---------- name
-	if err := info.unmarshallName(info_dictionary); err != nil {
+	if err := info.unmarshallName(info_dictionary, encoding); err != nil {
 		return err
 	}
 
 	// ---------- files
-	if err := info.unmarshallFiles(info_dictionary); err != nil {
+	if err := info.unmarshallFiles(info_dictionary, encoding); err != nil {
 		return err
 	}
 
+	// ---------- similar/collections
+	info.unmarshallSimilar(info_dictionary)
+
+	info.file_offsets = computeOffsets(info.Files)
+
 	b.Info = info
 
 	return nil
 }
 
+// UnmarshallEncoding unmarshall the Encoding attribute, the legacy encoding of the names and paths
+func (b *Bencode) UnmarshallEncoding() error {
+	value, err := b.unmarshallStringElement(DictionaryKeyEncoding)
+
+	if err != nil {
+		return err
+	}
+
+	b.Encoding = value
+
+	return nil
+}
+
 // UnmarshallUrlList unmarshall the Url List attribute
 func (b *Bencode) UnmarshallUrlList() error {
 	dictionary, ok := b.Data.(map[string]interface{})
@@ -306,6 +358,31 @@ func (b *Bencode) UnmarshallUrlList() error {
 	return nil
 }
 
+// UnmarshallHttpSeeds unmarshall the Http Seeds attribute
+func (b *Bencode) UnmarshallHttpSeeds() error {
+	dictionary, ok := b.Data.(map[string]interface{})
+
+	if !ok {
+		return ErrorDataIsNotADictionary
+	}
+
+	value, ok := dictionary[DictionaryKeyHttpSeeds]
+
+	if !ok {
+		return ErrorElementMissingInDictionary
+	}
+
+	http_seeds, err := utils.ToStringList(value)
+
+	if err != nil {
+		return err
+	}
+
+	b.HttpSeeds = http_seeds
+
+	return nil
+}
+
 // UnmarshallAll unmarshall all attribute
 func (b *Bencode) UnmarshallAll() (err error) {
 	endpoint_errors := []error{}
@@ -320,8 +397,11 @@ func (b *Bencode) UnmarshallAll() (err error) {
 	if err := b.UnmarshallUrlList(); err != nil {
 		endpoint_errors = append(endpoint_errors, err)
 	}
+	if err := b.UnmarshallHttpSeeds(); err != nil {
+		endpoint_errors = append(endpoint_errors, err)
+	}
 
-	if len(endpoint_errors) == 3 {
+	if len(endpoint_errors) == 4 {
 		return fmt.Errorf("%w: %v", ErrorNoEndpointFound, endpoint_errors)
 	}
 
@@ -333,6 +413,7 @@ func (b *Bencode) UnmarshallAll() (err error) {
 	b.UnmarshallComment()
 	b.UnmarshallCreatedBy()
 	b.UnmarshallCreationDate()
+	b.UnmarshallEncoding()
 
 	// ---------- info/files
 	if err := b.UnmarshallInfo(); err != nil {
@@ -0,0 +1,231 @@
+package bencode
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Severity is the importance of a Finding
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// codes of the findings, stable so they can be matched by the callers
+const (
+	FindingNotADictionary          = "not-a-dictionary"
+	FindingBadType                 = "bad-type"
+	FindingNoEndpoint              = "no-endpoint"
+	FindingEmptyAnnounceTier       = "empty-announce-tier"
+	FindingInvalidPieceLength      = "invalid-piece-length"
+	FindingPieceLengthNotPowerOf2  = "piece-length-not-power-of-two"
+	FindingPieceCountMismatch      = "piece-count-mismatch"
+	FindingZeroLength              = "zero-length"
+	FindingNegativeLength          = "negative-length"
+	FindingDuplicateFilePath       = "duplicate-file-path"
+	FindingEmptyPathComponent      = "empty-path-component"
+	FindingUnsafePath              = "unsafe-path"
+	FindingNonUTF8Name             = "non-utf8-name"
+	FindingUnknownEncoding         = "unknown-encoding"
+	FindingCreationDateInTheFuture = "creation-date-in-the-future"
+	FindingNegativeCreationDate    = "negative-creation-date"
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Finding is a problem found in a torrent by Validate
+type Finding struct {
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+// String returns the finding as a single line
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Code, f.Message)
+}
+
+// findings collects the findings of a validation
+type findings []Finding
+
+// add appends a finding
+func (f *findings) add(severity Severity, code string, format string, a ...interface{}) {
+	*f = append(*f, Finding{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// validateOptionalTypes reports the optional keys present with a bad type, UnmarshallAll ignores them
+func (f *findings) validateOptionalTypes(dictionary map[string]interface{}) {
+	keys := []struct {
+		key     string
+		integer bool
+	}{
+		{DictionaryKeyAnnounce, false},
+		{DictionaryKeyComment, false},
+		{DictionaryKeyCreatedBy, false},
+		{DictionaryKeyCreationDate, true},
+		{DictionaryKeyEncoding, false},
+	}
+
+	for _, key := range keys {
+		element, ok := dictionary[key.key]
+
+		if !ok {
+			continue
+		}
+
+		if _, is_integer := element.(int); key.integer && !is_integer {
+			f.add(SeverityWarning, FindingBadType, "[%T] for %s (expected integer), it is ignored", element, key.key)
+		}
+
+		if _, is_string := element.(string); !key.integer && !is_string {
+			f.add(SeverityWarning, FindingBadType, "[%T] for %s (expected string), it is ignored", element, key.key)
+		}
+	}
+}
+
+// validateEndpoints reports the trackers problems
+func (f *findings) validateEndpoints(b *Bencode) {
+	if len(b.Announce) == 0 && len(b.AnnounceList) == 0 && len(b.UrlList) == 0 && len(b.HttpSeeds) == 0 {
+		f.add(SeverityInfo, FindingNoEndpoint, "no tracker nor web seed, peers can only be found with the DHT or PEX")
+	}
+
+	for index, tier := range b.AnnounceList {
+		if len(tier) == 0 {
+			f.add(SeverityWarning, FindingEmptyAnnounceTier, "tier %d of %s is empty", index, DictionaryKeyAnnounceList)
+			continue
+		}
+
+		for _, announce := range tier {
+			if len(strings.TrimSpace(announce)) == 0 {
+				f.add(SeverityWarning, FindingEmptyAnnounceTier, "tier %d of %s has an empty url", index, DictionaryKeyAnnounceList)
+			}
+		}
+	}
+}
+
+// validateInfo reports the inconsistencies of the info section
+func (f *findings) validateInfo(info *Info) {
+	if !utf8.ValidString(info.DirectoryName) {
+		f.add(SeverityWarning, FindingNonUTF8Name, "%s [%q] is not valid UTF-8", DictionaryKeyName, info.DirectoryName)
+	}
+
+	if err := CheckPathComponent(info.DirectoryName); err != nil {
+		f.add(SeverityError, FindingUnsafePath, "%s: %v", DictionaryKeyName, err)
+	}
+
+	paths := map[string]int{}
+
+	for index, file := range info.Files {
+		if file.Length < 0 {
+			f.add(SeverityError, FindingNegativeLength, "file %d [%s] has a negative length %d", index, file.Path, file.Length)
+		}
+
+		for _, component := range file.DecomposedPath {
+			if len(component) == 0 {
+				f.add(SeverityError, FindingEmptyPathComponent, "file %d [%s] has an empty path component", index, file.Path)
+				break
+			}
+
+			if err := CheckPathComponent(component); err != nil {
+				f.add(SeverityError, FindingUnsafePath, "file %d: %v", index, err)
+				break
+			}
+		}
+
+		if !utf8.ValidString(file.Path) {
+			f.add(SeverityWarning, FindingNonUTF8Name, "file %d path [%q] is not valid UTF-8", index, file.Path)
+		}
+
+		if first, ok := paths[file.Path]; ok {
+			f.add(SeverityError, FindingDuplicateFilePath, "files %d and %d have the same path [%s]", first, index, file.Path)
+		} else {
+			paths[file.Path] = index
+		}
+	}
+
+	total_length := info.TotalLength()
+
+	if total_length == 0 {
+		f.add(SeverityError, FindingZeroLength, "the torrent has no content")
+	}
+
+	if info.PieceLength <= 0 {
+		f.add(SeverityError, FindingInvalidPieceLength, "%s %d is not positive", DictionaryKeyPieceLength, info.PieceLength)
+		return
+	}
+
+	if info.PieceLength&(info.PieceLength-1) != 0 {
+		f.add(SeverityWarning, FindingPieceLengthNotPowerOf2, "%s %d is not a power of two", DictionaryKeyPieceLength, info.PieceLength)
+	}
+
+	if total_length >= 0 {
+		if expected := (total_length + info.PieceLength - 1) / info.PieceLength; expected != len(info.Pieces) {
+			f.add(SeverityError, FindingPieceCountMismatch, "%d pieces for a total length of %d (expected %d)", len(info.Pieces), total_length, expected)
+		}
+	}
+}
+
+// Validate checks the consistency of an unmarshalled torrent (see UnmarshallAll)
+//
+// The findings are sorted by check, not by severity. A torrent without
+// any SeverityError finding is usable by the BitTorrent clients
+func (b *Bencode) Validate() []Finding {
+	f := findings{}
+
+	dictionary, ok := b.Data.(map[string]interface{})
+
+	if ok {
+		f.validateOptionalTypes(dictionary)
+	} else if b.Data != nil {
+		f.add(SeverityError, FindingNotADictionary, "the torrent is a [%T]", b.Data)
+	}
+
+	if len(b.Encoding) > 0 {
+		if _, err := Decode(b.Encoding, ""); err != nil {
+			f.add(SeverityWarning, FindingUnknownEncoding, "%s [%s] has no registered decoder, the names are shown as is", DictionaryKeyEncoding, b.Encoding)
+		}
+	}
+
+	f.validateEndpoints(b)
+	f.validateInfo(&b.Info)
+
+	if b.CreationDate < 0 {
+		f.add(SeverityWarning, FindingNegativeCreationDate, "%s %d is before 1970", DictionaryKeyCreationDate, b.CreationDate)
+	} else if creation_date := time.Unix(int64(b.CreationDate), 0); creation_date.After(time.Now()) {
+		f.add(SeverityWarning, FindingCreationDateInTheFuture, "%s %s is in the future", DictionaryKeyCreationDate, creation_date.UTC().Format(time.RFC3339))
+	}
+
+	return f
+}
+
+// HasErrors checks if some findings have the SeverityError severity
+func HasErrors(report []Finding) bool {
+	for _, finding := range report {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package bencode
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+func TestValidateTestFiles(t *testing.T) {
+	files := []string{
+		"../.test_files/arch.torrent",
+		"../.test_files/kubuntu.torrent",
+		"../.test_files/minecraft.torrent",
+		"../.test_files/ubuntu.torrent",
+	}
+
+	for index, file := range files {
+		f, err := os.Open(file)
+
+		if err != nil {
+			t.Errorf("failed to read file %d: %v", index, err)
+			continue
+		}
+
+		data, err := parser.ParseElement(bufio.NewReader(f))
+		f.Close()
+
+		if err != nil {
+			t.Errorf("failed to get data of file %d: %v", index, err)
+			continue
+		}
+
+		bc := Bencode{
+			Data: data,
+		}
+
+		if err := bc.UnmarshallAll(); err != nil {
+			t.Errorf("failed to unmarshall file %d: %v", index, err)
+			continue
+		}
+
+		if report := bc.Validate(); HasErrors(report) {
+			t.Errorf("test %d: unexpected errors %v", index, report)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid_info := Info{
+		DirectoryName: "dir",
+		PieceLength:   16384,
+		Pieces:        make([]Piece, 2),
+		Files: []File{
+			{Length: 20000, Path: "a", DecomposedPath: []string{"a"}},
+			{Length: 10000, Path: "b", DecomposedPath: []string{"b"}},
+		},
+	}
+
+	tests := []struct {
+		input    Bencode
+		expected []string
+	}{
+		{
+			input: Bencode{
+				Announce: "http://tracker.example.org/announce",
+				Info:     valid_info,
+			},
+			expected: []string{},
+		},
+		{
+			input: Bencode{
+				Info: valid_info,
+			},
+			expected: []string{FindingNoEndpoint},
+		},
+		{
+			input: Bencode{
+				Data: map[string]interface{}{
+					DictionaryKeyComment:      1,
+					DictionaryKeyCreationDate: "yesterday",
+				},
+				Announce:     "http://tracker.example.org/announce",
+				AnnounceList: [][]string{{}, {""}},
+				CreationDate: 1 << 40,
+				Info:         valid_info,
+			},
+			expected: []string{FindingBadType, FindingBadType, FindingEmptyAnnounceTier, FindingEmptyAnnounceTier, FindingCreationDateInTheFuture},
+		},
+		{
+			input: Bencode{
+				UrlList: []string{"http://mirror.example.org/"},
+				Info: Info{
+					DirectoryName: "dir\xff",
+					PieceLength:   10000,
+					Pieces:        make([]Piece, 1),
+					Files: []File{
+						{Length: 20000, Path: "a", DecomposedPath: []string{"a"}},
+						{Length: 10000, Path: "a", DecomposedPath: []string{"a"}},
+						{Length: 0, Path: "c//d", DecomposedPath: []string{"c", "", "d"}},
+					},
+				},
+			},
+			expected: []string{FindingNonUTF8Name, FindingDuplicateFilePath, FindingEmptyPathComponent, FindingPieceLengthNotPowerOf2, FindingPieceCountMismatch},
+		},
+		{
+			input: Bencode{
+				Announce:     "http://tracker.example.org/announce",
+				CreationDate: -1,
+				Info: Info{
+					DirectoryName: "empty",
+					Files:         []File{{Path: "empty"}},
+				},
+			},
+			expected: []string{FindingZeroLength, FindingInvalidPieceLength, FindingNegativeCreationDate},
+		},
+		{
+			input: Bencode{
+				Announce: "http://tracker.example.org/announce",
+				Info: Info{
+					DirectoryName: "..",
+					PieceLength:   16384,
+					Pieces:        make([]Piece, 1),
+					Files:         []File{{Length: 1, Path: "../passwd", DecomposedPath: []string{"..", "passwd"}}},
+				},
+			},
+			expected: []string{FindingUnsafePath, FindingUnsafePath},
+		},
+		{
+			input: Bencode{
+				Data:     []interface{}{},
+				Announce: "http://tracker.example.org/announce",
+				Info:     valid_info,
+			},
+			expected: []string{FindingNotADictionary},
+		},
+	}
+
+	for index, test := range tests {
+		codes := []string{}
+
+		for _, finding := range test.input.Validate() {
+			codes = append(codes, finding.Code)
+		}
+
+		if !reflect.DeepEqual(codes, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, codes)
+		}
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	finding := Finding{
+		Severity: SeverityError,
+		Code:     FindingZeroLength,
+		Message:  "the torrent has no content",
+	}
+
+	if expected := "error: zero-length: the torrent has no content"; finding.String() != expected {
+		t.Errorf("expected [%s] | [%s] output", expected, finding.String())
+	}
+
+	if HasErrors([]Finding{{Severity: SeverityWarning}}) || !HasErrors([]Finding{{Severity: SeverityInfo}, finding}) {
+		t.Errorf("bad HasErrors output")
+	}
+}
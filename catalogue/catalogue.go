@@ -0,0 +1,518 @@
+// Package catalogue provide a file-based catalogue of torrents keyed by info hash
+package catalogue
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+	"github.com/trixky/gobencode/utils"
+)
+
+const (
+	index_file_name   = "catalogue.bencode"
+	torrent_extension = ".torrent"
+)
+
+const (
+	DictionaryKeyInfoHash = "info hash"
+	DictionaryKeyName     = "name"
+	DictionaryKeySize     = "size"
+	DictionaryKeyFiles    = "files"
+	DictionaryKeyTrackers = "trackers"
+	DictionaryKeyAdded    = "added"
+	DictionaryKeyRemoved  = "removed"
+)
+
+var (
+	ErrorNotFound        = errors.New("torrent not found in the catalogue")
+	ErrorMissingInfo     = errors.New("info dictionary missing")
+	ErrorIndexCorrupted  = errors.New("catalogue index corrupted")
+	ErrorTorrentModified = errors.New("torrent file does not match its info hash")
+)
+
+// Entry is the metadata of a torrent of the catalogue
+type Entry struct {
+	InfoHash  [20]byte
+	Name      string
+	Size      int
+	Files     []string // complete paths of the files, the root directory included
+	Trackers  []string // announce urls of all the tiers, without repetition
+	AddedTime int
+}
+
+// Query selects entries, the zero value fields match every entry
+type Query struct {
+	Name        string // substring of the name, case insensitive
+	TrackerHost string // host of one of the trackers
+	Extension   string // extension of one of the files, with or without the dot
+	MinSize     int
+	MaxSize     int // 0 for no maximum
+}
+
+// Catalogue stores torrents in a directory: one .torrent file per info hash and an index of their metadata
+//
+// The index is an append-only list of bencoded entries, a later entry replaces a former one with the same info hash
+type Catalogue struct {
+	root    string
+	mutex   sync.RWMutex
+	entries map[[20]byte]Entry
+}
+
+// Open opens or creates a catalogue in a directory
+//
+// A truncated last entry of the index (an interrupted append) is removed, the entries before it are kept
+func Open(root string) (*Catalogue, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Catalogue{
+		root:    root,
+		entries: make(map[[20]byte]Entry),
+	}
+
+	index_path := filepath.Join(root, index_file_name)
+	data, err := os.ReadFile(index_path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for offset := 0; offset < len(data); {
+		element, n, err := parser.ParseBytes(data[offset:], parser.Options{})
+
+		var syntax_error *parser.SyntaxError
+
+		if errors.As(err, &syntax_error) && syntax_error.Truncated {
+			// the next appends follow the last complete entry
+			if err := os.Truncate(index_path, int64(offset)); err != nil {
+				return nil, err
+			}
+
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: offset %d: %v", ErrorIndexCorrupted, offset, err)
+		}
+
+		if err := c.load(element); err != nil {
+			return nil, fmt.Errorf("%w: offset %d: %v", ErrorIndexCorrupted, offset, err)
+		}
+
+		offset += n
+	}
+
+	return c, nil
+}
+
+// load applies an element of the index
+func (c *Catalogue) load(element interface{}) error {
+	dictionary, ok := element.(map[string]interface{})
+
+	if !ok {
+		return bencode.ErrorDataIsNotADictionary
+	}
+
+	if removed, ok := dictionary[DictionaryKeyRemoved].(string); ok {
+		info_hash, err := toInfoHash(removed)
+
+		if err != nil {
+			return err
+		}
+
+		delete(c.entries, info_hash)
+
+		return nil
+	}
+
+	entry, err := entryFromDictionary(dictionary)
+
+	if err != nil {
+		return err
+	}
+
+	c.entries[entry.InfoHash] = entry
+
+	return nil
+}
+
+// toInfoHash converts a 20 bytes string to an info hash
+func toInfoHash(str string) (info_hash [20]byte, err error) {
+	if len(str) != len(info_hash) {
+		return info_hash, fmt.Errorf("info hash of %d bytes", len(str))
+	}
+
+	copy(info_hash[:], str)
+
+	return info_hash, nil
+}
+
+// entryFromDictionary reads an entry of the index
+func entryFromDictionary(dictionary map[string]interface{}) (entry Entry, err error) {
+	info_hash, _ := dictionary[DictionaryKeyInfoHash].(string)
+
+	if entry.InfoHash, err = toInfoHash(info_hash); err != nil {
+		return entry, err
+	}
+
+	entry.Name, _ = dictionary[DictionaryKeyName].(string)
+	entry.Size, _ = dictionary[DictionaryKeySize].(int)
+	entry.AddedTime, _ = dictionary[DictionaryKeyAdded].(int)
+
+	if entry.Files, err = utils.ToStringList(dictionary[DictionaryKeyFiles]); err != nil {
+		return entry, fmt.Errorf("%s: %v", DictionaryKeyFiles, err)
+	}
+
+	if entry.Trackers, err = utils.ToStringList(dictionary[DictionaryKeyTrackers]); err != nil {
+		return entry, fmt.Errorf("%s: %v", DictionaryKeyTrackers, err)
+	}
+
+	return entry, nil
+}
+
+// toInterfaceList converts a string list for the encoder
+func toInterfaceList(strs []string) []interface{} {
+	list := make([]interface{}, len(strs))
+
+	for index, str := range strs {
+		list[index] = str
+	}
+
+	return list
+}
+
+// dictionary returns the entry as written in the index
+func (e Entry) dictionary() map[string]interface{} {
+	return map[string]interface{}{
+		DictionaryKeyInfoHash: string(e.InfoHash[:]),
+		DictionaryKeyName:     e.Name,
+		DictionaryKeySize:     e.Size,
+		DictionaryKeyFiles:    toInterfaceList(e.Files),
+		DictionaryKeyTrackers: toInterfaceList(e.Trackers),
+		DictionaryKeyAdded:    e.AddedTime,
+	}
+}
+
+// appendIndex writes an element at the end of the index
+func (c *Catalogue) appendIndex(element interface{}) error {
+	encoded, err := bencode.EncodeElement(element)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(c.root, index_file_name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(encoded); err != nil {
+		file.Close()
+		return err
+	}
+
+	// the entry is on disk before the catalogue relies on it
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// torrentPath returns the path of the .torrent file of an info hash
+func (c *Catalogue) torrentPath(info_hash [20]byte) string {
+	return filepath.Join(c.root, hex.EncodeToString(info_hash[:])+torrent_extension)
+}
+
+// rawInfo returns the info dictionary of bencoded torrent bytes as they are written, and the parsed torrent
+func rawInfo(data []byte) ([]byte, interface{}, error) {
+	element, _, err := parser.ParseBytes(data, parser.Options{})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := element.(map[string]interface{}); !ok {
+		return nil, nil, bencode.ErrorDataIsNotADictionary
+	}
+
+	// a slice of data, the info dictionary is not encoded again
+	raw_info, err := parser.RawValue(data, bencode.DictionaryKeyInfo)
+
+	if errors.Is(err, parser.ErrorKeyNotFound) {
+		return nil, nil, ErrorMissingInfo
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return raw_info, element, nil
+}
+
+// AddRaw adds a torrent from its bencoded bytes, which are kept as they are
+//
+// The info hash is the hash of the info dictionary as written, even if its keys are not sorted
+func (c *Catalogue) AddRaw(data []byte) (Entry, error) {
+	raw_info, parsed, err := rawInfo(data)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	bc := bencode.Bencode{
+		Data: parsed,
+	}
+
+	if err := bc.UnmarshallAll(); err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		InfoHash:  sha1.Sum(raw_info),
+		Name:      bc.Info.DirectoryName,
+		Size:      bc.Info.TotalLength(),
+		Files:     make([]string, len(bc.Info.Files)),
+		Trackers:  bc.Trackers(),
+		AddedTime: int(time.Now().Unix()),
+	}
+
+	for index, file := range bc.Info.Files {
+		entry.Files[index] = file.CompletePath
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	torrent_path := c.torrentPath(entry.InfoHash)
+	_, replaced := c.entries[entry.InfoHash]
+
+	// the file is renamed once written, a reader never sees a partial torrent
+	if err := os.WriteFile(torrent_path+".tmp", data, 0644); err != nil {
+		os.Remove(torrent_path + ".tmp")
+		return Entry{}, err
+	}
+
+	if err := os.Rename(torrent_path+".tmp", torrent_path); err != nil {
+		os.Remove(torrent_path + ".tmp")
+		return Entry{}, err
+	}
+
+	if err := c.appendIndex(entry.dictionary()); err != nil {
+		// a torrent file without entry would never be listed nor removed
+		if !replaced {
+			os.Remove(torrent_path)
+		}
+
+		return Entry{}, err
+	}
+
+	c.entries[entry.InfoHash] = entry
+
+	return entry, nil
+}
+
+// Add adds a parsed torrent, it is stored encoded from its parsed data
+//
+// The export is byte-identical to the original file if its keys were sorted, use AddRaw to keep any file as it is
+func (c *Catalogue) Add(bc *bencode.Bencode) (Entry, error) {
+	encoded, err := bencode.EncodeElement(bc.Data)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return c.AddRaw([]byte(encoded))
+}
+
+// Remove removes a torrent from the catalogue
+func (c *Catalogue) Remove(info_hash [20]byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[info_hash]; !ok {
+		return ErrorNotFound
+	}
+
+	if err := c.appendIndex(map[string]interface{}{DictionaryKeyRemoved: string(info_hash[:])}); err != nil {
+		return err
+	}
+
+	delete(c.entries, info_hash)
+
+	if err := os.Remove(c.torrentPath(info_hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Len returns the number of torrents of the catalogue
+func (c *Catalogue) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return len(c.entries)
+}
+
+// Get returns the entry of an info hash
+func (c *Catalogue) Get(info_hash [20]byte) (Entry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[info_hash]
+
+	return entry, ok
+}
+
+// matches checks if an entry is selected by a query
+func (q Query) matches(entry Entry) bool {
+	if len(q.Name) > 0 && !strings.Contains(strings.ToLower(entry.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+
+	if entry.Size < q.MinSize || q.MaxSize > 0 && entry.Size > q.MaxSize {
+		return false
+	}
+
+	if len(q.TrackerHost) > 0 {
+		found := false
+
+		for _, tracker := range entry.Trackers {
+			if tracker_url, err := url.Parse(tracker); err == nil && strings.EqualFold(tracker_url.Hostname(), q.TrackerHost) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Extension) > 0 {
+		extension := "." + strings.TrimPrefix(q.Extension, ".")
+		found := false
+
+		for _, file := range entry.Files {
+			if strings.EqualFold(path.Ext(file), extension) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find returns the entries selected by a query, sorted by name
+func (c *Catalogue) Find(query Query) []Entry {
+	c.mutex.RLock()
+	entries := []Entry{}
+
+	for _, entry := range c.entries {
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	c.mutex.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+
+		return bytes.Compare(entries[i].InfoHash[:], entries[j].InfoHash[:]) < 0
+	})
+
+	return entries
+}
+
+// Raw returns the bencoded bytes of a torrent as they were added
+func (c *Catalogue) Raw(info_hash [20]byte) ([]byte, error) {
+	c.mutex.RLock()
+	_, ok := c.entries[info_hash]
+	c.mutex.RUnlock()
+
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return os.ReadFile(c.torrentPath(info_hash))
+}
+
+// RawInfo returns the info dictionary of a torrent as it was added, its SHA-1 is the info hash
+func (c *Catalogue) RawInfo(info_hash [20]byte) ([]byte, error) {
+	data, err := c.Raw(info_hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw_info, _, err := rawInfo(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sha1.Sum(raw_info) != info_hash {
+		return nil, ErrorTorrentModified
+	}
+
+	return raw_info, nil
+}
+
+// Export writes a torrent as it was added
+func (c *Catalogue) Export(info_hash [20]byte, w io.Writer) error {
+	data, err := c.Raw(info_hash)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// Torrent returns a torrent of the catalogue unmarshalled
+func (c *Catalogue) Torrent(info_hash [20]byte) (bc bencode.Bencode, err error) {
+	data, err := c.Raw(info_hash)
+
+	if err != nil {
+		return bc, err
+	}
+
+	if bc.Data, err = parser.ParseElement(bufio.NewReader(bytes.NewReader(data))); err != nil {
+		return bc, err
+	}
+
+	if err := bc.UnmarshallAll(); err != nil {
+		return bc, err
+	}
+
+	bc.InfoHash = info_hash
+
+	return bc, nil
+}
@@ -0,0 +1,283 @@
+package catalogue
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+var test_files = []string{
+	"arch.torrent",
+	"kubuntu.torrent",
+	"minecraft.torrent",
+	"ubuntu.torrent",
+}
+
+// openTestCatalogue returns a catalogue with the test torrents and their bytes
+func openTestCatalogue(t *testing.T) (*Catalogue, string, map[string][]byte) {
+	root := t.TempDir()
+	c, err := Open(root)
+
+	if err != nil {
+		t.Fatalf("failed to open catalogue: %v", err)
+	}
+
+	files := map[string][]byte{}
+
+	for _, name := range test_files {
+		data, err := os.ReadFile(filepath.Join("..", ".test_files", name))
+
+		if err != nil {
+			t.Fatalf("failed to read file [%s]: %v", name, err)
+		}
+
+		if _, err := c.AddRaw(data); err != nil {
+			t.Fatalf("failed to add [%s]: %v", name, err)
+		}
+
+		files[name] = data
+	}
+
+	return c, root, files
+}
+
+// names returns the names of some entries
+func names(entries []Entry) []string {
+	output := []string{}
+
+	for _, entry := range entries {
+		output = append(output, entry.Name)
+	}
+
+	return output
+}
+
+func TestCatalogueFind(t *testing.T) {
+	c, _, _ := openTestCatalogue(t)
+
+	tests := []struct {
+		query    Query
+		expected []string
+	}{
+		{Query{}, []string{"Minecraft 1.15.2", "archlinux-2022.05.01-x86_64.iso", "kubuntu-22.04-desktop-amd64.iso", "ubuntu-22.04-desktop-amd64.iso"}},
+		{Query{Name: "UBUNTU"}, []string{"kubuntu-22.04-desktop-amd64.iso", "ubuntu-22.04-desktop-amd64.iso"}},
+		{Query{TrackerHost: "torrent.ubuntu.com"}, []string{"kubuntu-22.04-desktop-amd64.iso", "ubuntu-22.04-desktop-amd64.iso"}},
+		{Query{Extension: "exe"}, []string{"Minecraft 1.15.2"}},
+		{Query{Extension: ".ISO", MaxSize: 1000000000}, []string{"archlinux-2022.05.01-x86_64.iso"}},
+		{Query{MinSize: 866463744, MaxSize: 866463744}, []string{"archlinux-2022.05.01-x86_64.iso"}},
+		{Query{Name: "missing"}, []string{}},
+	}
+
+	for index, test := range tests {
+		if output := names(c.Find(test.query)); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestCatalogueExport(t *testing.T) {
+	c, root, files := openTestCatalogue(t)
+
+	// the index is read again from the disk
+	c, err := Open(root)
+
+	if err != nil {
+		t.Fatalf("failed to open catalogue: %v", err)
+	}
+
+	if c.Len() != len(test_files) {
+		t.Fatalf("expected [%d] | [%d] output entries", len(test_files), c.Len())
+	}
+
+	for name, data := range files {
+		bc := bencode.Bencode{}
+		bc.Data, _ = parser.ParseElement(bufio.NewReader(bytes.NewReader(data)))
+
+		if err := bc.UnmarshallAll(); err != nil {
+			t.Fatalf("failed to unmarshall [%s]: %v", name, err)
+		}
+
+		if err := bc.GetInfoHash(); err != nil {
+			t.Fatalf("failed to get info hash of [%s]: %v", name, err)
+		}
+
+		output := bytes.Buffer{}
+
+		if err := c.Export(bc.InfoHash, &output); err != nil {
+			t.Errorf("failed to export [%s]: %v", name, err)
+			continue
+		}
+
+		if !bytes.Equal(output.Bytes(), data) {
+			t.Errorf("expected [%s] to be exported byte-identical", name)
+		}
+
+		if raw_info, err := c.RawInfo(bc.InfoHash); err != nil || sha1.Sum(raw_info) != bc.InfoHash {
+			t.Errorf("expected the raw info of [%s] to match its info hash: %v", name, err)
+		}
+
+		if torrent, err := c.Torrent(bc.InfoHash); err != nil || torrent.Info.DirectoryName != bc.Info.DirectoryName {
+			t.Errorf("expected [%s] | [%s] output name: %v", bc.Info.DirectoryName, torrent.Info.DirectoryName, err)
+		}
+	}
+}
+
+func TestCatalogueRemove(t *testing.T) {
+	c, root, _ := openTestCatalogue(t)
+
+	entries := c.Find(Query{Name: "arch"})
+
+	if len(entries) != 1 {
+		t.Fatalf("expected [1] | [%d] output entries", len(entries))
+	}
+
+	if err := c.Remove(entries[0].InfoHash); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+
+	if err := c.Remove(entries[0].InfoHash); err != ErrorNotFound {
+		t.Errorf("expected [%v] | [%v] output", ErrorNotFound, err)
+	}
+
+	c, err := Open(root)
+
+	if err != nil {
+		t.Fatalf("failed to open catalogue: %v", err)
+	}
+
+	if _, ok := c.Get(entries[0].InfoHash); ok || c.Len() != len(test_files)-1 {
+		t.Errorf("expected the removed torrent to stay removed")
+	}
+
+	if _, err := c.Raw(entries[0].InfoHash); err != ErrorNotFound {
+		t.Errorf("expected [%v] | [%v] output", ErrorNotFound, err)
+	}
+}
+
+func TestCatalogueNonCanonical(t *testing.T) {
+	c, err := Open(t.TempDir())
+
+	if err != nil {
+		t.Fatalf("failed to open catalogue: %v", err)
+	}
+
+	raw_infos := []string{
+		// the keys of info are not sorted
+		"d4:name4:test12:piece lengthi16384e6:lengthi5e6:pieces20:" + strings.Repeat("a", 20) + "e",
+		// the numbers have leading zeros, they would be encoded without them
+		"d6:lengthi05e4:name5:test212:piece lengthi016384e6:pieces020:" + strings.Repeat("a", 20) + "e",
+	}
+
+	for index, raw_info := range raw_infos {
+		data := []byte("d8:announce13:http://a/test4:info" + raw_info + "e")
+
+		entry, err := c.AddRaw(data)
+
+		if err != nil {
+			t.Fatalf("test %d: failed to add: %v", index, err)
+		}
+
+		if entry.InfoHash != sha1.Sum([]byte(raw_info)) {
+			t.Errorf("test %d: expected the hash of the info as written", index)
+		}
+
+		if output, err := c.Raw(entry.InfoHash); err != nil || !bytes.Equal(output, data) {
+			t.Errorf("test %d: expected [%s] | [%s] output: %v", index, data, output, err)
+		}
+
+		if output, err := c.RawInfo(entry.InfoHash); err != nil || string(output) != raw_info {
+			t.Errorf("test %d: expected [%s] | [%s] output: %v", index, raw_info, output, err)
+		}
+	}
+
+	if _, err := c.AddRaw([]byte("d8:announce1:ae")); err != ErrorMissingInfo {
+		t.Errorf("expected [%v] | [%v] output", ErrorMissingInfo, err)
+	}
+}
+
+func TestCatalogueTornIndex(t *testing.T) {
+	c, root, _ := openTestCatalogue(t)
+	index_path := filepath.Join(root, index_file_name)
+
+	complete, err := os.ReadFile(index_path)
+
+	if err != nil {
+		t.Fatalf("failed to read the index: %v", err)
+	}
+
+	entries := c.Find(Query{})
+	last := entries[len(entries)-1].dictionary()
+	encoded, _ := bencode.EncodeElement(last)
+
+	// interrupted appends, cut in a length, in a string, in an integer and before the end
+	for _, cut := range []int{1, 30, len(encoded) - 20, len(encoded) - 1} {
+		if err := os.WriteFile(index_path, append(append([]byte{}, complete...), encoded[:cut]...), 0644); err != nil {
+			t.Fatalf("failed to write the index: %v", err)
+		}
+
+		c, err := Open(root)
+
+		if err != nil {
+			t.Errorf("cut %d: failed to open catalogue: %v", cut, err)
+			continue
+		}
+
+		if c.Len() != len(test_files) {
+			t.Errorf("cut %d: expected [%d] | [%d] output entries", cut, len(test_files), c.Len())
+		}
+
+		if output, _ := os.ReadFile(index_path); !bytes.Equal(output, complete) {
+			t.Errorf("cut %d: expected the torn entry to be removed from the index", cut)
+		}
+	}
+
+	// a corrupted entry followed by other bytes is not a torn append
+	if err := os.WriteFile(index_path, append(append([]byte{}, complete...), "x"+encoded...), 0644); err != nil {
+		t.Fatalf("failed to write the index: %v", err)
+	}
+
+	if _, err := Open(root); !errors.Is(err, ErrorIndexCorrupted) {
+		t.Errorf("expected [%v] | [%v] output", ErrorIndexCorrupted, err)
+	}
+}
+
+func TestCatalogueFailedAppend(t *testing.T) {
+	root := t.TempDir()
+	c, err := Open(root)
+
+	if err != nil {
+		t.Fatalf("failed to open catalogue: %v", err)
+	}
+
+	// the index can not be opened for writing
+	if err := os.Mkdir(filepath.Join(root, index_file_name), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("..", ".test_files", "minecraft.torrent"))
+
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	if _, err := c.AddRaw(data); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(root, "*"+torrent_extension+"*")); len(matches) != 0 {
+		t.Errorf("expected no torrent file | %v output", matches)
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("expected [0] | [%d] output entries", c.Len())
+	}
+}
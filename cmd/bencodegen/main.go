@@ -0,0 +1,45 @@
+// Command bencodegen generates the MarshalBencode and UnmarshalBencode methods of the structs with bencode tags
+//
+//	//go:generate go run github.com/trixky/gobencode/cmd/bencodegen -type File,Info -output file_bencode.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trixky/gobencode/codegen"
+)
+
+func main() {
+	type_names := flag.String("type", "", "comma separated names of the structs")
+	output := flag.String("output", "", "output file, <first type>_bencode.go by default")
+	directory := flag.String("dir", ".", "directory of the package")
+	flag.Parse()
+
+	if len(*type_names) == 0 {
+		fmt.Fprintln(os.Stderr, "bencodegen: -type is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names := strings.Split(*type_names, ",")
+
+	if len(*output) == 0 {
+		*output = strings.ToLower(names[0]) + "_bencode.go"
+	}
+
+	source, err := codegen.Generate(*directory, names)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bencodegen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(*directory, *output), source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "bencodegen: %v\n", err)
+		os.Exit(1)
+	}
+}
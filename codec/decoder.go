@@ -0,0 +1,371 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+const (
+	MaxDepth = 512 // nested lists and dictionaries skipped by Skip
+)
+
+var (
+	ErrorUnexpectedEnd   = errors.New("unexpected end of data")
+	ErrorUnexpectedToken = errors.New("unexpected token")
+	ErrorTrailingData    = errors.New("trailing data")
+	ErrorTooDeep         = errors.New("too many nested elements")
+	ErrorLengthMismatch  = errors.New("string length mismatch")
+	ErrorMissingKey      = errors.New("missing key")
+)
+
+// Decoder reads the tokens of bencoded data one after the other
+//
+// Only the canonical form is accepted: the integers and the string lengths have no leading zero and no negative zero,
+// the keys read by ReadKey are sorted without repetition.
+// The errors are *parser.SyntaxError located at the offset of the token
+type Decoder struct {
+	data   []byte
+	offset int
+}
+
+// NewDecoder creates a decoder reading some bencoded data
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// Offset returns the number of bytes read
+func (d *Decoder) Offset() int {
+	return d.offset
+}
+
+// errorAt returns a syntax error located at an offset
+func errorAt(offset int, err error) error {
+	return &parser.SyntaxError{Offset: offset, Err: err}
+}
+
+// expect reads a token character
+func (d *Decoder) expect(token byte, name string) error {
+	if d.offset >= len(d.data) {
+		return errorAt(d.offset, fmt.Errorf("%w: expected %s", ErrorUnexpectedEnd, name))
+	}
+
+	if d.data[d.offset] != token {
+		return errorAt(d.offset, fmt.Errorf("%w: expected %s, found [%c]", ErrorUnexpectedToken, name, d.data[d.offset]))
+	}
+
+	d.offset++
+
+	return nil
+}
+
+// More checks if a list or a dictionary has more elements, it is false at the end of the data
+func (d *Decoder) More() bool {
+	return d.offset < len(d.data) && d.data[d.offset] != 'e'
+}
+
+// ReadListStart reads the start of a list
+func (d *Decoder) ReadListStart() error {
+	return d.expect('l', "a list")
+}
+
+// ReadDictionaryStart reads the start of a dictionary
+func (d *Decoder) ReadDictionaryStart() error {
+	return d.expect('d', "a dictionary")
+}
+
+// ReadEnd reads the end of a list or of a dictionary
+func (d *Decoder) ReadEnd() error {
+	return d.expect('e', "an end")
+}
+
+// ReadBytes reads a string, the result is a part of the data and must not be modified
+func (d *Decoder) ReadBytes() ([]byte, error) {
+	start := d.offset
+	length := 0
+	position := d.offset
+
+	for ; position < len(d.data) && d.data[position] != ':'; position++ {
+		digit := d.data[position]
+
+		if digit < '0' || digit > '9' {
+			return nil, errorAt(position, fmt.Errorf("%w: expected a string, found [%c]", ErrorUnexpectedToken, digit))
+		}
+
+		if length > (len(d.data)-position)/10 {
+			return nil, errorAt(start, fmt.Errorf("%w: string longer than the data", ErrorUnexpectedEnd))
+		}
+
+		length = length*10 + int(digit-'0')
+	}
+
+	if position == start || position >= len(d.data) {
+		if position >= len(d.data) {
+			return nil, errorAt(position, fmt.Errorf("%w: expected a string", ErrorUnexpectedEnd))
+		}
+
+		return nil, errorAt(position, fmt.Errorf("%w: expected a string, found [:]", ErrorUnexpectedToken))
+	}
+
+	if d.data[start] == '0' && position-start > 1 {
+		return nil, errorAt(start, fmt.Errorf("%w: string length [%s]", parser.ErrorNotCanonical, d.data[start:position]))
+	}
+
+	position++ // :
+
+	if length > len(d.data)-position {
+		return nil, errorAt(start, fmt.Errorf("%w: string of %d bytes", ErrorUnexpectedEnd, length))
+	}
+
+	d.offset = position + length
+
+	return d.data[position:d.offset], nil
+}
+
+// ReadString reads a string
+func (d *Decoder) ReadString() (string, error) {
+	b, err := d.ReadBytes()
+
+	return string(b), err
+}
+
+// ReadFixedBytes reads a string of exactly len(dst) bytes into dst
+func (d *Decoder) ReadFixedBytes(dst []byte) error {
+	start := d.offset
+	b, err := d.ReadBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if len(b) != len(dst) {
+		return errorAt(start, fmt.Errorf("%w: %d bytes instead of %d", ErrorLengthMismatch, len(b), len(dst)))
+	}
+
+	copy(dst, b)
+
+	return nil
+}
+
+// readDigits reads the digits of an integer up to its end, with an optional minus sign
+func (d *Decoder) readDigits() (negative bool, digits []byte, err error) {
+	start := d.offset
+
+	if err := d.expect('i', "an integer"); err != nil {
+		return false, nil, err
+	}
+
+	position := d.offset
+
+	if position < len(d.data) && d.data[position] == '-' {
+		negative = true
+		position++
+	}
+
+	digits_start := position
+
+	for position < len(d.data) && d.data[position] >= '0' && d.data[position] <= '9' {
+		position++
+	}
+
+	if position >= len(d.data) {
+		return false, nil, errorAt(start, fmt.Errorf("%w: expected an integer", ErrorUnexpectedEnd))
+	}
+
+	if position == digits_start || d.data[position] != 'e' {
+		return false, nil, errorAt(position, fmt.Errorf("%w: [%c]", parser.ErrorIntegerCorrupted, d.data[position]))
+	}
+
+	if d.data[digits_start] == '0' && (position-digits_start > 1 || negative) {
+		return false, nil, errorAt(start, fmt.Errorf("%w: integer [%s]", parser.ErrorNotCanonical, d.data[d.offset:position]))
+	}
+
+	d.offset = position + 1
+
+	return negative, d.data[digits_start:position], nil
+}
+
+// ReadUint reads an integer fitting in an unsigned integer of some bits
+func (d *Decoder) ReadUint(bits int) (uint64, error) {
+	start := d.offset
+	negative, digits, err := d.readDigits()
+
+	if err != nil {
+		return 0, err
+	}
+
+	limit := uint64(1)<<uint(bits) - 1
+
+	if bits >= 64 {
+		limit = ^uint64(0)
+	}
+
+	value := uint64(0)
+
+	for _, digit := range digits {
+		if value > (limit-uint64(digit-'0'))/10 {
+			return 0, errorAt(start, fmt.Errorf("%w: [%s] overflows %d bits", parser.ErrorIntegerCorrupted, digits, bits))
+		}
+
+		value = value*10 + uint64(digit-'0')
+	}
+
+	if negative {
+		return 0, errorAt(start, fmt.Errorf("%w: [-%s] is negative", parser.ErrorIntegerCorrupted, digits))
+	}
+
+	return value, nil
+}
+
+// ReadInt reads an integer fitting in a signed integer of some bits
+func (d *Decoder) ReadInt(bits int) (int64, error) {
+	start := d.offset
+	negative, digits, err := d.readDigits()
+
+	if err != nil {
+		return 0, err
+	}
+
+	limit := uint64(1) << uint(bits-1) // the magnitude of the smallest value
+
+	if !negative {
+		limit--
+	}
+
+	value := uint64(0)
+
+	for _, digit := range digits {
+		if value > (limit-uint64(digit-'0'))/10 {
+			return 0, errorAt(start, fmt.Errorf("%w: [%s] overflows %d bits", parser.ErrorIntegerCorrupted, digits, bits))
+		}
+
+		value = value*10 + uint64(digit-'0')
+	}
+
+	if negative {
+		return -int64(value), nil
+	}
+
+	return int64(value), nil
+}
+
+// ReadBool reads an integer, 0 is false and 1 is true
+func (d *Decoder) ReadBool() (bool, error) {
+	start := d.offset
+	value, err := d.ReadInt(64)
+
+	if err != nil {
+		return false, err
+	}
+
+	if value != 0 && value != 1 {
+		return false, errorAt(start, fmt.Errorf("%w: [%d] is not a boolean", parser.ErrorIntegerCorrupted, value))
+	}
+
+	return value == 1, nil
+}
+
+// ReadKey reads a dictionary key, it must be greater than the previous key of the dictionary (nil for the first key)
+//
+// The repeated keys and the keys out of the raw string order are errors, like with the strict options of the parser
+func (d *Decoder) ReadKey(previous []byte) ([]byte, error) {
+	start := d.offset
+	key, err := d.ReadBytes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		switch bytes.Compare(key, previous) {
+		case 0:
+			return nil, errorAt(start, fmt.Errorf("%w: [%s]", parser.ErrorDuplicateKey, key))
+		case -1:
+			return nil, errorAt(start, fmt.Errorf("%w: [%s] after [%s]", parser.ErrorUnsortedKey, key, previous))
+		}
+	}
+
+	return key, nil
+}
+
+// skipLevel is a list or a dictionary opened by Skip
+type skipLevel struct {
+	dictionary bool
+	previous   []byte // last key of a dictionary, nil before the first one
+	value      bool   // the next element of a dictionary is the value of its last key
+}
+
+// Skip reads an element of any type and drops it, the keys of its dictionaries are read by ReadKey
+func (d *Decoder) Skip() error {
+	levels := []skipLevel{}
+
+	for {
+		if d.offset >= len(d.data) {
+			return errorAt(d.offset, fmt.Errorf("%w: expected an element", ErrorUnexpectedEnd))
+		}
+
+		token := d.data[d.offset]
+		top := len(levels) - 1
+
+		if top >= 0 && levels[top].dictionary && !levels[top].value && token != 'e' {
+			key, err := d.ReadKey(levels[top].previous)
+
+			if err != nil {
+				return err
+			}
+
+			levels[top].previous = key
+			levels[top].value = true
+
+			continue
+		}
+
+		switch {
+		case token == 'i':
+			if _, _, err := d.readDigits(); err != nil {
+				return err
+			}
+		case token >= '0' && token <= '9':
+			if _, err := d.ReadBytes(); err != nil {
+				return err
+			}
+		case token == 'l' || token == 'd':
+			if len(levels) == MaxDepth {
+				return errorAt(d.offset, ErrorTooDeep)
+			}
+
+			levels = append(levels, skipLevel{dictionary: token == 'd'})
+			d.offset++
+
+			continue
+		case token == 'e' && top >= 0 && !levels[top].value:
+			levels = levels[:top]
+			d.offset++
+		default:
+			return errorAt(d.offset, fmt.Errorf("%w: [%c]", parser.ErrorInvalidCharacterToStartElement, token))
+		}
+
+		// an element is complete
+		if len(levels) == 0 {
+			return nil
+		}
+
+		levels[len(levels)-1].value = false
+	}
+}
+
+// MissingKey returns the error of a required key missing in the dictionary starting at an offset
+func MissingKey(offset int, key string) error {
+	return errorAt(offset, fmt.Errorf("%w: [%s]", ErrorMissingKey, key))
+}
+
+// Finish checks that all the data was read
+func (d *Decoder) Finish() error {
+	if d.offset != len(d.data) {
+		return errorAt(d.offset, fmt.Errorf("%w: %d bytes", ErrorTrailingData, len(d.data)-d.offset))
+	}
+
+	return nil
+}
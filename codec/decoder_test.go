@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+func TestReadBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      error
+	}{
+		{"4:spam", "spam", nil},
+		{"0:", "", nil},
+		{"5:spam", "", ErrorUnexpectedEnd},
+		{"4spam", "", ErrorUnexpectedToken},
+		{":spam", "", ErrorUnexpectedToken},
+		{"i1e", "", ErrorUnexpectedToken},
+		{"99999999999999999999999:a", "", ErrorUnexpectedEnd},
+		{"03:abc", "", parser.ErrorNotCanonical},
+		{"00:", "", parser.ErrorNotCanonical},
+		{"", "", ErrorUnexpectedEnd},
+	}
+
+	for index, test := range tests {
+		output, err := NewDecoder([]byte(test.input)).ReadString()
+
+		if !errors.Is(err, test.err) || output != test.expected {
+			t.Errorf("test %d: expected [%s] [%v] | [%s] [%v] output", index, test.expected, test.err, output, err)
+		}
+	}
+}
+
+func TestReadInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		bits     int
+		expected int64
+		err      error
+	}{
+		{"i42e", 64, 42, nil},
+		{"i-42e", 64, -42, nil},
+		{"i127e", 8, 127, nil},
+		{"i128e", 8, 0, parser.ErrorIntegerCorrupted},
+		{"i-128e", 8, -128, nil},
+		{"i-129e", 8, 0, parser.ErrorIntegerCorrupted},
+		{"i9223372036854775807e", 64, math.MaxInt64, nil},
+		{"i-9223372036854775808e", 64, math.MinInt64, nil},
+		{"i9223372036854775808e", 64, 0, parser.ErrorIntegerCorrupted},
+		{"ie", 64, 0, parser.ErrorIntegerCorrupted},
+		{"i-e", 64, 0, parser.ErrorIntegerCorrupted},
+		{"i0e", 64, 0, nil},
+		{"i-0e", 64, 0, parser.ErrorNotCanonical},
+		{"i042e", 64, 0, parser.ErrorNotCanonical},
+		{"i1x", 64, 0, parser.ErrorIntegerCorrupted},
+		{"i12", 64, 0, ErrorUnexpectedEnd},
+		{"4:spam", 64, 0, ErrorUnexpectedToken},
+	}
+
+	for index, test := range tests {
+		output, err := NewDecoder([]byte(test.input)).ReadInt(test.bits)
+
+		if !errors.Is(err, test.err) || output != test.expected {
+			t.Errorf("test %d: expected [%d] [%v] | [%d] [%v] output", index, test.expected, test.err, output, err)
+		}
+	}
+}
+
+func TestReadUint(t *testing.T) {
+	tests := []struct {
+		input    string
+		bits     int
+		expected uint64
+		err      error
+	}{
+		{"i65535e", 16, 65535, nil},
+		{"i65536e", 16, 0, parser.ErrorIntegerCorrupted},
+		{"i18446744073709551615e", 64, math.MaxUint64, nil},
+		{"i18446744073709551616e", 64, 0, parser.ErrorIntegerCorrupted},
+		{"i-1e", 64, 0, parser.ErrorIntegerCorrupted},
+		{"i-0e", 64, 0, parser.ErrorNotCanonical},
+	}
+
+	for index, test := range tests {
+		output, err := NewDecoder([]byte(test.input)).ReadUint(test.bits)
+
+		if !errors.Is(err, test.err) || output != test.expected {
+			t.Errorf("test %d: expected [%d] [%v] | [%d] [%v] output", index, test.expected, test.err, output, err)
+		}
+	}
+}
+
+func TestReadOthers(t *testing.T) {
+	d := NewDecoder([]byte("d4:boolli1ei0ee4:hash3:abce"))
+
+	if err := d.ReadDictionaryStart(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if key, err := d.ReadString(); err != nil || key != "bool" {
+		t.Errorf("expected [bool] | [%s] output: %v", key, err)
+	}
+
+	if err := d.ReadListStart(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []bool{true, false} {
+		if output, err := d.ReadBool(); err != nil || output != expected {
+			t.Errorf("expected [%t] | [%t] output: %v", expected, output, err)
+		}
+	}
+
+	if d.More() {
+		t.Errorf("expected the end of the list")
+	}
+
+	if err := d.ReadEnd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d.ReadString()
+
+	short := [4]byte{}
+
+	if err := d.ReadFixedBytes(short[:]); !errors.Is(err, ErrorLengthMismatch) {
+		t.Errorf("expected [%v] | [%v] output", ErrorLengthMismatch, err)
+	}
+
+	var syntax_error *parser.SyntaxError
+
+	if err := NewDecoder([]byte("i2e")).ReadFixedBytes(short[:]); !errors.As(err, &syntax_error) || syntax_error.Offset != 0 {
+		t.Errorf("expected a syntax error at offset 0 | [%v] output", err)
+	}
+
+	if _, err := NewDecoder([]byte("i2e")).ReadBool(); !errors.Is(err, parser.ErrorIntegerCorrupted) {
+		t.Errorf("expected [%v] | [%v] output", parser.ErrorIntegerCorrupted, err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+		err    error
+	}{
+		{"i42e", 4, nil},
+		{"4:spamx", 6, nil},
+		{"d1:ali1e2:bcee1:b", 14, nil},
+		{"le", 2, nil},
+		{"e", 0, parser.ErrorInvalidCharacterToStartElement},
+		{"x", 0, parser.ErrorInvalidCharacterToStartElement},
+		{"l", 1, ErrorUnexpectedEnd},
+		{strings.Repeat("l", MaxDepth+1), MaxDepth, ErrorTooDeep},
+		{"di1ei2ee", 1, ErrorUnexpectedToken}, // the keys are strings
+		{"ld1:ai1ei2eee", 8, ErrorUnexpectedToken},
+		{"d1:ae", 4, parser.ErrorInvalidCharacterToStartElement},
+		{"d1:ai1e1:ai2ee", 10, parser.ErrorDuplicateKey},
+		{"d1:bi1e1:ai2ee", 10, parser.ErrorUnsortedKey},
+		{"d0:i1e1:ai2ee", 13, nil},
+	}
+
+	for index, test := range tests {
+		d := NewDecoder([]byte(test.input))
+		err := d.Skip()
+
+		if !errors.Is(err, test.err) || d.Offset() != test.offset {
+			t.Errorf("test %d: expected [%d] [%v] | [%d] [%v] output", index, test.offset, test.err, d.Offset(), err)
+		}
+	}
+
+	d := NewDecoder([]byte("i1ei2e"))
+	d.Skip()
+
+	if err := d.Finish(); !errors.Is(err, ErrorTrailingData) {
+		t.Errorf("expected [%v] | [%v] output", ErrorTrailingData, err)
+	}
+}
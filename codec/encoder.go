@@ -0,0 +1,81 @@
+// Package codec provide the encoder and the tokenizer used by the generated MarshalBencode and UnmarshalBencode methods
+//
+// The values are appended to a byte slice and read from one token after the other,
+// without reflection and without building the interface{} tree of the parser
+package codec
+
+import (
+	"strconv"
+)
+
+const (
+	IntSize = strconv.IntSize // bits of int and uint
+)
+
+// Marshaler is implemented by the types encoding themselves in the bencode format
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// Unmarshaler is implemented by the types decoding themselves from the bencode format
+type Unmarshaler interface {
+	UnmarshalBencode(data []byte) error
+}
+
+// AppendString appends a bencoded string
+func AppendString(dst []byte, s string) []byte {
+	dst = strconv.AppendInt(dst, int64(len(s)), 10)
+	dst = append(dst, ':')
+
+	return append(dst, s...)
+}
+
+// AppendBytes appends a bencoded string
+func AppendBytes(dst []byte, b []byte) []byte {
+	dst = strconv.AppendInt(dst, int64(len(b)), 10)
+	dst = append(dst, ':')
+
+	return append(dst, b...)
+}
+
+// AppendInt appends a bencoded integer
+func AppendInt(dst []byte, i int64) []byte {
+	dst = append(dst, 'i')
+	dst = strconv.AppendInt(dst, i, 10)
+
+	return append(dst, 'e')
+}
+
+// AppendUint appends a bencoded integer
+func AppendUint(dst []byte, u uint64) []byte {
+	dst = append(dst, 'i')
+	dst = strconv.AppendUint(dst, u, 10)
+
+	return append(dst, 'e')
+}
+
+// AppendBool appends a bencoded integer, 1 for true and 0 for false
+func AppendBool(dst []byte, b bool) []byte {
+	if b {
+		return append(dst, "i1e"...)
+	}
+
+	return append(dst, "i0e"...)
+}
+
+// AppendListStart appends the start of a list, it is closed by AppendEnd
+func AppendListStart(dst []byte) []byte {
+	return append(dst, 'l')
+}
+
+// AppendDictionaryStart appends the start of a dictionary, it is closed by AppendEnd
+//
+// The keys must be appended in the lexicographical order of their bytes
+func AppendDictionaryStart(dst []byte) []byte {
+	return append(dst, 'd')
+}
+
+// AppendEnd appends the end of a list or of a dictionary
+func AppendEnd(dst []byte) []byte {
+	return append(dst, 'e')
+}
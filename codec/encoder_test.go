@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		output   []byte
+		expected string
+	}{
+		{AppendString(nil, ""), "0:"},
+		{AppendString([]byte("x"), "spam"), "x4:spam"},
+		{AppendBytes(nil, []byte{0, 1}), "2:\x00\x01"},
+		{AppendInt(nil, 0), "i0e"},
+		{AppendInt(nil, math.MinInt64), "i-9223372036854775808e"},
+		{AppendUint(nil, math.MaxUint64), "i18446744073709551615e"},
+		{AppendBool(nil, true), "i1e"},
+		{AppendBool(nil, false), "i0e"},
+		{AppendEnd(AppendString(AppendListStart(nil), "a")), "l1:ae"},
+		{AppendEnd(AppendInt(AppendString(AppendDictionaryStart(nil), "a"), 1)), "d1:ai1ee"},
+	}
+
+	for index, test := range tests {
+		if string(test.output) != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, test.output)
+		}
+	}
+}
@@ -0,0 +1,636 @@
+// Package codegen provide a generator of MarshalBencode and UnmarshalBencode methods for the structs with bencode tags
+//
+// The generated methods use the codec package, without reflection:
+//
+//	type File struct {
+//		Length int      `bencode:"length,required"`
+//		Path   []string `bencode:"path,required"`
+//		Attr   string   `bencode:"attr,omitempty"`
+//		Cache  string   // not encoded, no bencode tag
+//	}
+//
+// The supported types are string, []byte, bool, the integers, the byte arrays (fixed length strings),
+// the slices, the maps with string keys, the structs generated in the same package and the pointers to them.
+// A pointer field to another supported type is encoded when it is not nil, it can not be an element of a slice or a map.
+//
+// A type of the package declaring its own AppendBencode and DecodeBencode methods is encoded and decoded with them:
+//
+//	type Pieces []Piece // written as a single string
+//
+// A struct can declare two unexported methods called by the generated ones:
+//
+//	func (x *File) encodingBencode() *File // returns the value to encode, the raw fields filled from the others
+//	func (x *File) decodedBencode() error  // sets the fields without a bencode tag once the dictionary is read
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	TagName         = "bencode"
+	TagOmitEmpty    = "omitempty"
+	TagRequired     = "required" // the dictionary is rejected without the key
+	CodecImportPath = "github.com/trixky/gobencode/codec"
+
+	hookEncoding = "encodingBencode"
+	hookDecoded  = "decodedBencode"
+)
+
+var (
+	ErrorTypeNotFound    = errors.New("type not found")
+	ErrorUnsupportedType = errors.New("unsupported type")
+	ErrorInvalidTag      = errors.New("invalid tag")
+	ErrorDuplicateKey    = errors.New("duplicate key")
+)
+
+type category int
+
+const (
+	categoryString category = iota
+	categoryBytes
+	categoryInt
+	categoryUint
+	categoryBool
+	categoryArray
+	categorySlice
+	categoryMap
+	categoryStruct
+	categoryPointer
+)
+
+// kind is a supported type
+type kind struct {
+	category category
+	expr     string // the type as written in the source
+	named    bool   // the type is declared in the package, a conversion is needed
+	bits     string // integers
+	elem     *kind  // slices, maps and pointers
+}
+
+// field is an encoded field of a struct
+type field struct {
+	name       string
+	key        string
+	omit_empty bool
+	required   bool
+	kind       *kind
+}
+
+// generator reads the types of a package
+type generator struct {
+	package_name string
+	types        map[string]ast.Expr
+	methods      map[string]bool // Type.method
+	uses_sort    bool
+}
+
+// Generate returns the source of the methods of some structs of the package in a directory
+//
+// The test files are not read
+func Generate(directory string, type_names []string) ([]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(directory, "*.go"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{types: map[string]ast.Expr{}, methods: map[string]bool{}}
+	file_set := token.NewFileSet()
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(file_set, path, nil, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		g.package_name = file.Name.Name
+
+		for _, declaration := range file.Decls {
+			if general, ok := declaration.(*ast.GenDecl); ok && general.Tok == token.TYPE {
+				for _, spec := range general.Specs {
+					type_spec := spec.(*ast.TypeSpec)
+					g.types[type_spec.Name.Name] = type_spec.Type
+				}
+			}
+
+			if function, ok := declaration.(*ast.FuncDecl); ok && function.Recv != nil && len(function.Recv.List) == 1 {
+				receiver := function.Recv.List[0].Type
+
+				if star, ok := receiver.(*ast.StarExpr); ok {
+					receiver = star.X
+				}
+
+				g.methods[types.ExprString(receiver)+"."+function.Name.Name] = true
+			}
+		}
+	}
+
+	body := &strings.Builder{}
+
+	for _, type_name := range type_names {
+		if err := g.generateType(body, type_name); err != nil {
+			return nil, fmt.Errorf("%s: %w", type_name, err)
+		}
+	}
+
+	source := &strings.Builder{}
+	source.WriteString("// Code generated by bencodegen. DO NOT EDIT.\n\n")
+	source.WriteString("package " + g.package_name + "\n\nimport (\n")
+
+	if g.uses_sort {
+		source.WriteString("\"sort\"\n\n")
+	}
+
+	source.WriteString(strconv.Quote(CodecImportPath) + "\n)\n")
+	source.WriteString(body.String())
+
+	return format.Source([]byte(source.String()))
+}
+
+// resolve returns the kind of a type
+func (g *generator) resolve(expr ast.Expr) (*kind, error) {
+	k := &kind{expr: types.ExprString(expr)}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			k.category = categoryString
+		case "bool":
+			k.category = categoryBool
+		case "int", "int8", "int16", "int32", "int64", "rune":
+			k.category, k.bits = categoryInt, integerBits(t.Name)
+		case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+			k.category, k.bits = categoryUint, integerBits(t.Name)
+		default:
+			underlying, ok := g.types[t.Name]
+
+			if !ok {
+				return nil, fmt.Errorf("%w: [%s] is not declared in the package", ErrorUnsupportedType, t.Name)
+			}
+
+			if _, ok := underlying.(*ast.StructType); ok {
+				k.category = categoryStruct
+				return k, nil
+			}
+
+			// a custom codec is used as the one of a struct
+			if g.methods[t.Name+".AppendBencode"] && g.methods[t.Name+".DecodeBencode"] {
+				k.category = categoryStruct
+				return k, nil
+			}
+
+			if _, ok := underlying.(*ast.Ident); ok && g.types[underlying.(*ast.Ident).Name] != nil {
+				return nil, fmt.Errorf("%w: [%s] is declared from another declared type", ErrorUnsupportedType, t.Name)
+			}
+
+			resolved, err := g.resolve(underlying)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if resolved.category == categoryStruct || resolved.category == categoryPointer {
+				return nil, fmt.Errorf("%w: [%s]", ErrorUnsupportedType, k.expr)
+			}
+
+			resolved.expr = k.expr
+			resolved.named = true
+
+			return resolved, nil
+		}
+	case *ast.ArrayType:
+		elem, err := g.resolve(t.Elt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.category == categoryPointer && elem.elem.category != categoryStruct {
+			return nil, fmt.Errorf("%w: [%s], only the pointers to structs can be elements", ErrorUnsupportedType, k.expr)
+		}
+
+		is_byte := elem.category == categoryUint && elem.bits == "8" && !elem.named
+
+		if t.Len != nil {
+			if !is_byte {
+				return nil, fmt.Errorf("%w: [%s], only the byte arrays are supported", ErrorUnsupportedType, k.expr)
+			}
+
+			k.category = categoryArray
+		} else if is_byte {
+			k.category = categoryBytes
+		} else {
+			k.category, k.elem = categorySlice, elem
+		}
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("%w: [%s], only the string keys are supported", ErrorUnsupportedType, k.expr)
+		}
+
+		elem, err := g.resolve(t.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.category == categoryPointer && elem.elem.category != categoryStruct {
+			return nil, fmt.Errorf("%w: [%s], only the pointers to structs can be elements", ErrorUnsupportedType, k.expr)
+		}
+
+		k.category, k.elem = categoryMap, elem
+	case *ast.StarExpr:
+		elem, err := g.resolve(t.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.category == categoryPointer {
+			return nil, fmt.Errorf("%w: [%s], the pointers to pointers are not supported", ErrorUnsupportedType, k.expr)
+		}
+
+		k.category, k.elem = categoryPointer, elem
+	default:
+		return nil, fmt.Errorf("%w: [%s]", ErrorUnsupportedType, k.expr)
+	}
+
+	return k, nil
+}
+
+// integerBits returns the bits of an integer type
+func integerBits(name string) string {
+	switch name {
+	case "int8", "uint8", "byte":
+		return "8"
+	case "int16", "uint16":
+		return "16"
+	case "int32", "uint32", "rune":
+		return "32"
+	case "int64", "uint64":
+		return "64"
+	}
+
+	return "codec.IntSize"
+}
+
+// fields returns the encoded fields of a struct sorted by key
+func (g *generator) fields(type_name string) ([]field, error) {
+	expr, ok := g.types[type_name]
+
+	if !ok {
+		return nil, ErrorTypeNotFound
+	}
+
+	struct_type, ok := expr.(*ast.StructType)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: not a struct", ErrorUnsupportedType)
+	}
+
+	fields := []field{}
+	keys := map[string]string{}
+
+	for _, struct_field := range struct_type.Fields.List {
+		if struct_field.Tag == nil {
+			continue
+		}
+
+		tag_value, _ := strconv.Unquote(struct_field.Tag.Value)
+		tag, ok := reflect.StructTag(tag_value).Lookup(TagName)
+
+		if !ok || tag == "-" {
+			continue
+		}
+
+		if len(struct_field.Names) != 1 {
+			return nil, fmt.Errorf("%w: [%s] must be on a single named field", ErrorInvalidTag, tag)
+		}
+
+		options := strings.Split(tag, ",")
+		f := field{name: struct_field.Names[0].Name, key: options[0]}
+
+		if len(f.key) == 0 {
+			return nil, fmt.Errorf("%w: no key for %s", ErrorInvalidTag, f.name)
+		}
+
+		for _, option := range options[1:] {
+			switch option {
+			case TagOmitEmpty:
+				f.omit_empty = true
+			case TagRequired:
+				f.required = true
+			default:
+				return nil, fmt.Errorf("%w: unknown option [%s] for %s", ErrorInvalidTag, option, f.name)
+			}
+		}
+
+		if f.omit_empty && f.required {
+			return nil, fmt.Errorf("%w: %s can not be both omitted and required", ErrorInvalidTag, f.name)
+		}
+
+		if other, ok := keys[f.key]; ok {
+			return nil, fmt.Errorf("%w: [%s] for %s and %s", ErrorDuplicateKey, f.key, other, f.name)
+		}
+
+		keys[f.key] = f.name
+
+		kind, err := g.resolve(struct_field.Type)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+
+		if f.omit_empty && kind.category == categoryStruct {
+			return nil, fmt.Errorf("%w: %s is a struct, it can not be omitted", ErrorInvalidTag, f.name)
+		}
+
+		f.kind = kind
+		fields = append(fields, f)
+	}
+
+	// the keys of a dictionary are sorted as raw strings
+	sort.Slice(fields, func(a, b int) bool {
+		return fields[a].key < fields[b].key
+	})
+
+	return fields, nil
+}
+
+// generateType writes the methods of a struct
+func (g *generator) generateType(w *strings.Builder, type_name string) error {
+	fields, err := g.fields(type_name)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n// MarshalBencode encodes a %s as a bencoded dictionary\n", type_name)
+	fmt.Fprintf(w, "func (x *%s) MarshalBencode() ([]byte, error) {\nreturn x.AppendBencode(nil), nil\n}\n", type_name)
+
+	fmt.Fprintf(w, "\n// AppendBencode appends the bencoded dictionary of a %s\n", type_name)
+	fmt.Fprintf(w, "func (x *%s) AppendBencode(dst []byte) []byte {\n", type_name)
+
+	if g.methods[type_name+"."+hookEncoding] {
+		fmt.Fprintf(w, "x = x.%s()\n", hookEncoding)
+	}
+
+	w.WriteString("dst = codec.AppendDictionaryStart(dst)\n")
+
+	for _, f := range fields {
+		value := "x." + f.name
+
+		if condition := omitCondition(value, f); len(condition) > 0 {
+			fmt.Fprintf(w, "\nif %s {\n", condition)
+			g.appendKeyValue(w, f, value)
+			w.WriteString("}\n")
+		} else {
+			w.WriteString("\n")
+			g.appendKeyValue(w, f, value)
+		}
+	}
+
+	w.WriteString("\nreturn codec.AppendEnd(dst)\n}\n")
+
+	fmt.Fprintf(w, "\n// UnmarshalBencode decodes a %s from a bencoded dictionary\n", type_name)
+	fmt.Fprintf(w, "func (x *%s) UnmarshalBencode(data []byte) error {\nd := codec.NewDecoder(data)\n\n", type_name)
+	w.WriteString("if err := x.DecodeBencode(d); err != nil {\nreturn err\n}\n\nreturn d.Finish()\n}\n")
+
+	required := []field{}
+
+	for _, f := range fields {
+		if f.required {
+			required = append(required, f)
+		}
+	}
+
+	decoded := g.methods[type_name+"."+hookDecoded]
+
+	fmt.Fprintf(w, "\n// DecodeBencode reads a %s from the dictionary at the position of a decoder\n//\n", type_name)
+
+	if decoded {
+		fmt.Fprintf(w, "// The unknown keys are skipped, the fields without a bencode tag are set by %s\n", hookDecoded)
+	} else {
+		w.WriteString("// The unknown keys are skipped, the fields without a bencode tag are kept\n")
+	}
+
+	fmt.Fprintf(w, "func (x *%s) DecodeBencode(d *codec.Decoder) error {\n", type_name)
+
+	if len(required) > 0 {
+		fmt.Fprintf(w, "start := d.Offset()\nfound := [%d]bool{}\n\n", len(required))
+	}
+
+	w.WriteString("if err := d.ReadDictionaryStart(); err != nil {\nreturn err\n}\n\nvar previous []byte\n\n")
+	w.WriteString("for d.More() {\nkey, err := d.ReadKey(previous)\n\nif err != nil {\nreturn err\n}\n\nprevious = key\n\nswitch string(key) {\n")
+
+	for _, f := range fields {
+		fmt.Fprintf(w, "case %s:\n", strconv.Quote(f.key))
+		g.decodeValue(w, "v0", f.kind, 0)
+		fmt.Fprintf(w, "x.%s = v0\n", f.name)
+
+		for index, r := range required {
+			if r.key == f.key {
+				fmt.Fprintf(w, "found[%d] = true\n", index)
+			}
+		}
+	}
+
+	w.WriteString("default:\nif err := d.Skip(); err != nil {\nreturn err\n}\n}\n}\n\n")
+
+	if len(required) == 0 && !decoded {
+		w.WriteString("return d.ReadEnd()\n}\n")
+		return nil
+	}
+
+	w.WriteString("if err := d.ReadEnd(); err != nil {\nreturn err\n}\n")
+
+	for index, r := range required {
+		fmt.Fprintf(w, "\nif !found[%d] {\nreturn codec.MissingKey(start, %s)\n}\n", index, strconv.Quote(r.key))
+	}
+
+	if decoded {
+		fmt.Fprintf(w, "\nreturn x.%s()\n}\n", hookDecoded)
+	} else {
+		w.WriteString("\nreturn nil\n}\n")
+	}
+
+	return nil
+}
+
+// omitCondition returns the condition to encode a field, empty if it is always encoded
+func omitCondition(value string, f field) string {
+	if f.kind.category == categoryPointer {
+		return value + " != nil"
+	}
+
+	if !f.omit_empty {
+		return ""
+	}
+
+	switch f.kind.category {
+	case categoryString, categoryBytes, categorySlice, categoryMap:
+		return "len(" + value + ") > 0"
+	case categoryInt, categoryUint:
+		return value + " != 0"
+	case categoryBool:
+		return value
+	case categoryArray:
+		return value + " != (" + f.kind.expr + "{})"
+	}
+
+	return ""
+}
+
+// appendKeyValue writes the encoding of a field
+func (g *generator) appendKeyValue(w *strings.Builder, f field, value string) {
+	fmt.Fprintf(w, "dst = codec.AppendString(dst, %s)\n", strconv.Quote(f.key))
+	g.appendValue(w, value, f.kind, 0)
+}
+
+// appendValue writes the encoding of a value
+func (g *generator) appendValue(w *strings.Builder, value string, k *kind, depth int) {
+	convert := func(base string) string {
+		if k.named {
+			return base + "(" + value + ")"
+		}
+
+		return value
+	}
+
+	switch k.category {
+	case categoryString:
+		fmt.Fprintf(w, "dst = codec.AppendString(dst, %s)\n", convert("string"))
+	case categoryBytes:
+		fmt.Fprintf(w, "dst = codec.AppendBytes(dst, %s)\n", convert("[]byte"))
+	case categoryInt:
+		fmt.Fprintf(w, "dst = codec.AppendInt(dst, int64(%s))\n", value)
+	case categoryUint:
+		fmt.Fprintf(w, "dst = codec.AppendUint(dst, uint64(%s))\n", value)
+	case categoryBool:
+		fmt.Fprintf(w, "dst = codec.AppendBool(dst, %s)\n", convert("bool"))
+	case categoryArray:
+		fmt.Fprintf(w, "dst = codec.AppendBytes(dst, %s[:])\n", value)
+	case categoryStruct:
+		fmt.Fprintf(w, "dst = %s.AppendBencode(dst)\n", value)
+	case categoryPointer:
+		if k.elem.category == categoryStruct {
+			fmt.Fprintf(w, "dst = %s.AppendBencode(dst)\n", value)
+		} else {
+			pointed := "*" + value
+
+			if k.elem.category == categoryArray {
+				pointed = "(" + pointed + ")" // sliced
+			}
+
+			g.appendValue(w, pointed, k.elem, depth)
+		}
+	case categorySlice:
+		element := fmt.Sprintf("v%d", depth+1)
+
+		w.WriteString("dst = codec.AppendListStart(dst)\n\n")
+		fmt.Fprintf(w, "for _, %s := range %s {\n", element, value)
+		g.appendElement(w, element, k.elem, depth+1)
+		w.WriteString("}\n\ndst = codec.AppendEnd(dst)\n")
+	case categoryMap:
+		keys := fmt.Sprintf("k%d", depth+1)
+		element := fmt.Sprintf("v%d", depth+1)
+		g.uses_sort = true
+
+		fmt.Fprintf(w, "dst = codec.AppendDictionaryStart(dst)\n%s := make([]string, 0, len(%s))\n\n", keys, value)
+		fmt.Fprintf(w, "for key := range %s {\n%s = append(%s, key)\n}\n\nsort.Strings(%s)\n\n", value, keys, keys, keys)
+		fmt.Fprintf(w, "for _, key := range %s {\n%s := %s[key]\ndst = codec.AppendString(dst, key)\n", keys, element, value)
+		g.appendElement(w, element, k.elem, depth+1)
+		w.WriteString("}\n\ndst = codec.AppendEnd(dst)\n")
+	}
+}
+
+// appendElement writes the encoding of an element of a list or of a dictionary, a nil pointer is an empty dictionary
+func (g *generator) appendElement(w *strings.Builder, value string, k *kind, depth int) {
+	if k.category == categoryPointer {
+		fmt.Fprintf(w, "if %s == nil {\ndst = append(dst, \"de\"...)\ncontinue\n}\n\n", value)
+	}
+
+	g.appendValue(w, value, k, depth)
+}
+
+// decodeValue writes the decoding of a value in a new variable
+func (g *generator) decodeValue(w *strings.Builder, variable string, k *kind, depth int) {
+	suffix := strconv.Itoa(depth)
+	read := func(method string, argument string, convert bool) {
+		raw := variable
+
+		if convert {
+			raw = "r" + suffix
+		}
+
+		fmt.Fprintf(w, "%s, err := d.%s(%s)\n\nif err != nil {\nreturn err\n}\n\n", raw, method, argument)
+
+		if convert {
+			fmt.Fprintf(w, "%s := %s(%s)\n", variable, k.expr, raw)
+		}
+	}
+
+	switch k.category {
+	case categoryString:
+		read("ReadString", "", k.named)
+	case categoryBool:
+		read("ReadBool", "", k.named)
+	case categoryInt:
+		read("ReadInt", k.bits, true) // int64 to the integer type
+	case categoryUint:
+		read("ReadUint", k.bits, true)
+	case categoryBytes:
+		fmt.Fprintf(w, "r%s, err := d.ReadBytes()\n\nif err != nil {\nreturn err\n}\n\n", suffix)
+		copied := "append([]byte(nil), r" + suffix + "...)"
+
+		if k.named {
+			copied = k.expr + "(" + copied + ")"
+		}
+
+		fmt.Fprintf(w, "%s := %s\n", variable, copied)
+	case categoryArray:
+		fmt.Fprintf(w, "var %s %s\n\nif err := d.ReadFixedBytes(%s[:]); err != nil {\nreturn err\n}\n\n", variable, k.expr, variable)
+	case categoryStruct:
+		fmt.Fprintf(w, "var %s %s\n\nif err := %s.DecodeBencode(d); err != nil {\nreturn err\n}\n\n", variable, k.expr, variable)
+	case categoryPointer:
+		if k.elem.category == categoryStruct {
+			fmt.Fprintf(w, "%s := new(%s)\n\nif err := %s.DecodeBencode(d); err != nil {\nreturn err\n}\n\n", variable, k.elem.expr, variable)
+		} else {
+			g.decodeValue(w, "p"+suffix, k.elem, depth)
+			fmt.Fprintf(w, "%s := &p%s\n", variable, suffix)
+		}
+	case categorySlice:
+		element := "v" + strconv.Itoa(depth+1)
+
+		fmt.Fprintf(w, "var %s %s\n\nif err := d.ReadListStart(); err != nil {\nreturn err\n}\n\nfor d.More() {\n", variable, k.expr)
+		g.decodeValue(w, element, k.elem, depth+1)
+		fmt.Fprintf(w, "%s = append(%s, %s)\n}\n\nif err := d.ReadEnd(); err != nil {\nreturn err\n}\n\n", variable, variable, element)
+	case categoryMap:
+		element := "v" + strconv.Itoa(depth+1)
+		key := "k" + strconv.Itoa(depth+1)
+		raw_key := "rk" + strconv.Itoa(depth+1)
+		previous := "pk" + strconv.Itoa(depth+1)
+
+		fmt.Fprintf(w, "%s := %s{}\n\nif err := d.ReadDictionaryStart(); err != nil {\nreturn err\n}\n\nvar %s []byte\n\nfor d.More() {\n", variable, k.expr, previous)
+		fmt.Fprintf(w, "%s, err := d.ReadKey(%s)\n\nif err != nil {\nreturn err\n}\n\n%s = %s\n%s := string(%s)\n\n", raw_key, previous, previous, raw_key, key, raw_key)
+		g.decodeValue(w, element, k.elem, depth+1)
+		fmt.Fprintf(w, "%s[%s] = %s\n}\n\nif err := d.ReadEnd(); err != nil {\nreturn err\n}\n\n", variable, key, element)
+	}
+}
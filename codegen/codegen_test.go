@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	tests := []struct {
+		directory  string
+		type_names []string
+		output     string
+	}{
+		{"internal/sample", []string{"Torrent", "Node"}, "sample_bencode.go"},
+		{"../bencode", []string{"File", "infoWire"}, "file_bencode.go"},
+		{"../tracker", []string{"ScrapeFile"}, "scrape_bencode.go"},
+	}
+
+	for index, test := range tests {
+		generated, err := Generate(test.directory, test.type_names)
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		committed, err := os.ReadFile(filepath.Join(test.directory, test.output))
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		if !bytes.Equal(generated, committed) {
+			t.Errorf("test %d: %s is not up to date, run go generate", index, test.output)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		source    string
+		type_name string
+		err       error
+	}{
+		{"type T struct{}", "Missing", ErrorTypeNotFound},
+		{"type T int", "T", ErrorUnsupportedType},
+		{"type T struct{ A float64 `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A map[int]string `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A interface{} `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A string `bencode:\"a\"`; B string `bencode:\"a\"` }", "T", ErrorDuplicateKey},
+		{"type T struct{ A string `bencode:\",omitempty\"` }", "T", ErrorInvalidTag},
+		{"type T struct{ A string `bencode:\"a,required\"` }", "T", nil},
+		{"type T struct{ A string `bencode:\"a,required,omitempty\"` }", "T", ErrorInvalidTag},
+		{"type T struct{ A string `bencode:\"a,unknown\"` }", "T", ErrorInvalidTag},
+		{"type T struct{ A *string `bencode:\"a\"` }", "T", nil},
+		{"type T struct{ A **string `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A []*string `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A map[string]*int `bencode:\"a\"` }", "T", ErrorUnsupportedType},
+		{"type T struct{ A, B string `bencode:\"a\"` }", "T", ErrorInvalidTag},
+		{"type T struct{ A U `bencode:\"a,omitempty\"` }\ntype U struct{}", "T", ErrorInvalidTag},
+		{"type T struct{ A U `bencode:\"a\"` }\ntype U struct{}", "T", nil},
+		{"type T struct{ A string; B string `bencode:\"-\"` }", "T", nil},
+		{"type T struct{ A U `bencode:\"a\"` }\ntype U float64", "T", ErrorUnsupportedType},
+		{"type T struct{ A U `bencode:\"a\"` }\ntype U float64\nfunc (u U) AppendBencode(dst []byte) []byte { return dst }\nfunc (u *U) DecodeBencode(d int) error { return nil }", "T", nil},
+	}
+
+	for index, test := range tests {
+		directory := t.TempDir()
+		source := "package p\n\n" + test.source + "\n"
+
+		if err := os.WriteFile(filepath.Join(directory, "p.go"), []byte(source), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := Generate(directory, []string{test.type_name}); !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+		}
+	}
+}
+
+func TestGenerateSortImport(t *testing.T) {
+	directory := t.TempDir()
+	source := "package p\n\ntype T struct{ A []string `bencode:\"a\"` }\n"
+
+	if err := os.WriteFile(filepath.Join(directory, "p.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	generated, err := Generate(directory, []string{"T"})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bytes.Contains(generated, []byte(`"sort"`)) {
+		t.Errorf("expected no sort import without maps")
+	}
+}
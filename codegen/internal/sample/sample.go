@@ -0,0 +1,42 @@
+// Package sample provide structs covering the types supported by the generator, with their generated methods
+package sample
+
+//go:generate go run github.com/trixky/gobencode/cmd/bencodegen -type Torrent,Node -output sample_bencode.go
+
+type Hash [20]byte
+
+type Kind string
+
+type Torrent struct {
+	Name     string            `bencode:"name"`
+	Raw      []byte            `bencode:"raw,omitempty"`
+	Size     int64             `bencode:"size"`
+	Port     uint16            `bencode:"port,omitempty"`
+	Private  bool              `bencode:"private,omitempty"`
+	Hash     Hash              `bencode:"info hash"`
+	Kind     Kind              `bencode:"kind,omitempty"`
+	Tiers    [][]string        `bencode:"announce-list,omitempty"`
+	Hashes   []Hash            `bencode:"similar,omitempty"`
+	Meta     map[string]int    `bencode:"meta,omitempty"`
+	Root     Node              `bencode:"root"`
+	Parent   *Node             `bencode:"parent"`
+	Children []*Node           `bencode:"children,omitempty"`
+	ByName   map[string]Node   `bencode:"by name,omitempty"`
+	Cache    string            `bencode:"-"`
+	Comment  string            // not encoded
+	Extra    map[string][]byte `bencode:"extra,omitempty"`
+	Pieces   *int              `bencode:"pieces"`
+}
+
+type Node struct {
+	ID      int8   `bencode:"id,required"`
+	Label   string `bencode:"label,omitempty"`
+	Labeled bool   // not encoded, set by decodedBencode
+}
+
+// decodedBencode sets the fields of a Node without a bencode tag
+func (x *Node) decodedBencode() error {
+	x.Labeled = len(x.Label) > 0
+
+	return nil
+}
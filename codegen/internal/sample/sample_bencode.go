@@ -0,0 +1,565 @@
+// Code generated by bencodegen. DO NOT EDIT.
+
+package sample
+
+import (
+	"sort"
+
+	"github.com/trixky/gobencode/codec"
+)
+
+// MarshalBencode encodes a Torrent as a bencoded dictionary
+func (x *Torrent) MarshalBencode() ([]byte, error) {
+	return x.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of a Torrent
+func (x *Torrent) AppendBencode(dst []byte) []byte {
+	dst = codec.AppendDictionaryStart(dst)
+
+	if len(x.Tiers) > 0 {
+		dst = codec.AppendString(dst, "announce-list")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Tiers {
+			dst = codec.AppendListStart(dst)
+
+			for _, v2 := range v1 {
+				dst = codec.AppendString(dst, v2)
+			}
+
+			dst = codec.AppendEnd(dst)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	if len(x.ByName) > 0 {
+		dst = codec.AppendString(dst, "by name")
+		dst = codec.AppendDictionaryStart(dst)
+		k1 := make([]string, 0, len(x.ByName))
+
+		for key := range x.ByName {
+			k1 = append(k1, key)
+		}
+
+		sort.Strings(k1)
+
+		for _, key := range k1 {
+			v1 := x.ByName[key]
+			dst = codec.AppendString(dst, key)
+			dst = v1.AppendBencode(dst)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	if len(x.Children) > 0 {
+		dst = codec.AppendString(dst, "children")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Children {
+			if v1 == nil {
+				dst = append(dst, "de"...)
+				continue
+			}
+
+			dst = v1.AppendBencode(dst)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	if len(x.Extra) > 0 {
+		dst = codec.AppendString(dst, "extra")
+		dst = codec.AppendDictionaryStart(dst)
+		k1 := make([]string, 0, len(x.Extra))
+
+		for key := range x.Extra {
+			k1 = append(k1, key)
+		}
+
+		sort.Strings(k1)
+
+		for _, key := range k1 {
+			v1 := x.Extra[key]
+			dst = codec.AppendString(dst, key)
+			dst = codec.AppendBytes(dst, v1)
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	dst = codec.AppendString(dst, "info hash")
+	dst = codec.AppendBytes(dst, x.Hash[:])
+
+	if len(x.Kind) > 0 {
+		dst = codec.AppendString(dst, "kind")
+		dst = codec.AppendString(dst, string(x.Kind))
+	}
+
+	if len(x.Meta) > 0 {
+		dst = codec.AppendString(dst, "meta")
+		dst = codec.AppendDictionaryStart(dst)
+		k1 := make([]string, 0, len(x.Meta))
+
+		for key := range x.Meta {
+			k1 = append(k1, key)
+		}
+
+		sort.Strings(k1)
+
+		for _, key := range k1 {
+			v1 := x.Meta[key]
+			dst = codec.AppendString(dst, key)
+			dst = codec.AppendInt(dst, int64(v1))
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	dst = codec.AppendString(dst, "name")
+	dst = codec.AppendString(dst, x.Name)
+
+	if x.Parent != nil {
+		dst = codec.AppendString(dst, "parent")
+		dst = x.Parent.AppendBencode(dst)
+	}
+
+	if x.Pieces != nil {
+		dst = codec.AppendString(dst, "pieces")
+		dst = codec.AppendInt(dst, int64(*x.Pieces))
+	}
+
+	if x.Port != 0 {
+		dst = codec.AppendString(dst, "port")
+		dst = codec.AppendUint(dst, uint64(x.Port))
+	}
+
+	if x.Private {
+		dst = codec.AppendString(dst, "private")
+		dst = codec.AppendBool(dst, x.Private)
+	}
+
+	if len(x.Raw) > 0 {
+		dst = codec.AppendString(dst, "raw")
+		dst = codec.AppendBytes(dst, x.Raw)
+	}
+
+	dst = codec.AppendString(dst, "root")
+	dst = x.Root.AppendBencode(dst)
+
+	if len(x.Hashes) > 0 {
+		dst = codec.AppendString(dst, "similar")
+		dst = codec.AppendListStart(dst)
+
+		for _, v1 := range x.Hashes {
+			dst = codec.AppendBytes(dst, v1[:])
+		}
+
+		dst = codec.AppendEnd(dst)
+	}
+
+	dst = codec.AppendString(dst, "size")
+	dst = codec.AppendInt(dst, int64(x.Size))
+
+	return codec.AppendEnd(dst)
+}
+
+// UnmarshalBencode decodes a Torrent from a bencoded dictionary
+func (x *Torrent) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := x.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads a Torrent from the dictionary at the position of a decoder
+//
+// The unknown keys are skipped, the fields without a bencode tag are kept
+func (x *Torrent) DecodeBencode(d *codec.Decoder) error {
+	if err := d.ReadDictionaryStart(); err != nil {
+		return err
+	}
+
+	var previous []byte
+
+	for d.More() {
+		key, err := d.ReadKey(previous)
+
+		if err != nil {
+			return err
+		}
+
+		previous = key
+
+		switch string(key) {
+		case "announce-list":
+			var v0 [][]string
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				var v1 []string
+
+				if err := d.ReadListStart(); err != nil {
+					return err
+				}
+
+				for d.More() {
+					v2, err := d.ReadString()
+
+					if err != nil {
+						return err
+					}
+
+					v1 = append(v1, v2)
+				}
+
+				if err := d.ReadEnd(); err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Tiers = v0
+		case "by name":
+			v0 := map[string]Node{}
+
+			if err := d.ReadDictionaryStart(); err != nil {
+				return err
+			}
+
+			var pk1 []byte
+
+			for d.More() {
+				rk1, err := d.ReadKey(pk1)
+
+				if err != nil {
+					return err
+				}
+
+				pk1 = rk1
+				k1 := string(rk1)
+
+				var v1 Node
+
+				if err := v1.DecodeBencode(d); err != nil {
+					return err
+				}
+
+				v0[k1] = v1
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.ByName = v0
+		case "children":
+			var v0 []*Node
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				v1 := new(Node)
+
+				if err := v1.DecodeBencode(d); err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Children = v0
+		case "extra":
+			v0 := map[string][]byte{}
+
+			if err := d.ReadDictionaryStart(); err != nil {
+				return err
+			}
+
+			var pk1 []byte
+
+			for d.More() {
+				rk1, err := d.ReadKey(pk1)
+
+				if err != nil {
+					return err
+				}
+
+				pk1 = rk1
+				k1 := string(rk1)
+
+				r1, err := d.ReadBytes()
+
+				if err != nil {
+					return err
+				}
+
+				v1 := append([]byte(nil), r1...)
+				v0[k1] = v1
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Extra = v0
+		case "info hash":
+			var v0 Hash
+
+			if err := d.ReadFixedBytes(v0[:]); err != nil {
+				return err
+			}
+
+			x.Hash = v0
+		case "kind":
+			r0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			v0 := Kind(r0)
+			x.Kind = v0
+		case "meta":
+			v0 := map[string]int{}
+
+			if err := d.ReadDictionaryStart(); err != nil {
+				return err
+			}
+
+			var pk1 []byte
+
+			for d.More() {
+				rk1, err := d.ReadKey(pk1)
+
+				if err != nil {
+					return err
+				}
+
+				pk1 = rk1
+				k1 := string(rk1)
+
+				r1, err := d.ReadInt(codec.IntSize)
+
+				if err != nil {
+					return err
+				}
+
+				v1 := int(r1)
+				v0[k1] = v1
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Meta = v0
+		case "name":
+			v0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			x.Name = v0
+		case "parent":
+			v0 := new(Node)
+
+			if err := v0.DecodeBencode(d); err != nil {
+				return err
+			}
+
+			x.Parent = v0
+		case "pieces":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			p0 := int(r0)
+			v0 := &p0
+			x.Pieces = v0
+		case "port":
+			r0, err := d.ReadUint(16)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := uint16(r0)
+			x.Port = v0
+		case "private":
+			v0, err := d.ReadBool()
+
+			if err != nil {
+				return err
+			}
+
+			x.Private = v0
+		case "raw":
+			r0, err := d.ReadBytes()
+
+			if err != nil {
+				return err
+			}
+
+			v0 := append([]byte(nil), r0...)
+			x.Raw = v0
+		case "root":
+			var v0 Node
+
+			if err := v0.DecodeBencode(d); err != nil {
+				return err
+			}
+
+			x.Root = v0
+		case "similar":
+			var v0 []Hash
+
+			if err := d.ReadListStart(); err != nil {
+				return err
+			}
+
+			for d.More() {
+				var v1 Hash
+
+				if err := d.ReadFixedBytes(v1[:]); err != nil {
+					return err
+				}
+
+				v0 = append(v0, v1)
+			}
+
+			if err := d.ReadEnd(); err != nil {
+				return err
+			}
+
+			x.Hashes = v0
+		case "size":
+			r0, err := d.ReadInt(64)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int64(r0)
+			x.Size = v0
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return d.ReadEnd()
+}
+
+// MarshalBencode encodes a Node as a bencoded dictionary
+func (x *Node) MarshalBencode() ([]byte, error) {
+	return x.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of a Node
+func (x *Node) AppendBencode(dst []byte) []byte {
+	dst = codec.AppendDictionaryStart(dst)
+
+	dst = codec.AppendString(dst, "id")
+	dst = codec.AppendInt(dst, int64(x.ID))
+
+	if len(x.Label) > 0 {
+		dst = codec.AppendString(dst, "label")
+		dst = codec.AppendString(dst, x.Label)
+	}
+
+	return codec.AppendEnd(dst)
+}
+
+// UnmarshalBencode decodes a Node from a bencoded dictionary
+func (x *Node) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := x.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads a Node from the dictionary at the position of a decoder
+//
+// The unknown keys are skipped, the fields without a bencode tag are set by decodedBencode
+func (x *Node) DecodeBencode(d *codec.Decoder) error {
+	start := d.Offset()
+	found := [1]bool{}
+
+	if err := d.ReadDictionaryStart(); err != nil {
+		return err
+	}
+
+	var previous []byte
+
+	for d.More() {
+		key, err := d.ReadKey(previous)
+
+		if err != nil {
+			return err
+		}
+
+		previous = key
+
+		switch string(key) {
+		case "id":
+			r0, err := d.ReadInt(8)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int8(r0)
+			x.ID = v0
+			found[0] = true
+		case "label":
+			v0, err := d.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			x.Label = v0
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.ReadEnd(); err != nil {
+		return err
+	}
+
+	if !found[0] {
+		return codec.MissingKey(start, "id")
+	}
+
+	return x.decodedBencode()
+}
@@ -0,0 +1,104 @@
+package sample
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/codec"
+	"github.com/trixky/gobencode/parser"
+)
+
+func TestRoundTrip(t *testing.T) {
+	pieces := 0
+	tests := []Torrent{
+		{Name: "empty", Parent: nil},
+		{
+			Name:     "full",
+			Raw:      []byte{0, 1, 2},
+			Size:     -1 << 40,
+			Port:     6881,
+			Private:  true,
+			Hash:     Hash{1, 2, 3},
+			Kind:     "movie",
+			Tiers:    [][]string{{"a", "b"}, {"c"}},
+			Hashes:   []Hash{{4}, {5}},
+			Meta:     map[string]int{"z": 1, "a": -2, "m": 0},
+			Root:     Node{ID: -128, Label: "root", Labeled: true},
+			Parent:   &Node{ID: 127},
+			Children: []*Node{{ID: 1, Label: "one", Labeled: true}, {ID: 2}},
+			ByName:   map[string]Node{"b": {ID: 2}, "a": {ID: 1}},
+			Extra:    map[string][]byte{"x": []byte("y")},
+			Pieces:   &pieces, // encoded even if it is zero
+		},
+	}
+
+	for index, test := range tests {
+		data, err := test.MarshalBencode()
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		element, err := parser.ParseElementWithOptions(bufio.NewReader(bytes.NewReader(data)), parser.Options{OrderedDictionaries: true})
+
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		if !parser.IsCanonical(element) {
+			t.Errorf("test %d: expected a canonical output | [%s]", index, data)
+		}
+
+		output := Torrent{Cache: "kept", Comment: "kept"}
+
+		if err := output.UnmarshalBencode(data); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", index, err)
+		}
+
+		test.Cache, test.Comment = "kept", "kept"
+
+		if !reflect.DeepEqual(output, test) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, test, output)
+		}
+	}
+}
+
+func TestUnmarshalBencode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Node
+		err      error
+	}{
+		{"d2:idi5ee", Node{ID: 5}, nil},
+		{"d1:ali1ei2ee2:idi5e5:label1:xe", Node{ID: 5, Label: "x", Labeled: true}, nil},
+		{"de", Node{}, codec.ErrorMissingKey},
+		{"d5:label1:xe", Node{Label: "x"}, codec.ErrorMissingKey},
+		{"d2:idi128ee", Node{}, parser.ErrorIntegerCorrupted},
+		{"d2:id1:xe", Node{}, codec.ErrorUnexpectedToken},
+		{"d2:idi5e", Node{ID: 5}, codec.ErrorUnexpectedEnd},
+		{"d2:idi5eei0e", Node{ID: 5}, codec.ErrorTrailingData},
+		{"li5ee", Node{}, codec.ErrorUnexpectedToken},
+		{"d2:idi5e2:idi6ee", Node{ID: 5}, parser.ErrorDuplicateKey},
+		{"d5:label1:x2:idi5ee", Node{Label: "x"}, parser.ErrorUnsortedKey},
+		{"d2:idi5e1:zi1e1:ai1ee", Node{ID: 5}, parser.ErrorUnsortedKey},
+		{"d1:adi1ei2ee2:idi5ee", Node{}, codec.ErrorUnexpectedToken},
+	}
+
+	for index, test := range tests {
+		output := Node{}
+		err := output.UnmarshalBencode([]byte(test.input))
+
+		if !errors.Is(err, test.err) || output != test.expected {
+			t.Errorf("test %d: expected [%+v] [%v] | [%+v] [%v] output", index, test.expected, test.err, output, err)
+		}
+	}
+
+	torrent := Torrent{}
+
+	if err := torrent.UnmarshalBencode([]byte("d9:info hash3:abce")); !errors.Is(err, codec.ErrorLengthMismatch) {
+		t.Errorf("expected [%v] | [%v] output", codec.ErrorLengthMismatch, err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package crossseed provide a matcher of the files of a torrent with the files already on disk
+//
+// The candidates are found by length and name, then confirmed by hashing some of their pieces
+package crossseed
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	DefaultSamplePieces = 3
+)
+
+// FileMatch is the local file found for a file of a torrent
+type FileMatch struct {
+	FileIndex  int
+	LocalPath  string // empty if no local file has the length of the file
+	Confirmed  bool   // the sample pieces matched Info.Pieces, always true for a zero-length file
+	Candidates int    // local files of the same length
+	Checked    int    // pieces hashed to find the file
+}
+
+// Result is the matching of the files of a torrent
+type Result struct {
+	Files      []FileMatch // the files of the torrent, padding files excluded
+	Completion float64     // percentage of the torrent bytes in the confirmed files, padding included
+}
+
+// Mapping returns the local path of the confirmed files by file index
+func (r *Result) Mapping() map[int]string {
+	mapping := map[int]string{}
+
+	for _, match := range r.Files {
+		if match.Confirmed && len(match.LocalPath) > 0 {
+			mapping[match.FileIndex] = match.LocalPath
+		}
+	}
+
+	return mapping
+}
+
+// Matcher finds the files of torrents in a library
+type Matcher struct {
+	SamplePieces int // pieces hashed in a file to confirm a candidate
+
+	library *Library
+}
+
+// NewMatcher creates a matcher hashing DefaultSamplePieces pieces per file
+func NewMatcher(library *Library) *Matcher {
+	return &Matcher{
+		SamplePieces: DefaultSamplePieces,
+		library:      library,
+	}
+}
+
+// Match finds the files of a torrent in the library
+//
+// A file containing whole pieces is confirmed by hashing some of them (the first, the last and evenly
+// spaced ones), a smaller file is confirmed by the pieces it shares with its neighbours
+func (m *Matcher) Match(ctx context.Context, bc *bencode.Bencode) (*Result, error) {
+	info := &bc.Info
+
+	if info.PieceLength <= 0 {
+		return nil, fmt.Errorf("%w: %d", bencode.ErrorInvalidPieceLength, info.PieceLength)
+	}
+
+	offsets := info.FileOffsets()
+	result := &Result{}
+	unconfirmed := []pendingMatch{} // files without whole pieces
+
+	for index := range info.Files {
+		file := &info.Files[index]
+
+		if file.IsPadding() {
+			continue
+		}
+
+		match := FileMatch{FileIndex: index}
+
+		if file.Length == 0 {
+			match.Confirmed = true
+			result.Files = append(result.Files, match)
+			continue
+		}
+
+		candidates := m.library.Candidates(file.Length, file.PathComponents())
+		match.Candidates = len(candidates)
+		samples := samplePieces(info, index, m.SamplePieces)
+
+		if len(samples) == 0 && len(candidates) > 0 {
+			match.LocalPath = candidates[0]
+			unconfirmed = append(unconfirmed, pendingMatch{position: len(result.Files), candidates: candidates})
+		}
+
+		for _, candidate := range candidates {
+			if len(samples) == 0 {
+				break
+			}
+
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			checked, ok := checkSamples(info, candidate, offsets[index], samples)
+			match.Checked += checked
+
+			if ok {
+				match.LocalPath = candidate
+				match.Confirmed = true
+				break
+			}
+		}
+
+		result.Files = append(result.Files, match)
+	}
+
+	if err := confirmSharedPieces(ctx, info, result, unconfirmed); err != nil {
+		return nil, err
+	}
+
+	result.Completion = completion(info, result)
+
+	return result, nil
+}
+
+// pendingMatch is a file confirmed after the others, with its candidates
+type pendingMatch struct {
+	position   int // in Result.Files
+	candidates []string
+}
+
+// samplePieces returns some pieces lying wholly inside a file: the first, the last and evenly spaced ones
+func samplePieces(info *bencode.Info, file_index int, count int) []int {
+	first, last, err := info.FileWholePieces(file_index)
+
+	if err != nil || last < first || count <= 0 {
+		return nil
+	}
+
+	total := last - first + 1
+
+	if count >= total {
+		count = total
+	}
+
+	samples := make([]int, count)
+
+	for index := range samples {
+		if count == 1 {
+			samples[index] = first
+		} else {
+			samples[index] = first + index*(total-1)/(count-1)
+		}
+	}
+
+	return samples
+}
+
+// checkSamples hashes some pieces of a local file, it returns the number of pieces hashed
+func checkSamples(info *bencode.Info, local_path string, file_offset int, samples []int) (int, bool) {
+	local_file, err := os.Open(local_path)
+
+	if err != nil {
+		return 0, false
+	}
+
+	defer local_file.Close()
+
+	for checked, piece_index := range samples {
+		piece_length, err := info.PieceLengthAt(piece_index)
+
+		if err != nil {
+			return checked + 1, false
+		}
+
+		data := make([]byte, piece_length)
+
+		if _, err := local_file.ReadAt(data, int64(piece_index*info.PieceLength-file_offset)); err != nil {
+			return checked + 1, false
+		}
+
+		if bencode.Piece(sha1.Sum(data)) != info.Pieces[piece_index] {
+			return checked + 1, false
+		}
+	}
+
+	return len(samples), true
+}
+
+// confirmSharedPieces confirms the files without whole pieces with the pieces they share with their neighbours,
+// each candidate of a file is tried until a shared piece matches
+func confirmSharedPieces(ctx context.Context, info *bencode.Info, result *Result, unconfirmed []pendingMatch) error {
+	matches := map[int]*FileMatch{}
+
+	for index := range result.Files {
+		matches[result.Files[index].FileIndex] = &result.Files[index]
+	}
+
+	for _, pending := range unconfirmed {
+		match := &result.Files[pending.position]
+		first, last, _ := info.FilePieces(match.FileIndex)
+
+		for _, candidate := range pending.candidates {
+			if match.Confirmed {
+				break
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			match.LocalPath = candidate
+
+			for piece_index := first; piece_index <= last && !match.Confirmed; piece_index++ {
+				spans, data, ok := readSharedPiece(info, matches, piece_index)
+
+				if !ok {
+					continue
+				}
+
+				match.Checked++
+
+				if bencode.Piece(sha1.Sum(data)) == info.Pieces[piece_index] {
+					for _, span := range spans {
+						if neighbour, ok := matches[span.FileIndex]; ok {
+							neighbour.Confirmed = true
+						}
+					}
+				}
+			}
+		}
+
+		if !match.Confirmed {
+			match.LocalPath = pending.candidates[0]
+		}
+	}
+
+	return nil
+}
+
+// readSharedPiece reads a piece from the local files matched so far, the padding files are read as zeros
+func readSharedPiece(info *bencode.Info, matches map[int]*FileMatch, piece_index int) ([]bencode.FileSpan, []byte, bool) {
+	spans, err := info.PieceSpans(piece_index)
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	piece := []byte{}
+
+	for _, span := range spans {
+		data := make([]byte, span.Length)
+
+		if !info.Files[span.FileIndex].IsPadding() {
+			match, ok := matches[span.FileIndex]
+
+			if !ok || len(match.LocalPath) == 0 {
+				return nil, nil, false
+			}
+
+			local_file, err := os.Open(match.LocalPath)
+
+			if err != nil {
+				return nil, nil, false
+			}
+
+			_, err = local_file.ReadAt(data, int64(span.Offset))
+			local_file.Close()
+
+			if err != nil {
+				return nil, nil, false
+			}
+		}
+
+		piece = append(piece, data...)
+	}
+
+	return spans, piece, true
+}
+
+// completion returns the percentage of the torrent bytes in the confirmed files, padding included
+func completion(info *bencode.Info, result *Result) float64 {
+	total_length := info.TotalLength()
+
+	if total_length == 0 {
+		return 100
+	}
+
+	found := 0
+
+	for _, file := range info.Files {
+		if file.IsPadding() {
+			found += file.Length
+		}
+	}
+
+	for _, match := range result.Files {
+		if match.Confirmed {
+			found += info.Files[match.FileIndex].Length
+		}
+	}
+
+	return float64(found) * 100 / float64(total_length)
+}
@@ -0,0 +1,177 @@
+package crossseed
+
+import (
+	"context"
+	"crypto/sha1"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+type testFile struct {
+	path    string
+	content string
+}
+
+// newTestTorrent returns a multi file torrent of some files
+func newTestTorrent(piece_length int, files []testFile) *bencode.Bencode {
+	bc := &bencode.Bencode{
+		Info: bencode.Info{
+			DirectoryName: "dir",
+			PieceLength:   piece_length,
+		},
+	}
+
+	content := ""
+
+	for _, file := range files {
+		bc.Info.Files = append(bc.Info.Files, bencode.File{
+			Length:         len(file.content),
+			Path:           file.path,
+			DecomposedPath: strings.Split(file.path, "/"),
+			CompletePath:   "dir/" + file.path,
+		})
+		content += file.content
+	}
+
+	for start := 0; start < len(content); start += piece_length {
+		end := start + piece_length
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		bc.Info.Pieces = append(bc.Info.Pieces, bencode.Piece(sha1.Sum([]byte(content[start:end]))))
+	}
+
+	return bc
+}
+
+func TestSamplePieces(t *testing.T) {
+	tests := []struct {
+		offset   int
+		length   int
+		count    int
+		expected []int
+	}{
+		{0, 38, 3, []int{0, 4, 9}},
+		{0, 38, 1, []int{0}},
+		{0, 38, 20, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{2, 12, 3, []int{1, 2}},
+		{2, 5, 3, nil},
+		{4, 4, 3, []int{1}},
+		{36, 2, 3, []int{9}},
+		{0, 38, 0, nil},
+	}
+
+	for index, test := range tests {
+		// the file is the second one of a torrent of 38 bytes
+		info := &bencode.Info{PieceLength: 4, Pieces: make([]bencode.Piece, 10), Files: []bencode.File{
+			{Length: test.offset},
+			{Length: test.length},
+			{Length: 38 - test.offset - test.length},
+		}}
+
+		if output := samplePieces(info, 1, test.count); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	// pieces: 0123 4567 89ab xyzT AIL! MISS
+	bc := newTestTorrent(4, []testFile{
+		{"big.bin", "0123456789ab"},
+		{"small.txt", "xyz"},
+		{"tail.txt", "TAIL!"},
+		{"empty", ""},
+		{"missing.bin", "MISS"},
+	})
+
+	directory := t.TempDir()
+
+	writeLibrary(t, directory, map[string]string{
+		"a/dir/big.bin":   "0123456789aX", // better name, wrong last piece
+		"b/other/copy":    "0123456789ab",
+		"a/dir/small.txt": "abc",
+		"b/small.txt":     "xyz",
+		"c/tail.txt":      "TAIL!",
+	})
+
+	library := NewLibrary(directory)
+
+	if err := library.Scan(context.Background()); err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	result, err := NewMatcher(library).Match(context.Background(), bc)
+
+	if err != nil {
+		t.Fatalf("failed to match: %v", err)
+	}
+
+	expected := []FileMatch{
+		{FileIndex: 0, LocalPath: filepath.Join(directory, "b", "other", "copy"), Confirmed: true, Candidates: 2, Checked: 6},
+		{FileIndex: 1, LocalPath: filepath.Join(directory, "b", "small.txt"), Confirmed: true, Candidates: 2, Checked: 2},
+		{FileIndex: 2, LocalPath: filepath.Join(directory, "c", "tail.txt"), Confirmed: true, Candidates: 1, Checked: 1},
+		{FileIndex: 3, Confirmed: true},
+		{FileIndex: 4},
+	}
+
+	if !reflect.DeepEqual(result.Files, expected) {
+		t.Errorf("expected %+v | %+v output", expected, result.Files)
+	}
+
+	if expected := 20.0 * 100 / 24; math.Abs(result.Completion-expected) > 0.001 {
+		t.Errorf("expected [%f] | [%f] output", expected, result.Completion)
+	}
+
+	if mapping := result.Mapping(); len(mapping) != 3 || mapping[2] != filepath.Join(directory, "c", "tail.txt") {
+		t.Errorf("expected 3 files | %v output", mapping)
+	}
+}
+
+func TestMatchUnconfirmed(t *testing.T) {
+	// the only piece is shared by small.txt and other.txt, no local copy of other.txt exists
+	bc := newTestTorrent(4, []testFile{
+		{"small.txt", "xy"},
+		{"other.txt", "zz"},
+	})
+
+	directory := t.TempDir()
+
+	writeLibrary(t, directory, map[string]string{
+		"dir/small.txt": "xy",
+		"elsewhere":     "ab",
+	})
+
+	library := NewLibrary(directory)
+
+	if err := library.Scan(context.Background()); err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	result, err := NewMatcher(library).Match(context.Background(), bc)
+
+	if err != nil {
+		t.Fatalf("failed to match: %v", err)
+	}
+
+	// the files keep their best candidate but the shared piece matches no pair of candidates
+	expected := []FileMatch{
+		{FileIndex: 0, LocalPath: filepath.Join(directory, "dir", "small.txt"), Candidates: 2, Checked: 2},
+		{FileIndex: 1, LocalPath: filepath.Join(directory, "dir", "small.txt"), Candidates: 2, Checked: 2},
+	}
+
+	if !reflect.DeepEqual(result.Files, expected) || result.Completion != 0 {
+		t.Errorf("expected %+v | %+v output (%f)", expected, result.Files, result.Completion)
+	}
+
+	if _, err := NewMatcher(library).Match(context.Background(), &bencode.Bencode{}); err == nil {
+		t.Errorf("expected an error for an invalid piece length")
+	}
+}
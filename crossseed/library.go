@@ -0,0 +1,126 @@
+package crossseed
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Library indexes the regular files of some directories by length
+type Library struct {
+	Directories []string
+
+	files map[int64][]string
+	count int
+	mutex sync.RWMutex
+}
+
+// NewLibrary creates an empty library of some directories, Scan must be called before matching
+func NewLibrary(directories ...string) *Library {
+	return &Library{
+		Directories: directories,
+		files:       map[int64][]string{},
+	}
+}
+
+// Scan walks the directories and indexes their regular files, the symlinks are not followed
+//
+// The previous index is replaced, the unreadable sub directories are skipped
+func (l *Library) Scan(ctx context.Context) error {
+	files := map[int64][]string{}
+	count := 0
+
+	for _, directory := range l.Directories {
+		err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err != nil {
+				if d != nil && d.IsDir() && path != directory {
+					return fs.SkipDir
+				}
+
+				return err
+			}
+
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+
+			if err != nil {
+				return nil
+			}
+
+			files[info.Size()] = append(files[info.Size()], path)
+			count++
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.files = files
+	l.count = count
+
+	return nil
+}
+
+// Count returns the number of indexed files
+func (l *Library) Count() int {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.count
+}
+
+// Candidates returns the indexed files of a length, the files sharing the most
+// trailing path components with the torrent file first (same name, same parent directory...)
+func (l *Library) Candidates(length int, components []string) []string {
+	l.mutex.RLock()
+	candidates := append([]string{}, l.files[int64(length)]...)
+	l.mutex.RUnlock()
+
+	scores := map[string]int{}
+
+	for _, candidate := range candidates {
+		scores[candidate] = nameScore(candidate, components)
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if scores[candidates[a]] != scores[candidates[b]] {
+			return scores[candidates[a]] > scores[candidates[b]]
+		}
+
+		return candidates[a] < candidates[b]
+	})
+
+	return candidates
+}
+
+// nameScore returns the number of trailing path components shared by a local file and a torrent file
+func nameScore(local_path string, components []string) int {
+	local_components := strings.Split(filepath.ToSlash(local_path), "/")
+	score := 0
+
+	for score < len(components) && score < len(local_components) {
+		if local_components[len(local_components)-1-score] != components[len(components)-1-score] {
+			break
+		}
+
+		score++
+	}
+
+	return score
+}
@@ -0,0 +1,101 @@
+package crossseed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeLibrary writes some files of a library, by path relative to a directory
+func writeLibrary(t *testing.T, directory string, files map[string]string) {
+	for path, content := range files {
+		local_path := filepath.Join(directory, filepath.FromSlash(path))
+
+		if err := os.MkdirAll(filepath.Dir(local_path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(local_path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	tests := []struct {
+		local_path string
+		components []string
+		expected   int
+	}{
+		{"/lib/dir/sub/a.txt", []string{"dir", "sub", "a.txt"}, 3},
+		{"/lib/other/sub/a.txt", []string{"dir", "sub", "a.txt"}, 2},
+		{"/lib/a.txt", []string{"dir", "sub", "a.txt"}, 1},
+		{"/lib/b.txt", []string{"dir", "sub", "a.txt"}, 0},
+		{"a.txt", []string{"a.txt"}, 1},
+	}
+
+	for index, test := range tests {
+		if output := nameScore(test.local_path, test.components); output != test.expected {
+			t.Errorf("test %d: expected [%d] | [%d] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+
+	writeLibrary(t, first, map[string]string{
+		"x/b.txt":     "12345",
+		"dir/a.txt":   "12345",
+		"other/a.txt": "abcde",
+		"short":       "1",
+	})
+	writeLibrary(t, second, map[string]string{
+		"a.txt": "ABCDE",
+	})
+
+	if err := os.Symlink(filepath.Join(first, "short"), filepath.Join(second, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	library := NewLibrary(first, second)
+
+	if err := library.Scan(context.Background()); err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	if library.Count() != 5 {
+		t.Errorf("expected [5] | [%d] output", library.Count())
+	}
+
+	tests := []struct {
+		length     int
+		components []string
+		expected   []string
+	}{
+		{5, []string{"dir", "a.txt"}, []string{
+			filepath.Join(first, "dir", "a.txt"),
+			filepath.Join(first, "other", "a.txt"),
+			filepath.Join(second, "a.txt"),
+			filepath.Join(first, "x", "b.txt"),
+		}},
+		{1, []string{"link"}, []string{filepath.Join(first, "short")}},
+		{2, []string{"a.txt"}, []string{}},
+	}
+
+	for index, test := range tests {
+		if output := library.Candidates(test.length, test.components); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := library.Scan(ctx); err != context.Canceled {
+		t.Errorf("expected [%v] | [%v] output", context.Canceled, err)
+	}
+}
@@ -0,0 +1,105 @@
+// Package dht provide the KRPC messages of the BitTorrent DHT
+//
+// http://www.bittorrent.org/beps/bep_0005.html
+package dht
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	CompactNodeLengthIPv4 = 26 // 20 bytes of id + 4 bytes of ip + 2 bytes of port
+	CompactNodeLengthIPv6 = 38 // 20 bytes of id + 16 bytes of ip + 2 bytes of port
+)
+
+var (
+	ErrorCompactNodesCorrupted = errors.New("compact nodes corrupted")
+	ErrorInvalidNodeID         = errors.New("invalid node id")
+)
+
+type NodeID [20]byte
+
+// String returns the hexadecimal form of the node id
+func (id NodeID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Distance returns the xor distance between two node ids
+func (id NodeID) Distance(other NodeID) (distance NodeID) {
+	for i := range id {
+		distance[i] = id[i] ^ other[i]
+	}
+
+	return
+}
+
+type Node struct {
+	ID   NodeID
+	IP   net.IP
+	Port int
+}
+
+// toNodeID converts a 20 bytes string to a node id
+func toNodeID(data string) (id NodeID, err error) {
+	if len(data) != len(id) {
+		return id, fmt.Errorf("%w: length %d", ErrorInvalidNodeID, len(data))
+	}
+
+	copy(id[:], data)
+
+	return id, nil
+}
+
+// DecodeCompactNodes decodes a compact node info list where each node takes node_length bytes
+//
+// http://www.bittorrent.org/beps/bep_0005.html (nodes)
+// http://www.bittorrent.org/beps/bep_0032.html (nodes6)
+func DecodeCompactNodes(data string, node_length int) ([]Node, error) {
+	if node_length != CompactNodeLengthIPv4 && node_length != CompactNodeLengthIPv6 {
+		return nil, fmt.Errorf("%w: invalid node length %d", ErrorCompactNodesCorrupted, node_length)
+	}
+
+	if len(data)%node_length != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", ErrorCompactNodesCorrupted, len(data), node_length)
+	}
+
+	ip_length := node_length - 22
+	nodes := make([]Node, 0, len(data)/node_length)
+
+	for start := 0; start < len(data); start += node_length {
+		node := Node{
+			IP:   make(net.IP, ip_length),
+			Port: int(binary.BigEndian.Uint16([]byte(data[start+20+ip_length : start+node_length]))),
+		}
+
+		copy(node.ID[:], data[start:start+20])
+		copy(node.IP, data[start+20:start+20+ip_length])
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// EncodeCompactNodes encodes nodes in the compact format, IPv4 and IPv6 nodes are returned separately
+func EncodeCompactNodes(nodes []Node) (nodes4 string, nodes6 string) {
+	buffer4 := []byte{}
+	buffer6 := []byte{}
+	port := make([]byte, 2)
+
+	for _, node := range nodes {
+		binary.BigEndian.PutUint16(port, uint16(node.Port))
+
+		if ip4 := node.IP.To4(); ip4 != nil {
+			buffer4 = append(append(append(buffer4, node.ID[:]...), ip4...), port...)
+		} else if ip6 := node.IP.To16(); ip6 != nil {
+			buffer6 = append(append(append(buffer6, node.ID[:]...), ip6...), port...)
+		}
+	}
+
+	return string(buffer4), string(buffer6)
+}
@@ -0,0 +1,99 @@
+package dht
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCompactNodes(t *testing.T) {
+	id := "abcdefghij0123456789"
+
+	tests := []struct {
+		input       string
+		node_length int
+		expected    []Node
+		fail        bool
+	}{
+		{
+			input:       "",
+			node_length: CompactNodeLengthIPv4,
+			expected:    []Node{},
+		},
+		{
+			input:       id + "\x7f\x00\x00\x01\x1a\xe1",
+			node_length: CompactNodeLengthIPv4,
+			expected:    []Node{{ID: NodeID{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}, IP: net.IP{127, 0, 0, 1}, Port: 6881}},
+		},
+		{
+			input:       id + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1",
+			node_length: CompactNodeLengthIPv6,
+			expected:    []Node{{ID: NodeID{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}, IP: net.IPv6loopback, Port: 6881}},
+		},
+		{
+			input:       id + "\x7f\x00\x00\x01\x1a",
+			node_length: CompactNodeLengthIPv4,
+			fail:        true,
+		},
+		{
+			input:       id + "\x7f\x00\x00\x01\x1a\xe1",
+			node_length: 20,
+			fail:        true,
+		},
+	}
+
+	for index, test := range tests {
+		output, err := DecodeCompactNodes(test.input, test.node_length)
+
+		if test.fail {
+			if err == nil {
+				t.Errorf("test %d: expected an error", index)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: failed to decode nodes: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestEncodeCompactNodes(t *testing.T) {
+	nodes := []Node{
+		{ID: NodeID{1}, IP: net.ParseIP("127.0.0.1"), Port: 6881},
+		{ID: NodeID{2}, IP: net.IPv6loopback, Port: 6882},
+	}
+
+	nodes4, nodes6 := EncodeCompactNodes(nodes)
+
+	if len(nodes4) != CompactNodeLengthIPv4 || len(nodes6) != CompactNodeLengthIPv6 {
+		t.Fatalf("bad lengths: %d | %d", len(nodes4), len(nodes6))
+	}
+
+	decoded4, _ := DecodeCompactNodes(nodes4, CompactNodeLengthIPv4)
+	decoded6, _ := DecodeCompactNodes(nodes6, CompactNodeLengthIPv6)
+
+	if decoded4[0].ID != nodes[0].ID || !decoded4[0].IP.Equal(nodes[0].IP) || decoded4[0].Port != 6881 {
+		t.Errorf("expected %v | %v output", nodes[0], decoded4[0])
+	}
+	if decoded6[0].ID != nodes[1].ID || !decoded6[0].IP.Equal(nodes[1].IP) || decoded6[0].Port != 6882 {
+		t.Errorf("expected %v | %v output", nodes[1], decoded6[0])
+	}
+}
+
+func TestNodeIDDistance(t *testing.T) {
+	a := NodeID{0xff, 0x0f}
+	b := NodeID{0x0f, 0x0f, 0x01}
+
+	if expected := (NodeID{0xf0, 0x00, 0x01}); a.Distance(b) != expected {
+		t.Errorf("expected %v | %v output", expected, a.Distance(b))
+	}
+	if a.Distance(a) != (NodeID{}) {
+		t.Errorf("distance to itself is not zero")
+	}
+}
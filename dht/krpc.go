@@ -0,0 +1,611 @@
+package dht
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+	"github.com/trixky/gobencode/tracker"
+)
+
+const (
+	DictionaryKeyTransactionID = "t"
+	DictionaryKeyType          = "y"
+	DictionaryKeyQuery         = "q"
+	DictionaryKeyArguments     = "a"
+	DictionaryKeyResponse      = "r"
+	DictionaryKeyError         = "e"
+	DictionaryKeyVersion       = "v"
+	DictionaryKeyID            = "id"
+	DictionaryKeyTarget        = "target"
+	DictionaryKeyInfoHash      = "info_hash"
+	DictionaryKeyPort          = "port"
+	DictionaryKeyToken         = "token"
+	DictionaryKeyImpliedPort   = "implied_port"
+	DictionaryKeyNodes         = "nodes"
+	DictionaryKeyNodes6        = "nodes6"
+	DictionaryKeyValues        = "values"
+)
+
+const (
+	TypeQuery    = "q"
+	TypeResponse = "r"
+	TypeError    = "e"
+
+	QueryPing         = "ping"
+	QueryFindNode     = "find_node"
+	QueryGetPeers     = "get_peers"
+	QueryAnnouncePeer = "announce_peer"
+
+	ErrorCodeGeneric       = 201
+	ErrorCodeServer        = 202
+	ErrorCodeProtocol      = 203
+	ErrorCodeMethodUnknown = 204
+)
+
+var (
+	ErrorMessageCorrupted      = errors.New("krpc message corrupted")
+	ErrorTrailingData          = errors.New("trailing data after message")
+	ErrorMissingKey            = errors.New("missing key")
+	ErrorBadType               = errors.New("bad type")
+	ErrorUnknownMessageType    = errors.New("unknown message type")
+	ErrorUnknownQuery          = errors.New("unknown query")
+	ErrorInvalidPort           = errors.New("invalid port")
+	ErrorInvalidImpliedPort    = errors.New("invalid implied port")
+	ErrorInvalidPeer           = errors.New("invalid compact peer")
+	ErrorMessageWithoutBody    = errors.New("message without query, response or error")
+	ErrorMessageWithManyBodies = errors.New("message with more than one query, response or error")
+)
+
+// Query is implemented by the typed KRPC queries
+type Query interface {
+	// Name returns the method name of the query (q)
+	Name() string
+	arguments() map[string]interface{}
+}
+
+type PingQuery struct {
+	ID NodeID
+}
+
+type FindNodeQuery struct {
+	ID     NodeID
+	Target NodeID
+}
+
+type GetPeersQuery struct {
+	ID       NodeID
+	InfoHash [20]byte
+}
+
+type AnnouncePeerQuery struct {
+	ID          NodeID
+	InfoHash    [20]byte
+	Port        int
+	Token       string
+	ImpliedPort bool // the port of the udp packet is used instead of Port
+}
+
+// Response holds the values returned by any query
+//
+// ping and announce_peer only return ID, find_node returns Nodes (and Nodes6),
+// get_peers returns Token and either Values or Nodes
+type Response struct {
+	ID     NodeID
+	Nodes  []Node
+	Nodes6 []Node
+	Token  string
+	Values []tracker.Peer
+}
+
+// KRPCError is the error message of the protocol
+type KRPCError struct {
+	Code    int
+	Message string
+}
+
+// Error returns the code and the message of the error
+func (e *KRPCError) Error() string {
+	return fmt.Sprintf("krpc error %d: %s", e.Code, e.Message)
+}
+
+// Message is a KRPC message, exactly one of Query, Response and Error is set
+type Message struct {
+	TransactionID string
+	Version       string
+	Query         Query
+	Response      *Response
+	Error         *KRPCError
+}
+
+// Name returns the method name of the query
+func (q PingQuery) Name() string {
+	return QueryPing
+}
+
+// arguments returns the arguments dictionary of the query
+func (q PingQuery) arguments() map[string]interface{} {
+	return map[string]interface{}{
+		DictionaryKeyID: string(q.ID[:]),
+	}
+}
+
+// Name returns the method name of the query
+func (q FindNodeQuery) Name() string {
+	return QueryFindNode
+}
+
+// arguments returns the arguments dictionary of the query
+func (q FindNodeQuery) arguments() map[string]interface{} {
+	return map[string]interface{}{
+		DictionaryKeyID:     string(q.ID[:]),
+		DictionaryKeyTarget: string(q.Target[:]),
+	}
+}
+
+// Name returns the method name of the query
+func (q GetPeersQuery) Name() string {
+	return QueryGetPeers
+}
+
+// arguments returns the arguments dictionary of the query
+func (q GetPeersQuery) arguments() map[string]interface{} {
+	return map[string]interface{}{
+		DictionaryKeyID:       string(q.ID[:]),
+		DictionaryKeyInfoHash: string(q.InfoHash[:]),
+	}
+}
+
+// Name returns the method name of the query
+func (q AnnouncePeerQuery) Name() string {
+	return QueryAnnouncePeer
+}
+
+// arguments returns the arguments dictionary of the query
+func (q AnnouncePeerQuery) arguments() map[string]interface{} {
+	implied_port := 0
+
+	if q.ImpliedPort {
+		implied_port = 1
+	}
+
+	return map[string]interface{}{
+		DictionaryKeyID:          string(q.ID[:]),
+		DictionaryKeyInfoHash:    string(q.InfoHash[:]),
+		DictionaryKeyPort:        q.Port,
+		DictionaryKeyToken:       q.Token,
+		DictionaryKeyImpliedPort: implied_port,
+	}
+}
+
+// Type returns the type of the message (y)
+func (m Message) Type() string {
+	switch {
+	case m.Query != nil:
+		return TypeQuery
+	case m.Response != nil:
+		return TypeResponse
+	case m.Error != nil:
+		return TypeError
+	default:
+		return ""
+	}
+}
+
+// encodeResponse returns the response dictionary
+func encodeResponse(response *Response) (map[string]interface{}, error) {
+	dictionary := map[string]interface{}{
+		DictionaryKeyID: string(response.ID[:]),
+	}
+
+	nodes4, nodes6 := EncodeCompactNodes(append(append([]Node{}, response.Nodes...), response.Nodes6...))
+
+	if len(nodes4) > 0 {
+		dictionary[DictionaryKeyNodes] = nodes4
+	}
+	if len(nodes6) > 0 {
+		dictionary[DictionaryKeyNodes6] = nodes6
+	}
+	if len(response.Token) > 0 {
+		dictionary[DictionaryKeyToken] = response.Token
+	}
+
+	if len(response.Values) > 0 {
+		values := []interface{}{}
+
+		for _, peer := range response.Values {
+			peers4, peers6 := tracker.EncodeCompactPeers([]tracker.Peer{peer})
+
+			if len(peers4) == 0 && len(peers6) == 0 {
+				return nil, fmt.Errorf("%w: [%v]", ErrorInvalidPeer, peer.IP)
+			}
+
+			values = append(values, peers4+peers6)
+		}
+
+		dictionary[DictionaryKeyValues] = values
+	}
+
+	return dictionary, nil
+}
+
+// Marshal encodes a message in the bencode format
+func Marshal(m Message) ([]byte, error) {
+	if len(m.TransactionID) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrorMissingKey, DictionaryKeyTransactionID)
+	}
+
+	dictionary := map[string]interface{}{
+		DictionaryKeyTransactionID: m.TransactionID,
+	}
+
+	if len(m.Version) > 0 {
+		dictionary[DictionaryKeyVersion] = m.Version
+	}
+
+	bodies := 0
+
+	if m.Query != nil {
+		bodies++
+		dictionary[DictionaryKeyType] = TypeQuery
+		dictionary[DictionaryKeyQuery] = m.Query.Name()
+		dictionary[DictionaryKeyArguments] = m.Query.arguments()
+	}
+	if m.Response != nil {
+		bodies++
+		response, err := encodeResponse(m.Response)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dictionary[DictionaryKeyType] = TypeResponse
+		dictionary[DictionaryKeyResponse] = response
+	}
+	if m.Error != nil {
+		bodies++
+		dictionary[DictionaryKeyType] = TypeError
+		dictionary[DictionaryKeyError] = []interface{}{m.Error.Code, m.Error.Message}
+	}
+
+	if bodies == 0 {
+		return nil, ErrorMessageWithoutBody
+	}
+	if bodies > 1 {
+		return nil, ErrorMessageWithManyBodies
+	}
+
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encoded), nil
+}
+
+// stringElement reads a required string from a dictionary
+func stringElement(dictionary map[string]interface{}, key string) (string, error) {
+	element, ok := dictionary[key]
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrorMissingKey, key)
+	}
+
+	value, ok := element.(string)
+
+	if !ok {
+		return "", fmt.Errorf("%w: [%T] for %s (expected string)", ErrorBadType, element, key)
+	}
+
+	return value, nil
+}
+
+// integerElement reads a required integer from a dictionary
+func integerElement(dictionary map[string]interface{}, key string) (int, error) {
+	element, ok := dictionary[key]
+
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrorMissingKey, key)
+	}
+
+	value, ok := element.(int)
+
+	if !ok {
+		return 0, fmt.Errorf("%w: [%T] for %s (expected integer)", ErrorBadType, element, key)
+	}
+
+	return value, nil
+}
+
+// dictionaryElement reads a required dictionary from a dictionary
+func dictionaryElement(dictionary map[string]interface{}, key string) (map[string]interface{}, error) {
+	element, ok := dictionary[key]
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorMissingKey, key)
+	}
+
+	value, ok := element.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("%w: [%T] for %s (expected dictionary)", ErrorBadType, element, key)
+	}
+
+	return value, nil
+}
+
+// nodeIDElement reads a required node id from a dictionary
+func nodeIDElement(dictionary map[string]interface{}, key string) (NodeID, error) {
+	value, err := stringElement(dictionary, key)
+
+	if err != nil {
+		return NodeID{}, err
+	}
+
+	id, err := toNodeID(value)
+
+	if err != nil {
+		return NodeID{}, fmt.Errorf("%w (%s)", err, key)
+	}
+
+	return id, nil
+}
+
+// decodeQuery decodes the arguments of a query
+func decodeQuery(name string, arguments map[string]interface{}) (Query, error) {
+	id, err := nodeIDElement(arguments, DictionaryKeyID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case QueryPing:
+		return PingQuery{ID: id}, nil
+	case QueryFindNode:
+		target, err := nodeIDElement(arguments, DictionaryKeyTarget)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return FindNodeQuery{ID: id, Target: target}, nil
+	case QueryGetPeers:
+		info_hash, err := nodeIDElement(arguments, DictionaryKeyInfoHash)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return GetPeersQuery{ID: id, InfoHash: info_hash}, nil
+	case QueryAnnouncePeer:
+		info_hash, err := nodeIDElement(arguments, DictionaryKeyInfoHash)
+
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := stringElement(arguments, DictionaryKeyToken)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query := AnnouncePeerQuery{
+			ID:       id,
+			InfoHash: info_hash,
+			Token:    token,
+		}
+
+		if _, ok := arguments[DictionaryKeyImpliedPort]; ok {
+			implied_port, err := integerElement(arguments, DictionaryKeyImpliedPort)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if implied_port != 0 && implied_port != 1 {
+				return nil, fmt.Errorf("%w: %d", ErrorInvalidImpliedPort, implied_port)
+			}
+
+			query.ImpliedPort = implied_port == 1
+		}
+
+		// http://www.bittorrent.org/beps/bep_0005.html
+		// "If it is present and non-zero, the port argument should be ignored"
+		if query.Port, err = integerElement(arguments, DictionaryKeyPort); err != nil && !query.ImpliedPort {
+			return nil, err
+		}
+
+		if !query.ImpliedPort && (query.Port <= 0 || query.Port > 65535) {
+			return nil, fmt.Errorf("%w: %d", ErrorInvalidPort, query.Port)
+		}
+
+		return query, nil
+	default:
+		return nil, fmt.Errorf("%w: [%s]", ErrorUnknownQuery, name)
+	}
+}
+
+// decodeResponse decodes the values of a response
+func decodeResponse(dictionary map[string]interface{}) (*Response, error) {
+	id, err := nodeIDElement(dictionary, DictionaryKeyID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{
+		ID: id,
+	}
+
+	nodes_keys := []struct {
+		key         string
+		node_length int
+		nodes       *[]Node
+	}{
+		{DictionaryKeyNodes, CompactNodeLengthIPv4, &response.Nodes},
+		{DictionaryKeyNodes6, CompactNodeLengthIPv6, &response.Nodes6},
+	}
+
+	for _, nodes_key := range nodes_keys {
+		if _, ok := dictionary[nodes_key.key]; !ok {
+			continue
+		}
+
+		compact_nodes, err := stringElement(dictionary, nodes_key.key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if *nodes_key.nodes, err = DecodeCompactNodes(compact_nodes, nodes_key.node_length); err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, nodes_key.key)
+		}
+	}
+
+	if _, ok := dictionary[DictionaryKeyToken]; ok {
+		if response.Token, err = stringElement(dictionary, DictionaryKeyToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if element, ok := dictionary[DictionaryKeyValues]; ok {
+		values, ok := element.([]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("%w: [%T] for %s (expected list)", ErrorBadType, element, DictionaryKeyValues)
+		}
+
+		for _, value := range values {
+			compact_peer, ok := value.(string)
+
+			if !ok {
+				return nil, fmt.Errorf("%w: [%T] in %s (expected string)", ErrorBadType, value, DictionaryKeyValues)
+			}
+
+			if len(compact_peer) != tracker.CompactPeerLengthIPv4 && len(compact_peer) != tracker.CompactPeerLengthIPv6 {
+				return nil, fmt.Errorf("%w: length %d", ErrorInvalidPeer, len(compact_peer))
+			}
+
+			peers, err := tracker.DecodeCompactPeers(compact_peer, len(compact_peer))
+
+			if err != nil {
+				return nil, err
+			}
+
+			response.Values = append(response.Values, peers...)
+		}
+	}
+
+	return response, nil
+}
+
+// decodeError decodes the list of an error message
+func decodeError(element interface{}) (*KRPCError, error) {
+	list, ok := element.([]interface{})
+
+	if !ok || len(list) != 2 {
+		return nil, fmt.Errorf("%w: %s need to be a list of 2 elements", ErrorMessageCorrupted, DictionaryKeyError)
+	}
+
+	code, ok := list[0].(int)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: [%T] for the error code (expected integer)", ErrorBadType, list[0])
+	}
+
+	message, ok := list[1].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: [%T] for the error message (expected string)", ErrorBadType, list[1])
+	}
+
+	return &KRPCError{Code: code, Message: message}, nil
+}
+
+// Unmarshal decodes and validates a message in the bencode format
+func Unmarshal(data []byte) (m Message, err error) {
+	// the lengths are bounded by the packet, a forged length can not allocate more,
+	// a message has a single canonical form: no repeated key, sorted keys, canonical numbers
+	element, n, err := parser.ParseBytes(data, parser.Options{
+		DuplicateKeys:    parser.DuplicateKeysError,
+		CanonicalNumbers: true,
+		SortedKeys:       true,
+	})
+
+	if err != nil {
+		return m, fmt.Errorf("%w: %v", ErrorMessageCorrupted, err)
+	}
+
+	if n != len(data) {
+		return m, ErrorTrailingData
+	}
+
+	dictionary, ok := element.(map[string]interface{})
+
+	if !ok {
+		return m, fmt.Errorf("%w: need to be a dictionary", ErrorMessageCorrupted)
+	}
+
+	if m.TransactionID, err = stringElement(dictionary, DictionaryKeyTransactionID); err != nil {
+		return m, err
+	}
+
+	if _, ok := dictionary[DictionaryKeyVersion]; ok {
+		if m.Version, err = stringElement(dictionary, DictionaryKeyVersion); err != nil {
+			return m, err
+		}
+	}
+
+	message_type, err := stringElement(dictionary, DictionaryKeyType)
+
+	if err != nil {
+		return m, err
+	}
+
+	switch message_type {
+	case TypeQuery:
+		name, err := stringElement(dictionary, DictionaryKeyQuery)
+
+		if err != nil {
+			return m, err
+		}
+
+		arguments, err := dictionaryElement(dictionary, DictionaryKeyArguments)
+
+		if err != nil {
+			return m, err
+		}
+
+		if m.Query, err = decodeQuery(name, arguments); err != nil {
+			return m, err
+		}
+	case TypeResponse:
+		response, err := dictionaryElement(dictionary, DictionaryKeyResponse)
+
+		if err != nil {
+			return m, err
+		}
+
+		if m.Response, err = decodeResponse(response); err != nil {
+			return m, err
+		}
+	case TypeError:
+		element, ok := dictionary[DictionaryKeyError]
+
+		if !ok {
+			return m, fmt.Errorf("%w: %s", ErrorMissingKey, DictionaryKeyError)
+		}
+
+		if m.Error, err = decodeError(element); err != nil {
+			return m, err
+		}
+	default:
+		return m, fmt.Errorf("%w: [%s]", ErrorUnknownMessageType, message_type)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,212 @@
+package dht
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/tracker"
+)
+
+var (
+	test_id     = NodeID{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}
+	test_target = NodeID{'m', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z', '1', '2', '3', '4', '5', '6'}
+)
+
+func TestMarshal(t *testing.T) {
+	// examples of http://www.bittorrent.org/beps/bep_0005.html
+	tests := []struct {
+		input    Message
+		expected string
+	}{
+		{
+			input:    Message{TransactionID: "aa", Query: PingQuery{ID: test_id}},
+			expected: "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe",
+		},
+		{
+			input:    Message{TransactionID: "aa", Response: &Response{ID: NodeID{'m', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z', '1', '2', '3', '4', '5', '6'}}},
+			expected: "d1:rd2:id20:mnopqrstuvwxyz123456e1:t2:aa1:y1:re",
+		},
+		{
+			input:    Message{TransactionID: "aa", Error: &KRPCError{Code: ErrorCodeGeneric, Message: "A Generic Error Ocurred"}},
+			expected: "d1:eli201e23:A Generic Error Ocurrede1:t2:aa1:y1:ee",
+		},
+		{
+			input:    Message{TransactionID: "aa", Query: FindNodeQuery{ID: test_id, Target: test_target}},
+			expected: "d1:ad2:id20:abcdefghij01234567896:target20:mnopqrstuvwxyz123456e1:q9:find_node1:t2:aa1:y1:qe",
+		},
+		{
+			input:    Message{TransactionID: "aa", Query: GetPeersQuery{ID: test_id, InfoHash: test_target}},
+			expected: "d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz123456e1:q9:get_peers1:t2:aa1:y1:qe",
+		},
+		{
+			input:    Message{TransactionID: "aa", Query: AnnouncePeerQuery{ID: test_id, InfoHash: test_target, Port: 6881, Token: "aoeusnth", ImpliedPort: true}},
+			expected: "d1:ad2:id20:abcdefghij012345678912:implied_porti1e9:info_hash20:mnopqrstuvwxyz1234564:porti6881e5:token8:aoeusnthe1:q13:announce_peer1:t2:aa1:y1:qe",
+		},
+		{
+			input: Message{TransactionID: "aa", Version: "GB01", Response: &Response{
+				ID:     test_id,
+				Token:  "aoeusnth",
+				Values: []tracker.Peer{{IP: net.IP{127, 0, 0, 1}, Port: 6881}},
+			}},
+			expected: "d1:rd2:id20:abcdefghij01234567895:token8:aoeusnth6:valuesl6:\x7f\x00\x00\x01\x1a\xe1ee1:t2:aa1:v4:GB011:y1:re",
+		},
+	}
+
+	for index, test := range tests {
+		output, err := Marshal(test.input)
+
+		if err != nil {
+			t.Errorf("test %d: failed to marshal: %v", index, err)
+			continue
+		}
+
+		if test.expected != string(output) {
+			t.Errorf("test %d: expected [%q] | [%q] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    Message
+		expected error
+	}{
+		{input: Message{Query: PingQuery{}}, expected: ErrorMissingKey},
+		{input: Message{TransactionID: "aa"}, expected: ErrorMessageWithoutBody},
+		{input: Message{TransactionID: "aa", Query: PingQuery{}, Error: &KRPCError{}}, expected: ErrorMessageWithManyBodies},
+		{input: Message{TransactionID: "aa", Response: &Response{Values: []tracker.Peer{{}}}}, expected: ErrorInvalidPeer},
+	}
+
+	for index, test := range tests {
+		if _, err := Marshal(test.input); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Message
+	}{
+		{
+			input:    "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe",
+			expected: Message{TransactionID: "aa", Query: PingQuery{ID: test_id}},
+		},
+		{
+			input:    "d1:ad2:id20:abcdefghij01234567896:target20:mnopqrstuvwxyz123456e1:q9:find_node1:t2:aa1:y1:qe",
+			expected: Message{TransactionID: "aa", Query: FindNodeQuery{ID: test_id, Target: test_target}},
+		},
+		{
+			input:    "d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz123456e1:q9:get_peers1:t2:aa1:y1:qe",
+			expected: Message{TransactionID: "aa", Query: GetPeersQuery{ID: test_id, InfoHash: test_target}},
+		},
+		{
+			input:    "d1:ad2:id20:abcdefghij012345678912:implied_porti1e9:info_hash20:mnopqrstuvwxyz1234564:porti6881e5:token8:aoeusnthe1:q13:announce_peer1:t2:aa1:y1:qe",
+			expected: Message{TransactionID: "aa", Query: AnnouncePeerQuery{ID: test_id, InfoHash: test_target, Port: 6881, Token: "aoeusnth", ImpliedPort: true}},
+		},
+		{
+			input:    "d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz1234564:porti6881e5:token8:aoeusnthe1:q13:announce_peer1:t2:aa1:y1:qe",
+			expected: Message{TransactionID: "aa", Query: AnnouncePeerQuery{ID: test_id, InfoHash: test_target, Port: 6881, Token: "aoeusnth"}},
+		},
+		{
+			input: "d1:rd2:id20:abcdefghij01234567895:nodes26:mnopqrstuvwxyz123456\x7f\x00\x00\x01\x1a\xe1e1:t2:aa1:y1:re",
+			expected: Message{TransactionID: "aa", Response: &Response{
+				ID:    test_id,
+				Nodes: []Node{{ID: test_target, IP: net.IP{127, 0, 0, 1}, Port: 6881}},
+			}},
+		},
+		{
+			input: "d1:rd2:id20:abcdefghij01234567895:token8:aoeusnth6:valuesl6:\x7f\x00\x00\x01\x1a\xe118:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe2ee1:t2:aa1:v4:GB011:y1:re",
+			expected: Message{TransactionID: "aa", Version: "GB01", Response: &Response{
+				ID:     test_id,
+				Token:  "aoeusnth",
+				Values: []tracker.Peer{{IP: net.IP{127, 0, 0, 1}, Port: 6881}, {IP: net.IPv6loopback, Port: 6882}},
+			}},
+		},
+		{
+			input:    "d1:eli204e14:Method Unknowne1:t2:aa1:y1:ee",
+			expected: Message{TransactionID: "aa", Error: &KRPCError{Code: ErrorCodeMethodUnknown, Message: "Method Unknown"}},
+		},
+	}
+
+	for index, test := range tests {
+		output, err := Unmarshal([]byte(test.input))
+
+		if err != nil {
+			t.Errorf("test %d: failed to unmarshal: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{input: "", expected: ErrorMessageCorrupted},
+		{input: "le", expected: ErrorMessageCorrupted},
+		{input: "d1:t2:aa1:y1:qed", expected: ErrorTrailingData},
+		{input: "d1:y1:qe", expected: ErrorMissingKey},
+		{input: "d1:ti1e1:y1:qe", expected: ErrorBadType},
+		{input: "d1:t2:aa1:y1:xe", expected: ErrorUnknownMessageType},
+		{input: "d1:ad2:id20:abcdefghij0123456789e1:q4:pong1:t2:aa1:y1:qe", expected: ErrorUnknownQuery},
+		{input: "d1:ad2:id3:abce1:q4:ping1:t2:aa1:y1:qe", expected: ErrorInvalidNodeID},
+		{input: "d1:ale1:q4:ping1:t2:aa1:y1:qe", expected: ErrorBadType},
+		{input: "d1:ad2:id20:abcdefghij0123456789e1:q9:find_node1:t2:aa1:y1:qe", expected: ErrorMissingKey},
+		{input: "d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz1234564:porti6881ee1:q13:announce_peer1:t2:aa1:y1:qe", expected: ErrorMissingKey},
+		{input: "d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz1234564:porti0e5:token1:xe1:q13:announce_peer1:t2:aa1:y1:qe", expected: ErrorInvalidPort},
+		{input: "d1:ad2:id20:abcdefghij012345678912:implied_porti2e9:info_hash20:mnopqrstuvwxyz1234564:porti1e5:token1:xe1:q13:announce_peer1:t2:aa1:y1:qe", expected: ErrorInvalidImpliedPort},
+		{input: "d1:rd2:id20:abcdefghij01234567895:nodes3:abce1:t2:aa1:y1:re", expected: ErrorCompactNodesCorrupted},
+		{input: "d1:rd2:id20:abcdefghij01234567896:valuesl3:abcee1:t2:aa1:y1:re", expected: ErrorInvalidPeer},
+		{input: "d1:rd2:id20:abcdefghij01234567896:valuesli1eee1:t2:aa1:y1:re", expected: ErrorBadType},
+		{input: "d1:eli201ee1:t2:aa1:y1:ee", expected: ErrorMessageCorrupted},
+		{input: "d1:el3:abc3:abce1:t2:aa1:y1:ee", expected: ErrorBadType},
+		{input: "d1:t99999999999999999999:e", expected: ErrorMessageCorrupted},
+		{input: "d1:t9000000000:e", expected: ErrorMessageCorrupted},
+		{input: "d1:t02:aa1:y1:qe", expected: ErrorMessageCorrupted},
+		{input: "d1:eli03e3:abce1:t2:aa1:y1:ee", expected: ErrorMessageCorrupted},
+		{input: "d1:eli-0e3:abce1:t2:aa1:y1:ee", expected: ErrorMessageCorrupted},
+		{input: "d1:eli+1e3:abce1:t2:aa1:y1:ee", expected: ErrorMessageCorrupted},
+		{input: "d1:t2:aa1:t2:bb1:y1:qe", expected: ErrorMessageCorrupted}, // repeated t
+		{input: "d1:y1:q1:t2:aae", expected: ErrorMessageCorrupted},        // unsorted keys
+		{input: "d1:ad6:target20:mnopqrstuvwxyz1234562:id20:abcdefghij0123456789e1:q9:find_node1:t2:aa1:y1:qe", expected: ErrorMessageCorrupted},
+	}
+
+	for index, test := range tests {
+		if _, err := Unmarshal([]byte(test.input)); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	message := Message{TransactionID: "\x00\xff", Response: &Response{
+		ID:     test_id,
+		Nodes:  []Node{{ID: test_target, IP: net.IP{10, 0, 0, 1}, Port: 1}},
+		Nodes6: []Node{{ID: test_id, IP: net.ParseIP("2001:db8::1"), Port: 2}},
+	}}
+
+	encoded, err := Marshal(message)
+
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	decoded, err := Unmarshal(encoded)
+
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if decoded.Type() != TypeResponse || len(decoded.Response.Nodes) != 1 || len(decoded.Response.Nodes6) != 1 || !decoded.Response.Nodes6[0].IP.Equal(message.Response.Nodes6[0].IP) {
+		t.Errorf("expected %+v | %+v output", message.Response, decoded.Response)
+	}
+}
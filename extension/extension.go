@@ -0,0 +1,256 @@
+// Package extension provide the messages of the extension protocol and of ut_metadata
+//
+// http://www.bittorrent.org/beps/bep_0010.html
+// http://www.bittorrent.org/beps/bep_0009.html
+package extension
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/trixky/gobencode"
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	DictionaryKeyM            = "m"
+	DictionaryKeyV            = "v"
+	DictionaryKeyP            = "p"
+	DictionaryKeyYourIP       = "yourip"
+	DictionaryKeyReqq         = "reqq"
+	DictionaryKeyMetadataSize = "metadata_size"
+	DictionaryKeyIPv4         = "ipv4"
+	DictionaryKeyIPv6         = "ipv6"
+)
+
+const (
+	MessageIDExtended     = 20 // peer wire message id of the extended messages
+	ExtendedIDHandshake   = 0
+	ExtensionNameMetadata = "ut_metadata"
+)
+
+var (
+	ErrorHandshakeCorrupted = errors.New("extended handshake corrupted")
+	ErrorMessageCorrupted   = errors.New("extended message corrupted")
+	ErrorInvalidIP          = errors.New("invalid ip")
+)
+
+// Handshake is the extended handshake, sent right after the BitTorrent handshake
+type Handshake struct {
+	M            map[string]int // extension name to the extended message id, 0 disables an extension
+	V            string         // client name and version
+	P            int            // local tcp listen port
+	YourIP       net.IP         // ip of the receiver as seen by the sender
+	IPv4         net.IP
+	IPv6         net.IP
+	Reqq         int // number of outstanding requests supported
+	MetadataSize int // size of the info dictionary (ut_metadata)
+}
+
+// Marshal encodes the handshake dictionary
+func (h Handshake) Marshal() ([]byte, error) {
+	m := map[string]interface{}{}
+
+	for name, id := range h.M {
+		m[name] = id
+	}
+
+	dictionary := map[string]interface{}{
+		DictionaryKeyM: m,
+	}
+
+	if len(h.V) > 0 {
+		dictionary[DictionaryKeyV] = h.V
+	}
+	if h.P > 0 {
+		dictionary[DictionaryKeyP] = h.P
+	}
+	if h.Reqq > 0 {
+		dictionary[DictionaryKeyReqq] = h.Reqq
+	}
+	if h.MetadataSize > 0 {
+		dictionary[DictionaryKeyMetadataSize] = h.MetadataSize
+	}
+
+	if h.YourIP != nil {
+		if ip4 := h.YourIP.To4(); ip4 != nil {
+			dictionary[DictionaryKeyYourIP] = string(ip4)
+		} else if ip6 := h.YourIP.To16(); ip6 != nil {
+			dictionary[DictionaryKeyYourIP] = string(ip6)
+		} else {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidIP, DictionaryKeyYourIP)
+		}
+	}
+	if h.IPv4 != nil {
+		ip4 := h.IPv4.To4()
+
+		if ip4 == nil {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidIP, DictionaryKeyIPv4)
+		}
+
+		dictionary[DictionaryKeyIPv4] = string(ip4)
+	}
+	if h.IPv6 != nil {
+		if len(h.IPv6) != net.IPv6len || h.IPv6.To4() != nil {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidIP, DictionaryKeyIPv6)
+		}
+
+		dictionary[DictionaryKeyIPv6] = string(h.IPv6)
+	}
+
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encoded), nil
+}
+
+// compactIP converts the raw bytes of an ip, with the allowed lengths
+func compactIP(dictionary map[string]interface{}, key string, lengths ...int) (net.IP, error) {
+	element, ok := dictionary[key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	raw_ip, ok := element.(string)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: bad type [%T] for %s", ErrorHandshakeCorrupted, element, key)
+	}
+
+	for _, length := range lengths {
+		if len(raw_ip) == length {
+			return net.IP(raw_ip), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: bad length %d for %s", ErrorInvalidIP, len(raw_ip), key)
+}
+
+// optionalInteger reads an optional integer of a dictionary
+func optionalInteger(dictionary map[string]interface{}, key string) (int, error) {
+	element, ok := dictionary[key]
+
+	if !ok {
+		return 0, nil
+	}
+
+	value, ok := element.(int)
+
+	if !ok {
+		return 0, fmt.Errorf("%w: bad type [%T] for %s", ErrorHandshakeCorrupted, element, key)
+	}
+
+	return value, nil
+}
+
+// UnmarshalHandshake decodes the handshake dictionary
+//
+// unknown keys are ignored as required by the specification
+func UnmarshalHandshake(data []byte) (h Handshake, err error) {
+	element, rest, err := gobencode.ParseFromBytes(data)
+
+	if err != nil {
+		return h, fmt.Errorf("%w: %v", ErrorHandshakeCorrupted, err)
+	}
+
+	if len(rest) > 0 {
+		return h, fmt.Errorf("%w: trailing data", ErrorHandshakeCorrupted)
+	}
+
+	dictionary, ok := element.(map[string]interface{})
+
+	if !ok {
+		return h, fmt.Errorf("%w: need to be a dictionary", ErrorHandshakeCorrupted)
+	}
+
+	h.M = map[string]int{}
+
+	if element, ok := dictionary[DictionaryKeyM]; ok {
+		m, ok := element.(map[string]interface{})
+
+		if !ok {
+			return h, fmt.Errorf("%w: bad type [%T] for %s", ErrorHandshakeCorrupted, element, DictionaryKeyM)
+		}
+
+		for name, element := range m {
+			id, ok := element.(int)
+
+			if !ok || id < 0 || id > 255 {
+				return h, fmt.Errorf("%w: invalid id for extension [%s]", ErrorHandshakeCorrupted, name)
+			}
+
+			h.M[name] = id
+		}
+	}
+
+	if element, ok := dictionary[DictionaryKeyV]; ok {
+		if h.V, ok = element.(string); !ok {
+			return h, fmt.Errorf("%w: bad type [%T] for %s", ErrorHandshakeCorrupted, element, DictionaryKeyV)
+		}
+	}
+
+	integers := []struct {
+		key   string
+		value *int
+	}{
+		{DictionaryKeyP, &h.P},
+		{DictionaryKeyReqq, &h.Reqq},
+		{DictionaryKeyMetadataSize, &h.MetadataSize},
+	}
+
+	for _, integer := range integers {
+		if *integer.value, err = optionalInteger(dictionary, integer.key); err != nil {
+			return h, err
+		}
+	}
+
+	if h.MetadataSize < 0 {
+		return h, fmt.Errorf("%w: negative %s", ErrorHandshakeCorrupted, DictionaryKeyMetadataSize)
+	}
+
+	if h.YourIP, err = compactIP(dictionary, DictionaryKeyYourIP, net.IPv4len, net.IPv6len); err != nil {
+		return h, err
+	}
+	if h.IPv4, err = compactIP(dictionary, DictionaryKeyIPv4, net.IPv4len); err != nil {
+		return h, err
+	}
+	if h.IPv6, err = compactIP(dictionary, DictionaryKeyIPv6, net.IPv6len); err != nil {
+		return h, err
+	}
+
+	return h, nil
+}
+
+// EncodeMessage frames an extended message for the peer wire: <length><20><extended id><payload>
+func EncodeMessage(extended_id byte, payload []byte) []byte {
+	message := make([]byte, 6, 6+len(payload))
+
+	binary.BigEndian.PutUint32(message[0:4], uint32(2+len(payload)))
+	message[4] = MessageIDExtended
+	message[5] = extended_id
+
+	return append(message, payload...)
+}
+
+// DecodeMessage reads the extended id and the payload of a framed extended message
+func DecodeMessage(message []byte) (extended_id byte, payload []byte, err error) {
+	if len(message) < 6 {
+		return 0, nil, fmt.Errorf("%w: too short", ErrorMessageCorrupted)
+	}
+
+	if length := binary.BigEndian.Uint32(message[0:4]); int(length) != len(message)-4 {
+		return 0, nil, fmt.Errorf("%w: length %d for %d bytes", ErrorMessageCorrupted, length, len(message)-4)
+	}
+
+	if message[4] != MessageIDExtended {
+		return 0, nil, fmt.Errorf("%w: message id %d is not %d", ErrorMessageCorrupted, message[4], MessageIDExtended)
+	}
+
+	return message[5], message[6:], nil
+}
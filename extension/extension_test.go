@@ -0,0 +1,143 @@
+package extension
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestHandshakeMarshal(t *testing.T) {
+	tests := []struct {
+		input    Handshake
+		expected string
+	}{
+		{
+			input:    Handshake{},
+			expected: "d1:mdee",
+		},
+		{
+			// example of http://www.bittorrent.org/beps/bep_0010.html
+			input: Handshake{
+				M: map[string]int{"LT_metadata": 1, "ut_pex": 2},
+				P: 6881,
+				V: "µTorrent 1.2",
+			},
+			expected: "d1:md11:LT_metadatai1e6:ut_pexi2ee1:pi6881e1:v13:µTorrent 1.2e",
+		},
+		{
+			input: Handshake{
+				M:            map[string]int{ExtensionNameMetadata: 3},
+				YourIP:       net.ParseIP("10.0.0.1"),
+				IPv4:         net.IP{192, 168, 0, 1},
+				IPv6:         net.IPv6loopback,
+				Reqq:         250,
+				MetadataSize: 31235,
+			},
+			expected: "d4:ipv44:\xc0\xa8\x00\x014:ipv616:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x011:md11:ut_metadatai3ee13:metadata_sizei31235e4:reqqi250e6:yourip4:\x0a\x00\x00\x01e",
+		},
+	}
+
+	for index, test := range tests {
+		output, err := test.input.Marshal()
+
+		if err != nil {
+			t.Errorf("test %d: failed to marshal: %v", index, err)
+			continue
+		}
+
+		if test.expected != string(output) {
+			t.Errorf("test %d: expected [%q] | [%q] output", index, test.expected, output)
+		}
+	}
+
+	if _, err := (Handshake{IPv4: net.IPv6loopback}).Marshal(); !errors.Is(err, ErrorInvalidIP) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidIP, err)
+	}
+}
+
+func TestUnmarshalHandshake(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Handshake
+		err      error
+	}{
+		{
+			input: "d1:md11:LT_metadatai1e6:ut_pexi2ee1:pi6881e1:v13:µTorrent 1.2e",
+			expected: Handshake{
+				M: map[string]int{"LT_metadata": 1, "ut_pex": 2},
+				P: 6881,
+				V: "µTorrent 1.2",
+			},
+		},
+		{
+			input: "d12:complete_agoi-1e4:ipv616:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x011:md11:ut_metadatai0ee13:metadata_sizei31235e6:yourip16:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01e",
+			expected: Handshake{
+				M:            map[string]int{ExtensionNameMetadata: 0},
+				IPv6:         net.IPv6loopback,
+				YourIP:       net.IPv6loopback,
+				MetadataSize: 31235,
+			},
+		},
+		{input: "le", err: ErrorHandshakeCorrupted},
+		{input: "dee", err: ErrorHandshakeCorrupted},
+		{input: "d1:mli1eee", err: ErrorHandshakeCorrupted},
+		{input: "d1:md6:ut_pexi256eee", err: ErrorHandshakeCorrupted},
+		{input: "d1:p4:6881e", err: ErrorHandshakeCorrupted},
+		{input: "d13:metadata_sizei-1ee", err: ErrorHandshakeCorrupted},
+		{input: "d4:ipv416:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01e", err: ErrorInvalidIP},
+		{input: "d6:yourip3:abce", err: ErrorInvalidIP},
+		{input: "d1:v9000000000:e", err: ErrorHandshakeCorrupted},
+		{input: "d1:v99999999999999999999:e", err: ErrorHandshakeCorrupted},
+	}
+
+	for index, test := range tests {
+		output, err := UnmarshalHandshake([]byte(test.input))
+
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: failed to unmarshal: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestEncodeDecodeMessage(t *testing.T) {
+	message := EncodeMessage(3, []byte("d8:msg_typei0e5:piecei0ee"))
+
+	if expected := "\x00\x00\x00\x1b\x14\x03d8:msg_typei0e5:piecei0ee"; expected != string(message) {
+		t.Errorf("expected [%q] | [%q] output", expected, message)
+	}
+
+	extended_id, payload, err := DecodeMessage(message)
+
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	if extended_id != 3 || string(payload) != "d8:msg_typei0e5:piecei0ee" {
+		t.Errorf("bad message: %d [%s]", extended_id, payload)
+	}
+
+	corrupted := []string{
+		"\x00\x00\x00\x02\x14",
+		"\x00\x00\x00\x03\x14\x00",
+		"\x00\x00\x00\x02\x13\x00",
+	}
+
+	for index, test := range corrupted {
+		if _, _, err := DecodeMessage([]byte(test)); !errors.Is(err, ErrorMessageCorrupted) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, ErrorMessageCorrupted, err)
+		}
+	}
+}
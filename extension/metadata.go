@@ -0,0 +1,256 @@
+package extension
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+
+	"github.com/trixky/gobencode"
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	DictionaryKeyMsgType   = "msg_type"
+	DictionaryKeyPiece     = "piece"
+	DictionaryKeyTotalSize = "total_size"
+
+	MetadataTypeRequest = 0
+	MetadataTypeData    = 1
+	MetadataTypeReject  = 2
+
+	MetadataPieceSize = 16384           // 16 KiB
+	MetadataMaxSize   = 8 * 1024 * 1024 // refuse absurd metadata sizes announced by peers
+)
+
+var (
+	ErrorMetadataMessageCorrupted = errors.New("metadata message corrupted")
+	ErrorInvalidMetadataSize      = errors.New("invalid metadata size")
+	ErrorInvalidMetadataPiece     = errors.New("invalid metadata piece")
+	ErrorMetadataIncomplete       = errors.New("metadata incomplete")
+	ErrorMetadataHashMismatch     = errors.New("metadata hash mismatch")
+	ErrorMetadataNotADictionary   = errors.New("metadata is not a dictionary")
+)
+
+// MetadataMessage is a ut_metadata request, data or reject message
+//
+// TotalSize and Data are only used by data messages
+type MetadataMessage struct {
+	Type      int
+	Piece     int
+	TotalSize int
+	Data      []byte
+}
+
+// Marshal encodes the message, the data of a data message follows the dictionary
+func (m MetadataMessage) Marshal() ([]byte, error) {
+	dictionary := map[string]interface{}{
+		DictionaryKeyMsgType: m.Type,
+		DictionaryKeyPiece:   m.Piece,
+	}
+
+	if m.Type == MetadataTypeData {
+		dictionary[DictionaryKeyTotalSize] = m.TotalSize
+	}
+
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Type == MetadataTypeData {
+		return append([]byte(encoded), m.Data...), nil
+	}
+
+	return []byte(encoded), nil
+}
+
+// UnmarshalMetadataMessage decodes a message, the bytes following the dictionary of a data message are its data
+func UnmarshalMetadataMessage(payload []byte) (m MetadataMessage, err error) {
+	element, rest, err := gobencode.ParseFromBytes(payload)
+
+	if err != nil {
+		return m, fmt.Errorf("%w: %v", ErrorMetadataMessageCorrupted, err)
+	}
+
+	dictionary, ok := element.(map[string]interface{})
+
+	if !ok {
+		return m, fmt.Errorf("%w: need to be a dictionary", ErrorMetadataMessageCorrupted)
+	}
+
+	if m.Type, ok = dictionary[DictionaryKeyMsgType].(int); !ok {
+		return m, fmt.Errorf("%w: missing %s", ErrorMetadataMessageCorrupted, DictionaryKeyMsgType)
+	}
+
+	if m.Piece, ok = dictionary[DictionaryKeyPiece].(int); !ok || m.Piece < 0 {
+		return m, fmt.Errorf("%w: missing %s", ErrorMetadataMessageCorrupted, DictionaryKeyPiece)
+	}
+
+	switch m.Type {
+	case MetadataTypeRequest, MetadataTypeReject:
+		if len(rest) > 0 {
+			return m, fmt.Errorf("%w: trailing data", ErrorMetadataMessageCorrupted)
+		}
+	case MetadataTypeData:
+		if m.TotalSize, ok = dictionary[DictionaryKeyTotalSize].(int); !ok {
+			return m, fmt.Errorf("%w: missing %s", ErrorMetadataMessageCorrupted, DictionaryKeyTotalSize)
+		}
+
+		if len(rest) > MetadataPieceSize {
+			return m, fmt.Errorf("%w: %d bytes of data", ErrorMetadataMessageCorrupted, len(rest))
+		}
+
+		m.Data = rest
+	default:
+		return m, fmt.Errorf("%w: unknown %s %d", ErrorMetadataMessageCorrupted, DictionaryKeyMsgType, m.Type)
+	}
+
+	return m, nil
+}
+
+// MetadataAssembler collects the pieces of the info dictionary received from peers
+type MetadataAssembler struct {
+	info_hash [20]byte
+	size      int
+	pieces    [][]byte
+}
+
+// NewMetadataAssembler creates an assembler for the metadata_size announced in an extended handshake
+func NewMetadataAssembler(info_hash [20]byte, size int) (*MetadataAssembler, error) {
+	if size <= 0 || size > MetadataMaxSize {
+		return nil, fmt.Errorf("%w: %d", ErrorInvalidMetadataSize, size)
+	}
+
+	return &MetadataAssembler{
+		info_hash: info_hash,
+		size:      size,
+		pieces:    make([][]byte, (size+MetadataPieceSize-1)/MetadataPieceSize),
+	}, nil
+}
+
+// PieceCount returns the number of 16 KiB pieces of the metadata
+func (a *MetadataAssembler) PieceCount() int {
+	return len(a.pieces)
+}
+
+// pieceLength returns the expected length of a piece, only the last one can be shorter
+func (a *MetadataAssembler) pieceLength(piece int) int {
+	if piece == len(a.pieces)-1 {
+		return a.size - piece*MetadataPieceSize
+	}
+
+	return MetadataPieceSize
+}
+
+// Missing returns the pieces that still need to be requested
+func (a *MetadataAssembler) Missing() []int {
+	missing := []int{}
+
+	for piece, data := range a.pieces {
+		if data == nil {
+			missing = append(missing, piece)
+		}
+	}
+
+	return missing
+}
+
+// Complete checks if all the pieces have been received
+func (a *MetadataAssembler) Complete() bool {
+	return len(a.Missing()) == 0
+}
+
+// Requests returns the request messages of the missing pieces
+func (a *MetadataAssembler) Requests() []MetadataMessage {
+	requests := []MetadataMessage{}
+
+	for _, piece := range a.Missing() {
+		requests = append(requests, MetadataMessage{
+			Type:  MetadataTypeRequest,
+			Piece: piece,
+		})
+	}
+
+	return requests
+}
+
+// Add stores the data of a data message
+func (a *MetadataAssembler) Add(m MetadataMessage) error {
+	if m.Type != MetadataTypeData {
+		return fmt.Errorf("%w: not a data message", ErrorInvalidMetadataPiece)
+	}
+
+	if m.TotalSize != a.size {
+		return fmt.Errorf("%w: total size %d (expected %d)", ErrorInvalidMetadataSize, m.TotalSize, a.size)
+	}
+
+	if m.Piece < 0 || m.Piece >= len(a.pieces) {
+		return fmt.Errorf("%w: index %d out of %d pieces", ErrorInvalidMetadataPiece, m.Piece, len(a.pieces))
+	}
+
+	if len(m.Data) != a.pieceLength(m.Piece) {
+		return fmt.Errorf("%w: piece %d length %d (expected %d)", ErrorInvalidMetadataPiece, m.Piece, len(m.Data), a.pieceLength(m.Piece))
+	}
+
+	a.pieces[m.Piece] = append([]byte{}, m.Data...)
+
+	return nil
+}
+
+// Bytes returns the raw info dictionary once all the pieces are received and its hash is verified
+//
+// The pieces are dropped when the hash does not match, so they can be requested again from other peers
+func (a *MetadataAssembler) Bytes() ([]byte, error) {
+	if missing := a.Missing(); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %d/%d pieces missing", ErrorMetadataIncomplete, len(missing), len(a.pieces))
+	}
+
+	metadata := make([]byte, 0, a.size)
+
+	for _, data := range a.pieces {
+		metadata = append(metadata, data...)
+	}
+
+	if sha1.Sum(metadata) != a.info_hash {
+		a.pieces = make([][]byte, len(a.pieces))
+
+		return nil, ErrorMetadataHashMismatch
+	}
+
+	return metadata, nil
+}
+
+// Bencode returns the unmarshalled torrent of the verified metadata
+//
+// The metadata only holds the info section, trackers must come from elsewhere (magnet link, ...)
+func (a *MetadataAssembler) Bencode() (bc bencode.Bencode, err error) {
+	metadata, err := a.Bytes()
+
+	if err != nil {
+		return bc, err
+	}
+
+	info, rest, err := gobencode.ParseFromBytes(metadata)
+
+	if err != nil {
+		return bc, err
+	}
+
+	if _, ok := info.(map[string]interface{}); !ok || len(rest) > 0 {
+		return bc, ErrorMetadataNotADictionary
+	}
+
+	bc.Data = map[string]interface{}{
+		bencode.DictionaryKeyInfo: info,
+	}
+
+	if err := bc.UnmarshallInfo(); err != nil {
+		return bc, fmt.Errorf("failed to unmarshall info: %w", err)
+	}
+
+	// the hash of the raw metadata has been verified, it stays valid even for non canonical encodings
+	bc.InfoHash = a.info_hash
+
+	return bc, nil
+}
@@ -0,0 +1,212 @@
+package extension
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+// readTestMetadata returns the raw info dictionary of a test torrent
+func readTestMetadata(t *testing.T, file string) []byte {
+	f, err := os.Open(file)
+
+	if err != nil {
+		t.Fatalf("failed to open [%s]: %v", file, err)
+	}
+
+	defer f.Close()
+
+	data, err := parser.ParseElement(bufio.NewReader(f))
+
+	if err != nil {
+		t.Fatalf("failed to parse [%s]: %v", file, err)
+	}
+
+	info, err := bencode.EncodeElement(data.(map[string]interface{})[bencode.DictionaryKeyInfo])
+
+	if err != nil {
+		t.Fatalf("failed to encode the info of [%s]: %v", file, err)
+	}
+
+	return []byte(info)
+}
+
+func TestMetadataMessage(t *testing.T) {
+	tests := []struct {
+		input    MetadataMessage
+		expected string
+	}{
+		// examples of http://www.bittorrent.org/beps/bep_0009.html
+		{
+			input:    MetadataMessage{Type: MetadataTypeRequest, Piece: 0},
+			expected: "d8:msg_typei0e5:piecei0ee",
+		},
+		{
+			input:    MetadataMessage{Type: MetadataTypeData, Piece: 0, TotalSize: 3425, Data: []byte("xxxxxxxx")},
+			expected: "d8:msg_typei1e5:piecei0e10:total_sizei3425eexxxxxxxx",
+		},
+		{
+			input:    MetadataMessage{Type: MetadataTypeReject, Piece: 0},
+			expected: "d8:msg_typei2e5:piecei0ee",
+		},
+	}
+
+	for index, test := range tests {
+		output, err := test.input.Marshal()
+
+		if err != nil {
+			t.Errorf("test %d: failed to marshal: %v", index, err)
+			continue
+		}
+
+		if test.expected != string(output) {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+			continue
+		}
+
+		decoded, err := UnmarshalMetadataMessage(output)
+
+		if err != nil {
+			t.Errorf("test %d: failed to unmarshal: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.input, decoded) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.input, decoded)
+		}
+	}
+}
+
+func TestUnmarshalMetadataMessageErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"le",
+		"d5:piecei0ee",
+		"d8:msg_typei0ee",
+		"d8:msg_typei0e5:piecei-1ee",
+		"d8:msg_typei0e5:piecei0eexxx",
+		"d8:msg_typei1e5:piecei0eexxx",
+		"d8:msg_typei3e5:piecei0ee",
+		"d8:msg_typei1e5:piecei0e10:total_sizei8e1:x9000000000:e",
+		"d8:msg_typei1e5:piecei0e10:total_sizei8e1:x99999999999999999999:e",
+	}
+
+	for index, test := range tests {
+		if _, err := UnmarshalMetadataMessage([]byte(test)); !errors.Is(err, ErrorMetadataMessageCorrupted) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, ErrorMetadataMessageCorrupted, err)
+		}
+	}
+}
+
+func TestMetadataAssembler(t *testing.T) {
+	metadata := readTestMetadata(t, "../.test_files/minecraft.torrent")
+	info_hash := sha1.Sum(metadata)
+
+	assembler, err := NewMetadataAssembler(info_hash, len(metadata))
+
+	if err != nil {
+		t.Fatalf("failed to create the assembler: %v", err)
+	}
+
+	if expected := (len(metadata) + MetadataPieceSize - 1) / MetadataPieceSize; assembler.PieceCount() != expected || len(assembler.Requests()) != expected {
+		t.Fatalf("expected %d pieces | %d output", expected, assembler.PieceCount())
+	}
+
+	if _, err := assembler.Bencode(); !errors.Is(err, ErrorMetadataIncomplete) {
+		t.Errorf("expected [%v] | [%v] output", ErrorMetadataIncomplete, err)
+	}
+
+	// pieces are received in reverse order, through the wire format
+	for piece := assembler.PieceCount() - 1; piece >= 0; piece-- {
+		end := (piece + 1) * MetadataPieceSize
+
+		if end > len(metadata) {
+			end = len(metadata)
+		}
+
+		payload, _ := MetadataMessage{Type: MetadataTypeData, Piece: piece, TotalSize: len(metadata), Data: metadata[piece*MetadataPieceSize : end]}.Marshal()
+		message, err := UnmarshalMetadataMessage(payload)
+
+		if err != nil {
+			t.Fatalf("failed to unmarshal piece %d: %v", piece, err)
+		}
+
+		if err := assembler.Add(message); err != nil {
+			t.Fatalf("failed to add piece %d: %v", piece, err)
+		}
+	}
+
+	if !assembler.Complete() {
+		t.Fatalf("assembler not complete, missing %v", assembler.Missing())
+	}
+
+	bc, err := assembler.Bencode()
+
+	if err != nil {
+		t.Fatalf("failed to get the bencode: %v", err)
+	}
+
+	if bc.InfoHash != info_hash || bc.Info.DirectoryName != "Minecraft 1.15.2" || len(bc.Info.Files) != 3 {
+		t.Errorf("bad bencode: %x %s %d files", bc.InfoHash, bc.Info.DirectoryName, len(bc.Info.Files))
+	}
+
+	if err := bc.GetInfoHash(); err != nil || bc.InfoHash != info_hash {
+		t.Errorf("info hash of the bencode does not match: %x (%v)", bc.InfoHash, err)
+	}
+}
+
+func TestMetadataAssemblerErrors(t *testing.T) {
+	if _, err := NewMetadataAssembler([20]byte{}, 0); !errors.Is(err, ErrorInvalidMetadataSize) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidMetadataSize, err)
+	}
+	if _, err := NewMetadataAssembler([20]byte{}, MetadataMaxSize+1); !errors.Is(err, ErrorInvalidMetadataSize) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidMetadataSize, err)
+	}
+
+	metadata := []byte("d4:name4:teste")
+	assembler, _ := NewMetadataAssembler([20]byte{1}, len(metadata))
+
+	tests := []struct {
+		input    MetadataMessage
+		expected error
+	}{
+		{input: MetadataMessage{Type: MetadataTypeReject}, expected: ErrorInvalidMetadataPiece},
+		{input: MetadataMessage{Type: MetadataTypeData, TotalSize: 3, Data: metadata}, expected: ErrorInvalidMetadataSize},
+		{input: MetadataMessage{Type: MetadataTypeData, Piece: 1, TotalSize: len(metadata), Data: metadata}, expected: ErrorInvalidMetadataPiece},
+		{input: MetadataMessage{Type: MetadataTypeData, TotalSize: len(metadata), Data: metadata[1:]}, expected: ErrorInvalidMetadataPiece},
+	}
+
+	for index, test := range tests {
+		if err := assembler.Add(test.input); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+
+	// the pieces are dropped when the hash does not match
+	if err := assembler.Add(MetadataMessage{Type: MetadataTypeData, TotalSize: len(metadata), Data: metadata}); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	if _, err := assembler.Bencode(); !errors.Is(err, ErrorMetadataHashMismatch) {
+		t.Errorf("expected [%v] | [%v] output", ErrorMetadataHashMismatch, err)
+	}
+	if assembler.Complete() {
+		t.Errorf("pieces kept after a hash mismatch")
+	}
+
+	// a length prefix longer than the metadata
+	metadata = []byte("d4:name9000000000:e")
+	assembler, _ = NewMetadataAssembler(sha1.Sum(metadata), len(metadata))
+
+	if err := assembler.Add(MetadataMessage{Type: MetadataTypeData, TotalSize: len(metadata), Data: metadata}); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	if _, err := assembler.Bencode(); !errors.Is(err, parser.ErrorDictionaryElementCorrupted) {
+		t.Errorf("expected [%v] | [%v] output", parser.ErrorDictionaryElementCorrupted, err)
+	}
+}
@@ -22,6 +22,20 @@ func ParseFromReader(reader io.Reader) (data interface{}, err error) {
 	return
 }
 
+// ParseFromBytes parses the first bencoded element of data and returns the bytes following it
+//
+// Some messages (ut_metadata data, ...) are a bencoded element followed by raw bytes,
+// the string lengths are bounded by the remaining bytes of data
+func ParseFromBytes(data []byte) (element interface{}, rest []byte, err error) {
+	element, consumed, err := parser.ParseBytes(data, parser.Options{})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return element, data[consumed:], nil
+}
+
 // UnmarshallFromReader parses and unmarshall the bencode format from reader in a Bencode structre
 func UnmarshallFromReader(reader io.Reader) (bc bencode.Bencode, err error) {
 	data, err := ParseFromReader(reader)
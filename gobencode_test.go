@@ -1,8 +1,12 @@
 package gobencode
 
 import (
+	"errors"
 	"os"
+	"reflect"
 	"testing"
+
+	"github.com/trixky/gobencode/parser"
 )
 
 func TestUnmarshallFromReader(t *testing.T) {
@@ -42,3 +46,59 @@ func TestUnmarshallFromReader(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFromBytes(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      interface{}
+		expected_rest string
+	}{
+		{
+			input:         "i1e",
+			expected:      1,
+			expected_rest: "",
+		},
+		{
+			input:         "d8:msg_typei1e5:piecei0e10:total_sizei8eeabcdefgh",
+			expected:      map[string]interface{}{"msg_type": 1, "piece": 0, "total_size": 8},
+			expected_rest: "abcdefgh",
+		},
+		{
+			input:         "l3:ouie3:non",
+			expected:      []interface{}{"oui"},
+			expected_rest: "3:non",
+		},
+	}
+
+	for index, test := range tests {
+		output, rest, err := ParseFromBytes([]byte(test.input))
+
+		if err != nil {
+			t.Errorf("test %d: failed to parse: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+		if test.expected_rest != string(rest) {
+			t.Errorf("test %d: expected rest [%s] | [%s] output", index, test.expected_rest, rest)
+		}
+	}
+
+	errors_tests := []struct {
+		input    string
+		expected error
+	}{
+		{input: "d3:oui", expected: parser.ErrorDictionaryElementCorrupted},                            // truncated
+		{input: "9000000000:abc", expected: parser.ErrorStringTooLong},                                 // oversized length
+		{input: "99999999999999999999:abc", expected: parser.ErrorStringTooLong},                       // overflowing length
+		{input: "d8:msg_typei1e5:piece9000000000:e", expected: parser.ErrorDictionaryElementCorrupted}, // oversized length in a dictionary
+	}
+
+	for index, test := range errors_tests {
+		if _, _, err := ParseFromBytes([]byte(test.input)); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+}
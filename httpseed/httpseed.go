@@ -0,0 +1,232 @@
+// Package httpseed provide a client and a server of the Hoffman-style http seeding
+//
+// http://www.bittorrent.org/beps/bep_0017.html
+package httpseed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/internal/mirror"
+)
+
+const (
+	ParameterInfoHash = "info_hash"
+	ParameterPiece    = "piece"
+	ParameterRanges   = "ranges"
+
+	MaxRanges = 64 // ranges of a request, their total length is at most the piece length
+)
+
+var (
+	ErrorNoHttpSeedFound    = errors.New("no http seed found")
+	ErrorUnexpectedHttpCode = errors.New("unexpected http code")
+	ErrorSeedBusy           = errors.New("http seed busy")
+	ErrorPieceUnavailable   = errors.New("piece unavailable from all the http seeds")
+	ErrorInvalidRanges      = errors.New("invalid ranges")
+)
+
+// Range is a byte range of a piece, End is included
+type Range struct {
+	Start int
+	End   int
+}
+
+// formatRanges returns ranges as the ranges parameter: 0-16383,32768-49151
+func formatRanges(ranges []Range) string {
+	parts := make([]string, len(ranges))
+
+	for index, r := range ranges {
+		parts[index] = strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// parseRanges reads the ranges parameter, the ranges must be in a piece of some length
+//
+// Overlapping ranges would make a response longer than the piece, the total length is bounded by the piece length
+func parseRanges(parameter string, piece_length int) ([]Range, error) {
+	ranges := []Range{}
+	parts := strings.Split(parameter, ",")
+	length := 0
+
+	if len(parts) > MaxRanges {
+		return nil, fmt.Errorf("%w: %d ranges, at most %d", ErrorInvalidRanges, len(parts), MaxRanges)
+	}
+
+	for _, part := range parts {
+		bounds := strings.SplitN(part, "-", 2)
+
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("%w: [%s]", ErrorInvalidRanges, part)
+		}
+
+		start, start_err := strconv.Atoi(bounds[0])
+		end, end_err := strconv.Atoi(bounds[1])
+
+		if start_err != nil || end_err != nil || start < 0 || end < start || end >= piece_length {
+			return nil, fmt.Errorf("%w: [%s]", ErrorInvalidRanges, part)
+		}
+
+		if length += end - start + 1; length > piece_length {
+			return nil, fmt.Errorf("%w: %d bytes requested in a piece of %d bytes", ErrorInvalidRanges, length, piece_length)
+		}
+
+		ranges = append(ranges, Range{Start: start, End: end})
+	}
+
+	return ranges, nil
+}
+
+// Client downloads the pieces of a torrent from its http seeds (httpseeds)
+//
+// A piece failing on an http seed (http error, busy seed, hash mismatch...) is retried on the next one
+type Client struct {
+	HttpClient *http.Client
+
+	info      *bencode.Info
+	info_hash [20]byte
+	seeds     *mirror.List
+}
+
+// NewClient creates a client for the http seeds of a torrent
+func NewClient(bc *bencode.Bencode) (*Client, error) {
+	seeds, err := mirror.NewList(bc.HttpSeeds)
+
+	if err != nil {
+		return nil, ErrorNoHttpSeedFound
+	}
+
+	return &Client{
+		HttpClient: http.DefaultClient,
+		info:       &bc.Info,
+		info_hash:  bc.InfoHash,
+		seeds:      seeds,
+	}, nil
+}
+
+// Seeds returns the http seeds, the last successful one first
+func (c *Client) Seeds() []string {
+	return c.seeds.Urls()
+}
+
+// PieceUrl returns the url requesting a piece, or some ranges of it, from an http seed
+func PieceUrl(seed string, info_hash [20]byte, piece_index int, ranges []Range) string {
+	parameters := ParameterInfoHash + "=" + url.QueryEscape(string(info_hash[:])) + "&" + ParameterPiece + "=" + strconv.Itoa(piece_index)
+
+	if len(ranges) > 0 {
+		parameters += "&" + ParameterRanges + "=" + formatRanges(ranges)
+	}
+
+	separator := "?"
+
+	if strings.Contains(seed, "?") {
+		separator = "&"
+	}
+
+	return seed + separator + parameters
+}
+
+// fetch requests a piece, or some ranges of it, from an http seed
+func (c *Client) fetch(ctx context.Context, seed string, piece_index int, ranges []Range, length int) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, PieceUrl(seed, c.info_hash, piece_index, ranges), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.HttpClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusServiceUnavailable:
+		// the body is the number of seconds to wait before retrying
+		retry, _ := io.ReadAll(io.LimitReader(response.Body, 32))
+
+		return nil, fmt.Errorf("%w: retry in [%s] seconds", ErrorSeedBusy, strings.TrimSpace(string(retry)))
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrorUnexpectedHttpCode, response.StatusCode)
+	}
+
+	data := make([]byte, length)
+
+	if _, err := io.ReadFull(response.Body, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Piece downloads a piece and checks its hash, each http seed is tried until one succeeds
+func (c *Client) Piece(ctx context.Context, piece_index int) ([]byte, error) {
+	piece_length, err := c.info.PieceLengthAt(piece_index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.seeds.Try(ctx, func(seed string) ([]byte, error) {
+		data, err := c.fetch(ctx, seed, piece_index, nil, piece_length)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return data, c.info.VerifyPiece(piece_index, data)
+	}, true)
+
+	if errors.Is(err, mirror.ErrorAllFailed) {
+		return nil, fmt.Errorf("%w: piece %d: %v", ErrorPieceUnavailable, piece_index, err)
+	}
+
+	return data, err
+}
+
+// Ranges downloads some ranges of a piece from the first http seed answering, they can not be checked
+func (c *Client) Ranges(ctx context.Context, piece_index int, ranges []Range) ([]byte, error) {
+	piece_length, err := c.info.PieceLengthAt(piece_index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parseRanges(formatRanges(ranges), piece_length); err != nil || len(ranges) == 0 {
+		return nil, fmt.Errorf("%w: [%s]", ErrorInvalidRanges, formatRanges(ranges))
+	}
+
+	length := 0
+
+	for _, r := range ranges {
+		length += r.End - r.Start + 1
+	}
+
+	// the ranges are not checked, the seed is not promoted
+	data, err := c.seeds.Try(ctx, func(seed string) ([]byte, error) {
+		return c.fetch(ctx, seed, piece_index, ranges, length)
+	}, false)
+
+	if errors.Is(err, mirror.ErrorAllFailed) {
+		return nil, fmt.Errorf("%w: piece %d: %v", ErrorPieceUnavailable, piece_index, err)
+	}
+
+	return data, err
+}
+
+// Download downloads all the pieces in a directory, the files are laid out as their LocalPath
+func (c *Client) Download(ctx context.Context, root string) error {
+	return mirror.Download(ctx, c.info, root, c.Piece)
+}
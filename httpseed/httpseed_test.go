@@ -0,0 +1,208 @@
+package httpseed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+type testFile struct {
+	path    []string
+	content []byte
+}
+
+// newTorrent creates a multi file torrent with its http seeds, the files are written in a directory
+func newTorrent(t *testing.T, root string, files []testFile, piece_length int, http_seeds []string) *bencode.Bencode {
+	content := []byte{}
+	file_list := []interface{}{}
+
+	for _, file := range files {
+		path := []interface{}{}
+
+		for _, component := range file.path {
+			path = append(path, component)
+		}
+
+		file_list = append(file_list, map[string]interface{}{
+			bencode.DictionaryKeyLength: len(file.content),
+			bencode.DictionaryKeyPath:   path,
+		})
+		content = append(content, file.content...)
+
+		local_path := filepath.Join(append([]string{root, "dir"}, file.path...)...)
+
+		if err := os.MkdirAll(filepath.Dir(local_path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(local_path, file.content, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	pieces := ""
+
+	for start := 0; start < len(content); start += piece_length {
+		end := start + piece_length
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		hash := sha1.Sum(content[start:end])
+		pieces += string(hash[:])
+	}
+
+	seeds := []interface{}{}
+
+	for _, seed := range http_seeds {
+		seeds = append(seeds, seed)
+	}
+
+	bc := &bencode.Bencode{
+		Data: map[string]interface{}{
+			bencode.DictionaryKeyHttpSeeds: seeds,
+			bencode.DictionaryKeyInfo: map[string]interface{}{
+				bencode.DictionaryKeyName:        "dir",
+				bencode.DictionaryKeyPieceLength: piece_length,
+				bencode.DictionaryKeyPieces:      pieces,
+				bencode.DictionaryKeyFiles:       file_list,
+			},
+		},
+	}
+
+	if err := bc.UnmarshallAll(); err != nil {
+		t.Fatalf("failed to unmarshall torrent: %v", err)
+	}
+
+	return bc
+}
+
+var test_files = []testFile{
+	{path: []string{"a.txt"}, content: []byte(strings.Repeat("a", 10))},
+	{path: []string{"sub", "b.txt"}, content: []byte(strings.Repeat("b", 7))},
+}
+
+func TestPieceUrl(t *testing.T) {
+	info_hash := [20]byte{0x01, 'a', ' '}
+
+	tests := []struct {
+		seed     string
+		ranges   []Range
+		expected string
+	}{
+		{"http://a/seed", nil, "http://a/seed?info_hash=%01a+%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00&piece=3"},
+		{"http://a/seed?key=1", []Range{{0, 9}, {20, 29}}, "http://a/seed?key=1&info_hash=%01a+%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00%00&piece=3&ranges=0-9,20-29"},
+	}
+
+	for index, test := range tests {
+		if output := PieceUrl(test.seed, info_hash, 3, test.ranges); output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Range
+	}{
+		{"0-3", []Range{{0, 3}}},
+		{"0-0,2-3", []Range{{0, 0}, {2, 3}}},
+		{"0-1,0-1", []Range{{0, 1}, {0, 1}}},
+		{"0-3,0-0", nil}, // longer than the piece
+		{strings.Repeat("0-0,", MaxRanges) + "0-0", nil},
+		{"0-4", nil},
+		{"2-1", nil},
+		{"-1-2", nil},
+		{"a-b", nil},
+		{"", nil},
+	}
+
+	for index, test := range tests {
+		output, err := parseRanges(test.input, 4)
+
+		if test.expected == nil {
+			if !errors.Is(err, ErrorInvalidRanges) {
+				t.Errorf("test %d: expected [%v] | [%v] output", index, ErrorInvalidRanges, err)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output: %v", index, test.expected, output, err)
+		}
+	}
+}
+
+func TestClientDownload(t *testing.T) {
+	seed_root := t.TempDir()
+	bc := newTorrent(t, seed_root, test_files, 4, nil)
+
+	handler := NewHandler()
+	handler.Add(bc, seed_root)
+
+	seed := httptest.NewServer(handler)
+	defer seed.Close()
+
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("30"))
+	}))
+	defer busy.Close()
+
+	bc.HttpSeeds = []string{busy.URL, seed.URL}
+	c, err := NewClient(bc)
+
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	root := t.TempDir()
+
+	if err := c.Download(context.Background(), root); err != nil {
+		t.Fatalf("failed to download: %v", err)
+	}
+
+	for _, file := range test_files {
+		content, err := os.ReadFile(filepath.Join(append([]string{root, "dir"}, file.path...)...))
+
+		if err != nil || !bytes.Equal(content, file.content) {
+			t.Errorf("expected [%s] | [%s] output: %v", file.content, content, err)
+		}
+	}
+
+	if c.Seeds()[0] != seed.URL {
+		t.Errorf("expected [%s] | [%s] output first seed", seed.URL, c.Seeds()[0])
+	}
+
+	// the second piece is aabb
+	if data, err := c.Ranges(context.Background(), 2, []Range{{0, 0}, {3, 3}}); err != nil || string(data) != "ab" {
+		t.Errorf("expected [ab] | [%s] output: %v", data, err)
+	}
+
+	if _, err := c.Ranges(context.Background(), 0, []Range{{0, 4}}); !errors.Is(err, ErrorInvalidRanges) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidRanges, err)
+	}
+
+	bc.HttpSeeds = []string{busy.URL}
+	c, _ = NewClient(bc)
+
+	if _, err := c.Piece(context.Background(), 0); !errors.Is(err, ErrorPieceUnavailable) || !strings.Contains(err.Error(), ErrorSeedBusy.Error()) {
+		t.Errorf("expected [%v] | [%v] output", ErrorSeedBusy, err)
+	}
+
+	if _, err := NewClient(&bencode.Bencode{}); err != ErrorNoHttpSeedFound {
+		t.Errorf("expected [%v] | [%v] output", ErrorNoHttpSeedFound, err)
+	}
+}
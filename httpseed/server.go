@@ -0,0 +1,159 @@
+package httpseed
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	ServerDefaultRetryAfter = 10 // seconds
+)
+
+type seededTorrent struct {
+	info *bencode.Info
+	root string
+}
+
+// Handler serves the pieces of torrents from their download directories, for tests or a LAN distribution
+//
+// http://www.bittorrent.org/beps/bep_0017.html
+type Handler struct {
+	Verify      bool // the pieces are checked before being served
+	MaxRequests int  // requests served at the same time, the others are answered busy (0 for no limit)
+	RetryAfter  int  // seconds sent to the busy clients
+
+	torrents map[[20]byte]seededTorrent
+	requests int
+	mutex    sync.Mutex
+}
+
+// NewHandler creates a handler serving no torrent, the pieces are checked
+func NewHandler() *Handler {
+	return &Handler{
+		Verify:     true,
+		RetryAfter: ServerDefaultRetryAfter,
+		torrents:   map[[20]byte]seededTorrent{},
+	}
+}
+
+// Add serves a torrent from a download directory, the files are laid out as their LocalPath
+func (h *Handler) Add(bc *bencode.Bencode, root string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.torrents[bc.InfoHash] = seededTorrent{
+		info: &bc.Info,
+		root: root,
+	}
+}
+
+// Remove stops serving a torrent
+func (h *Handler) Remove(info_hash [20]byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.torrents, info_hash)
+}
+
+// acquire counts a request, it returns false if the handler is busy
+func (h *Handler) acquire() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.MaxRequests > 0 && h.requests >= h.MaxRequests {
+		return false
+	}
+
+	h.requests++
+
+	return true
+}
+
+// release counts the end of a request
+func (h *Handler) release() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.requests--
+}
+
+// ServeHTTP answers ?info_hash=&piece=&ranges= requests with the bytes of the piece or of its ranges
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.acquire() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(strconv.Itoa(h.RetryAfter)))
+		return
+	}
+
+	defer h.release()
+
+	query := r.URL.Query()
+	info_hash := [20]byte{}
+
+	if parameter := query.Get(ParameterInfoHash); len(parameter) != len(info_hash) {
+		http.Error(w, "invalid info hash", http.StatusBadRequest)
+		return
+	} else {
+		copy(info_hash[:], parameter)
+	}
+
+	h.mutex.Lock()
+	torrent, ok := h.torrents[info_hash]
+	h.mutex.Unlock()
+
+	if !ok {
+		http.Error(w, "unknown info hash", http.StatusNotFound)
+		return
+	}
+
+	piece_index, err := strconv.Atoi(query.Get(ParameterPiece))
+
+	if err != nil {
+		http.Error(w, "invalid piece", http.StatusBadRequest)
+		return
+	}
+
+	piece_length, err := torrent.info.PieceLengthAt(piece_index)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ranges := []Range{{Start: 0, End: piece_length - 1}}
+
+	if parameter := query.Get(ParameterRanges); len(parameter) > 0 {
+		if ranges, err = parseRanges(parameter, piece_length); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := torrent.info.ReadPiece(torrent.root, piece_index)
+
+	if err == nil && h.Verify {
+		err = torrent.info.VerifyPiece(piece_index, data)
+	}
+
+	if err != nil {
+		http.Error(w, "piece unavailable", http.StatusNotFound)
+		return
+	}
+
+	length := 0
+
+	for _, r := range ranges {
+		length += r.End - r.Start + 1
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(length))
+	w.WriteHeader(http.StatusOK)
+
+	for _, r := range ranges {
+		w.Write(data[r.Start : r.End+1])
+	}
+}
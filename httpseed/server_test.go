@@ -0,0 +1,109 @@
+package httpseed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	root := t.TempDir()
+	bc := newTorrent(t, root, test_files, 4, nil)
+
+	handler := NewHandler()
+	handler.Add(bc, root)
+
+	tests := []struct {
+		url      string
+		code     int
+		expected string
+	}{
+		{PieceUrl("/", bc.InfoHash, 0, nil), http.StatusOK, "aaaa"},
+		{PieceUrl("/", bc.InfoHash, 2, nil), http.StatusOK, "aabb"},
+		{PieceUrl("/", bc.InfoHash, 4, nil), http.StatusOK, "b"},
+		{PieceUrl("/", bc.InfoHash, 2, []Range{{1, 2}, {0, 0}}), http.StatusOK, "aba"},
+		{PieceUrl("/", bc.InfoHash, 2, []Range{{0, 3}, {0, 3}, {0, 3}}), http.StatusBadRequest, ""}, // amplified response
+		{PieceUrl("/", bc.InfoHash, 4, []Range{{0, 1}}), http.StatusBadRequest, ""},
+		{PieceUrl("/", bc.InfoHash, 5, nil), http.StatusBadRequest, ""},
+		{PieceUrl("/", [20]byte{1}, 0, nil), http.StatusNotFound, ""},
+		{"/?info_hash=abc&piece=0", http.StatusBadRequest, ""},
+	}
+
+	for index, test := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+		if recorder.Code != test.code {
+			t.Errorf("test %d: expected [%d] | [%d] output code", index, test.code, recorder.Code)
+			continue
+		}
+
+		if test.code == http.StatusOK && recorder.Body.String() != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, recorder.Body.String())
+		}
+	}
+}
+
+func TestHandlerVerify(t *testing.T) {
+	root := t.TempDir()
+	bc := newTorrent(t, root, test_files, 4, nil)
+
+	if err := os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("corrupted!"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	handler := NewHandler()
+	handler.Add(bc, root)
+
+	tests := []struct {
+		verify bool
+		code   int
+	}{
+		{true, http.StatusNotFound},
+		{false, http.StatusOK},
+	}
+
+	for index, test := range tests {
+		handler.Verify = test.verify
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, PieceUrl("/", bc.InfoHash, 0, nil), nil))
+
+		if recorder.Code != test.code {
+			t.Errorf("test %d: expected [%d] | [%d] output code", index, test.code, recorder.Code)
+		}
+	}
+
+	handler.Remove(bc.InfoHash)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, PieceUrl("/", bc.InfoHash, 0, nil), nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected [%d] | [%d] output code", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestHandlerBusy(t *testing.T) {
+	handler := NewHandler()
+	handler.MaxRequests = 1
+	handler.RetryAfter = 5
+
+	// a request is being served
+	handler.acquire()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != "5" {
+		t.Errorf("expected [503] [5] | [%d] [%s] output", recorder.Code, recorder.Body.String())
+	}
+
+	handler.release()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected [%d] | [%d] output code", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,196 @@
+package gobencode
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	torrent_extension = ".torrent"
+)
+
+// IndexResult is a torrent file found by IndexDir, Err is set if it can not be read
+type IndexResult struct {
+	Path      string
+	InfoHash  [20]byte
+	Name      string
+	Size      int
+	FileCount int
+	Trackers  []string // announce urls of all the tiers, without repetition
+	Err       error
+}
+
+// IndexStats is a snapshot of the progress of IndexDir
+type IndexStats struct {
+	Found      int  // torrent files found by the walk
+	Indexed    int  // torrent files sent without error
+	Failed     int  // torrent files or directories that can not be read
+	Duplicates int  // torrent files skipped because their info hash was already sent
+	WalkDone   bool // all the torrent files are found, Found is final
+}
+
+// IndexProgress counts the torrent files of IndexDir while it runs
+type IndexProgress struct {
+	found      int64
+	indexed    int64
+	failed     int64
+	duplicates int64
+	walk_done  int32
+}
+
+// Stats returns the current progress
+func (p *IndexProgress) Stats() IndexStats {
+	return IndexStats{
+		Found:      int(atomic.LoadInt64(&p.found)),
+		Indexed:    int(atomic.LoadInt64(&p.indexed)),
+		Failed:     int(atomic.LoadInt64(&p.failed)),
+		Duplicates: int(atomic.LoadInt64(&p.duplicates)),
+		WalkDone:   atomic.LoadInt32(&p.walk_done) == 1,
+	}
+}
+
+// indexFile parses a torrent file
+func indexFile(path string) IndexResult {
+	result := IndexResult{
+		Path: path,
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	defer file.Close()
+
+	bc, err := UnmarshallFromReader(file)
+
+	if err == nil {
+		err = bc.GetInfoHash()
+	}
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.InfoHash = bc.InfoHash
+	result.Name = bc.Info.DirectoryName
+	result.Size = bc.Info.TotalLength()
+	result.FileCount = len(bc.Info.Files)
+	result.Trackers = bc.Trackers()
+
+	return result
+}
+
+// IndexDir walks a directory tree and parses its .torrent files with some workers
+//
+// The results are sent on the channel, which is closed when the walk is over or the context is canceled.
+// A torrent with an info hash already sent is skipped and counted in the progress.
+func IndexDir(ctx context.Context, root string, workers int) (<-chan IndexResult, *IndexProgress) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make(chan IndexResult, workers)
+	paths := make(chan string, workers)
+	progress := &IndexProgress{}
+
+	// send stops when the context is canceled
+	send := func(result IndexResult) bool {
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(paths)
+		defer atomic.StoreInt32(&progress.walk_done, 1)
+
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err != nil {
+				atomic.AddInt64(&progress.failed, 1)
+
+				if !send(IndexResult{Path: path, Err: err}) {
+					return ctx.Err()
+				}
+
+				// the unreadable directory is skipped
+				return nil
+			}
+
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(path), torrent_extension) {
+				return nil
+			}
+
+			atomic.AddInt64(&progress.found, 1)
+
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	seen := map[[20]byte]bool{}
+	seen_mutex := sync.Mutex{}
+	wait_group := sync.WaitGroup{}
+
+	for worker := 0; worker < workers; worker++ {
+		wait_group.Add(1)
+
+		go func() {
+			defer wait_group.Done()
+
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				result := indexFile(path)
+
+				if result.Err != nil {
+					atomic.AddInt64(&progress.failed, 1)
+					send(result)
+					continue
+				}
+
+				seen_mutex.Lock()
+				duplicate := seen[result.InfoHash]
+				seen[result.InfoHash] = true
+				seen_mutex.Unlock()
+
+				if duplicate {
+					atomic.AddInt64(&progress.duplicates, 1)
+					continue
+				}
+
+				if send(result) {
+					atomic.AddInt64(&progress.indexed, 1)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wait_group.Wait()
+		close(results)
+	}()
+
+	return results, progress
+}
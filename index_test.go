@@ -0,0 +1,113 @@
+package gobencode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// copyTestFile copies a test torrent in a directory
+func copyTestFile(t *testing.T, name string, destination string) {
+	data, err := os.ReadFile(filepath.Join(".test_files", name))
+
+	if err != nil {
+		t.Fatalf("failed to read file [%s]: %v", name, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := os.WriteFile(destination, data, 0644); err != nil {
+		t.Fatalf("failed to write file [%s]: %v", destination, err)
+	}
+}
+
+func TestIndexDir(t *testing.T) {
+	root := t.TempDir()
+
+	copyTestFile(t, "arch.torrent", filepath.Join(root, "arch.torrent"))
+	copyTestFile(t, "minecraft.torrent", filepath.Join(root, "games", "minecraft.TORRENT"))
+	copyTestFile(t, "ubuntu.torrent", filepath.Join(root, "linux", "deep", "ubuntu.torrent"))
+	copyTestFile(t, "ubuntu.torrent", filepath.Join(root, "linux", "ubuntu copy.torrent"))
+	copyTestFile(t, "kubuntu.torrent", filepath.Join(root, "linux", "kubuntu.txt"))
+
+	if err := os.WriteFile(filepath.Join(root, "broken.torrent"), []byte("d8:announce"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	results, progress := IndexDir(context.Background(), root, 3)
+
+	names := []string{}
+	failed := 0
+
+	for result := range results {
+		if result.Err != nil {
+			failed++
+			continue
+		}
+
+		names = append(names, result.Name)
+
+		if result.Name == "Minecraft 1.15.2" && (result.FileCount != 3 || len(result.Trackers) == 0) {
+			t.Errorf("expected [3] files and some trackers | [%d] [%v] output", result.FileCount, result.Trackers)
+		}
+
+		if result.Name == "archlinux-2022.05.01-x86_64.iso" && result.Size != 866463744 {
+			t.Errorf("expected [866463744] | [%d] output size", result.Size)
+		}
+	}
+
+	sort.Strings(names)
+	expected := []string{"Minecraft 1.15.2", "archlinux-2022.05.01-x86_64.iso", "ubuntu-22.04-desktop-amd64.iso"}
+
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v | %v output", expected, names)
+	}
+
+	for index := range expected {
+		if names[index] != expected[index] {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, expected[index], names[index])
+		}
+	}
+
+	stats := progress.Stats()
+
+	if failed != 1 || stats != (IndexStats{Found: 5, Indexed: 3, Failed: 1, Duplicates: 1, WalkDone: true}) {
+		t.Errorf("expected [1] failure | [%d] output, stats %+v", failed, stats)
+	}
+}
+
+func TestIndexDirCanceled(t *testing.T) {
+	root := t.TempDir()
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		copyTestFile(t, "arch.torrent", filepath.Join(root, name+".torrent"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, _ := IndexDir(ctx, root, 2)
+
+	// the channel must be closed even if nothing reads it
+	for range results {
+	}
+
+	results, _ = IndexDir(context.Background(), filepath.Join(root, "missing"), 2)
+	count := 0
+
+	for result := range results {
+		if result.Err == nil {
+			t.Errorf("expected an error for a missing root")
+		}
+
+		count++
+	}
+
+	if count != 1 {
+		t.Errorf("expected [1] | [%d] output results", count)
+	}
+}
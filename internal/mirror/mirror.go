@@ -0,0 +1,111 @@
+// Package mirror provide the rotation of the http sources of a torrent (web seeds, http seeds)
+// and the download of all its pieces from them
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+var (
+	ErrorNoUrl     = errors.New("no http url")
+	ErrorAllFailed = errors.New("all the urls failed")
+)
+
+// List is a list of http urls tried in order, the last successful one first
+type List struct {
+	urls  []string
+	mutex sync.Mutex
+}
+
+// NewList creates a list of the http and https urls of some urls
+func NewList(urls []string) (*List, error) {
+	l := &List{}
+
+	for _, url := range urls {
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			l.urls = append(l.urls, url)
+		}
+	}
+
+	if len(l.urls) == 0 {
+		return nil, ErrorNoUrl
+	}
+
+	return l, nil
+}
+
+// Urls returns the urls, the last successful one first
+func (l *List) Urls() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return append([]string{}, l.urls...)
+}
+
+// Promote moves a successful url to the front
+func (l *List) Promote(url string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for index, u := range l.urls {
+		if u == url {
+			copy(l.urls[1:index+1], l.urls[:index])
+			l.urls[0] = url
+			return
+		}
+	}
+}
+
+// Try calls fetch with each url until one succeeds, the successful url is promoted if asked
+//
+// The error is the context error if it is done, or ErrorAllFailed with the error of each url
+func (l *List) Try(ctx context.Context, fetch func(url string) ([]byte, error), promote bool) ([]byte, error) {
+	url_errors := []string{}
+
+	for _, url := range l.Urls() {
+		data, err := fetch(url)
+
+		if err == nil {
+			if promote {
+				l.Promote(url)
+			}
+
+			return data, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		url_errors = append(url_errors, fmt.Sprintf("%s: %v", url, err))
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrorAllFailed, strings.Join(url_errors, ", "))
+}
+
+// Download downloads all the pieces of a torrent in a directory, the files are laid out as their LocalPath
+func Download(ctx context.Context, info *bencode.Info, root string, piece func(ctx context.Context, piece_index int) ([]byte, error)) error {
+	if err := info.CreateFiles(root); err != nil {
+		return err
+	}
+
+	for piece_index := range info.Pieces {
+		data, err := piece(ctx, piece_index)
+
+		if err != nil {
+			return err
+		}
+
+		if err := info.WritePiece(root, piece_index, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewList(t *testing.T) {
+	l, err := NewList([]string{"ftp://a/", "http://b/", "https://c/"})
+
+	if err != nil {
+		t.Fatalf("failed to create the list: %v", err)
+	}
+
+	if expected := []string{"http://b/", "https://c/"}; !reflect.DeepEqual(l.Urls(), expected) {
+		t.Errorf("expected %v | %v output", expected, l.Urls())
+	}
+
+	if _, err := NewList([]string{"ftp://a/"}); err != ErrorNoUrl {
+		t.Errorf("expected [%v] | [%v] output", ErrorNoUrl, err)
+	}
+}
+
+func TestTry(t *testing.T) {
+	tests := []struct {
+		working  string
+		promote  bool
+		expected []string // urls after the try
+		err      error
+	}{
+		{working: "http://a/", promote: true, expected: []string{"http://a/", "http://b/", "http://c/"}},
+		{working: "http://c/", promote: true, expected: []string{"http://c/", "http://a/", "http://b/"}},
+		{working: "http://c/", promote: false, expected: []string{"http://a/", "http://b/", "http://c/"}},
+		{working: "", promote: true, expected: []string{"http://a/", "http://b/", "http://c/"}, err: ErrorAllFailed},
+	}
+
+	for index, test := range tests {
+		l, _ := NewList([]string{"http://a/", "http://b/", "http://c/"})
+		tried := []string{}
+
+		data, err := l.Try(context.Background(), func(url string) ([]byte, error) {
+			tried = append(tried, url)
+
+			if url != test.working {
+				return nil, errors.New("failed")
+			}
+
+			return []byte(url), nil
+		}, test.promote)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+			continue
+		}
+
+		if err == nil && string(data) != test.working {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.working, data)
+		}
+
+		if !reflect.DeepEqual(l.Urls(), test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, l.Urls())
+		}
+	}
+
+	// the next urls are not tried once the context is done
+	l, _ := NewList([]string{"http://a/", "http://b/"})
+	ctx, cancel := context.WithCancel(context.Background())
+	tried := 0
+
+	_, err := l.Try(ctx, func(url string) ([]byte, error) {
+		tried++
+		cancel()
+
+		return nil, ctx.Err()
+	}, true)
+
+	if err != context.Canceled || tried != 1 {
+		t.Errorf("expected [%v] after 1 try | [%v] after %d output", context.Canceled, err, tried)
+	}
+}
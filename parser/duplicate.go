@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type DuplicatePolicy int
+
+const (
+	DuplicateKeysLastWins   DuplicatePolicy = iota // the last value is kept (default)
+	DuplicateKeysFirstWins                         // the first value is kept
+	DuplicateKeysError                             // a repeated key is an error
+	DuplicateKeysCollectAll                        // all the values are kept in a DuplicateValues
+)
+
+const (
+	dictionary_key_info = "info" // dictionary checked by the strict mode
+)
+
+var (
+	ErrorDuplicateKey = errors.New("duplicate dictionary key")
+)
+
+// DuplicateValues holds all the values of a repeated key with DuplicateKeysCollectAll, in their order
+//
+// The *OrderedDict keep the repeated keys as separate entries instead
+type DuplicateValues []interface{}
+
+// Duplicate is a key repeated in a dictionary
+type Duplicate struct {
+	Path    []interface{} // keys and indexes of the dictionary, see bencode.KeyPath
+	Key     string
+	Offsets []int // offsets of each occurrence of the key, from the start of the parsed element
+}
+
+// Report lists the anomalies found while parsing
+type Report struct {
+	Duplicates []Duplicate
+}
+
+// isSimpleKey checks if a key can be written without quotes
+func isSimpleKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FormatPath returns a path of keys and indexes as info.files[0].path or info["piece length"], the root is "."
+//
+// It is the format of bencode.KeyPath
+func FormatPath(path []interface{}) string {
+	if len(path) == 0 {
+		return "."
+	}
+
+	builder := strings.Builder{}
+
+	for _, segment := range path {
+		switch segment := segment.(type) {
+		case int:
+			builder.WriteString("[" + strconv.Itoa(segment) + "]")
+		case string:
+			if isSimpleKey(segment) {
+				if builder.Len() > 0 {
+					builder.WriteByte('.')
+				}
+
+				builder.WriteString(segment)
+			} else {
+				builder.WriteString("[" + strconv.Quote(segment) + "]")
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// String returns the duplicate as a single line
+func (d Duplicate) String() string {
+	offsets := make([]string, len(d.Offsets))
+
+	for index, offset := range d.Offsets {
+		offsets[index] = strconv.Itoa(offset)
+	}
+
+	return fmt.Sprintf("%s: key [%s] repeated at offsets %s", FormatPath(d.Path), d.Key, strings.Join(offsets, ", "))
+}
+
+// isInfoKey checks if a key of a dictionary is the info dictionary of a torrent or is in it
+func isInfoKey(path []interface{}, key string) bool {
+	if len(path) == 0 {
+		return key == dictionary_key_info
+	}
+
+	return path[0] == dictionary_key_info
+}
+
+// duplicate reports a repeated key of the current dictionary and applies the policy
+func (d *decoder) duplicate(key string, first_offset int, offset int) error {
+	// the first offset of a key is unique in the element, it identifies the repeated key
+	if index, reported := d.duplicates[first_offset]; reported {
+		d.report.Duplicates[index].Offsets = append(d.report.Duplicates[index].Offsets, offset)
+	} else {
+		if d.duplicates == nil {
+			d.duplicates = map[int]int{}
+		}
+
+		d.duplicates[first_offset] = len(d.report.Duplicates)
+		d.report.Duplicates = append(d.report.Duplicates, Duplicate{
+			Path:    append([]interface{}{}, d.path...),
+			Key:     key,
+			Offsets: []int{first_offset, offset},
+		})
+	}
+
+	if d.options.DuplicateKeys == DuplicateKeysError || d.options.Strict && isInfoKey(d.path, key) {
+		return fmt.Errorf("%w [%s] in %s at offset %d (first at offset %d)", ErrorDuplicateKey, key, FormatPath(d.path), offset, first_offset)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		options  Options
+		expected interface{}
+		err      bool
+	}{
+		{input: "d1:ai1e1:ai2ee", options: Options{}, expected: map[string]interface{}{"a": 2}},
+		{input: "d1:ai1e1:ai2ee", options: Options{DuplicateKeys: DuplicateKeysFirstWins}, expected: map[string]interface{}{"a": 1}},
+		{input: "d1:ai1e1:ai2ee", options: Options{DuplicateKeys: DuplicateKeysError}, err: true},
+		{input: "d1:ai1e1:ai2e1:ai3ee", options: Options{DuplicateKeys: DuplicateKeysCollectAll}, expected: map[string]interface{}{"a": DuplicateValues{1, 2, 3}}},
+		{input: "d1:ai1e1:ai2ee", options: Options{DuplicateKeys: DuplicateKeysFirstWins, OrderedDictionaries: true}, expected: &OrderedDict{Entries: []DictionaryEntry{{Key: "a", Value: 1}}}},
+		{input: "d1:ai1e1:ai2ee", options: Options{OrderedDictionaries: true}, expected: &OrderedDict{Entries: []DictionaryEntry{{Key: "a", Value: 1}, {Key: "a", Value: 2}}}},
+		// strict mode
+		{input: "d1:ai1e1:ai2ee", options: Options{Strict: true}, expected: map[string]interface{}{"a": 2}},
+		{input: "d4:infod1:ai1e1:ai2eee", options: Options{Strict: true}, err: true},
+		{input: "d4:infod1:ai1ee4:infod1:ai2eee", options: Options{Strict: true}, err: true},
+		{input: "d4:infod1:ai1ee4:infod1:ai2eee", options: Options{Strict: true, DuplicateKeys: DuplicateKeysFirstWins}, err: true},
+		{input: "d4:infod1:ai1ee4:infod1:ai2eee", options: Options{}, expected: map[string]interface{}{"info": map[string]interface{}{"a": 2}}},
+		{input: "d4:infod5:filesld1:ai1e1:ai2eeeee", options: Options{Strict: true, DuplicateKeys: DuplicateKeysFirstWins}, err: true},
+		{input: "ld4:infod1:ai1e1:ai2eeee", options: Options{Strict: true}, expected: []interface{}{map[string]interface{}{"info": map[string]interface{}{"a": 2}}}},
+	}
+
+	for index, test := range tests {
+		output, err := ParseElementWithOptions(bufio.NewReader(strings.NewReader(test.input)), test.options)
+
+		if test.err {
+			if !errors.Is(err, ErrorDuplicateKey) {
+				t.Errorf("test %d: expected [%v] | [%v] output error", index, ErrorDuplicateKey, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: failed to parse input [%s]: %v", index, test.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestDuplicateReport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "d1:ai1e1:bi2ee", expected: []string{}},
+		{input: "d1:ai1e1:ai2ee", expected: []string{".: key [a] repeated at offsets 1, 7"}},
+		{input: "d1:ai1e1:ai2e1:ai3ee", expected: []string{".: key [a] repeated at offsets 1, 7, 13"}},
+		{input: "d4:infod5:filesld1:ai1e1:ai2eeeee", expected: []string{"info.files[0]: key [a] repeated at offsets 17, 23"}},
+		{input: "ld1:ai1e1:ai2eed1:ai1e1:ai2eee", expected: []string{"[0]: key [a] repeated at offsets 2, 8", "[1]: key [a] repeated at offsets 16, 22"}},
+		{input: "d4:infod9:file treed1:ai1e1:ai2eeee", expected: []string{`info["file tree"]: key [a] repeated at offsets 20, 26`}},
+		{input: "d1:ai1e1:bi1e1:ai2e1:bi2e1:ai3ee", expected: []string{".: key [a] repeated at offsets 1, 13, 25", ".: key [b] repeated at offsets 7, 19"}},
+	}
+
+	for index, test := range tests {
+		_, report, err := ParseElementWithReport(bufio.NewReader(strings.NewReader(test.input)), Options{})
+
+		if err != nil {
+			t.Errorf("test %d: failed to parse input [%s]: %v", index, test.input, err)
+			continue
+		}
+
+		output := []string{}
+
+		for _, duplicate := range report.Duplicates {
+			output = append(output, duplicate.String())
+		}
+
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"sort"
+)
+
+// DictionaryEntry is a key and its value in an OrderedDict
+type DictionaryEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedDict is a dictionary keeping the order of its keys, as read or as inserted
+//
+// Unlike map[string]interface{}, it can hold a non-canonical dictionary (unsorted or repeated keys)
+type OrderedDict struct {
+	Entries []DictionaryEntry
+}
+
+// NewOrderedDict converts a map to an OrderedDict with sorted keys, the nested dictionaries are converted too
+func NewOrderedDict(dictionary map[string]interface{}) *OrderedDict {
+	keys := make([]string, 0, len(dictionary))
+
+	for key := range dictionary {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	ordered_dictionary := &OrderedDict{
+		Entries: make([]DictionaryEntry, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		ordered_dictionary.Entries = append(ordered_dictionary.Entries, DictionaryEntry{Key: key, Value: ToOrdered(dictionary[key])})
+	}
+
+	return ordered_dictionary
+}
+
+// Len returns the number of entries
+func (o *OrderedDict) Len() int {
+	return len(o.Entries)
+}
+
+// Keys returns the keys in their order
+func (o *OrderedDict) Keys() []string {
+	keys := make([]string, len(o.Entries))
+
+	for index, entry := range o.Entries {
+		keys[index] = entry.Key
+	}
+
+	return keys
+}
+
+// Get returns the value of a key, the last one if the key is repeated like in the map form
+func (o *OrderedDict) Get(key string) (interface{}, bool) {
+	for index := len(o.Entries) - 1; index >= 0; index-- {
+		if o.Entries[index].Key == key {
+			return o.Entries[index].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Set replaces the value of a key in place, or appends it at the end
+func (o *OrderedDict) Set(key string, value interface{}) {
+	for index := len(o.Entries) - 1; index >= 0; index-- {
+		if o.Entries[index].Key == key {
+			o.Entries[index].Value = value
+			return
+		}
+	}
+
+	o.Entries = append(o.Entries, DictionaryEntry{Key: key, Value: value})
+}
+
+// Delete removes every entry of a key
+func (o *OrderedDict) Delete(key string) {
+	entries := o.Entries[:0]
+
+	for _, entry := range o.Entries {
+		if entry.Key != key {
+			entries = append(entries, entry)
+		}
+	}
+
+	o.Entries = entries
+}
+
+// Sort orders the entries by key as raw strings, the order of repeated keys is kept
+func (o *OrderedDict) Sort() {
+	sort.SliceStable(o.Entries, func(i, j int) bool {
+		return o.Entries[i].Key < o.Entries[j].Key
+	})
+}
+
+// IsSorted checks if the keys are in the canonical order: sorted as raw strings without repetition
+func (o *OrderedDict) IsSorted() bool {
+	for index := 1; index < len(o.Entries); index++ {
+		if o.Entries[index-1].Key >= o.Entries[index].Key {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsCanonical checks if the dictionary and all its nested dictionaries are sorted, see IsSorted
+func (o *OrderedDict) IsCanonical() bool {
+	return IsCanonical(o)
+}
+
+// Map converts the dictionary to the map form, the nested dictionaries are converted too
+//
+// The last value of a repeated key is kept
+func (o *OrderedDict) Map() map[string]interface{} {
+	dictionary := make(map[string]interface{}, len(o.Entries))
+
+	for _, entry := range o.Entries {
+		dictionary[entry.Key] = ToMap(entry.Value)
+	}
+
+	return dictionary
+}
+
+// IsCanonical checks if all the *OrderedDict of a parsed element are sorted, the maps are always canonical
+func IsCanonical(element interface{}) bool {
+	switch element := element.(type) {
+	case *OrderedDict:
+		if !element.IsSorted() {
+			return false
+		}
+
+		for _, entry := range element.Entries {
+			if !IsCanonical(entry.Value) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, sub_element := range element {
+			if !IsCanonical(sub_element) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for _, sub_element := range element {
+			if !IsCanonical(sub_element) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ToMap converts all the *OrderedDict of a parsed element to the map form
+func ToMap(element interface{}) interface{} {
+	switch element := element.(type) {
+	case *OrderedDict:
+		return element.Map()
+	case []interface{}:
+		list := make([]interface{}, len(element))
+
+		for index, sub_element := range element {
+			list[index] = ToMap(sub_element)
+		}
+
+		return list
+	case map[string]interface{}:
+		dictionary := make(map[string]interface{}, len(element))
+
+		for key, sub_element := range element {
+			dictionary[key] = ToMap(sub_element)
+		}
+
+		return dictionary
+	}
+
+	return element
+}
+
+// ToOrdered converts all the maps of a parsed element to *OrderedDict with sorted keys
+func ToOrdered(element interface{}) interface{} {
+	switch element := element.(type) {
+	case map[string]interface{}:
+		return NewOrderedDict(element)
+	case []interface{}:
+		list := make([]interface{}, len(element))
+
+		for index, sub_element := range element {
+			list[index] = ToOrdered(sub_element)
+		}
+
+		return list
+	case *OrderedDict:
+		ordered_dictionary := &OrderedDict{
+			Entries: make([]DictionaryEntry, len(element.Entries)),
+		}
+
+		for index, entry := range element.Entries {
+			ordered_dictionary.Entries[index] = DictionaryEntry{Key: entry.Key, Value: ToOrdered(entry.Value)}
+		}
+
+		return ordered_dictionary
+	}
+
+	return element
+}
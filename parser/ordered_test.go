@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOrderedDictionary(t *testing.T) {
+	tests := []struct {
+		input     string
+		keys      []string
+		canonical bool
+	}{
+		{input: "de", keys: []string{}, canonical: true},
+		{input: "d1:ai1e1:bi2ee", keys: []string{"a", "b"}, canonical: true},
+		{input: "d1:bi2e1:ai1ee", keys: []string{"b", "a"}, canonical: false},
+		{input: "d1:ai1e1:ai2ee", keys: []string{"a", "a"}, canonical: false},
+		{input: "d1:ald1:zi0e1:yi0eeee", keys: []string{"a"}, canonical: false},
+	}
+
+	for index, test := range tests {
+		output, err := ParseElementWithOptions(bufio.NewReader(strings.NewReader(test.input)), Options{OrderedDictionaries: true})
+
+		if err != nil {
+			t.Errorf("test %d: failed to parse input [%s]: %v", index, test.input, err)
+			continue
+		}
+
+		dictionary, ok := output.(*OrderedDict)
+
+		if !ok {
+			t.Errorf("test %d: expected [*OrderedDict] | [%T] output", index, output)
+			continue
+		}
+
+		if keys := dictionary.Keys(); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("test %d: expected %v | %v output", index, test.keys, keys)
+		}
+
+		if dictionary.IsCanonical() != test.canonical {
+			t.Errorf("test %d: expected [%t] | [%t] output canonical", index, test.canonical, dictionary.IsCanonical())
+		}
+	}
+}
+
+func TestOrderedDictConversions(t *testing.T) {
+	dictionary := map[string]interface{}{
+		"b": 1,
+		"a": []interface{}{map[string]interface{}{"d": "x", "c": "y"}},
+	}
+
+	ordered := NewOrderedDict(dictionary)
+
+	if keys := ordered.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("expected [a b] | %v output", keys)
+	}
+
+	if !ordered.IsCanonical() {
+		t.Errorf("expected a canonical dictionary")
+	}
+
+	if output := ordered.Map(); !reflect.DeepEqual(output, dictionary) {
+		t.Errorf("expected %v | %v output", dictionary, output)
+	}
+
+	if output := ToMap(ToOrdered(dictionary)); !reflect.DeepEqual(output, dictionary) {
+		t.Errorf("expected %v | %v output", dictionary, output)
+	}
+}
+
+func TestOrderedDictEdition(t *testing.T) {
+	dictionary := &OrderedDict{}
+
+	dictionary.Set("z", 1)
+	dictionary.Set("a", 2)
+	dictionary.Set("z", 3)
+
+	if value, ok := dictionary.Get("z"); !ok || value != 3 || dictionary.Len() != 2 {
+		t.Errorf("expected [3] | [%v] output", value)
+	}
+
+	if dictionary.IsSorted() {
+		t.Errorf("expected an unsorted dictionary")
+	}
+
+	dictionary.Sort()
+
+	if keys := dictionary.Keys(); !reflect.DeepEqual(keys, []string{"a", "z"}) {
+		t.Errorf("expected [a z] | %v output", keys)
+	}
+
+	dictionary.Delete("a")
+
+	if _, ok := dictionary.Get("a"); ok || dictionary.Len() != 1 {
+		t.Errorf("expected [a] to be deleted")
+	}
+}
@@ -3,9 +3,12 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/trixky/gobencode/utils"
 )
@@ -17,6 +20,8 @@ const (
 	char_double_dot = ':'
 	char_end        = 'e'
 	char_negative   = '-'
+
+	max_preallocated_string = 1 << 16 // longer strings are read as they come, their length is not trusted
 )
 
 var (
@@ -29,25 +34,99 @@ var (
 	ErrorListElementCorrupted           = errors.New("list element corrupted")
 	ErrorDictionaryKeyCorrupted         = errors.New("dictionary key corrupted")
 	ErrorDictionaryElementCorrupted     = errors.New("dictionary element corrupted")
+	ErrorStringTooLong                  = errors.New("string longer than the input")
+	ErrorNotCanonical                   = errors.New("non canonical number")
+	ErrorNotADictionary                 = errors.New("not a dictionary")
+	ErrorKeyNotFound                    = errors.New("dictionary key not found")
+	ErrorUnsortedKey                    = errors.New("unsorted dictionary key")
 )
 
+// SyntaxError is the error of a parsing, located at the offset where it stopped
+//
+// It wraps the errors above, they can be checked with errors.Is
+type SyntaxError struct {
+	Offset    int  // bytes read from the start of the element
+	Truncated bool // the input ended before the element (a torn write, a partial message...)
+	Err       error
+}
+
+// Error returns the error with its offset
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the wrapped error
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Options changes the parsed representation of the elements
+//
+// The *OrderedDict keep every entry of a repeated key, except with DuplicateKeysFirstWins or DuplicateKeysError
+type Options struct {
+	OrderedDictionaries bool            // dictionaries are parsed as *OrderedDict instead of map[string]interface{}
+	DuplicateKeys       DuplicatePolicy // what to do with the repeated keys of a dictionary
+	Strict              bool            // repeated keys in the info dictionary are an error whatever the policy
+	CanonicalNumbers    bool            // the integers and string lengths with a leading zero, a sign or -0 are errors
+	SortedKeys          bool            // the keys of a dictionary out of the raw string order are an error
+	SyntaxErrors        bool            // the errors are *SyntaxError with their offset instead of the errors above
+}
+
+// decoder parses elements from a reader with some options
+type decoder struct {
+	reader     *bufio.Reader
+	options    Options
+	offset     int           // bytes read from the start of the element
+	path       []interface{} // keys and indexes of the element being parsed
+	report     Report
+	duplicates map[int]int // index in report.Duplicates of the repeated keys, by offset of their first occurrence
+	bounded    bool        // the input length is known (ParseBytes)
+	limit      int         // the input length when bounded
+	truncated  bool        // the input ended before the element
+}
+
+// ended records a read error caused by the end of the input
+func (d *decoder) ended(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		d.truncated = true
+	}
+
+	return err
+}
+
 // parseBytes parses a byte array in the bencode format from a reader
-func parseBytes(bufioReader *bufio.Reader, b byte) (element interface{}, err error) {
+func (d *decoder) parseBytes(b byte) (element interface{}, err error) {
 	len, _ := utils.ByteToInteger(b)
+	leading_zero := b == '0'
+	length_offset := d.offset - 1 // offset of the first digit
 
 	for {
-		b, err = bufioReader.ReadByte()
+		b, err = d.reader.ReadByte()
 
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByteContent, err)
+			return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByteContent, d.ended(err))
 		}
 
+		d.offset++
+
 		if b == char_double_dot {
-			str, err := utils.ReadNBytes(bufioReader, len)
+			if d.options.CanonicalNumbers && leading_zero && d.offset-length_offset > 2 {
+				return nil, fmt.Errorf("%w: string length with a leading zero", ErrorNotCanonical)
+			}
+
+			if d.bounded && len > d.limit-d.offset {
+				d.truncated = true
+				return nil, fmt.Errorf("%w: %d bytes", ErrorStringTooLong, len)
+			}
+
+			str, err := d.readString(len)
+
 			if err != nil {
-				return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByteContent, err)
+				return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByteContent, d.ended(err))
 			}
 
+			d.offset += len
+
 			return str, nil
 		} else {
 			integer, ok := utils.ByteToInteger(b)
@@ -56,21 +135,63 @@ func parseBytes(bufioReader *bufio.Reader, b byte) (element interface{}, err err
 				return nil, fmt.Errorf("%w: [%c]", ErrorInvalidStringLengthCharacter, b)
 			}
 
+			// the overflows are rejected, the lengths of ParseBytes as soon as they exceed the input
+			if len > (int(^uint(0)>>1)-integer)/10 || d.bounded && len*10+integer > d.limit-d.offset {
+				d.truncated = d.bounded
+				return nil, fmt.Errorf("%w: length starting with [%d%d]", ErrorStringTooLong, len, integer)
+			}
+
 			len *= 10
 			len += integer
 		}
 	}
 }
 
+// readString reads a string of some bytes, the long ones are read without allocating their announced length
+func (d *decoder) readString(length int) (string, error) {
+	if length <= max_preallocated_string || d.bounded {
+		return utils.ReadNBytes(d.reader, length)
+	}
+
+	builder := strings.Builder{}
+
+	if _, err := io.CopyN(&builder, d.reader, int64(length)); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// isCanonicalInteger checks that an integer has no sign, no leading zero and is not -0
+func isCanonicalInteger(integer string) bool {
+	digits := strings.TrimPrefix(integer, string(char_negative))
+
+	if len(digits) == 0 || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+
+	if digits[0] == '0' {
+		return integer == "0"
+	}
+
+	return true
+}
+
 // parseInteger parses an integer in the bencode format from a reader
-func parseInteger(bufioReader *bufio.Reader) (element interface{}, err error) {
-	buffer, err := bufioReader.ReadBytes(char_end)
+func (d *decoder) parseInteger() (element interface{}, err error) {
+	buffer, err := d.reader.ReadBytes(char_end)
+	d.offset += len(buffer)
 
 	if err != nil {
-		return nil, err
+		return nil, d.ended(err)
 	}
 
 	buffer_str := string(buffer)[:len(buffer)-1]
+
+	if d.options.CanonicalNumbers && !isCanonicalInteger(buffer_str) {
+		return nil, fmt.Errorf("%w: [%s]", ErrorNotCanonical, buffer_str)
+	}
+
 	integer, err := strconv.Atoi(buffer_str)
 
 	if err != nil {
@@ -81,16 +202,21 @@ func parseInteger(bufioReader *bufio.Reader) (element interface{}, err error) {
 }
 
 // parseList parses a list in the bencode format from a reader
-func parseList(bufioReader *bufio.Reader) (element interface{}, err error) {
+func (d *decoder) parseList() (element interface{}, err error) {
 	list := make([]interface{}, 0)
 
-	for {
-		element, err := ParseElement(bufioReader)
+	for index := 0; ; index++ {
+		d.path = append(d.path, index)
+		element, err := d.parseElement()
+		d.path = d.path[:len(d.path)-1]
 
 		if err != nil {
 			if err == ErrorEnd {
 				break
 			}
+			if errors.Is(err, ErrorDuplicateKey) || errors.Is(err, ErrorUnsortedKey) {
+				return nil, err
+			}
 			return nil, fmt.Errorf("%w: %v", ErrorListElementCorrupted, err)
 		}
 
@@ -101,11 +227,23 @@ func parseList(bufioReader *bufio.Reader) (element interface{}, err error) {
 }
 
 // parseDictionary parses a dictionary in the bencode format from a reader
-func parseDictionary(bufioReader *bufio.Reader) (element interface{}, err error) {
-	dictionary := make(map[string]interface{})
+func (d *decoder) parseDictionary() (element interface{}, err error) {
+	var dictionary map[string]interface{}
+	var ordered_dictionary *OrderedDict
+
+	// only the form returned is allocated
+	if d.options.OrderedDictionaries {
+		ordered_dictionary = &OrderedDict{}
+	} else {
+		dictionary = make(map[string]interface{})
+	}
+
+	key_offsets := make(map[string]int) // offset of the first occurrence of each key
+	previous_key := ""
 
 	for {
-		key, err := ParseElement(bufioReader)
+		key_offset := d.offset
+		key, err := d.parseElement()
 
 		if err != nil {
 			if err == ErrorEnd {
@@ -120,41 +258,187 @@ func parseDictionary(bufioReader *bufio.Reader) (element interface{}, err error)
 			return nil, fmt.Errorf("%w: bad type [%T], (expected string)", ErrorDictionaryKeyCorrupted, key)
 		}
 
-		element, err := ParseElement(bufioReader)
+		// an equal key is a repeated key, see the duplicate policy
+		if d.options.SortedKeys && len(key_offsets) > 0 && string_key < previous_key {
+			return nil, fmt.Errorf("%w [%s] after [%s] in %s at offset %d", ErrorUnsortedKey, string_key, previous_key, FormatPath(d.path), key_offset)
+		}
+
+		previous_key = string_key
+
+		d.path = append(d.path, string_key)
+		element, err := d.parseElement()
+		d.path = d.path[:len(d.path)-1]
 
 		if err != nil {
 			if err == ErrorEnd {
 				return nil, err
 			}
+			if errors.Is(err, ErrorDuplicateKey) || errors.Is(err, ErrorUnsortedKey) {
+				return nil, err
+			}
 			return nil, fmt.Errorf("%w: %v", ErrorDictionaryElementCorrupted, err)
 		}
 
-		dictionary[string_key] = element
+		first_offset, repeated := key_offsets[string_key]
+
+		if !repeated {
+			key_offsets[string_key] = key_offset
+		} else if err := d.duplicate(string_key, first_offset, key_offset); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case repeated && d.options.DuplicateKeys == DuplicateKeysFirstWins:
+		case d.options.OrderedDictionaries:
+			ordered_dictionary.Entries = append(ordered_dictionary.Entries, DictionaryEntry{Key: string_key, Value: element})
+		case repeated && d.options.DuplicateKeys == DuplicateKeysCollectAll:
+			if values, ok := dictionary[string_key].(DuplicateValues); ok {
+				dictionary[string_key] = append(values, element)
+			} else {
+				dictionary[string_key] = DuplicateValues{dictionary[string_key], element}
+			}
+		default:
+			dictionary[string_key] = element
+		}
+	}
+
+	if d.options.OrderedDictionaries {
+		return ordered_dictionary, nil
 	}
 
 	return dictionary, nil
 }
 
-// ParseElement parses any type of element in the bencode format from a reader
-func ParseElement(bufioReader *bufio.Reader) (element interface{}, err error) {
-	b, err := bufioReader.ReadByte()
+// parseElement parses any type of element in the bencode format from a reader
+func (d *decoder) parseElement() (element interface{}, err error) {
+	b, err := d.reader.ReadByte()
 
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByte, err)
+		return nil, fmt.Errorf("%w: %v", ErrorFailedToReadByte, d.ended(err))
 	}
 
+	d.offset++
+
 	switch {
 	case b >= '0' && b <= '9': // bytes
-		return parseBytes(bufioReader, b)
+		return d.parseBytes(b)
 	case b == char_integer: // integer
-		return parseInteger(bufioReader)
+		return d.parseInteger()
 	case b == char_list: // list
-		return parseList(bufioReader)
+		return d.parseList()
 	case b == char_dictionary: // dict
-		return parseDictionary(bufioReader)
+		return d.parseDictionary()
 	case b == char_end: // end
 		return nil, ErrorEnd
 	default:
 		return nil, fmt.Errorf("%w: [%c]", ErrorInvalidCharacterToStartElement, b)
 	}
 }
+
+// ParseElement parses any type of element in the bencode format from a reader
+func ParseElement(bufioReader *bufio.Reader) (element interface{}, err error) {
+	return ParseElementWithOptions(bufioReader, Options{})
+}
+
+// ParseElementWithOptions parses any type of element in the bencode format from a reader with some options
+func ParseElementWithOptions(bufioReader *bufio.Reader, options Options) (element interface{}, err error) {
+	element, _, err = ParseElementWithReport(bufioReader, options)
+
+	return
+}
+
+// ParseElementWithReport parses any type of element in the bencode format from a reader with some options,
+// it also reports the anomalies found (repeated keys...)
+//
+// The errors are *SyntaxError with Options.SyntaxErrors
+func ParseElementWithReport(bufioReader *bufio.Reader, options Options) (element interface{}, report Report, err error) {
+	d := decoder{
+		reader:  bufioReader,
+		options: options,
+	}
+
+	element, err = d.parseElement()
+
+	if err != nil {
+		if options.SyntaxErrors {
+			err = &SyntaxError{Offset: d.offset, Truncated: d.truncated, Err: err}
+		}
+
+		return nil, d.report, err
+	}
+
+	return element, d.report, nil
+}
+
+// ParseBytes parses the first element of data with some options and returns the number of bytes read
+//
+// The string lengths are checked against the remaining bytes before any allocation,
+// it is the parser of the untrusted inputs (network messages). The errors are *SyntaxError
+func ParseBytes(data []byte, options Options) (element interface{}, n int, err error) {
+	d := decoder{
+		reader:  bufio.NewReader(bytes.NewReader(data)),
+		options: options,
+		bounded: true,
+		limit:   len(data),
+	}
+
+	element, err = d.parseElement()
+
+	if err != nil {
+		return nil, d.offset, &SyntaxError{Offset: d.offset, Truncated: d.truncated, Err: err}
+	}
+
+	return element, d.offset, nil
+}
+
+// RawValue returns the value of a key of a bencoded dictionary as it is written, the last one if the key is repeated
+//
+// The info dictionary of a torrent is hashed as it is written, its raw value is hashed without encoding it again
+func RawValue(data []byte, key string) ([]byte, error) {
+	d := decoder{
+		reader:  bufio.NewReader(bytes.NewReader(data)),
+		bounded: true,
+		limit:   len(data),
+	}
+
+	if len(data) == 0 || data[0] != char_dictionary {
+		return nil, &SyntaxError{Offset: 0, Truncated: len(data) == 0, Err: ErrorNotADictionary}
+	}
+
+	d.reader.ReadByte()
+	d.offset++
+
+	var raw []byte
+
+	for {
+		element, err := d.parseElement()
+
+		if err == ErrorEnd {
+			break
+		} else if err != nil {
+			return nil, &SyntaxError{Offset: d.offset, Truncated: d.truncated, Err: fmt.Errorf("%w: %v", ErrorDictionaryKeyCorrupted, err)}
+		}
+
+		element_key, ok := element.(string)
+
+		if !ok {
+			return nil, &SyntaxError{Offset: d.offset, Err: fmt.Errorf("%w: bad type [%T], (expected string)", ErrorDictionaryKeyCorrupted, element)}
+		}
+
+		value_offset := d.offset
+
+		if _, err := d.parseElement(); err != nil {
+			return nil, &SyntaxError{Offset: d.offset, Truncated: d.truncated, Err: fmt.Errorf("%w: %v", ErrorDictionaryElementCorrupted, err)}
+		}
+
+		if element_key == key {
+			raw = data[value_offset:d.offset]
+		}
+	}
+
+	if raw == nil {
+		return nil, fmt.Errorf("%w [%s]", ErrorKeyNotFound, key)
+	}
+
+	return raw, nil
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -22,7 +23,7 @@ func TestParseBytes(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		output, err := parseBytes(bufio.NewReader(strings.NewReader(test.input[1:])), byte(test.input[0]))
+		output, err := (&decoder{reader: bufio.NewReader(strings.NewReader(test.input[1:]))}).parseBytes(byte(test.input[0]))
 
 		if err != nil {
 			t.Errorf("failed to parse input [%s]: %v\n", test.input, err)
@@ -57,7 +58,7 @@ func TestParseInteger(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		output, err := parseInteger(bufio.NewReader(strings.NewReader(test.input[1:])))
+		output, err := (&decoder{reader: bufio.NewReader(strings.NewReader(test.input[1:]))}).parseInteger()
 
 		if err != nil {
 			t.Errorf("failed to parse input [%s]: %v\n", test.input, err)
@@ -103,7 +104,7 @@ func TestParseList(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		output, err := parseList(bufio.NewReader(strings.NewReader(test.input[1:])))
+		output, err := (&decoder{reader: bufio.NewReader(strings.NewReader(test.input[1:]))}).parseList()
 
 		if err != nil {
 			t.Errorf("failed to parse input [%s]: %v\n", test.input, err)
@@ -151,7 +152,7 @@ func TestParseDictionaryionary(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		output, err := parseDictionary(bufio.NewReader(strings.NewReader(test.input[1:])))
+		output, err := (&decoder{reader: bufio.NewReader(strings.NewReader(test.input[1:]))}).parseDictionary()
 
 		if err != nil {
 			t.Errorf("failed to parse input [%s]: %v\n", test.input, err)
@@ -196,3 +197,162 @@ func TestParseElementBasicTorrent(t *testing.T) {
 		}
 	}
 }
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		input    string
+		offset   int
+		expected error
+	}{
+		{input: "", offset: 0, expected: ErrorFailedToReadByte},
+		{input: "e", offset: 1, expected: ErrorEnd},
+		{input: "x", offset: 1, expected: ErrorInvalidCharacterToStartElement},
+		{input: "d3:key", offset: 6, expected: ErrorDictionaryElementCorrupted},
+		{input: "li1ei2x", offset: 7, expected: ErrorListElementCorrupted},
+		{input: "d1:ai1e1:ai2ee", offset: 13, expected: ErrorDuplicateKey},
+	}
+
+	for index, test := range tests {
+		_, err := ParseElementWithOptions(bufio.NewReader(strings.NewReader(test.input)), Options{DuplicateKeys: DuplicateKeysError, SyntaxErrors: true})
+
+		syntax_error, ok := err.(*SyntaxError)
+
+		if !ok {
+			t.Errorf("test %d: expected [*SyntaxError] | [%T] output", index, err)
+			continue
+		}
+
+		if syntax_error.Offset != test.offset {
+			t.Errorf("test %d: expected [%d] | [%d] output offset", index, test.offset, syntax_error.Offset)
+		}
+
+		if test.expected != nil && !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+
+	// the errors are not wrapped by default
+	if _, err := ParseElement(bufio.NewReader(strings.NewReader("e"))); err != ErrorEnd {
+		t.Errorf("expected [%v] | [%v] output", ErrorEnd, err)
+	}
+}
+
+func TestParseBytesUntrusted(t *testing.T) {
+	tests := []struct {
+		input     string
+		canonical bool
+		expected  error // nil if the input is parsed
+		n         int
+	}{
+		{input: "4:spam", n: 6},
+		{input: "4:spami1e", n: 6},
+		{input: "li-3e0:e", canonical: true, n: 8},
+		{input: "9000000000:", expected: ErrorStringTooLong},
+		{input: "99999999999999999999:", expected: ErrorStringTooLong},
+		{input: "d1:t9000000000:e", expected: ErrorDictionaryElementCorrupted},
+		{input: "5:spam", expected: ErrorStringTooLong},
+		{input: "04:spam", n: 7},
+		{input: "04:spam", canonical: true, expected: ErrorNotCanonical},
+		{input: "i03e", canonical: true, expected: ErrorNotCanonical},
+		{input: "i-0e", canonical: true, expected: ErrorNotCanonical},
+		{input: "i+1e", canonical: true, expected: ErrorNotCanonical},
+		{input: "0:", canonical: true, n: 2},
+		{input: "i0e", canonical: true, n: 3},
+	}
+
+	for index, test := range tests {
+		_, n, err := ParseBytes([]byte(test.input), Options{CanonicalNumbers: test.canonical})
+
+		if !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+			continue
+		}
+
+		if err == nil && n != test.n {
+			t.Errorf("test %d: expected [%d] | [%d] output", index, test.n, n)
+		}
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		expected string
+		err      error
+	}{
+		{input: "d4:infod1:bi1e1:ai02eee", key: "info", expected: "d1:bi1e1:ai02ee"},
+		{input: "d1:a3:abc4:infoi1e4:infoi2ee", key: "info", expected: "i2e"},
+		{input: "d1:a3:abce", key: "info", err: ErrorKeyNotFound},
+		{input: "l4:infoe", key: "info", err: ErrorNotADictionary},
+		{input: "", key: "info", err: ErrorNotADictionary},
+		{input: "d4:infod1:a", key: "info", err: ErrorDictionaryElementCorrupted},
+		{input: "di1e1:ae", key: "info", err: ErrorDictionaryKeyCorrupted},
+	}
+
+	for index, test := range tests {
+		output, err := RawValue([]byte(test.input), test.key)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+			continue
+		}
+
+		if string(output) != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestSyntaxErrorTruncated(t *testing.T) {
+	tests := []struct {
+		input     string
+		truncated bool
+	}{
+		{input: "", truncated: true},
+		{input: "d1:a", truncated: true},
+		{input: "d1:ai12", truncated: true},
+		{input: "d1:a5:ab", truncated: true},
+		{input: "l4", truncated: true},
+		{input: "d1:ai1e", truncated: true},
+		{input: "x", truncated: false},
+		{input: "d1:aixee", truncated: false},
+		{input: "li1e:e", truncated: false},
+	}
+
+	for index, test := range tests {
+		_, _, err := ParseBytes([]byte(test.input), Options{})
+
+		syntax_error, ok := err.(*SyntaxError)
+
+		if !ok {
+			t.Errorf("test %d: expected [*SyntaxError] | [%T] output", index, err)
+			continue
+		}
+
+		if syntax_error.Truncated != test.truncated {
+			t.Errorf("test %d: expected [%t] | [%t] output", index, test.truncated, syntax_error.Truncated)
+		}
+	}
+}
+
+func TestParseSortedKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		sorted   bool
+		expected error
+	}{
+		{input: "d1:ai1e1:bi2ee", sorted: true},
+		{input: "d1:bi2e1:ai1ee"},
+		{input: "d1:bi2e1:ai1ee", sorted: true, expected: ErrorUnsortedKey},
+		{input: "d1:ad1:yi1e1:xi2eee", sorted: true, expected: ErrorUnsortedKey},
+		{input: "ld1:bi2e1:ai1eee", sorted: true, expected: ErrorUnsortedKey},
+		{input: "d1:ai1e1:ai2ee", sorted: true}, // a repeated key follows the duplicate policy
+	}
+
+	for index, test := range tests {
+		if _, _, err := ParseBytes([]byte(test.input), Options{SortedKeys: test.sorted}); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+}
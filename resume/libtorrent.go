@@ -0,0 +1,371 @@
+package resume
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/tracker"
+	"github.com/trixky/gobencode/utils"
+)
+
+const (
+	LibtorrentKeyFileFormat      = "file-format"
+	LibtorrentKeyFileVersion     = "file-version"
+	LibtorrentKeyInfoHash        = "info-hash"
+	LibtorrentKeyName            = "name"
+	LibtorrentKeySavePath        = "save_path"
+	LibtorrentKeyPieces          = "pieces"
+	LibtorrentKeyFilePriority    = "file_priority"
+	LibtorrentKeyPiecePriority   = "piece_priority"
+	LibtorrentKeyTrackers        = "trackers"
+	LibtorrentKeyUrlList         = "url-list"
+	LibtorrentKeyMappedFiles     = "mapped_files"
+	LibtorrentKeyPeers           = "peers"
+	LibtorrentKeyPeers6          = "peers6"
+	LibtorrentKeyBannedPeers     = "banned_peers"
+	LibtorrentKeyBannedPeers6    = "banned_peers6"
+	LibtorrentKeyTotalUploaded   = "total_uploaded"
+	LibtorrentKeyTotalDownloaded = "total_downloaded"
+	LibtorrentKeyActiveTime      = "active_time"
+	LibtorrentKeySeedingTime     = "seeding_time"
+	LibtorrentKeyFinishedTime    = "finished_time"
+	LibtorrentKeyAddedTime       = "added_time"
+	LibtorrentKeyCompletedTime   = "completed_time"
+	LibtorrentKeyPaused          = "paused"
+	LibtorrentKeyAutoManaged     = "auto_managed"
+	LibtorrentKeySeedMode        = "seed_mode"
+	LibtorrentKeyInfo            = "info"
+	LibtorrentKeyQBtTags         = "qBt-tags"
+
+	LibtorrentFileFormat  = "libtorrent resume file"
+	LibtorrentFileVersion = 1
+
+	libtorrent_piece_have = 0x01 // lowest bit of each byte of the pieces string
+)
+
+// LibtorrentResume is the resume data written by libtorrent (.fastresume files of qBittorrent, Deluge...)
+//
+// https://www.libtorrent.org/manual-ref.html#fast-resume
+type LibtorrentResume struct {
+	InfoHash        [20]byte
+	Name            string
+	SavePath        string
+	Pieces          []bool // completed pieces
+	FilePriority    []int
+	PiecePriority   []int
+	Trackers        [][]string
+	UrlList         []string
+	MappedFiles     []string // renamed files, empty for the files that kept their name
+	Peers           []tracker.Peer
+	BannedPeers     []tracker.Peer
+	TotalUploaded   int
+	TotalDownloaded int
+	ActiveTime      int
+	SeedingTime     int
+	FinishedTime    int
+	AddedTime       int
+	CompletedTime   int
+	Paused          bool
+	AutoManaged     bool
+	SeedMode        bool
+	Info            map[string]interface{} // embedded info dictionary, if any
+
+	// Extra holds the keys unknown to this structure (qBt-category, qBt-tags, unfinished...),
+	// they are written back untouched
+	Extra map[string]interface{}
+}
+
+// peers reads an optional pair of compact IPv4 and IPv6 peer lists
+func (f fields) peers(key4 string, key6 string, value *[]tracker.Peer) error {
+	keys := []struct {
+		key         string
+		peer_length int
+	}{
+		{key4, tracker.CompactPeerLengthIPv4},
+		{key6, tracker.CompactPeerLengthIPv6},
+	}
+
+	for _, key := range keys {
+		compact_peers := ""
+
+		if err := f.string(key.key, &compact_peers); err != nil {
+			return err
+		}
+
+		peers, err := tracker.DecodeCompactPeers(compact_peers, key.peer_length)
+
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, key.key, err)
+		}
+
+		*value = append(*value, peers...)
+	}
+
+	return nil
+}
+
+// ReadLibtorrent reads a libtorrent resume file
+func ReadLibtorrent(reader io.Reader) (*LibtorrentResume, error) {
+	dictionary, err := readDictionary(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := fields(copyDictionary(dictionary))
+	r := &LibtorrentResume{}
+
+	file_format := ""
+
+	if err := f.string(LibtorrentKeyFileFormat, &file_format); err != nil {
+		return nil, err
+	}
+
+	if file_format != LibtorrentFileFormat {
+		return nil, fmt.Errorf("%w: bad %s [%s]", ErrorResumeCorrupted, LibtorrentKeyFileFormat, file_format)
+	}
+
+	file_version := 0
+	pieces := ""
+	piece_priority := ""
+	trackers := []interface{}{}
+	url_list := []interface{}{}
+	mapped_files := []interface{}{}
+
+	steps := []error{
+		f.integer(LibtorrentKeyFileVersion, &file_version),
+		f.hash(LibtorrentKeyInfoHash, &r.InfoHash),
+		f.string(LibtorrentKeyName, &r.Name),
+		f.string(LibtorrentKeySavePath, &r.SavePath),
+		f.string(LibtorrentKeyPieces, &pieces),
+		f.integerList(LibtorrentKeyFilePriority, &r.FilePriority),
+		f.string(LibtorrentKeyPiecePriority, &piece_priority),
+		f.list(LibtorrentKeyTrackers, &trackers),
+		f.list(LibtorrentKeyUrlList, &url_list),
+		f.list(LibtorrentKeyMappedFiles, &mapped_files),
+		f.peers(LibtorrentKeyPeers, LibtorrentKeyPeers6, &r.Peers),
+		f.peers(LibtorrentKeyBannedPeers, LibtorrentKeyBannedPeers6, &r.BannedPeers),
+		f.integer(LibtorrentKeyTotalUploaded, &r.TotalUploaded),
+		f.integer(LibtorrentKeyTotalDownloaded, &r.TotalDownloaded),
+		f.integer(LibtorrentKeyActiveTime, &r.ActiveTime),
+		f.integer(LibtorrentKeySeedingTime, &r.SeedingTime),
+		f.integer(LibtorrentKeyFinishedTime, &r.FinishedTime),
+		f.integer(LibtorrentKeyAddedTime, &r.AddedTime),
+		f.integer(LibtorrentKeyCompletedTime, &r.CompletedTime),
+		f.boolean(LibtorrentKeyPaused, &r.Paused),
+		f.boolean(LibtorrentKeyAutoManaged, &r.AutoManaged),
+		f.boolean(LibtorrentKeySeedMode, &r.SeedMode),
+		f.dictionary(LibtorrentKeyInfo, &r.Info),
+	}
+
+	for _, err := range steps {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.Pieces = make([]bool, len(pieces))
+
+	for index := range pieces {
+		r.Pieces[index] = pieces[index]&libtorrent_piece_have != 0
+	}
+
+	for index := range piece_priority {
+		r.PiecePriority = append(r.PiecePriority, int(piece_priority[index]))
+	}
+
+	if r.Trackers, err = utils.ToListOfStringList(trackers); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, LibtorrentKeyTrackers, err)
+	}
+	if r.UrlList, err = utils.ToStringList(url_list); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, LibtorrentKeyUrlList, err)
+	}
+	if r.MappedFiles, err = utils.ToStringList(mapped_files); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, LibtorrentKeyMappedFiles, err)
+	}
+
+	r.Extra = f.rest()
+
+	return r, nil
+}
+
+// dictionary returns the resume data as a bencode dictionary
+func (r *LibtorrentResume) dictionary() map[string]interface{} {
+	dictionary := copyDictionary(r.Extra)
+
+	pieces := make([]byte, len(r.Pieces))
+
+	for index, complete := range r.Pieces {
+		if complete {
+			pieces[index] = libtorrent_piece_have
+		}
+	}
+
+	peers4, peers6 := tracker.EncodeCompactPeers(r.Peers)
+	banned_peers4, banned_peers6 := tracker.EncodeCompactPeers(r.BannedPeers)
+
+	values := map[string]interface{}{
+		LibtorrentKeyFileFormat:      LibtorrentFileFormat,
+		LibtorrentKeyFileVersion:     LibtorrentFileVersion,
+		LibtorrentKeyInfoHash:        string(r.InfoHash[:]),
+		LibtorrentKeyName:            r.Name,
+		LibtorrentKeySavePath:        r.SavePath,
+		LibtorrentKeyPieces:          string(pieces),
+		LibtorrentKeyFilePriority:    toIntegerList(r.FilePriority),
+		LibtorrentKeyTrackers:        toTiers(r.Trackers),
+		LibtorrentKeyUrlList:         toInterfaceList(r.UrlList),
+		LibtorrentKeyPeers:           peers4,
+		LibtorrentKeyPeers6:          peers6,
+		LibtorrentKeyBannedPeers:     banned_peers4,
+		LibtorrentKeyBannedPeers6:    banned_peers6,
+		LibtorrentKeyTotalUploaded:   r.TotalUploaded,
+		LibtorrentKeyTotalDownloaded: r.TotalDownloaded,
+		LibtorrentKeyActiveTime:      r.ActiveTime,
+		LibtorrentKeySeedingTime:     r.SeedingTime,
+		LibtorrentKeyFinishedTime:    r.FinishedTime,
+		LibtorrentKeyAddedTime:       r.AddedTime,
+		LibtorrentKeyCompletedTime:   r.CompletedTime,
+		LibtorrentKeyPaused:          fromBoolean(r.Paused),
+		LibtorrentKeyAutoManaged:     fromBoolean(r.AutoManaged),
+		LibtorrentKeySeedMode:        fromBoolean(r.SeedMode),
+	}
+
+	for key, value := range values {
+		dictionary[key] = value
+	}
+
+	if len(r.PiecePriority) > 0 {
+		piece_priority := make([]byte, len(r.PiecePriority))
+
+		for index, priority := range r.PiecePriority {
+			piece_priority[index] = byte(priority)
+		}
+
+		dictionary[LibtorrentKeyPiecePriority] = string(piece_priority)
+	}
+	if len(r.MappedFiles) > 0 {
+		dictionary[LibtorrentKeyMappedFiles] = toInterfaceList(r.MappedFiles)
+	}
+	if r.Info != nil {
+		dictionary[LibtorrentKeyInfo] = r.Info
+	}
+
+	return dictionary
+}
+
+// Write writes the resume data in the libtorrent format
+func (r *LibtorrentResume) Write(writer io.Writer) error {
+	return writeDictionary(writer, r.dictionary())
+}
+
+// Join checks that the resume data belongs to a torrent and reports its completed pieces
+//
+// In seed mode, libtorrent considers every piece complete without checking them
+func (r *LibtorrentResume) Join(bc *bencode.Bencode) (Completion, error) {
+	pieces := r.Pieces
+
+	if r.SeedMode {
+		pieces = allSet(len(bc.Info.Pieces), true)
+	}
+
+	return newCompletion(bc, r.InfoHash, pieces)
+}
+
+// State converts the resume data, the labels are the qBittorrent tags
+func (r *LibtorrentResume) State(bc *bencode.Bencode) (ResumeState, error) {
+	completion, err := r.Join(bc)
+
+	if err != nil {
+		return ResumeState{}, err
+	}
+
+	if err := checkFiles(bc, len(r.FilePriority)); err != nil {
+		return ResumeState{}, err
+	}
+
+	state := ResumeState{
+		InfoHash:       r.InfoHash,
+		Name:           r.Name,
+		SavePath:       r.SavePath,
+		Pieces:         append([]bool{}, completion.Pieces...),
+		FilePriorities: append([]int{}, r.FilePriority...),
+		Trackers:       r.Trackers,
+		Uploaded:       r.TotalUploaded,
+		Downloaded:     r.TotalDownloaded,
+		AddedTime:      r.AddedTime,
+		CompletedTime:  r.CompletedTime,
+		Paused:         r.Paused,
+	}
+
+	if len(state.Name) == 0 {
+		state.Name = bc.Info.DirectoryName
+	}
+
+	if len(state.Trackers) == 0 {
+		state.Trackers = torrentTrackers(bc)
+	}
+
+	if tags, ok := r.Extra[LibtorrentKeyQBtTags]; ok {
+		if state.Labels, err = utils.ToStringList(tags); err != nil {
+			return ResumeState{}, fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, LibtorrentKeyQBtTags, err)
+		}
+	}
+
+	return state, nil
+}
+
+// LibtorrentFromState converts a resume state to the libtorrent format
+func LibtorrentFromState(state ResumeState) *LibtorrentResume {
+	r := &LibtorrentResume{
+		InfoHash:        state.InfoHash,
+		Name:            state.Name,
+		SavePath:        state.SavePath,
+		Pieces:          append([]bool{}, state.Pieces...),
+		FilePriority:    append([]int{}, state.FilePriorities...),
+		Trackers:        state.Trackers,
+		TotalUploaded:   state.Uploaded,
+		TotalDownloaded: state.Downloaded,
+		AddedTime:       state.AddedTime,
+		CompletedTime:   state.CompletedTime,
+		Paused:          state.Paused,
+	}
+
+	if len(state.Labels) > 0 {
+		r.Extra = map[string]interface{}{
+			LibtorrentKeyQBtTags: toInterfaceList(state.Labels),
+		}
+	}
+
+	return r
+}
+
+// JoinLibtorrent joins resume files to their torrents by info hash
+//
+// the resume files without torrent are returned separately
+func JoinLibtorrent(resumes []*LibtorrentResume, torrents []*bencode.Bencode) (completions []Completion, orphans []*LibtorrentResume, err error) {
+	by_info_hash := map[[20]byte]*bencode.Bencode{}
+
+	for _, bc := range torrents {
+		by_info_hash[bc.InfoHash] = bc
+	}
+
+	for _, r := range resumes {
+		bc, ok := by_info_hash[r.InfoHash]
+
+		if !ok {
+			orphans = append(orphans, r)
+			continue
+		}
+
+		completion, err := r.Join(bc)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%x: %w", r.InfoHash, err)
+		}
+
+		completions = append(completions, completion)
+	}
+
+	return completions, orphans, nil
+}
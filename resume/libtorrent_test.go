@@ -0,0 +1,186 @@
+package resume
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/tracker"
+)
+
+func TestLibtorrentRoundTrip(t *testing.T) {
+	tests := []LibtorrentResume{
+		{
+			InfoHash:        [20]byte{1, 2, 3},
+			Name:            "debian.iso",
+			SavePath:        "/downloads",
+			Pieces:          []bool{true, false, true, true},
+			FilePriority:    []int{4},
+			PiecePriority:   []int{1, 0, 7, 4},
+			Trackers:        [][]string{{"udp://tracker.example.org:6969"}, {"http://backup.example.org/announce"}},
+			UrlList:         []string{"http://mirror.example.org/"},
+			MappedFiles:     []string{"renamed.iso"},
+			Peers:           []tracker.Peer{{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 6881}, {IP: net.ParseIP("2001:db8::1"), Port: 51413}},
+			BannedPeers:     []tracker.Peer{{IP: net.IPv4(10, 0, 0, 2).To4(), Port: 1}},
+			TotalUploaded:   1000,
+			TotalDownloaded: 2000,
+			ActiveTime:      30,
+			SeedingTime:     10,
+			FinishedTime:    10,
+			AddedTime:       1600000000,
+			CompletedTime:   1600000020,
+			Paused:          true,
+			AutoManaged:     true,
+			Info:            map[string]interface{}{"name": "debian.iso"},
+			Extra:           map[string]interface{}{"qBt-category": "linux", "qBt-tags": []interface{}{"iso"}},
+		},
+		{
+			InfoHash:     [20]byte{4, 5, 6},
+			Pieces:       []bool{},
+			FilePriority: []int{1, 0},
+			SeedMode:     true,
+		},
+	}
+
+	for index, test := range tests {
+		buffer := bytes.Buffer{}
+
+		if err := test.Write(&buffer); err != nil {
+			t.Errorf("test %d: failed to write: %v", index, err)
+			continue
+		}
+
+		encoded := buffer.String()
+		r, err := ReadLibtorrent(&buffer)
+
+		if err != nil {
+			t.Errorf("test %d: failed to read: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(*r, test) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, test, *r)
+		}
+
+		rewritten := bytes.Buffer{}
+
+		if err := r.Write(&rewritten); err != nil {
+			t.Errorf("test %d: failed to rewrite: %v", index, err)
+		} else if rewritten.String() != encoded {
+			t.Errorf("test %d: expected [%s] | [%s] rewritten output", index, encoded, rewritten.String())
+		}
+	}
+}
+
+func TestReadLibtorrentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"i42e", ErrorResumeCorrupted},
+		{"d4:name5:debiane", ErrorResumeCorrupted},
+		{"d11:file-format22:libtorrent resume file9:info-hash3:abce", ErrorResumeCorrupted},
+		{"d11:file-format22:libtorrent resume file6:piecesi1ee", ErrorBadType},
+		{"d11:file-format22:libtorrent resume file5:peers5:abcdee", ErrorResumeCorrupted},
+		{"d11:file-format22:libtorrent resume file8:trackersl3:abcee", ErrorResumeCorrupted},
+	}
+
+	for index, test := range tests {
+		if _, err := ReadLibtorrent(strings.NewReader(test.input)); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, test.expected, err)
+		}
+	}
+}
+
+func TestLibtorrentJoin(t *testing.T) {
+	bc := loadTorrent(t, "../.test_files/minecraft.torrent")
+	piece_count := len(bc.Info.Pieces)
+
+	tests := []struct {
+		resume   LibtorrentResume
+		expected int
+		err      error
+	}{
+		{
+			resume:   LibtorrentResume{InfoHash: bc.InfoHash, Pieces: append([]bool{true}, make([]bool, piece_count-1)...)},
+			expected: 1,
+		},
+		{
+			resume:   LibtorrentResume{InfoHash: bc.InfoHash, SeedMode: true},
+			expected: piece_count,
+		},
+		{
+			resume: LibtorrentResume{InfoHash: bc.InfoHash, Pieces: []bool{true}},
+			err:    ErrorPieceCountMismatch,
+		},
+		{
+			resume: LibtorrentResume{Pieces: make([]bool, piece_count)},
+			err:    ErrorInfoHashMismatch,
+		},
+	}
+
+	for index, test := range tests {
+		completion, err := test.resume.Join(bc)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, test.err, err)
+			continue
+		}
+
+		if err == nil && completion.CompletedCount != test.expected {
+			t.Errorf("test %d: expected [%d] | [%d] completed pieces", index, test.expected, completion.CompletedCount)
+		}
+	}
+
+	resumes := []*LibtorrentResume{&tests[0].resume, {InfoHash: [20]byte{9}}}
+	completions, orphans, err := JoinLibtorrent(resumes, []*bencode.Bencode{bc})
+
+	if err != nil {
+		t.Fatalf("failed to join: %v", err)
+	}
+
+	if len(completions) != 1 || completions[0].Torrent != bc {
+		t.Errorf("expected [1] | [%d] completions", len(completions))
+	}
+
+	if len(orphans) != 1 || orphans[0] != resumes[1] {
+		t.Errorf("expected [1] | [%d] orphans", len(orphans))
+	}
+}
+
+func TestLibtorrentState(t *testing.T) {
+	bc := loadTorrent(t, "../.test_files/minecraft.torrent")
+
+	state := ResumeState{
+		InfoHash:       bc.InfoHash,
+		Name:           bc.Info.DirectoryName,
+		SavePath:       "/downloads",
+		Pieces:         append([]bool{true}, make([]bool, len(bc.Info.Pieces)-1)...),
+		FilePriorities: []int{PriorityDontDownload, PriorityNormal, PriorityHigh},
+		Trackers:       [][]string{{"http://tracker.example.org/announce"}},
+		Uploaded:       10,
+		Downloaded:     20,
+		AddedTime:      1600000000,
+		CompletedTime:  1600000020,
+		Paused:         true,
+		Labels:         []string{"games"},
+	}
+
+	converted, err := LibtorrentFromState(state).State(bc)
+
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if !reflect.DeepEqual(converted, state) {
+		t.Errorf("expected [%+v] | [%+v] output", state, converted)
+	}
+
+	if _, err := (&LibtorrentResume{InfoHash: bc.InfoHash, SeedMode: true, FilePriority: []int{1}}).State(bc); !errors.Is(err, ErrorResumeCorrupted) {
+		t.Errorf("expected [%v] | [%v] error", ErrorResumeCorrupted, err)
+	}
+}
@@ -0,0 +1,286 @@
+// Package resume provide the resume files of some BitTorrent clients
+package resume
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+var (
+	ErrorResumeCorrupted    = errors.New("resume file corrupted")
+	ErrorInfoHashMismatch   = errors.New("info hash mismatch")
+	ErrorPieceCountMismatch = errors.New("piece count mismatch")
+	ErrorBadType            = errors.New("bad type")
+)
+
+// Completion reports which pieces of a torrent are marked complete by a resume file
+type Completion struct {
+	Torrent        *bencode.Bencode
+	Pieces         []bool
+	CompletedCount int
+}
+
+// Percent returns the percentage of completed pieces
+func (c Completion) Percent() float64 {
+	if len(c.Pieces) == 0 {
+		return 0
+	}
+
+	return float64(c.CompletedCount) * 100 / float64(len(c.Pieces))
+}
+
+// newCompletion checks that a bitfield matches a torrent
+func newCompletion(bc *bencode.Bencode, info_hash [20]byte, pieces []bool) (Completion, error) {
+	if info_hash != bc.InfoHash {
+		return Completion{}, fmt.Errorf("%w: %x (expected %x)", ErrorInfoHashMismatch, info_hash, bc.InfoHash)
+	}
+
+	if len(pieces) != len(bc.Info.Pieces) {
+		return Completion{}, fmt.Errorf("%w: %d (expected %d)", ErrorPieceCountMismatch, len(pieces), len(bc.Info.Pieces))
+	}
+
+	completion := Completion{
+		Torrent: bc,
+		Pieces:  pieces,
+	}
+
+	for _, complete := range pieces {
+		if complete {
+			completion.CompletedCount++
+		}
+	}
+
+	return completion, nil
+}
+
+// readDictionary parses a bencoded dictionary from a reader
+func readDictionary(reader io.Reader) (map[string]interface{}, error) {
+	bufioReader, ok := reader.(*bufio.Reader)
+
+	if !ok {
+		bufioReader = bufio.NewReader(reader)
+	}
+
+	data, err := parser.ParseElement(bufioReader)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorResumeCorrupted, err)
+	}
+
+	dictionary, ok := data.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("%w: need to be a dictionary", ErrorResumeCorrupted)
+	}
+
+	return dictionary, nil
+}
+
+// writeDictionary encodes a dictionary to a writer
+func writeDictionary(writer io.Writer, dictionary map[string]interface{}) error {
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, encoded)
+
+	return err
+}
+
+// fields reads the typed values of a dictionary, the keys read are removed from the dictionary
+//
+// missing keys are ignored, present keys with a bad type are reported
+type fields map[string]interface{}
+
+// pop removes a key from the fields
+func (f fields) pop(key string) (interface{}, bool) {
+	element, ok := f[key]
+
+	delete(f, key)
+
+	return element, ok
+}
+
+// string reads an optional string
+func (f fields) string(key string, value *string) error {
+	element, ok := f.pop(key)
+
+	if !ok {
+		return nil
+	}
+
+	if *value, ok = element.(string); !ok {
+		return fmt.Errorf("%w: [%T] for %s (expected string)", ErrorBadType, element, key)
+	}
+
+	return nil
+}
+
+// integer reads an optional integer
+func (f fields) integer(key string, value *int) error {
+	element, ok := f.pop(key)
+
+	if !ok {
+		return nil
+	}
+
+	if *value, ok = element.(int); !ok {
+		return fmt.Errorf("%w: [%T] for %s (expected integer)", ErrorBadType, element, key)
+	}
+
+	return nil
+}
+
+// boolean reads an optional integer used as a boolean
+func (f fields) boolean(key string, value *bool) error {
+	integer := 0
+
+	if err := f.integer(key, &integer); err != nil {
+		return err
+	}
+
+	*value = integer != 0
+
+	return nil
+}
+
+// hash reads an optional 20 bytes string
+func (f fields) hash(key string, value *[20]byte) error {
+	raw := ""
+
+	if err := f.string(key, &raw); err != nil {
+		return err
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if len(raw) != len(value) {
+		return fmt.Errorf("%w: %s length %d", ErrorResumeCorrupted, key, len(raw))
+	}
+
+	copy(value[:], raw)
+
+	return nil
+}
+
+// dictionary reads an optional dictionary
+func (f fields) dictionary(key string, value *map[string]interface{}) error {
+	element, ok := f.pop(key)
+
+	if !ok {
+		return nil
+	}
+
+	if *value, ok = element.(map[string]interface{}); !ok {
+		return fmt.Errorf("%w: [%T] for %s (expected dictionary)", ErrorBadType, element, key)
+	}
+
+	return nil
+}
+
+// list reads an optional list
+func (f fields) list(key string, value *[]interface{}) error {
+	element, ok := f.pop(key)
+
+	if !ok {
+		return nil
+	}
+
+	if *value, ok = element.([]interface{}); !ok {
+		return fmt.Errorf("%w: [%T] for %s (expected list)", ErrorBadType, element, key)
+	}
+
+	return nil
+}
+
+// integerList reads an optional list of integers
+func (f fields) integerList(key string, value *[]int) error {
+	list := []interface{}{}
+
+	if err := f.list(key, &list); err != nil {
+		return err
+	}
+
+	for _, element := range list {
+		integer, ok := element.(int)
+
+		if !ok {
+			return fmt.Errorf("%w: [%T] in %s (expected integer)", ErrorBadType, element, key)
+		}
+
+		*value = append(*value, integer)
+	}
+
+	return nil
+}
+
+// rest returns the keys that have not been read
+func (f fields) rest() map[string]interface{} {
+	if len(f) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}(f)
+}
+
+// copyDictionary returns a shallow copy of a dictionary
+func copyDictionary(dictionary map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(dictionary))
+
+	for key, element := range dictionary {
+		copied[key] = element
+	}
+
+	return copied
+}
+
+// toInterfaceList converts a list of strings for the encoder
+func toInterfaceList(list []string) []interface{} {
+	interface_list := make([]interface{}, len(list))
+
+	for index, element := range list {
+		interface_list[index] = element
+	}
+
+	return interface_list
+}
+
+// toTiers converts a list of tiers for the encoder
+func toTiers(tiers [][]string) []interface{} {
+	interface_tiers := make([]interface{}, len(tiers))
+
+	for index, tier := range tiers {
+		interface_tiers[index] = toInterfaceList(tier)
+	}
+
+	return interface_tiers
+}
+
+// toIntegerList converts a list of integers for the encoder
+func toIntegerList(list []int) []interface{} {
+	interface_list := make([]interface{}, len(list))
+
+	for index, element := range list {
+		interface_list[index] = element
+	}
+
+	return interface_list
+}
+
+// fromBoolean converts a boolean to the integer used by resume files
+func fromBoolean(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
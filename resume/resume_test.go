@@ -0,0 +1,159 @@
+package resume
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+// loadTorrent reads the info section and the info hash of a test torrent
+func loadTorrent(t *testing.T, file string) *bencode.Bencode {
+	t.Helper()
+
+	f, err := os.Open(file)
+
+	if err != nil {
+		t.Fatalf("failed to read file [%s]: %v", file, err)
+	}
+
+	defer f.Close()
+
+	data, err := parser.ParseElement(bufio.NewReader(f))
+
+	if err != nil {
+		t.Fatalf("failed to parse file [%s]: %v", file, err)
+	}
+
+	bc := &bencode.Bencode{
+		Data: data,
+	}
+
+	if err := bc.UnmarshallInfo(); err != nil {
+		t.Fatalf("failed to unmarshall info of [%s]: %v", file, err)
+	}
+
+	if err := bc.GetInfoHash(); err != nil {
+		t.Fatalf("failed to get the info hash of [%s]: %v", file, err)
+	}
+
+	return bc
+}
+
+func TestNewCompletion(t *testing.T) {
+	bc := loadTorrent(t, "../.test_files/minecraft.torrent")
+
+	half := make([]bool, len(bc.Info.Pieces))
+
+	for index := range half {
+		half[index] = index%2 == 0
+	}
+
+	tests := []struct {
+		info_hash [20]byte
+		pieces    []bool
+		expected  int
+		err       error
+	}{
+		{
+			info_hash: bc.InfoHash,
+			pieces:    make([]bool, len(bc.Info.Pieces)),
+			expected:  0,
+		},
+		{
+			info_hash: bc.InfoHash,
+			pieces:    half,
+			expected:  (len(half) + 1) / 2,
+		},
+		{
+			info_hash: [20]byte{1},
+			pieces:    half,
+			err:       ErrorInfoHashMismatch,
+		},
+		{
+			info_hash: bc.InfoHash,
+			pieces:    half[1:],
+			err:       ErrorPieceCountMismatch,
+		},
+	}
+
+	for index, test := range tests {
+		completion, err := newCompletion(bc, test.info_hash, test.pieces)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, test.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if completion.CompletedCount != test.expected {
+			t.Errorf("test %d: expected [%d] | [%d] completed pieces", index, test.expected, completion.CompletedCount)
+		}
+
+		if expected := float64(test.expected) * 100 / float64(len(test.pieces)); completion.Percent() != expected {
+			t.Errorf("test %d: expected [%f] | [%f] percent", index, expected, completion.Percent())
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	f := fields{
+		"string":  "value",
+		"integer": 42,
+		"boolean": 1,
+		"hash":    "01234567890123456789",
+		"list":    []interface{}{1, 2, 3},
+		"unknown": "kept",
+	}
+
+	value_string := ""
+	value_integer := 0
+	value_boolean := false
+	value_hash := [20]byte{}
+	value_list := []int{}
+
+	steps := []error{
+		f.string("string", &value_string),
+		f.integer("integer", &value_integer),
+		f.boolean("boolean", &value_boolean),
+		f.hash("hash", &value_hash),
+		f.integerList("list", &value_list),
+		f.string("missing", &value_string),
+	}
+
+	for index, err := range steps {
+		if err != nil {
+			t.Errorf("step %d: unexpected error: %v", index, err)
+		}
+	}
+
+	if value_string != "value" || value_integer != 42 || !value_boolean || string(value_hash[:]) != "01234567890123456789" || len(value_list) != 3 {
+		t.Errorf("bad values: [%s] [%d] [%t] [%x] %v", value_string, value_integer, value_boolean, value_hash, value_list)
+	}
+
+	if rest := f.rest(); len(rest) != 1 || rest["unknown"] != "kept" {
+		t.Errorf("expected [map[unknown:kept]] | [%v] rest", rest)
+	}
+
+	bad_types := fields{
+		"string":  1,
+		"integer": "1",
+		"list":    []interface{}{"1"},
+	}
+
+	if err := bad_types.string("string", &value_string); !errors.Is(err, ErrorBadType) {
+		t.Errorf("expected [%v] | [%v] error for a string", ErrorBadType, err)
+	}
+	if err := bad_types.integer("integer", &value_integer); !errors.Is(err, ErrorBadType) {
+		t.Errorf("expected [%v] | [%v] error for an integer", ErrorBadType, err)
+	}
+	if err := bad_types.integerList("list", &value_list); !errors.Is(err, ErrorBadType) {
+		t.Errorf("expected [%v] | [%v] error for an integer list", ErrorBadType, err)
+	}
+}
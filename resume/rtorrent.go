@@ -0,0 +1,482 @@
+package resume
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	RTorrentKeyDirectory         = "directory"
+	RTorrentKeyState             = "state"
+	RTorrentKeyComplete          = "complete"
+	RTorrentKeyChunksDone        = "chunks_done"
+	RTorrentKeyChunksWanted      = "chunks_wanted"
+	RTorrentKeyTotalUploaded     = "total_uploaded"
+	RTorrentKeyTotalDownloaded   = "total_downloaded"
+	RTorrentKeyTimestampStarted  = "timestamp.started"
+	RTorrentKeyTimestampFinished = "timestamp.finished"
+	RTorrentKeyCustom1           = "custom1"
+	RTorrentKeyTiedToFile        = "tied_to_file"
+
+	RTorrentKeyBitfield  = "bitfield"
+	RTorrentKeyFiles     = "files"
+	RTorrentKeyPriority  = "priority"
+	RTorrentKeyMTime     = "mtime"
+	RTorrentKeyCompleted = "completed"
+	RTorrentKeyTrackers  = "trackers"
+	RTorrentKeyEnabled   = "enabled"
+
+	RTorrentPriorityOff    = 0
+	RTorrentPriorityNormal = 1
+	RTorrentPriorityHigh   = 2
+)
+
+// RTorrentSession is the .rtorrent dictionary of the rTorrent session directory
+type RTorrentSession struct {
+	Directory         string // save path, including the root directory of multi file torrents
+	Started           bool
+	Complete          bool
+	ChunksDone        int
+	ChunksWanted      int
+	TotalUploaded     int
+	TotalDownloaded   int
+	TimestampStarted  int
+	TimestampFinished int
+	Custom1           string // label of ruTorrent
+	TiedToFile        string
+	Extra             map[string]interface{} // unknown keys, written back untouched
+}
+
+// RTorrentFile is the resume data of a file of a torrent
+type RTorrentFile struct {
+	Priority  int // RTorrentPriorityOff, RTorrentPriorityNormal or RTorrentPriorityHigh
+	MTime     int // modification time of the file on disk, rTorrent checks it before trusting the bitfield
+	Completed int // completed chunks overlapping the file
+	Extra     map[string]interface{}
+}
+
+// RTorrentLibtorrentResume is the .libtorrent_resume dictionary of the rTorrent session directory
+type RTorrentLibtorrentResume struct {
+	// Bitfield holds the completed chunks, it is nil when rTorrent only stored
+	// their count (CompletedChunks), which happens when none or all of them are completed
+	Bitfield        []bool
+	CompletedChunks int
+	Files           []RTorrentFile
+	Trackers        map[string]bool // tracker url to enabled
+	Extra           map[string]interface{}
+}
+
+// RTorrentResume is the resume data written by rTorrent, split in two files
+// (<HASH>.torrent.rtorrent and <HASH>.torrent.libtorrent_resume)
+type RTorrentResume struct {
+	InfoHash   [20]byte // not stored in the files, see InfoHashFromFileName
+	Session    RTorrentSession
+	Libtorrent RTorrentLibtorrentResume
+}
+
+// readRTorrentSession reads the .rtorrent dictionary
+func readRTorrentSession(reader io.Reader) (session RTorrentSession, err error) {
+	dictionary, err := readDictionary(reader)
+
+	if err != nil {
+		return session, err
+	}
+
+	f := fields(copyDictionary(dictionary))
+
+	steps := []error{
+		f.string(RTorrentKeyDirectory, &session.Directory),
+		f.boolean(RTorrentKeyState, &session.Started),
+		f.boolean(RTorrentKeyComplete, &session.Complete),
+		f.integer(RTorrentKeyChunksDone, &session.ChunksDone),
+		f.integer(RTorrentKeyChunksWanted, &session.ChunksWanted),
+		f.integer(RTorrentKeyTotalUploaded, &session.TotalUploaded),
+		f.integer(RTorrentKeyTotalDownloaded, &session.TotalDownloaded),
+		f.integer(RTorrentKeyTimestampStarted, &session.TimestampStarted),
+		f.integer(RTorrentKeyTimestampFinished, &session.TimestampFinished),
+		f.string(RTorrentKeyCustom1, &session.Custom1),
+		f.string(RTorrentKeyTiedToFile, &session.TiedToFile),
+	}
+
+	for _, err := range steps {
+		if err != nil {
+			return session, err
+		}
+	}
+
+	session.Extra = f.rest()
+
+	return session, nil
+}
+
+// readRTorrentLibtorrentResume reads the .libtorrent_resume dictionary
+func readRTorrentLibtorrentResume(reader io.Reader) (r RTorrentLibtorrentResume, err error) {
+	dictionary, err := readDictionary(reader)
+
+	if err != nil {
+		return r, err
+	}
+
+	f := fields(copyDictionary(dictionary))
+
+	switch bitfield := f[RTorrentKeyBitfield].(type) {
+	case nil:
+	case int:
+		r.CompletedChunks = bitfield
+	case string:
+		// the exact chunk count is only known with the torrent, the spare bits are kept until State
+		r.Bitfield, _ = unpackBitfield(bitfield, len(bitfield)*8)
+	default:
+		return r, fmt.Errorf("%w: [%T] for %s (expected string or integer)", ErrorBadType, bitfield, RTorrentKeyBitfield)
+	}
+
+	delete(f, RTorrentKeyBitfield)
+
+	files := []interface{}{}
+	trackers := map[string]interface{}{}
+
+	if err := f.list(RTorrentKeyFiles, &files); err != nil {
+		return r, err
+	}
+	if err := f.dictionary(RTorrentKeyTrackers, &trackers); err != nil {
+		return r, err
+	}
+
+	for _, element := range files {
+		file_dictionary, ok := element.(map[string]interface{})
+
+		if !ok {
+			return r, fmt.Errorf("%w: [%T] in %s (expected dictionary)", ErrorBadType, element, RTorrentKeyFiles)
+		}
+
+		file_fields := fields(copyDictionary(file_dictionary))
+		file := RTorrentFile{}
+
+		steps := []error{
+			file_fields.integer(RTorrentKeyPriority, &file.Priority),
+			file_fields.integer(RTorrentKeyMTime, &file.MTime),
+			file_fields.integer(RTorrentKeyCompleted, &file.Completed),
+		}
+
+		for _, err := range steps {
+			if err != nil {
+				return r, err
+			}
+		}
+
+		file.Extra = file_fields.rest()
+		r.Files = append(r.Files, file)
+	}
+
+	if len(trackers) > 0 {
+		r.Trackers = map[string]bool{}
+	}
+
+	for url, element := range trackers {
+		tracker_dictionary, ok := element.(map[string]interface{})
+
+		if !ok {
+			return r, fmt.Errorf("%w: [%T] for tracker [%s] (expected dictionary)", ErrorBadType, element, url)
+		}
+
+		enabled, _ := tracker_dictionary[RTorrentKeyEnabled].(int)
+		r.Trackers[url] = enabled != 0
+	}
+
+	r.Extra = f.rest()
+
+	return r, nil
+}
+
+// ReadRTorrent reads the two resume files of a torrent of the rTorrent session directory
+func ReadRTorrent(session_reader io.Reader, libtorrent_resume_reader io.Reader) (*RTorrentResume, error) {
+	session, err := readRTorrentSession(session_reader)
+
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent: %w", err)
+	}
+
+	libtorrent_resume, err := readRTorrentLibtorrentResume(libtorrent_resume_reader)
+
+	if err != nil {
+		return nil, fmt.Errorf("libtorrent_resume: %w", err)
+	}
+
+	return &RTorrentResume{
+		Session:    session,
+		Libtorrent: libtorrent_resume,
+	}, nil
+}
+
+// Write writes the two resume files of a torrent
+func (r *RTorrentResume) Write(session_writer io.Writer, libtorrent_resume_writer io.Writer) error {
+	session := copyDictionary(r.Session.Extra)
+
+	session_values := map[string]interface{}{
+		RTorrentKeyDirectory:         r.Session.Directory,
+		RTorrentKeyState:             fromBoolean(r.Session.Started),
+		RTorrentKeyComplete:          fromBoolean(r.Session.Complete),
+		RTorrentKeyChunksDone:        r.Session.ChunksDone,
+		RTorrentKeyChunksWanted:      r.Session.ChunksWanted,
+		RTorrentKeyTotalUploaded:     r.Session.TotalUploaded,
+		RTorrentKeyTotalDownloaded:   r.Session.TotalDownloaded,
+		RTorrentKeyTimestampStarted:  r.Session.TimestampStarted,
+		RTorrentKeyTimestampFinished: r.Session.TimestampFinished,
+		RTorrentKeyCustom1:           r.Session.Custom1,
+		RTorrentKeyTiedToFile:        r.Session.TiedToFile,
+	}
+
+	for key, value := range session_values {
+		session[key] = value
+	}
+
+	libtorrent_resume := copyDictionary(r.Libtorrent.Extra)
+
+	if r.Libtorrent.Bitfield != nil {
+		libtorrent_resume[RTorrentKeyBitfield] = packBitfield(r.Libtorrent.Bitfield)
+	} else {
+		libtorrent_resume[RTorrentKeyBitfield] = r.Libtorrent.CompletedChunks
+	}
+
+	files := make([]interface{}, len(r.Libtorrent.Files))
+
+	for index, file := range r.Libtorrent.Files {
+		file_dictionary := copyDictionary(file.Extra)
+
+		file_dictionary[RTorrentKeyPriority] = file.Priority
+		file_dictionary[RTorrentKeyMTime] = file.MTime
+		file_dictionary[RTorrentKeyCompleted] = file.Completed
+
+		files[index] = file_dictionary
+	}
+
+	libtorrent_resume[RTorrentKeyFiles] = files
+
+	if r.Libtorrent.Trackers != nil {
+		trackers := map[string]interface{}{}
+
+		for url, enabled := range r.Libtorrent.Trackers {
+			trackers[url] = map[string]interface{}{
+				RTorrentKeyEnabled: fromBoolean(enabled),
+			}
+		}
+
+		libtorrent_resume[RTorrentKeyTrackers] = trackers
+	}
+
+	if err := writeDictionary(session_writer, session); err != nil {
+		return err
+	}
+
+	return writeDictionary(libtorrent_resume_writer, libtorrent_resume)
+}
+
+// pieces returns the completed chunks
+func (r *RTorrentResume) pieces(bc *bencode.Bencode) ([]bool, error) {
+	piece_count := len(bc.Info.Pieces)
+
+	if r.Libtorrent.Bitfield == nil {
+		switch r.Libtorrent.CompletedChunks {
+		case 0:
+			return allSet(piece_count, false), nil
+		case piece_count:
+			return allSet(piece_count, true), nil
+		}
+
+		return nil, fmt.Errorf("%w: %d completed chunks without bitfield", ErrorPieceCountMismatch, r.Libtorrent.CompletedChunks)
+	}
+
+	return unpackBitfield(packBitfield(r.Libtorrent.Bitfield), piece_count)
+}
+
+// rtorrentSavePath returns the directory containing the torrent content
+func rtorrentSavePath(directory string, bc *bencode.Bencode) string {
+	if bc.IsMultiFile() && filepath.Base(directory) == bc.Info.DirectoryName {
+		return filepath.Dir(directory)
+	}
+
+	return directory
+}
+
+// State converts the resume data, the first label is the ruTorrent label (custom1)
+func (r *RTorrentResume) State(bc *bencode.Bencode) (ResumeState, error) {
+	if r.InfoHash != [20]byte{} && r.InfoHash != bc.InfoHash {
+		return ResumeState{}, fmt.Errorf("%w: %x (expected %x)", ErrorInfoHashMismatch, r.InfoHash, bc.InfoHash)
+	}
+
+	pieces, err := r.pieces(bc)
+
+	if err != nil {
+		return ResumeState{}, err
+	}
+
+	if err := checkFiles(bc, len(r.Libtorrent.Files)); err != nil {
+		return ResumeState{}, err
+	}
+
+	state := ResumeState{
+		InfoHash:      bc.InfoHash,
+		Name:          bc.Info.DirectoryName,
+		SavePath:      rtorrentSavePath(r.Session.Directory, bc),
+		Pieces:        pieces,
+		Trackers:      torrentTrackers(bc),
+		Uploaded:      r.Session.TotalUploaded,
+		Downloaded:    r.Session.TotalDownloaded,
+		AddedTime:     r.Session.TimestampStarted,
+		CompletedTime: r.Session.TimestampFinished,
+		Paused:        !r.Session.Started,
+	}
+
+	for _, file := range r.Libtorrent.Files {
+		priority := PriorityNormal
+
+		switch file.Priority {
+		case RTorrentPriorityOff:
+			priority = PriorityDontDownload
+		case RTorrentPriorityHigh:
+			priority = PriorityHigh
+		}
+
+		state.FilePriorities = append(state.FilePriorities, priority)
+	}
+
+	if len(r.Session.Custom1) > 0 {
+		state.Labels = []string{r.Session.Custom1}
+	}
+
+	return state, nil
+}
+
+// RTorrentFromState converts a resume state to the rTorrent format
+//
+// rTorrent compares the modification times of the files before trusting the bitfield,
+// they can be filled with SetModificationTimes once the content is in place
+func RTorrentFromState(state ResumeState, bc *bencode.Bencode) (*RTorrentResume, error) {
+	if state.InfoHash != bc.InfoHash {
+		return nil, fmt.Errorf("%w: %x (expected %x)", ErrorInfoHashMismatch, state.InfoHash, bc.InfoHash)
+	}
+
+	if err := checkPieces(bc, state.Pieces); err != nil {
+		return nil, err
+	}
+	if err := checkFiles(bc, len(state.FilePriorities)); err != nil {
+		return nil, err
+	}
+
+	r := &RTorrentResume{
+		InfoHash: state.InfoHash,
+		Session: RTorrentSession{
+			Directory:         state.SavePath,
+			Started:           !state.Paused,
+			Complete:          isAllSet(state.Pieces, true),
+			ChunksWanted:      len(state.Pieces),
+			TotalUploaded:     state.Uploaded,
+			TotalDownloaded:   state.Downloaded,
+			TimestampStarted:  state.AddedTime,
+			TimestampFinished: state.CompletedTime,
+		},
+	}
+
+	if bc.IsMultiFile() {
+		r.Session.Directory = filepath.Join(state.SavePath, bc.Info.DirectoryName)
+	}
+
+	if len(state.Labels) > 0 {
+		r.Session.Custom1 = state.Labels[0]
+	}
+
+	for _, complete := range state.Pieces {
+		if complete {
+			r.Session.ChunksDone++
+		}
+	}
+
+	if r.Session.ChunksDone == 0 || r.Session.ChunksDone == len(state.Pieces) {
+		r.Libtorrent.CompletedChunks = r.Session.ChunksDone
+	} else {
+		r.Libtorrent.Bitfield = append([]bool{}, state.Pieces...)
+	}
+
+	for index := range bc.Info.Files {
+		rtorrent_file := RTorrentFile{
+			Priority: RTorrentPriorityNormal,
+		}
+
+		if index < len(state.FilePriorities) {
+			switch {
+			case state.FilePriorities[index] == PriorityDontDownload:
+				rtorrent_file.Priority = RTorrentPriorityOff
+			case state.FilePriorities[index] > PriorityNormal:
+				rtorrent_file.Priority = RTorrentPriorityHigh
+			}
+		}
+
+		first, last, err := bc.Info.FilePieces(index)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the files need more pieces than the torrent declares
+		if last >= len(state.Pieces) {
+			return nil, fmt.Errorf("%w: file %d ends in piece %d, the torrent has %d pieces", ErrorPieceCountMismatch, index, last, len(state.Pieces))
+		}
+
+		for piece := first; piece <= last; piece++ {
+			if state.Pieces[piece] {
+				rtorrent_file.Completed++
+			}
+		}
+
+		r.Libtorrent.Files = append(r.Libtorrent.Files, rtorrent_file)
+	}
+
+	trackers := map[string]bool{}
+
+	for _, tier := range state.Trackers {
+		for _, url := range tier {
+			trackers[url] = true
+		}
+	}
+
+	if len(trackers) > 0 {
+		r.Libtorrent.Trackers = trackers
+	}
+
+	return r, nil
+}
+
+// SetModificationTimes reads the modification times of the files of the torrent on disk
+func (r *RTorrentResume) SetModificationTimes(bc *bencode.Bencode) error {
+	if len(r.Libtorrent.Files) != len(bc.Info.Files) {
+		return fmt.Errorf("%w: %d files for %d files", ErrorResumeCorrupted, len(r.Libtorrent.Files), len(bc.Info.Files))
+	}
+
+	for index, file := range bc.Info.Files {
+		components := file.DecomposedPath
+
+		if !bc.IsMultiFile() {
+			components = []string{bc.Info.DirectoryName}
+		}
+
+		file_path, err := bencode.JoinLocalPath(r.Session.Directory, components, bencode.PathPolicyReject)
+
+		if err != nil {
+			return err
+		}
+
+		stat, err := os.Stat(file_path)
+
+		if err != nil {
+			return err
+		}
+
+		r.Libtorrent.Files[index].MTime = int(stat.ModTime().Unix())
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package resume
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+func TestRTorrentRoundTrip(t *testing.T) {
+	tests := []RTorrentResume{
+		{
+			Session: RTorrentSession{
+				Directory:         "/downloads/Minecraft 1.15.2",
+				Started:           true,
+				ChunksDone:        1,
+				ChunksWanted:      16,
+				TotalUploaded:     1000,
+				TotalDownloaded:   2000,
+				TimestampStarted:  1600000000,
+				TimestampFinished: 1600000020,
+				Custom1:           "games",
+				TiedToFile:        "/watch/minecraft.torrent",
+				Extra:             map[string]interface{}{"views": []interface{}{"main"}},
+			},
+			Libtorrent: RTorrentLibtorrentResume{
+				Bitfield: append([]bool{true}, make([]bool, 15)...),
+				Files: []RTorrentFile{
+					{Priority: RTorrentPriorityHigh, MTime: 1600000010, Completed: 1, Extra: map[string]interface{}{"uncertain": 0}},
+					{Priority: RTorrentPriorityOff},
+				},
+				Trackers: map[string]bool{"http://tracker.example.org/announce": true, "dht://": false},
+				Extra:    map[string]interface{}{"uncertain_pieces.timestamp": 1600000030},
+			},
+		},
+		{
+			Session: RTorrentSession{
+				Complete: true,
+			},
+			Libtorrent: RTorrentLibtorrentResume{
+				CompletedChunks: 1820,
+				Files:           []RTorrentFile{},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		session := bytes.Buffer{}
+		libtorrent_resume := bytes.Buffer{}
+
+		if err := test.Write(&session, &libtorrent_resume); err != nil {
+			t.Errorf("test %d: failed to write: %v", index, err)
+			continue
+		}
+
+		r, err := ReadRTorrent(&session, &libtorrent_resume)
+
+		if err != nil {
+			t.Errorf("test %d: failed to read: %v", index, err)
+			continue
+		}
+
+		if len(test.Libtorrent.Files) == 0 {
+			r.Libtorrent.Files = test.Libtorrent.Files
+		}
+
+		if !reflect.DeepEqual(*r, test) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, test, *r)
+		}
+	}
+}
+
+func TestRTorrentState(t *testing.T) {
+	minecraft := loadTorrent(t, "../.test_files/minecraft.torrent")
+	arch := loadTorrent(t, "../.test_files/arch.torrent")
+
+	some_pieces := make([]bool, len(minecraft.Info.Pieces))
+	some_pieces[0] = true
+	some_pieces[len(some_pieces)-1] = true
+
+	states := []ResumeState{
+		{
+			InfoHash:       minecraft.InfoHash,
+			Name:           minecraft.Info.DirectoryName,
+			SavePath:       "/downloads",
+			Pieces:         some_pieces,
+			FilePriorities: []int{PriorityDontDownload, PriorityNormal, PriorityHigh},
+			Trackers:       [][]string{{"http://tracker.example.org/announce"}},
+			Uploaded:       10,
+			Downloaded:     20,
+			AddedTime:      1600000000,
+			Labels:         []string{"games"},
+		},
+		{
+			InfoHash:       arch.InfoHash,
+			Name:           arch.Info.DirectoryName,
+			SavePath:       "/downloads",
+			Pieces:         allSet(len(arch.Info.Pieces), true),
+			FilePriorities: []int{PriorityNormal},
+			Paused:         true,
+		},
+	}
+
+	for index, state := range states {
+		bc := arch
+
+		if state.InfoHash == minecraft.InfoHash {
+			bc = minecraft
+		}
+
+		r, err := RTorrentFromState(state, bc)
+
+		if err != nil {
+			t.Errorf("test %d: failed to convert: %v", index, err)
+			continue
+		}
+
+		converted, err := r.State(bc)
+
+		if err != nil {
+			t.Errorf("test %d: failed to convert back: %v", index, err)
+			continue
+		}
+
+		// the trackers come from the torrent
+		state.Trackers = converted.Trackers
+
+		if !reflect.DeepEqual(converted, state) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, state, converted)
+		}
+	}
+
+	r, err := RTorrentFromState(states[0], minecraft)
+
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if expected := filepath.Join("/downloads", "Minecraft 1.15.2"); r.Session.Directory != expected {
+		t.Errorf("expected [%s] | [%s] directory", expected, r.Session.Directory)
+	}
+
+	completed := 0
+
+	for _, file := range r.Libtorrent.Files {
+		completed += file.Completed
+	}
+
+	if completed != 2 {
+		t.Errorf("expected [2] | [%d] completed chunks in the files", completed)
+	}
+
+	if _, err := (&RTorrentResume{Libtorrent: RTorrentLibtorrentResume{CompletedChunks: 3}}).State(minecraft); !errors.Is(err, ErrorPieceCountMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorPieceCountMismatch, err)
+	}
+}
+
+func TestRTorrentSetModificationTimes(t *testing.T) {
+	minecraft := loadTorrent(t, "../.test_files/minecraft.torrent")
+	root := t.TempDir()
+	modification_time := time.Unix(1600000000, 0)
+
+	r, err := RTorrentFromState(ResumeState{InfoHash: minecraft.InfoHash, SavePath: root, Pieces: make([]bool, len(minecraft.Info.Pieces))}, minecraft)
+
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if err := r.SetModificationTimes(minecraft); err == nil {
+		t.Errorf("expected an error for missing files")
+	}
+
+	for _, file := range minecraft.Info.Files {
+		file_path := filepath.Join(append([]string{root, minecraft.Info.DirectoryName}, file.DecomposedPath...)...)
+
+		if err := os.MkdirAll(filepath.Dir(file_path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file_path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file_path, modification_time, modification_time); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.SetModificationTimes(minecraft); err != nil {
+		t.Fatalf("failed to set the modification times: %v", err)
+	}
+
+	for index, file := range r.Libtorrent.Files {
+		if file.MTime != int(modification_time.Unix()) {
+			t.Errorf("file %d: expected [%d] | [%d] modification time", index, modification_time.Unix(), file.MTime)
+		}
+	}
+}
+
+func TestRTorrentFromStateMissingPieces(t *testing.T) {
+	// one piece declared for a file of three pieces
+	bc := &bencode.Bencode{
+		Info: bencode.Info{
+			PieceLength: 16384,
+			Pieces:      make([]bencode.Piece, 1),
+			Files:       []bencode.File{{Length: 40000, Path: "a"}},
+		},
+	}
+
+	state := ResumeState{InfoHash: bc.InfoHash, Pieces: []bool{true}}
+
+	if _, err := RTorrentFromState(state, bc); !errors.Is(err, ErrorPieceCountMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorPieceCountMismatch, err)
+	}
+}
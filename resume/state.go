@@ -0,0 +1,176 @@
+package resume
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+// file priorities of a ResumeState, they use the libtorrent scale
+const (
+	PriorityDontDownload = 0
+	PriorityLow          = 1
+	PriorityNormal       = 4
+	PriorityHigh         = 7
+)
+
+// ResumeState is the resume data shared by all the clients, it allows to convert between the formats
+type ResumeState struct {
+	InfoHash       [20]byte
+	Name           string
+	SavePath       string // directory containing the torrent content (its root directory for multi file torrents)
+	Pieces         []bool // completed pieces
+	FilePriorities []int  // PriorityDontDownload, PriorityLow, PriorityNormal or PriorityHigh
+	Trackers       [][]string
+	Uploaded       int
+	Downloaded     int
+	AddedTime      int // unix timestamps
+	CompletedTime  int
+	Paused         bool
+	Labels         []string
+}
+
+// Resume is implemented by the resume data of every client
+type Resume interface {
+	// State checks the resume data against its torrent and converts it
+	State(bc *bencode.Bencode) (ResumeState, error)
+}
+
+// info_hash_file_name matches the 40 hexadecimal characters of an info hash in a file name
+var info_hash_file_name = regexp.MustCompile(`(?:^|[^0-9A-Fa-f])([0-9A-Fa-f]{40})(?:[^0-9A-Fa-f]|$)`)
+
+// InfoHashFromFileName extracts the info hash of a resume file name
+//
+// Transmission and rTorrent do not store the info hash in their resume files, it is in their names
+// (<hash>.resume, <name>.<hash>.resume, <HASH>.torrent.rtorrent, ...)
+func InfoHashFromFileName(name string) (info_hash [20]byte, err error) {
+	matches := info_hash_file_name.FindStringSubmatch(filepath.Base(name))
+
+	if matches == nil {
+		return info_hash, fmt.Errorf("%w: no info hash in [%s]", ErrorResumeCorrupted, name)
+	}
+
+	hex.Decode(info_hash[:], []byte(matches[1]))
+
+	return info_hash, nil
+}
+
+// States converts the resume data of many torrents, the resume data are matched to the torrents by info hash
+//
+// the info hashes of the resume data without torrent are returned separately
+func States(torrents []*bencode.Bencode, resumes map[[20]byte]Resume) (states []ResumeState, orphans [][20]byte, err error) {
+	by_info_hash := map[[20]byte]*bencode.Bencode{}
+
+	for _, bc := range torrents {
+		by_info_hash[bc.InfoHash] = bc
+	}
+
+	for info_hash, r := range resumes {
+		bc, ok := by_info_hash[info_hash]
+
+		if !ok {
+			orphans = append(orphans, info_hash)
+			continue
+		}
+
+		state, err := r.State(bc)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%x: %w", info_hash, err)
+		}
+
+		states = append(states, state)
+	}
+
+	return states, orphans, nil
+}
+
+// checkPieces checks that completed pieces match a torrent
+func checkPieces(bc *bencode.Bencode, pieces []bool) error {
+	if len(pieces) != len(bc.Info.Pieces) {
+		return fmt.Errorf("%w: %d (expected %d)", ErrorPieceCountMismatch, len(pieces), len(bc.Info.Pieces))
+	}
+
+	return nil
+}
+
+// checkFiles checks that per file values match a torrent, they are optional
+func checkFiles(bc *bencode.Bencode, count int) error {
+	if count > 0 && count != len(bc.Info.Files) {
+		return fmt.Errorf("%w: %d file priorities for %d files", ErrorResumeCorrupted, count, len(bc.Info.Files))
+	}
+
+	return nil
+}
+
+// torrentTrackers returns the tiers of a torrent
+func torrentTrackers(bc *bencode.Bencode) [][]string {
+	if len(bc.AnnounceList) > 0 {
+		return bc.AnnounceList
+	}
+
+	if len(bc.Announce) > 0 {
+		return [][]string{{bc.Announce}}
+	}
+
+	return nil
+}
+
+// packBitfield encodes booleans in a bitfield, the first one is the highest bit of the first byte
+func packBitfield(bits []bool) string {
+	bitfield := make([]byte, (len(bits)+7)/8)
+
+	for index, bit := range bits {
+		if bit {
+			bitfield[index/8] |= 0x80 >> (index % 8)
+		}
+	}
+
+	return string(bitfield)
+}
+
+// unpackBitfield decodes a bitfield of count booleans, the spare bits of the last byte must be cleared
+func unpackBitfield(bitfield string, count int) ([]bool, error) {
+	if len(bitfield) != (count+7)/8 {
+		return nil, fmt.Errorf("%w: bitfield of %d bytes for %d bits", ErrorResumeCorrupted, len(bitfield), count)
+	}
+
+	bits := make([]bool, count)
+
+	for index := range bits {
+		bits[index] = bitfield[index/8]&(0x80>>(index%8)) != 0
+	}
+
+	for index := count; index < len(bitfield)*8; index++ {
+		if bitfield[index/8]&(0x80>>(index%8)) != 0 {
+			return nil, fmt.Errorf("%w: spare bits set in the bitfield", ErrorResumeCorrupted)
+		}
+	}
+
+	return bits, nil
+}
+
+// allSet returns count booleans set to value
+func allSet(count int, value bool) []bool {
+	bits := make([]bool, count)
+
+	for index := range bits {
+		bits[index] = value
+	}
+
+	return bits
+}
+
+// isAllSet checks if all the booleans are set to value
+func isAllSet(bits []bool, value bool) bool {
+	for _, bit := range bits {
+		if bit != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package resume
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+func TestBitfield(t *testing.T) {
+	tests := []struct {
+		bits     []bool
+		expected string
+	}{
+		{[]bool{}, ""},
+		{[]bool{true}, "\x80"},
+		{[]bool{true, false, false, false, false, false, false, true}, "\x81"},
+		{[]bool{false, true, false, false, false, false, false, false, true}, "\x40\x80"},
+	}
+
+	for index, test := range tests {
+		if output := packBitfield(test.bits); output != test.expected {
+			t.Errorf("test %d: expected [%x] | [%x] output", index, test.expected, output)
+		}
+
+		bits, err := unpackBitfield(test.expected, len(test.bits))
+
+		if err != nil {
+			t.Errorf("test %d: failed to unpack: %v", index, err)
+		} else if !reflect.DeepEqual(bits, test.bits) {
+			t.Errorf("test %d: expected [%v] | [%v] unpacked output", index, test.bits, bits)
+		}
+	}
+
+	errors_tests := []struct {
+		bitfield string
+		count    int
+	}{
+		{"\x80", 9},
+		{"\x80\x00", 8},
+		{"\x81", 7},
+	}
+
+	for index, test := range errors_tests {
+		if _, err := unpackBitfield(test.bitfield, test.count); !errors.Is(err, ErrorResumeCorrupted) {
+			t.Errorf("test %d: expected [%v] | [%v] error", index, ErrorResumeCorrupted, err)
+		}
+	}
+}
+
+func TestInfoHashFromFileName(t *testing.T) {
+	expected := [20]byte{0x2c, 0x6b, 0x68, 0x58, 0xd6, 0x1d, 0xa9, 0x54, 0x3d, 0x42, 0x31, 0xa7, 0x1d, 0xb4, 0xb1, 0xc9, 0x26, 0x4b, 0x06, 0x85}
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"2c6b6858d61da9543d4231a71db4b1c9264b0685.resume", true},
+		{"/var/lib/transmission/resume/ubuntu.2c6b6858d61da9543d4231a71db4b1c9264b0685.resume", true},
+		{"session/2C6B6858D61DA9543D4231A71DB4B1C9264B0685.torrent.libtorrent_resume", true},
+		{"2c6b6858d61da9543d4231a71db4b1c9264b06851.resume", false},
+		{"ubuntu.resume", false},
+	}
+
+	for index, test := range tests {
+		info_hash, err := InfoHashFromFileName(test.name)
+
+		if test.ok != (err == nil) {
+			t.Errorf("test %d: unexpected error: %v", index, err)
+			continue
+		}
+
+		if test.ok && info_hash != expected {
+			t.Errorf("test %d: expected [%x] | [%x] output", index, expected, info_hash)
+		}
+	}
+}
+
+func TestStates(t *testing.T) {
+	minecraft := loadTorrent(t, "../.test_files/minecraft.torrent")
+	arch := loadTorrent(t, "../.test_files/arch.torrent")
+
+	resumes := map[[20]byte]Resume{
+		minecraft.InfoHash: &LibtorrentResume{InfoHash: minecraft.InfoHash, SeedMode: true},
+		arch.InfoHash:      &TransmissionResume{Blocks: TransmissionBlocksAll},
+		{1}:                &TransmissionResume{},
+	}
+
+	states, orphans, err := States([]*bencode.Bencode{minecraft, arch}, resumes)
+
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if len(states) != 2 {
+		t.Errorf("expected [2] | [%d] states", len(states))
+	}
+
+	for _, state := range states {
+		if !isAllSet(state.Pieces, true) {
+			t.Errorf("%x: expected all the pieces completed", state.InfoHash)
+		}
+	}
+
+	if !reflect.DeepEqual(orphans, [][20]byte{{1}}) {
+		t.Errorf("expected [%v] | [%v] orphans", [][20]byte{{1}}, orphans)
+	}
+
+	resumes[arch.InfoHash] = &TransmissionResume{InfoHash: minecraft.InfoHash}
+
+	if _, _, err := States([]*bencode.Bencode{minecraft, arch}, resumes); !errors.Is(err, ErrorInfoHashMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorInfoHashMismatch, err)
+	}
+}
@@ -0,0 +1,401 @@
+package resume
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/utils"
+)
+
+const (
+	TransmissionKeyName              = "name"
+	TransmissionKeyDestination       = "destination"
+	TransmissionKeyIncompleteDir     = "incomplete-dir"
+	TransmissionKeyDownloaded        = "downloaded"
+	TransmissionKeyUploaded          = "uploaded"
+	TransmissionKeyCorrupt           = "corrupt"
+	TransmissionKeyAddedDate         = "added-date"
+	TransmissionKeyDoneDate          = "done-date"
+	TransmissionKeyActivityDate      = "activity-date"
+	TransmissionKeySeedingTime       = "seeding-time-seconds"
+	TransmissionKeyDownloadingTime   = "downloading-time-seconds"
+	TransmissionKeyPaused            = "paused"
+	TransmissionKeyPriority          = "priority"
+	TransmissionKeyDND               = "dnd"
+	TransmissionKeyLabels            = "labels"
+	TransmissionKeyBandwidthPriority = "bandwidth-priority"
+	TransmissionKeyProgress          = "progress"
+	TransmissionKeyBlocks            = "blocks"
+	TransmissionKeyHave              = "have"     // legacy
+	TransmissionKeyBitfield          = "bitfield" // legacy
+
+	TransmissionBlocksAll  = "all"
+	TransmissionBlocksNone = "none"
+
+	TransmissionPriorityLow    = -1
+	TransmissionPriorityNormal = 0
+	TransmissionPriorityHigh   = 1
+
+	transmission_max_block_size = 16384 // 16 KiB
+)
+
+// TransmissionResume is the resume data written by Transmission (<hash>.resume files)
+type TransmissionResume struct {
+	InfoHash          [20]byte // not stored in the file, see InfoHashFromFileName
+	Name              string
+	Destination       string
+	IncompleteDir     string
+	Downloaded        int
+	Uploaded          int
+	Corrupt           int
+	AddedDate         int
+	DoneDate          int
+	ActivityDate      int
+	SeedingTime       int
+	DownloadingTime   int
+	Paused            bool
+	Priority          []int  // per file, TransmissionPriorityLow, TransmissionPriorityNormal or TransmissionPriorityHigh
+	DND               []bool // per file, do not download
+	Labels            []string
+	BandwidthPriority int
+
+	// Blocks is TransmissionBlocksAll, TransmissionBlocksNone or a bitfield of the completed blocks
+	Blocks string
+
+	ProgressExtra map[string]interface{} // unknown keys of the progress dictionary (mtimes, time-checked...)
+	Extra         map[string]interface{} // unknown keys, written back untouched
+}
+
+// transmissionBlockSize returns the block size used by Transmission for a piece length
+func transmissionBlockSize(piece_length int) int {
+	block_size := piece_length
+
+	for block_size > transmission_max_block_size {
+		block_size /= 2
+	}
+
+	return block_size
+}
+
+// transmissionBlocks returns the number of blocks of a torrent and their size
+func transmissionBlocks(bc *bencode.Bencode) (count int, size int) {
+	size = transmissionBlockSize(bc.Info.PieceLength)
+
+	if size <= 0 {
+		return 0, 0
+	}
+
+	return (bc.Info.TotalLength() + size - 1) / size, size
+}
+
+// pieceBlocks returns the first and the last block overlapping a piece
+//
+// a piece starting at or after the end of the files (more pieces than data) is an error
+func pieceBlocks(bc *bencode.Bencode, piece int, block_size int) (first int, last int, err error) {
+	if block_size <= 0 {
+		return 0, 0, fmt.Errorf("%w: %d", bencode.ErrorInvalidPieceLength, bc.Info.PieceLength)
+	}
+
+	start := piece * bc.Info.PieceLength
+	end := start + bc.Info.PieceLength
+	total := bc.Info.TotalLength()
+
+	if start >= total {
+		return 0, 0, fmt.Errorf("%w: piece %d starts at %d, after the %d bytes of the files", ErrorPieceCountMismatch, piece, start, total)
+	}
+
+	if end > total {
+		end = total
+	}
+
+	return start / block_size, (end - 1) / block_size, nil
+}
+
+// ReadTransmission reads a Transmission resume file
+func ReadTransmission(reader io.Reader) (*TransmissionResume, error) {
+	dictionary, err := readDictionary(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := fields(copyDictionary(dictionary))
+	r := &TransmissionResume{}
+
+	dnd := []int{}
+	labels := []interface{}{}
+	progress := map[string]interface{}{}
+
+	steps := []error{
+		f.string(TransmissionKeyName, &r.Name),
+		f.string(TransmissionKeyDestination, &r.Destination),
+		f.string(TransmissionKeyIncompleteDir, &r.IncompleteDir),
+		f.integer(TransmissionKeyDownloaded, &r.Downloaded),
+		f.integer(TransmissionKeyUploaded, &r.Uploaded),
+		f.integer(TransmissionKeyCorrupt, &r.Corrupt),
+		f.integer(TransmissionKeyAddedDate, &r.AddedDate),
+		f.integer(TransmissionKeyDoneDate, &r.DoneDate),
+		f.integer(TransmissionKeyActivityDate, &r.ActivityDate),
+		f.integer(TransmissionKeySeedingTime, &r.SeedingTime),
+		f.integer(TransmissionKeyDownloadingTime, &r.DownloadingTime),
+		f.boolean(TransmissionKeyPaused, &r.Paused),
+		f.integerList(TransmissionKeyPriority, &r.Priority),
+		f.integerList(TransmissionKeyDND, &dnd),
+		f.list(TransmissionKeyLabels, &labels),
+		f.integer(TransmissionKeyBandwidthPriority, &r.BandwidthPriority),
+		f.dictionary(TransmissionKeyProgress, &progress),
+	}
+
+	for _, err := range steps {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, value := range dnd {
+		r.DND = append(r.DND, value != 0)
+	}
+
+	if r.Labels, err = utils.ToStringList(labels); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrorResumeCorrupted, TransmissionKeyLabels, err)
+	}
+
+	progress_fields := fields(copyDictionary(progress))
+
+	if err := progress_fields.string(TransmissionKeyBlocks, &r.Blocks); err != nil {
+		return nil, err
+	}
+
+	r.ProgressExtra = progress_fields.rest()
+	r.Extra = f.rest()
+
+	return r, nil
+}
+
+// dictionary returns the resume data as a bencode dictionary
+func (r *TransmissionResume) dictionary() map[string]interface{} {
+	dictionary := copyDictionary(r.Extra)
+	progress := copyDictionary(r.ProgressExtra)
+
+	dnd := make([]int, len(r.DND))
+
+	for index, value := range r.DND {
+		dnd[index] = fromBoolean(value)
+	}
+
+	if len(r.Blocks) > 0 {
+		progress[TransmissionKeyBlocks] = r.Blocks
+	}
+
+	values := map[string]interface{}{
+		TransmissionKeyName:              r.Name,
+		TransmissionKeyDestination:       r.Destination,
+		TransmissionKeyDownloaded:        r.Downloaded,
+		TransmissionKeyUploaded:          r.Uploaded,
+		TransmissionKeyCorrupt:           r.Corrupt,
+		TransmissionKeyAddedDate:         r.AddedDate,
+		TransmissionKeyDoneDate:          r.DoneDate,
+		TransmissionKeyActivityDate:      r.ActivityDate,
+		TransmissionKeySeedingTime:       r.SeedingTime,
+		TransmissionKeyDownloadingTime:   r.DownloadingTime,
+		TransmissionKeyPaused:            fromBoolean(r.Paused),
+		TransmissionKeyPriority:          toIntegerList(r.Priority),
+		TransmissionKeyDND:               toIntegerList(dnd),
+		TransmissionKeyLabels:            toInterfaceList(r.Labels),
+		TransmissionKeyBandwidthPriority: r.BandwidthPriority,
+		TransmissionKeyProgress:          progress,
+	}
+
+	for key, value := range values {
+		dictionary[key] = value
+	}
+
+	if len(r.IncompleteDir) > 0 {
+		dictionary[TransmissionKeyIncompleteDir] = r.IncompleteDir
+	}
+
+	return dictionary
+}
+
+// Write writes the resume data in the Transmission format
+func (r *TransmissionResume) Write(writer io.Writer) error {
+	return writeDictionary(writer, r.dictionary())
+}
+
+// pieces returns the completed pieces, from the blocks or from the legacy keys of older versions
+func (r *TransmissionResume) pieces(bc *bencode.Bencode) ([]bool, error) {
+	piece_count := len(bc.Info.Pieces)
+
+	switch r.Blocks {
+	case TransmissionBlocksAll:
+		return allSet(piece_count, true), nil
+	case TransmissionBlocksNone:
+		return allSet(piece_count, false), nil
+	case "":
+		if have, _ := r.ProgressExtra[TransmissionKeyHave].(string); have == TransmissionBlocksAll {
+			return allSet(piece_count, true), nil
+		}
+
+		if bitfield, ok := r.ProgressExtra[TransmissionKeyBitfield].(string); ok {
+			return unpackBitfield(bitfield, piece_count)
+		}
+
+		return allSet(piece_count, false), nil
+	}
+
+	block_count, block_size := transmissionBlocks(bc)
+	blocks, err := unpackBitfield(r.Blocks, block_count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pieces := make([]bool, piece_count)
+
+	for piece := range pieces {
+		first, last, err := pieceBlocks(bc, piece, block_size)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pieces[piece] = isAllSet(blocks[first:last+1], true)
+	}
+
+	return pieces, nil
+}
+
+// State converts the resume data
+func (r *TransmissionResume) State(bc *bencode.Bencode) (ResumeState, error) {
+	if r.InfoHash != [20]byte{} && r.InfoHash != bc.InfoHash {
+		return ResumeState{}, fmt.Errorf("%w: %x (expected %x)", ErrorInfoHashMismatch, r.InfoHash, bc.InfoHash)
+	}
+
+	pieces, err := r.pieces(bc)
+
+	if err != nil {
+		return ResumeState{}, err
+	}
+
+	if err := checkFiles(bc, len(r.Priority)); err != nil {
+		return ResumeState{}, err
+	}
+	if err := checkFiles(bc, len(r.DND)); err != nil {
+		return ResumeState{}, err
+	}
+
+	state := ResumeState{
+		InfoHash:      bc.InfoHash,
+		Name:          r.Name,
+		SavePath:      r.Destination,
+		Pieces:        pieces,
+		Trackers:      torrentTrackers(bc),
+		Uploaded:      r.Uploaded,
+		Downloaded:    r.Downloaded,
+		AddedTime:     r.AddedDate,
+		CompletedTime: r.DoneDate,
+		Paused:        r.Paused,
+		Labels:        r.Labels,
+	}
+
+	if len(state.Name) == 0 {
+		state.Name = bc.Info.DirectoryName
+	}
+
+	if len(r.Priority) > 0 || len(r.DND) > 0 {
+		state.FilePriorities = make([]int, len(bc.Info.Files))
+
+		for index := range state.FilePriorities {
+			state.FilePriorities[index] = PriorityNormal
+
+			if index < len(r.Priority) {
+				switch {
+				case r.Priority[index] < TransmissionPriorityNormal:
+					state.FilePriorities[index] = PriorityLow
+				case r.Priority[index] > TransmissionPriorityNormal:
+					state.FilePriorities[index] = PriorityHigh
+				}
+			}
+
+			if index < len(r.DND) && r.DND[index] {
+				state.FilePriorities[index] = PriorityDontDownload
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// TransmissionFromState converts a resume state to the Transmission format
+//
+// The completed pieces are converted to the blocks of the torrent
+func TransmissionFromState(state ResumeState, bc *bencode.Bencode) (*TransmissionResume, error) {
+	if state.InfoHash != bc.InfoHash {
+		return nil, fmt.Errorf("%w: %x (expected %x)", ErrorInfoHashMismatch, state.InfoHash, bc.InfoHash)
+	}
+
+	if err := checkPieces(bc, state.Pieces); err != nil {
+		return nil, err
+	}
+	if err := checkFiles(bc, len(state.FilePriorities)); err != nil {
+		return nil, err
+	}
+
+	r := &TransmissionResume{
+		InfoHash:    state.InfoHash,
+		Name:        state.Name,
+		Destination: state.SavePath,
+		Downloaded:  state.Downloaded,
+		Uploaded:    state.Uploaded,
+		AddedDate:   state.AddedTime,
+		DoneDate:    state.CompletedTime,
+		Paused:      state.Paused,
+		Labels:      state.Labels,
+	}
+
+	for _, priority := range state.FilePriorities {
+		transmission_priority := TransmissionPriorityNormal
+
+		switch {
+		case priority < PriorityNormal:
+			transmission_priority = TransmissionPriorityLow
+		case priority > PriorityNormal:
+			transmission_priority = TransmissionPriorityHigh
+		}
+
+		r.Priority = append(r.Priority, transmission_priority)
+		r.DND = append(r.DND, priority == PriorityDontDownload)
+	}
+
+	switch {
+	case isAllSet(state.Pieces, true):
+		r.Blocks = TransmissionBlocksAll
+	case isAllSet(state.Pieces, false):
+		r.Blocks = TransmissionBlocksNone
+	default:
+		block_count, block_size := transmissionBlocks(bc)
+		blocks := allSet(block_count, true)
+
+		// a block is complete only if all the pieces it overlaps are complete
+		for piece, complete := range state.Pieces {
+			if complete {
+				continue
+			}
+
+			first, last, err := pieceBlocks(bc, piece, block_size)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for block := first; block <= last; block++ {
+				blocks[block] = false
+			}
+		}
+
+		r.Blocks = packBitfield(blocks)
+	}
+
+	return r, nil
+}
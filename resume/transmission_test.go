@@ -0,0 +1,179 @@
+package resume
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+func TestTransmissionRoundTrip(t *testing.T) {
+	tests := []TransmissionResume{
+		{
+			Name:              "archlinux.iso",
+			Destination:       "/downloads",
+			IncompleteDir:     "/incomplete",
+			Downloaded:        2000,
+			Uploaded:          1000,
+			Corrupt:           16384,
+			AddedDate:         1600000000,
+			DoneDate:          1600000020,
+			ActivityDate:      1600000030,
+			SeedingTime:       10,
+			DownloadingTime:   20,
+			Paused:            true,
+			Priority:          []int{TransmissionPriorityLow, TransmissionPriorityHigh},
+			DND:               []bool{true, false},
+			Labels:            []string{"linux"},
+			BandwidthPriority: 1,
+			Blocks:            "\xff\x80",
+			ProgressExtra:     map[string]interface{}{"time-checked": 1600000040},
+			Extra:             map[string]interface{}{"group": "isos"},
+		},
+		{
+			Priority: []int{},
+			DND:      []bool{},
+			Labels:   []string{},
+			Blocks:   TransmissionBlocksAll,
+		},
+	}
+
+	for index, test := range tests {
+		buffer := bytes.Buffer{}
+
+		if err := test.Write(&buffer); err != nil {
+			t.Errorf("test %d: failed to write: %v", index, err)
+			continue
+		}
+
+		r, err := ReadTransmission(&buffer)
+
+		if err != nil {
+			t.Errorf("test %d: failed to read: %v", index, err)
+			continue
+		}
+
+		if len(test.DND) == 0 {
+			r.DND = test.DND
+		}
+		if len(test.Priority) == 0 {
+			r.Priority = test.Priority
+		}
+		if len(test.Labels) == 0 {
+			r.Labels = test.Labels
+		}
+
+		if !reflect.DeepEqual(*r, test) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, test, *r)
+		}
+	}
+}
+
+func TestTransmissionState(t *testing.T) {
+	arch := loadTorrent(t, "../.test_files/arch.torrent")
+	minecraft := loadTorrent(t, "../.test_files/minecraft.torrent")
+
+	some_pieces := make([]bool, len(arch.Info.Pieces))
+	some_pieces[0] = true
+	some_pieces[len(some_pieces)-1] = true
+
+	states := []ResumeState{
+		{
+			InfoHash:       arch.InfoHash,
+			Name:           arch.Info.DirectoryName,
+			SavePath:       "/downloads",
+			Pieces:         some_pieces,
+			FilePriorities: []int{PriorityHigh},
+			Uploaded:       10,
+			Downloaded:     20,
+			AddedTime:      1600000000,
+			Paused:         true,
+			Labels:         []string{"linux"},
+		},
+		{
+			InfoHash: arch.InfoHash,
+			Name:     arch.Info.DirectoryName,
+			Pieces:   allSet(len(arch.Info.Pieces), true),
+		},
+		{
+			InfoHash:       minecraft.InfoHash,
+			Name:           minecraft.Info.DirectoryName,
+			Pieces:         append(allSet(len(minecraft.Info.Pieces)-1, true), false),
+			FilePriorities: []int{PriorityDontDownload, PriorityLow, PriorityNormal},
+		},
+	}
+
+	for index, state := range states {
+		bc := arch
+
+		if state.InfoHash == minecraft.InfoHash {
+			bc = minecraft
+		}
+
+		r, err := TransmissionFromState(state, bc)
+
+		if err != nil {
+			t.Errorf("test %d: failed to convert: %v", index, err)
+			continue
+		}
+
+		converted, err := r.State(bc)
+
+		if err != nil {
+			t.Errorf("test %d: failed to convert back: %v", index, err)
+			continue
+		}
+
+		state.Trackers = converted.Trackers
+
+		if !reflect.DeepEqual(converted, state) {
+			t.Errorf("test %d: expected [%+v] | [%+v] output", index, state, converted)
+		}
+	}
+
+	// the arch pieces are made of 32 blocks, one missing block makes the piece incomplete
+	block_count, _ := transmissionBlocks(arch)
+	blocks := allSet(block_count, true)
+	blocks[47] = false
+
+	pieces, err := (&TransmissionResume{Blocks: packBitfield(blocks)}).pieces(arch)
+
+	if err != nil {
+		t.Fatalf("failed to read the blocks: %v", err)
+	}
+
+	if pieces[1] || !pieces[0] || !pieces[2] {
+		t.Errorf("expected [true false true] | %v pieces", pieces[:3])
+	}
+
+	if _, err := TransmissionFromState(ResumeState{InfoHash: arch.InfoHash}, arch); !errors.Is(err, ErrorPieceCountMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorPieceCountMismatch, err)
+	}
+
+	if _, err := (&TransmissionResume{Blocks: "\xff"}).State(arch); !errors.Is(err, ErrorResumeCorrupted) {
+		t.Errorf("expected [%v] | [%v] error", ErrorResumeCorrupted, err)
+	}
+}
+
+func TestTransmissionPiecesAfterEnd(t *testing.T) {
+	// four pieces declared for 100 bytes of data
+	bc := &bencode.Bencode{
+		Info: bencode.Info{
+			PieceLength: 16384,
+			Pieces:      make([]bencode.Piece, 4),
+			Files:       []bencode.File{{Length: 100, Path: "a"}},
+		},
+	}
+
+	if _, err := (&TransmissionResume{Blocks: "\x80"}).State(bc); !errors.Is(err, ErrorPieceCountMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorPieceCountMismatch, err)
+	}
+
+	state := ResumeState{InfoHash: bc.InfoHash, Pieces: []bool{true, false, false, false}}
+
+	if _, err := TransmissionFromState(state, bc); !errors.Is(err, ErrorPieceCountMismatch) {
+		t.Errorf("expected [%v] | [%v] error", ErrorPieceCountMismatch, err)
+	}
+}
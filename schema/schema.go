@@ -0,0 +1,233 @@
+// Package schema provide a declarative validation of bencoded documents: types,
+// required and extra keys, integer ranges, string and list lengths, string patterns,
+// the schema of the list elements and alternative schemas
+//
+// A schema is built in Go or loaded from a JSON or bencoded schema file:
+//
+//	{
+//		"type": "dictionary",
+//		"required": ["id"],
+//		"keys": {
+//			"id": {"type": "string", "min-length": 20, "max-length": 20},
+//			"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+//		},
+//		"extra": {}
+//	}
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+var (
+	ErrorInvalidSchema = errors.New("invalid schema")
+)
+
+// Type is the type of a bencoded element
+type Type string
+
+const (
+	TypeAny        Type = ""
+	TypeString     Type = "string"
+	TypeInteger    Type = "integer"
+	TypeList       Type = "list"
+	TypeDictionary Type = "dictionary"
+)
+
+// Schema describes the bencoded elements accepted at a position of a document
+//
+// The constraints of another type than Type are ignored, MinLength and MaxLength
+// count the bytes of a string, the elements of a list or the keys of a dictionary
+type Schema struct {
+	Type           Type               `json:"type,omitempty"`
+	Minimum        *int               `json:"minimum,omitempty"`         // integers
+	Maximum        *int               `json:"maximum,omitempty"`         // integers
+	MinLength      *int               `json:"min-length,omitempty"`      // strings, lists and dictionaries
+	MaxLength      *int               `json:"max-length,omitempty"`      // strings, lists and dictionaries
+	LengthMultiple int                `json:"length-multiple,omitempty"` // strings, 0 for any length
+	Pattern        string             `json:"pattern,omitempty"`         // strings, a regexp matching the whole string
+	Elements       *Schema            `json:"elements,omitempty"`        // lists, nil for any element
+	Keys           map[string]*Schema `json:"keys,omitempty"`            // dictionaries
+	Required       []string           `json:"required,omitempty"`        // dictionaries
+	RequiredAny    [][]string         `json:"required-any,omitempty"`    // dictionaries, at least one key of each group
+	Extra          *Schema            `json:"extra,omitempty"`           // dictionaries, the keys not in Keys, nil forbids them with TypeDictionary
+	AnyOf          []*Schema          `json:"any-of,omitempty"`          // the element is also accepted by one of these schemas
+}
+
+// Int returns a pointer to an integer, for the bounds of a schema built in Go
+func Int(i int) *int {
+	return &i
+}
+
+// Any returns a schema accepting any element
+func Any() *Schema {
+	return &Schema{}
+}
+
+// String returns a schema accepting the strings
+func String() *Schema {
+	return &Schema{Type: TypeString}
+}
+
+// Integer returns a schema accepting the integers
+func Integer() *Schema {
+	return &Schema{Type: TypeInteger}
+}
+
+// ListOf returns a schema accepting the lists of some elements
+func ListOf(elements *Schema) *Schema {
+	return &Schema{Type: TypeList, Elements: elements}
+}
+
+// Check reports the first inconsistency of a schema: an unknown type, a bad pattern or empty bounds
+func (s *Schema) Check() error {
+	return s.check(bencode.KeyPath{})
+}
+
+// check checks a schema located by a path in its parent schemas
+func (s *Schema) check(path bencode.KeyPath) error {
+	if s == nil {
+		return fmt.Errorf("%w: %s: nil schema", ErrorInvalidSchema, path)
+	}
+
+	switch s.Type {
+	case TypeAny, TypeString, TypeInteger, TypeList, TypeDictionary:
+	default:
+		return fmt.Errorf("%w: %s: unknown type [%s]", ErrorInvalidSchema, path, s.Type)
+	}
+
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return fmt.Errorf("%w: %s: minimum %d is greater than maximum %d", ErrorInvalidSchema, path, *s.Minimum, *s.Maximum)
+	}
+
+	if s.MinLength != nil && *s.MinLength < 0 || s.MaxLength != nil && *s.MaxLength < 0 || s.LengthMultiple < 0 {
+		return fmt.Errorf("%w: %s: negative length", ErrorInvalidSchema, path)
+	}
+
+	if s.MinLength != nil && s.MaxLength != nil && *s.MinLength > *s.MaxLength {
+		return fmt.Errorf("%w: %s: min length %d is greater than max length %d", ErrorInvalidSchema, path, *s.MinLength, *s.MaxLength)
+	}
+
+	if _, err := compilePattern(s.Pattern); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrorInvalidSchema, path, err)
+	}
+
+	for _, group := range s.RequiredAny {
+		if len(group) == 0 {
+			return fmt.Errorf("%w: %s: empty required-any group", ErrorInvalidSchema, path)
+		}
+	}
+
+	if s.Elements != nil {
+		if err := s.Elements.check(path.Key("elements")); err != nil {
+			return err
+		}
+	}
+
+	for key, key_schema := range s.Keys {
+		if err := key_schema.check(path.Key("keys").Key(key)); err != nil {
+			return err
+		}
+	}
+
+	if s.Extra != nil {
+		if err := s.Extra.check(path.Key("extra")); err != nil {
+			return err
+		}
+	}
+
+	for index, alternative := range s.AnyOf {
+		if err := alternative.check(path.Key("any-of").Index(index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compilePattern compiles a pattern matching a whole string, the empty pattern is nil
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// LoadJSON reads a schema from a JSON document, the unknown keys are errors
+func LoadJSON(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	s := &Schema{}
+
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidSchema, err)
+	}
+
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LoadBencode reads a schema from a bencoded document with the keys of the JSON documents, its strings must be UTF-8
+func LoadBencode(data []byte) (*Schema, error) {
+	element, err := parser.ParseElement(bufio.NewReader(bytes.NewReader(data)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON strings are UTF-8, an invalid string would be replaced by U+FFFD and match another pattern or key
+	if err := checkUTF8(element, []interface{}{}); err != nil {
+		return nil, err
+	}
+
+	// the bencoded elements (strings, integers, lists and dictionaries) are JSON elements
+	json_data, err := json.Marshal(element)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidSchema, err)
+	}
+
+	return LoadJSON(json_data)
+}
+
+// checkUTF8 checks that the strings and the keys of a bencoded schema are UTF-8
+func checkUTF8(element interface{}, path []interface{}) error {
+	switch element := element.(type) {
+	case string:
+		if !utf8.ValidString(element) {
+			return fmt.Errorf("%w: %s is not UTF-8", ErrorInvalidSchema, parser.FormatPath(path))
+		}
+	case []interface{}:
+		for index, value := range element {
+			if err := checkUTF8(value, append(path, index)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range element {
+			if !utf8.ValidString(key) {
+				return fmt.Errorf("%w: key [%q] of %s is not UTF-8", ErrorInvalidSchema, key, parser.FormatPath(path))
+			}
+
+			if err := checkUTF8(value, append(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input *Schema
+		err   error
+	}{
+		{Any(), nil},
+		{Torrent(), nil},
+		{&Schema{Type: "float"}, ErrorInvalidSchema},
+		{&Schema{Minimum: Int(2), Maximum: Int(1)}, ErrorInvalidSchema},
+		{&Schema{MinLength: Int(-1)}, ErrorInvalidSchema},
+		{&Schema{MinLength: Int(3), MaxLength: Int(2)}, ErrorInvalidSchema},
+		{&Schema{LengthMultiple: -20}, ErrorInvalidSchema},
+		{&Schema{Pattern: "("}, ErrorInvalidSchema},
+		{&Schema{RequiredAny: [][]string{{}}}, ErrorInvalidSchema},
+		{ListOf(&Schema{Type: "float"}), ErrorInvalidSchema},
+		{&Schema{Keys: map[string]*Schema{"a": nil}}, ErrorInvalidSchema},
+		{&Schema{Extra: &Schema{Pattern: "["}}, ErrorInvalidSchema},
+		{&Schema{AnyOf: []*Schema{String(), nil}}, ErrorInvalidSchema},
+	}
+
+	for index, test := range tests {
+		if err := test.input.Check(); !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.err, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	expected := &Schema{
+		Type:     TypeDictionary,
+		Required: []string{"id"},
+		Keys: map[string]*Schema{
+			"id":   {Type: TypeString, MinLength: Int(20), MaxLength: Int(20)},
+			"port": {Type: TypeInteger, Minimum: Int(1), Maximum: Int(65535)},
+		},
+		Extra: Any(),
+	}
+
+	json_schema := `{
+		"type": "dictionary",
+		"required": ["id"],
+		"keys": {
+			"id": {"type": "string", "min-length": 20, "max-length": 20},
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+		},
+		"extra": {}
+	}`
+
+	output, err := LoadJSON([]byte(json_schema))
+
+	if err != nil || !reflect.DeepEqual(expected, output) {
+		t.Errorf("expected [%+v] | [%+v] output: %v", expected, output, err)
+	}
+
+	bencode_schema := "d5:extrade4:keysd2:idd10:max-lengthi20e10:min-lengthi20e4:type6:stringe" +
+		"4:portd7:maximumi65535e7:minimumi1e4:type7:integeree8:requiredl2:ide4:type10:dictionarye"
+
+	output, err = LoadBencode([]byte(bencode_schema))
+
+	if err != nil || !reflect.DeepEqual(expected, output) {
+		t.Errorf("expected [%+v] | [%+v] output: %v", expected, output, err)
+	}
+
+	errors_tests := []string{
+		`{"type": "float"}`,
+		`{"typo": "string"}`,
+		`{"minimum": "1"}`,
+		`{"pattern": "("}`,
+		`[]`,
+	}
+
+	for index, test := range errors_tests {
+		if _, err := LoadJSON([]byte(test)); !errors.Is(err, ErrorInvalidSchema) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, ErrorInvalidSchema, err)
+		}
+	}
+
+	if _, err := LoadBencode([]byte("d4:type5:floate")); !errors.Is(err, ErrorInvalidSchema) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidSchema, err)
+	}
+
+	// the non UTF-8 strings are not replaced by U+FFFD
+	for index, test := range []string{"d7:pattern1:\xffe", "d4:keysd1:\xffdeee", "d8:requiredl1:a1:\xffee"} {
+		if _, err := LoadBencode([]byte(test)); !errors.Is(err, ErrorInvalidSchema) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, ErrorInvalidSchema, err)
+		}
+	}
+
+	if _, err := LoadBencode([]byte("d4:type")); err == nil {
+		t.Errorf("expected an error for a corrupted bencoded schema")
+	}
+}
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"github.com/trixky/gobencode/bencode"
+)
+
+// Torrent returns the schema of a torrent file, the rules of bencode.UnmarshallAll:
+// an endpoint (announce, announce-list, url-list or httpseeds) and an info dictionary
+// with a piece length, pieces of 20 bytes, a name and files or a length
+//
+// The optional keys ignored by UnmarshallAll when malformed (comment, creation date, ...)
+// are reported, the unknown keys are allowed and url-list can be a single url (BEP 19).
+// A new schema is returned by each call
+func Torrent() *Schema {
+	strings := ListOf(String())
+
+	file := &Schema{
+		Type:     TypeDictionary,
+		Required: []string{bencode.DictionaryKeyLength, bencode.DictionaryKeyPath},
+		Keys: map[string]*Schema{
+			bencode.DictionaryKeyLength: {Type: TypeInteger, Minimum: Int(0)},
+			bencode.DictionaryKeyPath:   {Type: TypeList, MinLength: Int(1), Elements: &Schema{Type: TypeString, MinLength: Int(1)}},
+			bencode.DictionaryKeyAttr:   String(),
+		},
+		Extra: Any(),
+	}
+
+	info := &Schema{
+		Type: TypeDictionary,
+		Required: []string{
+			bencode.DictionaryKeyPieceLength,
+			bencode.DictionaryKeyPieces,
+			bencode.DictionaryKeyName,
+		},
+		RequiredAny: [][]string{{bencode.DictionaryKeyFiles, bencode.DictionaryKeyLength}},
+		Keys: map[string]*Schema{
+			bencode.DictionaryKeyPieceLength: {Type: TypeInteger, Minimum: Int(1)},
+			bencode.DictionaryKeyPieces:      {Type: TypeString, LengthMultiple: 20},
+			bencode.DictionaryKeyName:        String(),
+			bencode.DictionaryKeyFiles:       ListOf(file),
+			bencode.DictionaryKeyLength:      {Type: TypeInteger, Minimum: Int(0)},
+			bencode.DictionaryKeyAttr:        String(),
+			bencode.DictionaryKeySimilar:     ListOf(&Schema{Type: TypeString, MinLength: Int(20), MaxLength: Int(20)}),
+			bencode.DictionaryKeyCollections: ListOf(String()),
+		},
+		Extra: Any(),
+	}
+
+	return &Schema{
+		Type:     TypeDictionary,
+		Required: []string{bencode.DictionaryKeyInfo},
+		RequiredAny: [][]string{{
+			bencode.DictionaryKeyAnnounce,
+			bencode.DictionaryKeyAnnounceList,
+			bencode.DictionaryKeyUrlList,
+			bencode.DictionaryKeyHttpSeeds,
+		}},
+		Keys: map[string]*Schema{
+			bencode.DictionaryKeyAnnounce:     String(),
+			bencode.DictionaryKeyAnnounceList: ListOf(strings),
+			bencode.DictionaryKeyUrlList:      {AnyOf: []*Schema{String(), strings}}, // a single url is allowed by BEP 19
+			bencode.DictionaryKeyHttpSeeds:    strings,
+			bencode.DictionaryKeyComment:      String(),
+			bencode.DictionaryKeyCreatedBy:    String(),
+			bencode.DictionaryKeyCreationDate: Integer(),
+			bencode.DictionaryKeyEncoding:     String(),
+			bencode.DictionaryKeyInfo:         info,
+		},
+		Extra: Any(),
+	}
+}
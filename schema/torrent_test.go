@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+func TestTorrentFiles(t *testing.T) {
+	files := []string{"arch", "kubuntu", "minecraft", "ubuntu"}
+
+	for _, file := range files {
+		f, err := os.Open("../.test_files/" + file + ".torrent")
+
+		if err != nil {
+			t.Fatalf("failed to read file [%s]: %v", file, err)
+		}
+
+		element, err := parser.ParseElement(bufio.NewReader(f))
+		f.Close()
+
+		if err != nil {
+			t.Fatalf("failed to parse file [%s]: %v", file, err)
+		}
+
+		if output := Torrent().Validate(element); len(output) > 0 {
+			t.Errorf("%s: expected no violation | %v output", file, output)
+		}
+	}
+}
+
+func TestTorrent(t *testing.T) {
+	pieces := "6:pieces20:" + strings.Repeat("p", 20)
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"d8:announce3:url4:infod6:lengthi5e4:name1:a12:piece lengthi16384e" + pieces + "ee", []string{}},
+		{"d8:url-listl3:urle4:infod5:filesld6:lengthi5e4:pathl1:beee4:name1:a12:piece lengthi16384e" + pieces + "ee", []string{}},
+		{"d4:infod6:lengthi5e4:name1:a12:piece lengthi16384e" + pieces + "ee", []string{
+			". missing-key",
+		}},
+		{"d8:announce3:url4:infod4:name1:a12:piece lengthi0e6:pieces3:abcee", []string{
+			"info missing-key",
+			`info["piece length"] out-of-range`,
+			"info.pieces bad-length",
+		}},
+		{"d8:announce3:url4:infod5:filesld6:lengthi-1e4:pathleed4:pathl0:eee12:piece lengthi16384e" + pieces + "ee", []string{
+			"info.name missing-key",
+			"info.files[0].length out-of-range",
+			"info.files[0].path bad-length",
+			"info.files[1].length missing-key",
+			"info.files[1].path[0] bad-length",
+		}},
+		{"d8:announcei1e13:creation date3:now4:infoi1ee", []string{
+			"announce bad-type",
+			`["creation date"] bad-type`,
+			"info bad-type",
+		}},
+	}
+
+	for index, test := range tests {
+		element, err := parser.ParseElement(bufio.NewReader(strings.NewReader(test.input)))
+
+		if err != nil {
+			t.Fatalf("test %d: failed to parse: %v", index, err)
+		}
+
+		if output := violations(Torrent().Validate(element)); !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %q | %q output", index, test.expected, output)
+		}
+
+		// a torrent without violation is unmarshalled
+		bc := bencode.Bencode{Data: element}
+
+		if err := bc.UnmarshallAll(); (err == nil) != (len(test.expected) == 0) {
+			t.Errorf("test %d: expected a consistent UnmarshallAll error | [%v] output", index, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+// codes of the violations, stable so they can be matched by the callers
+const (
+	ViolationBadType         = "bad-type"
+	ViolationMissingKey      = "missing-key"
+	ViolationUnexpectedKey   = "unexpected-key"
+	ViolationOutOfRange      = "out-of-range"
+	ViolationBadLength       = "bad-length"
+	ViolationPatternMismatch = "pattern-mismatch"
+	ViolationInvalidSchema   = "invalid-schema"
+)
+
+// Violation is an element of a document not accepted by its schema
+type Violation struct {
+	Path    bencode.KeyPath
+	Code    string
+	Message string
+}
+
+// String returns the violation as a single line
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Path, v.Code, v.Message)
+}
+
+// validator collects the violations of a validation
+type validator struct {
+	violations []Violation
+	patterns   map[string]*regexp.Regexp
+}
+
+// add appends a violation
+func (v *validator) add(path bencode.KeyPath, code string, format string, a ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Path:    path,
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// typeOf returns the type of a parsed element, TypeAny if it is not a bencoded element
+func typeOf(element interface{}) Type {
+	switch element.(type) {
+	case string:
+		return TypeString
+	case int:
+		return TypeInteger
+	case []interface{}:
+		return TypeList
+	case map[string]interface{}, *parser.OrderedDict:
+		return TypeDictionary
+	}
+
+	return TypeAny
+}
+
+// Validate checks a tree returned by parser.ParseElement and returns every violation,
+// the dictionaries can be maps or *parser.OrderedDict
+//
+// The violations follow the document, the keys of a map in their byte order
+func (s *Schema) Validate(element interface{}) []Violation {
+	v := validator{patterns: map[string]*regexp.Regexp{}}
+	v.validate(s, element, bencode.KeyPath{})
+
+	return v.violations
+}
+
+// validate checks an element located by a path
+func (v *validator) validate(s *Schema, element interface{}, path bencode.KeyPath) {
+	if s == nil {
+		return
+	}
+
+	element_type := typeOf(element)
+
+	if element_type == TypeAny {
+		v.add(path, ViolationBadType, "[%T] is not a bencoded element", element)
+		return
+	}
+
+	if s.Type != TypeAny && s.Type != element_type {
+		v.add(path, ViolationBadType, "%s instead of %s", element_type, s.Type)
+		return
+	}
+
+	if len(s.AnyOf) > 0 && !v.validateAnyOf(s.AnyOf, element, path) {
+		return
+	}
+
+	switch element := element.(type) {
+	case string:
+		v.validateLength(s, len(element), "bytes", path)
+		v.validateString(s, element, path)
+	case int:
+		if s.Minimum != nil && element < *s.Minimum {
+			v.add(path, ViolationOutOfRange, "%d is lower than %d", element, *s.Minimum)
+		}
+
+		if s.Maximum != nil && element > *s.Maximum {
+			v.add(path, ViolationOutOfRange, "%d is greater than %d", element, *s.Maximum)
+		}
+	case []interface{}:
+		v.validateLength(s, len(element), "elements", path)
+
+		for index, list_element := range element {
+			v.validate(s.Elements, list_element, path.Index(index))
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(element))
+
+		for key := range element {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		entries := make([]parser.DictionaryEntry, len(keys))
+
+		for index, key := range keys {
+			entries[index] = parser.DictionaryEntry{Key: key, Value: element[key]}
+		}
+
+		v.validateDictionary(s, entries, path)
+	case *parser.OrderedDict:
+		v.validateDictionary(s, element.Entries, path)
+	}
+}
+
+// validateAnyOf checks an element against some alternatives, the violations of the closest
+// alternative are reported when none accepts it: of the type of the element, with the fewest violations
+func (v *validator) validateAnyOf(alternatives []*Schema, element interface{}, path bencode.KeyPath) bool {
+	var closest []Violation
+	closest_typed := false
+
+	for index, alternative := range alternatives {
+		alternative_validator := validator{patterns: v.patterns}
+		alternative_validator.validate(alternative, element, path)
+
+		if len(alternative_validator.violations) == 0 {
+			return true
+		}
+
+		typed := alternative.Type == typeOf(element)
+
+		if index == 0 || typed && !closest_typed || typed == closest_typed && len(alternative_validator.violations) < len(closest) {
+			closest, closest_typed = alternative_validator.violations, typed
+		}
+	}
+
+	v.violations = append(v.violations, closest...)
+
+	return false
+}
+
+// validateLength checks the length of a string, a list or a dictionary
+func (v *validator) validateLength(s *Schema, length int, unit string, path bencode.KeyPath) {
+	if s.MinLength != nil && length < *s.MinLength {
+		v.add(path, ViolationBadLength, "%d %s, at least %d expected", length, unit, *s.MinLength)
+	}
+
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.add(path, ViolationBadLength, "%d %s, at most %d expected", length, unit, *s.MaxLength)
+	}
+}
+
+// validateString checks the length multiple and the pattern of a string
+func (v *validator) validateString(s *Schema, element string, path bencode.KeyPath) {
+	if s.LengthMultiple > 0 && len(element)%s.LengthMultiple != 0 {
+		v.add(path, ViolationBadLength, "%d bytes is not a multiple of %d", len(element), s.LengthMultiple)
+	}
+
+	if len(s.Pattern) == 0 {
+		return
+	}
+
+	pattern, ok := v.patterns[s.Pattern]
+
+	if !ok {
+		var err error
+
+		if pattern, err = compilePattern(s.Pattern); err != nil {
+			v.add(path, ViolationInvalidSchema, "pattern [%s]: %v", s.Pattern, err)
+		}
+
+		v.patterns[s.Pattern] = pattern
+	}
+
+	if pattern != nil && !pattern.MatchString(element) {
+		v.add(path, ViolationPatternMismatch, "[%s] does not match [%s]", element, s.Pattern)
+	}
+}
+
+// validateDictionary checks the entries of a dictionary, a repeated key is checked for each value
+func (v *validator) validateDictionary(s *Schema, entries []parser.DictionaryEntry, path bencode.KeyPath) {
+	present := map[string]bool{}
+
+	for _, entry := range entries {
+		present[entry.Key] = true
+	}
+
+	v.validateLength(s, len(present), "keys", path)
+
+	for _, key := range s.Required {
+		if !present[key] {
+			v.add(path.Key(key), ViolationMissingKey, "required key is missing")
+		}
+	}
+
+	for _, group := range s.RequiredAny {
+		found := false
+
+		for _, key := range group {
+			found = found || present[key]
+		}
+
+		if !found {
+			v.add(path, ViolationMissingKey, "one of the keys %q is required", group)
+		}
+	}
+
+	for _, entry := range entries {
+		key_schema, ok := s.Keys[entry.Key]
+
+		if !ok && s.Type == TypeDictionary {
+			if key_schema = s.Extra; key_schema == nil {
+				v.add(path.Key(entry.Key), ViolationUnexpectedKey, "key is not allowed")
+				continue
+			}
+		}
+
+		v.validate(key_schema, entry.Value, path.Key(entry.Key))
+	}
+}
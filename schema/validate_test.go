@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+// parse parses a bencoded document, with ordered dictionaries or not
+func parse(t *testing.T, input string, ordered bool) interface{} {
+	element, err := parser.ParseElementWithOptions(bufio.NewReader(strings.NewReader(input)), parser.Options{OrderedDictionaries: ordered})
+
+	if err != nil {
+		t.Fatalf("failed to parse [%s]: %v", input, err)
+	}
+
+	return element
+}
+
+// violations returns the paths and codes of some violations
+func violations(report []Violation) []string {
+	output := []string{}
+
+	for _, violation := range report {
+		output = append(output, violation.Path.String()+" "+violation.Code)
+	}
+
+	return output
+}
+
+func TestValidate(t *testing.T) {
+	peer := &Schema{
+		Type:     TypeDictionary,
+		Required: []string{"id", "port"},
+		Keys: map[string]*Schema{
+			"id":   {Type: TypeString, MinLength: Int(2), MaxLength: Int(4)},
+			"ip":   {Type: TypeString, Pattern: `[0-9]+(\.[0-9]+){3}`},
+			"port": {Type: TypeInteger, Minimum: Int(1), Maximum: Int(65535)},
+		},
+	}
+
+	message := &Schema{
+		Type:        TypeDictionary,
+		RequiredAny: [][]string{{"peers", "peers6"}},
+		Keys: map[string]*Schema{
+			"peers":  {Type: TypeList, MaxLength: Int(2), Elements: peer},
+			"pieces": {Type: TypeString, LengthMultiple: 4},
+		},
+		Extra: Integer(),
+	}
+
+	tests := []struct {
+		schema   *Schema
+		input    string
+		expected []string
+	}{
+		{message, "d5:peersld2:id2:ab4:porti6881eeee", []string{}},
+		{message, "de", []string{". missing-key"}},
+		{message, "li1ee", []string{". bad-type"}},
+		{message, "d5:peersi1ee", []string{"peers bad-type"}},
+		{message, "d5:peersldee4:xtrai1e6:pieces3:abce", []string{
+			"peers[0].id missing-key",
+			"peers[0].port missing-key",
+			"pieces bad-length",
+		}},
+		{message, "d5:extra1:x5:peersld2:id1:a2:ip3:1.24:porti0eed2:id5:abcde5:otheri1e4:porti65536eedee6:peers6i1ee", []string{
+			"extra bad-type",
+			"peers bad-length",
+			"peers[0].id bad-length",
+			"peers[0].ip pattern-mismatch",
+			"peers[0].port out-of-range",
+			"peers[1].id bad-length",
+			"peers[1].other unexpected-key",
+			"peers[1].port out-of-range",
+			"peers[2].id missing-key",
+			"peers[2].port missing-key",
+		}},
+		{Any(), "d1:ali1e1:bee", []string{}},
+		{&Schema{MinLength: Int(3)}, "d1:ai1ee", []string{". bad-length"}},
+		{&Schema{Keys: map[string]*Schema{"a": String()}}, "d1:ai1e1:bi1ee", []string{"a bad-type"}},
+		{&Schema{Pattern: "("}, "1:a", []string{". invalid-schema"}},
+		{ListOf(&Schema{Pattern: "a+"}), "l1:a2:aa1:be", []string{"[2] pattern-mismatch"}},
+		{&Schema{AnyOf: []*Schema{String(), ListOf(String())}}, "l1:ae", []string{}},
+		{&Schema{AnyOf: []*Schema{String(), ListOf(String())}}, "li1ee", []string{"[0] bad-type"}},
+		{&Schema{AnyOf: []*Schema{Integer(), ListOf(String())}}, "1:a", []string{". bad-type"}},
+		{&Schema{Type: TypeString, MaxLength: Int(1), AnyOf: []*Schema{{Pattern: "a+"}}}, "2:aa", []string{". bad-length"}},
+	}
+
+	for index, test := range tests {
+		for _, ordered := range []bool{false, true} {
+			output := violations(test.schema.Validate(parse(t, test.input, ordered)))
+
+			if !reflect.DeepEqual(test.expected, output) {
+				t.Errorf("test %d (ordered %t): expected %q | %q output", index, ordered, test.expected, output)
+			}
+		}
+	}
+}
+
+func TestValidateRepeatedKeys(t *testing.T) {
+	s := &Schema{Type: TypeDictionary, Keys: map[string]*Schema{"a": Integer()}, MaxLength: Int(1)}
+	input := parse(t, "d1:ai1e1:a1:xe", true)
+
+	expected := []string{"a bad-type"}
+
+	if output := violations(s.Validate(input)); !reflect.DeepEqual(expected, output) {
+		t.Errorf("expected %q | %q output", expected, output)
+	}
+
+	if output := s.Validate(nil); len(output) != 1 || output[0].String() != ".: bad-type: [<nil>] is not a bencoded element" {
+		t.Errorf("expected a single bad-type violation | %v output", output)
+	}
+}
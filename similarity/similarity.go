@@ -0,0 +1,309 @@
+// Package similarity provide a detection of the torrents sharing identical files across a corpus
+//
+// A file is identified by its length and by the hashes of the pieces lying wholly inside it,
+// or by its v2 pieces root when the file tree of the parsed data has one (BEP 52),
+// the torrents sharing a file are grouped in clusters
+//
+// http://www.bittorrent.org/beps/bep_0038.html
+// http://www.bittorrent.org/beps/bep_0052.html
+package similarity
+
+import (
+	"sort"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+// FileKey identifies the content of a file
+type FileKey struct {
+	Length      int
+	PieceLength int
+	Offset      int    // offset of the first whole piece in the file
+	Pieces      string // hashes of the whole pieces of the file
+	PiecesRoot  string // v2 merkle root of the file, only the length is set with it
+}
+
+// FileRef is a file of a torrent of the corpus
+type FileRef struct {
+	Torrent int // index of the torrent in the corpus
+	File    int // index of the file in Info.Files
+}
+
+// SharedFile is a file found in several torrents
+type SharedFile struct {
+	Key   FileKey
+	Files []FileRef
+}
+
+// Cluster is a group of torrents sharing files, directly or through other torrents
+type Cluster struct {
+	Torrents []int        // indexes of the torrents in the corpus
+	Shared   []SharedFile // files found in at least two torrents of the cluster
+	Declared bool         // at least one link of the cluster comes from similar or collections (BEP 38)
+}
+
+// Corpus indexes the files of many torrents
+type Corpus struct {
+	UseDeclared bool // the torrents are also linked by their similar info hashes and their collections
+
+	torrents []*bencode.Bencode
+	files    map[FileKey][]FileRef
+	rooted   map[FileRef]bool // files indexed under a v2 key
+}
+
+// NewCorpus creates an empty corpus, the declared relations are used
+func NewCorpus() *Corpus {
+	return &Corpus{
+		UseDeclared: true,
+		files:       map[FileKey][]FileRef{},
+		rooted:      map[FileRef]bool{},
+	}
+}
+
+// Key returns the key of a file of a torrent, false if no piece lies wholly inside the file
+func Key(info *bencode.Info, file_index int) (FileKey, bool) {
+	first, last, err := info.FileWholePieces(file_index)
+
+	if err != nil || last < first || info.Files[file_index].IsPadding() {
+		return FileKey{}, false
+	}
+
+	file_offset, _ := info.FileOffset(file_index)
+
+	pieces := make([]byte, 0, (last-first+1)*20)
+
+	for piece_index := first; piece_index <= last; piece_index++ {
+		pieces = append(pieces, info.Pieces[piece_index][:]...)
+	}
+
+	return FileKey{
+		Length:      info.Files[file_index].Length,
+		PieceLength: info.PieceLength,
+		Offset:      first*info.PieceLength - file_offset,
+		Pieces:      string(pieces),
+	}, true
+}
+
+// RootKey returns the v2 key of a file of a torrent, false if the parsed data has no pieces root for it
+//
+// The file is looked up in the file tree of the info dictionary by its path as stored in the torrent,
+// the name is the only component of a single file torrent
+func RootKey(bc *bencode.Bencode, file_index int) (FileKey, bool) {
+	dictionary, _ := bc.Data.(map[string]interface{})
+	info_dictionary, _ := dictionary[bencode.DictionaryKeyInfo].(map[string]interface{})
+	node, _ := info_dictionary[bencode.DictionaryKeyFileTree].(map[string]interface{})
+
+	file := bc.Info.Files[file_index]
+	path := file.RawPath
+
+	if len(path) == 0 {
+		path = file.DecomposedPath
+	}
+
+	if !bc.IsMultiFile() {
+		if path = []string{bc.Info.RawName}; len(bc.Info.RawName) == 0 {
+			path = []string{bc.Info.DirectoryName}
+		}
+	}
+
+	for _, component := range path {
+		node, _ = node[component].(map[string]interface{})
+	}
+
+	// the properties of a file are under an empty key
+	properties, _ := node[""].(map[string]interface{})
+	length, _ := properties[bencode.DictionaryKeyLength].(int)
+	pieces_root, _ := properties[bencode.DictionaryKeyPiecesRoot].(string)
+
+	if len(pieces_root) != 32 || length != file.Length {
+		return FileKey{}, false
+	}
+
+	return FileKey{
+		Length:     file.Length,
+		PiecesRoot: pieces_root,
+	}, true
+}
+
+// Add indexes the files of a torrent, it returns the index of the torrent in the corpus
+//
+// The file of an hybrid torrent is indexed by its v2 key and by its v1 key, to match the v1 only torrents
+func (c *Corpus) Add(bc *bencode.Bencode) int {
+	torrent := len(c.torrents)
+	c.torrents = append(c.torrents, bc)
+
+	for file_index := range bc.Info.Files {
+		ref := FileRef{Torrent: torrent, File: file_index}
+
+		if key, ok := RootKey(bc, file_index); ok {
+			c.files[key] = append(c.files[key], ref)
+			c.rooted[ref] = true
+		}
+
+		if key, ok := Key(&bc.Info, file_index); ok {
+			c.files[key] = append(c.files[key], ref)
+		}
+	}
+
+	return torrent
+}
+
+// Len returns the number of torrents in the corpus
+func (c *Corpus) Len() int {
+	return len(c.torrents)
+}
+
+// Torrent returns a torrent of the corpus
+func (c *Corpus) Torrent(index int) *bencode.Bencode {
+	return c.torrents[index]
+}
+
+// Clusters returns the groups of at least two torrents sharing files, sorted by their first torrent
+func (c *Corpus) Clusters() []Cluster {
+	parents := make([]int, len(c.torrents))
+	declared := map[int]bool{} // roots of the sets joined by a declared relation
+
+	for index := range parents {
+		parents[index] = index
+	}
+
+	find := func(index int) int {
+		for parents[index] != index {
+			parents[index] = parents[parents[index]]
+			index = parents[index]
+		}
+
+		return index
+	}
+
+	union := func(a int, b int, is_declared bool) {
+		root_a, root_b := find(a), find(b)
+
+		if root_a != root_b {
+			parents[root_b] = root_a
+		}
+
+		if is_declared || declared[root_b] {
+			declared[root_a] = true
+		}
+	}
+
+	for _, refs := range c.files {
+		for _, ref := range refs[1:] {
+			union(refs[0].Torrent, ref.Torrent, false)
+		}
+	}
+
+	if c.UseDeclared {
+		c.unionDeclared(func(a int, b int) { union(a, b, true) })
+	}
+
+	groups := map[int][]int{}
+
+	for index := range c.torrents {
+		root := find(index)
+		groups[root] = append(groups[root], index)
+	}
+
+	shared := map[int][]SharedFile{}
+
+	for key, refs := range c.files {
+		// the files all having a v2 key are listed under it, not again under their v1 key
+		if !spansTorrents(refs) || (len(key.PiecesRoot) == 0 && c.allRooted(refs)) {
+			continue
+		}
+
+		root := find(refs[0].Torrent)
+		shared[root] = append(shared[root], SharedFile{Key: key, Files: append([]FileRef{}, refs...)})
+	}
+
+	clusters := []Cluster{}
+
+	for root, torrents := range groups {
+		if len(torrents) < 2 {
+			continue
+		}
+
+		files := shared[root]
+
+		sort.Slice(files, func(a, b int) bool {
+			if files[a].Files[0] == files[b].Files[0] {
+				// a file is under at most a v2 key and a v1 key, the v2 one first
+				return len(files[a].Key.PiecesRoot) > len(files[b].Key.PiecesRoot)
+			}
+
+			return lessRef(files[a].Files[0], files[b].Files[0])
+		})
+
+		clusters = append(clusters, Cluster{
+			Torrents: torrents,
+			Shared:   files,
+			Declared: declared[root],
+		})
+	}
+
+	sort.Slice(clusters, func(a, b int) bool {
+		return clusters[a].Torrents[0] < clusters[b].Torrents[0]
+	})
+
+	return clusters
+}
+
+// unionDeclared links the torrents declaring each other as similar, or declaring a same collection
+func (c *Corpus) unionDeclared(union func(a int, b int)) {
+	by_info_hash := map[[20]byte]int{}
+	by_collection := map[string]int{}
+
+	for index, bc := range c.torrents {
+		if _, ok := by_info_hash[bc.InfoHash]; !ok {
+			by_info_hash[bc.InfoHash] = index
+		}
+	}
+
+	for index, bc := range c.torrents {
+		for _, info_hash := range bc.Info.Similar {
+			if other, ok := by_info_hash[info_hash]; ok && other != index {
+				union(index, other)
+			}
+		}
+
+		for _, collection := range bc.Info.Collections {
+			if other, ok := by_collection[collection]; ok {
+				union(other, index)
+			} else {
+				by_collection[collection] = index
+			}
+		}
+	}
+}
+
+// allRooted checks if all the files are indexed under a v2 key
+func (c *Corpus) allRooted(refs []FileRef) bool {
+	for _, ref := range refs {
+		if !c.rooted[ref] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// spansTorrents checks if some files are in at least two torrents
+func spansTorrents(refs []FileRef) bool {
+	for _, ref := range refs[1:] {
+		if ref.Torrent != refs[0].Torrent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lessRef orders the files by torrent then by file
+func lessRef(a FileRef, b FileRef) bool {
+	if a.Torrent != b.Torrent {
+		return a.Torrent < b.Torrent
+	}
+
+	return a.File < b.File
+}
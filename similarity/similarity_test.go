@@ -0,0 +1,234 @@
+package similarity
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+// newTestTorrent returns a torrent of some file contents with pieces of 4 bytes
+func newTestTorrent(id byte, contents ...string) *bencode.Bencode {
+	bc := &bencode.Bencode{
+		InfoHash: [20]byte{id},
+		Info: bencode.Info{
+			DirectoryName: "dir",
+			PieceLength:   4,
+		},
+	}
+
+	content := ""
+
+	for index, file_content := range contents {
+		bc.Info.Files = append(bc.Info.Files, bencode.File{Length: len(file_content), Path: fmt.Sprintf("file%d", index)})
+		content += file_content
+	}
+
+	for start := 0; start < len(content); start += 4 {
+		end := start + 4
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		bc.Info.Pieces = append(bc.Info.Pieces, bencode.Piece(sha1.Sum([]byte(content[start:end]))))
+	}
+
+	return bc
+}
+
+func TestKey(t *testing.T) {
+	bc := newTestTorrent(1, "q", "AAAABBBBCC", "\x00", "xy")
+	bc.Info.Files[2].Attributes = "p"
+
+	tests := []struct {
+		file_index int
+		ok         bool
+		offset     int
+		pieces     int
+	}{
+		{0, false, 0, 0},
+		{1, true, 3, 1}, // ABBB
+		{2, false, 0, 0},
+		{3, true, 0, 1}, // the last piece of the torrent is shorter
+		{4, false, 0, 0},
+	}
+
+	for index, test := range tests {
+		key, ok := Key(&bc.Info, test.file_index)
+
+		if ok != test.ok || key.Offset != test.offset || len(key.Pieces) != test.pieces*20 {
+			t.Errorf("test %d: expected [%t %d %d] | [%t %d %d] output", index, test.ok, test.offset, test.pieces, ok, key.Offset, len(key.Pieces)/20)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	big := "AAAABBBBCCCC"
+
+	torrents := []*bencode.Bencode{
+		newTestTorrent(1, big, "12345"),
+		newTestTorrent(2, "qqqq", big),
+		newTestTorrent(3, "q", big), // not aligned on the pieces
+		newTestTorrent(4, "DDDDDDDD"),
+		newTestTorrent(5, "EEEE"),
+		newTestTorrent(6, "FFFF"),
+		newTestTorrent(7, "qqqq"),
+	}
+
+	torrents[3].Info.Similar = [][20]byte{torrents[2].InfoHash, {99}}
+	torrents[4].Info.Collections = []string{"series"}
+	torrents[5].Info.Collections = []string{"other", "series"}
+
+	corpus := NewCorpus()
+
+	for index, bc := range torrents {
+		if output := corpus.Add(bc); output != index {
+			t.Errorf("expected [%d] | [%d] output index", index, output)
+		}
+	}
+
+	big_key, _ := Key(&torrents[0].Info, 0)
+	q_key, _ := Key(&torrents[1].Info, 0)
+
+	content_cluster := Cluster{
+		Torrents: []int{0, 1, 6},
+		Shared: []SharedFile{
+			{Key: big_key, Files: []FileRef{{Torrent: 0, File: 0}, {Torrent: 1, File: 1}}},
+			{Key: q_key, Files: []FileRef{{Torrent: 1, File: 0}, {Torrent: 6, File: 0}}},
+		},
+	}
+
+	tests := []struct {
+		use_declared bool
+		expected     []Cluster
+	}{
+		{true, []Cluster{
+			content_cluster,
+			{Torrents: []int{2, 3}, Declared: true},
+			{Torrents: []int{4, 5}, Declared: true},
+		}},
+		{false, []Cluster{content_cluster}},
+	}
+
+	for index, test := range tests {
+		corpus.UseDeclared = test.use_declared
+
+		if output := corpus.Clusters(); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.expected, output)
+		}
+	}
+
+	if corpus.Len() != len(torrents) || corpus.Torrent(2) != torrents[2] {
+		t.Errorf("expected [%d] torrents | [%d] output", len(torrents), corpus.Len())
+	}
+}
+
+// withFileTree sets the parsed data of a torrent, with a v2 file tree giving some pieces roots to its files
+func withFileTree(bc *bencode.Bencode, roots ...string) *bencode.Bencode {
+	file_tree := map[string]interface{}{}
+
+	for index, root := range roots {
+		bc.Info.Files[index].RawPath = []string{bc.Info.Files[index].Path}
+
+		file_tree[bc.Info.Files[index].Path] = map[string]interface{}{
+			"": map[string]interface{}{
+				bencode.DictionaryKeyLength:     bc.Info.Files[index].Length,
+				bencode.DictionaryKeyPiecesRoot: root,
+			},
+		}
+	}
+
+	bc.Data = map[string]interface{}{
+		bencode.DictionaryKeyInfo: map[string]interface{}{
+			bencode.DictionaryKeyFiles:    []interface{}{},
+			bencode.DictionaryKeyFileTree: file_tree,
+		},
+	}
+
+	return bc
+}
+
+func TestRootKey(t *testing.T) {
+	root := string(make([]byte, 32))
+	bc := withFileTree(newTestTorrent(1, "q", "AAAA", "xy"), root, "short")
+	bc.Info.Files[2].RawPath = []string{"file2"}
+
+	single := newTestTorrent(2, "AAAA")
+	single.Info.RawName = "single"
+	single.Data = map[string]interface{}{
+		bencode.DictionaryKeyInfo: map[string]interface{}{
+			bencode.DictionaryKeyFileTree: map[string]interface{}{
+				"single": map[string]interface{}{
+					"": map[string]interface{}{bencode.DictionaryKeyLength: 4, bencode.DictionaryKeyPiecesRoot: root},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		bc         *bencode.Bencode
+		file_index int
+		ok         bool
+	}{
+		{bc, 0, true},
+		{bc, 1, false}, // the pieces root is not 32 bytes long
+		{bc, 2, false}, // not in the file tree
+		{single, 0, true},
+		{newTestTorrent(3, "AAAA"), 0, false}, // no parsed data
+	}
+
+	for index, test := range tests {
+		key, ok := RootKey(test.bc, test.file_index)
+
+		if ok != test.ok || (ok && (key.PiecesRoot != root || key.Length != test.bc.Info.Files[test.file_index].Length)) {
+			t.Errorf("test %d: expected [%t] | [%t %+v] output", index, test.ok, ok, key)
+		}
+	}
+}
+
+func TestClustersPiecesRoot(t *testing.T) {
+	root_a := string(append(make([]byte, 31), 'a'))
+	root_b := string(append(make([]byte, 31), 'b'))
+	root_c := string(append(make([]byte, 31), 'c'))
+
+	torrents := []*bencode.Bencode{
+		withFileTree(newTestTorrent(1, "q", "AAAABBBB"), root_a, root_b), // the v1 pieces are not aligned on the files
+		withFileTree(newTestTorrent(2, "AAAABBBB", "q"), root_b, root_a),
+		newTestTorrent(3, "AAAABBBB"), // v1 only
+		withFileTree(newTestTorrent(4, "CCCC"), root_c),
+		withFileTree(newTestTorrent(5, "CCCC"), root_c), // shared under both keys, listed once
+	}
+
+	corpus := NewCorpus()
+
+	for _, bc := range torrents {
+		corpus.Add(bc)
+	}
+
+	a_key, _ := RootKey(torrents[0], 0)
+	b_key, _ := RootKey(torrents[0], 1)
+	c_key, _ := RootKey(torrents[3], 0)
+	v1_key, _ := Key(&torrents[2].Info, 0)
+
+	expected := []Cluster{
+		{
+			Torrents: []int{0, 1, 2},
+			Shared: []SharedFile{
+				{Key: a_key, Files: []FileRef{{Torrent: 0, File: 0}, {Torrent: 1, File: 1}}},
+				{Key: b_key, Files: []FileRef{{Torrent: 0, File: 1}, {Torrent: 1, File: 0}}},
+				{Key: v1_key, Files: []FileRef{{Torrent: 1, File: 0}, {Torrent: 2, File: 0}}},
+			},
+		},
+		{
+			Torrents: []int{3, 4},
+			Shared:   []SharedFile{{Key: c_key, Files: []FileRef{{Torrent: 3, File: 0}, {Torrent: 4, File: 0}}}},
+		},
+	}
+
+	if output := corpus.Clusters(); !reflect.DeepEqual(output, expected) {
+		t.Errorf("expected %+v | %+v output", expected, output)
+	}
+}
@@ -0,0 +1,211 @@
+package torrentfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileInfo describes a file or a directory of a torrent, the torrents have no modification time
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+// dir is an opened directory of a torrent
+type dir struct {
+	info    *fileInfo
+	entries []*node
+	fsys    *FS
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir returns the next entries of the directory, all of them if count <= 0
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if count > 0 && len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if count <= 0 || count > len(d.entries) {
+		count = len(d.entries)
+	}
+
+	entries := make([]fs.DirEntry, count)
+
+	for index, n := range d.entries[:count] {
+		entries[index] = fs.FileInfoToDirEntry(d.fsys.fileInfo(n))
+	}
+
+	d.entries = d.entries[count:]
+
+	return entries, nil
+}
+
+// file is an opened file of a torrent
+//
+// Without verification the bytes are read from the local file, with verification they are
+// copied from the checked pieces, the last piece is kept for the sequential reads
+type file struct {
+	name   string
+	info   *fileInfo
+	fsys   *FS
+	offset int      // offset of the file in the concatenation of all the files
+	local  *os.File // nil with verification
+
+	position    int64
+	piece_index int
+	piece       []byte
+	mutex       sync.Mutex
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Close() error {
+	if f.local != nil {
+		return f.local.Close()
+	}
+
+	return nil
+}
+
+// Read reads the file from the current position
+func (f *file) Read(p []byte) (int, error) {
+	f.mutex.Lock()
+	position := f.position
+	f.mutex.Unlock()
+
+	n, err := f.ReadAt(p, position)
+
+	f.mutex.Lock()
+	f.position += int64(n)
+	f.mutex.Unlock()
+
+	if err == io.EOF && n > 0 {
+		return n, nil
+	}
+
+	return n, err
+}
+
+// Seek moves the current position
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.position
+	case io.SeekEnd:
+		offset += f.info.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.position = offset
+
+	return offset, nil
+}
+
+// ReadAt reads the file from an offset
+func (f *file) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset >= f.info.size {
+		return 0, io.EOF
+	}
+
+	n := len(p)
+
+	if remaining := f.info.size - offset; int64(n) > remaining {
+		n = int(remaining)
+	}
+
+	var err error
+
+	if f.local != nil {
+		if _, err = f.local.ReadAt(p[:n], offset); err == io.EOF {
+			err = io.ErrUnexpectedEOF // the local file is shorter than in the torrent
+		}
+	} else {
+		err = f.readVerified(p[:n], f.offset+int(offset))
+	}
+
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// readVerified copies bytes from the checked pieces, position is in the concatenation of all the files
+func (f *file) readVerified(p []byte, position int) error {
+	info := f.fsys.info
+
+	for len(p) > 0 {
+		piece_index := position / info.PieceLength
+		piece, err := f.verifiedPiece(piece_index)
+
+		if err != nil {
+			return err
+		}
+
+		copied := copy(p, piece[position-piece_index*info.PieceLength:])
+		p = p[copied:]
+		position += copied
+	}
+
+	return nil
+}
+
+// verifiedPiece reads and checks a piece, the last one is cached
+func (f *file) verifiedPiece(piece_index int) ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.piece_index == piece_index {
+		return f.piece, nil
+	}
+
+	piece, err := f.fsys.info.ReadPiece(f.fsys.root, piece_index)
+
+	if err == nil {
+		err = f.fsys.info.VerifyPiece(piece_index, piece)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.piece_index = piece_index
+	f.piece = piece
+
+	return piece, nil
+}
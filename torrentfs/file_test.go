@@ -0,0 +1,128 @@
+package torrentfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+func TestReadVerified(t *testing.T) {
+	root := t.TempDir()
+	info := newTestInfo(t, root, 4, test_files)
+
+	// the second piece (aaaa) is corrupted, the first and the third are not
+	if err := os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("aaaaXaaaaa"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	fsys, err := New(info, root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	tests := []struct {
+		verify   bool
+		offset   int64
+		length   int
+		expected string
+		err      error
+	}{
+		{false, 0, 10, "aaaaXaaaaa", nil},
+		{true, 0, 4, "aaaa", nil},
+		{true, 8, 2, "aa", nil},
+		{true, 2, 4, "", bencode.ErrorPieceHashMismatch},
+		{true, 8, 4, "aa", io.EOF},
+		{true, 10, 1, "", io.EOF},
+	}
+
+	for index, test := range tests {
+		fsys.Verify = test.verify
+		f, err := fsys.Open("dir/a.txt")
+
+		if err != nil {
+			t.Fatalf("test %d: failed to open: %v", index, err)
+		}
+
+		data := make([]byte, test.length)
+		n, err := f.(io.ReaderAt).ReadAt(data, test.offset)
+		f.Close()
+
+		if !errors.Is(err, test.err) || string(data[:n]) != test.expected {
+			t.Errorf("test %d: expected [%s] [%v] | [%s] [%v] output", index, test.expected, test.err, data[:n], err)
+		}
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	root := t.TempDir()
+	info := newTestInfo(t, root, 4, test_files)
+
+	// the fifth piece is shared by b.txt and run.sh
+	if err := os.Remove(filepath.Join(root, "dir", "sub", "deep", "run.sh")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	fsys, err := New(info, root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	if _, err := fsys.Open("dir/sub/deep/run.sh"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected [%v] | [%v] output", fs.ErrNotExist, err)
+	}
+
+	fsys.Verify = true
+
+	if _, err := fs.ReadFile(fsys, "dir/sub/b.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected [%v] | [%v] output", fs.ErrNotExist, err)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := New(newTestInfo(t, root, 4, test_files), root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	f, err := fsys.Open("dir/sub/deep/run.sh")
+
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	defer f.Close()
+
+	tests := []struct {
+		offset   int64
+		whence   int
+		expected string
+	}{
+		{2, io.SeekStart, "/bin/sh"},
+		{-2, io.SeekEnd, "sh"},
+		{-4, io.SeekCurrent, "n/sh"},
+	}
+
+	for index, test := range tests {
+		if _, err := f.(io.Seeker).Seek(test.offset, test.whence); err != nil {
+			t.Errorf("test %d: failed to seek: %v", index, err)
+			continue
+		}
+
+		if data, err := io.ReadAll(f); err != nil || string(data) != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output: %v", index, test.expected, data, err)
+		}
+	}
+
+	if _, err := f.(io.Seeker).Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected [%v] | [%v] output", fs.ErrInvalid, err)
+	}
+}
@@ -0,0 +1,228 @@
+// Package torrentfs provide an io/fs view of the content of a torrent stored in a download directory
+package torrentfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+var (
+	ErrorPathConflict = errors.New("path conflict")
+)
+
+// node is a file or a directory of the logical tree of a torrent
+type node struct {
+	name       string
+	file_index int // -1 for a directory
+	children   map[string]*node
+}
+
+// isDir checks if the node is a directory
+func (n *node) isDir() bool {
+	return n.file_index < 0
+}
+
+// sortedChildren returns the children of a directory sorted by name
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.children))
+
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+
+	sort.Slice(children, func(a, b int) bool {
+		return children[a].name < children[b].name
+	})
+
+	return children
+}
+
+// FS is the file tree of a torrent (DirectoryName/DecomposedPath) read from a download directory
+//
+// The padding files are not part of the tree, the files are read at their LocalPath
+type FS struct {
+	Verify bool // the pieces are checked against Info.Pieces before their bytes are returned
+
+	info    *bencode.Info
+	root    string
+	offsets []int
+	tree    *node
+}
+
+// New creates the file tree of a torrent downloaded in a directory, unsafe paths are rejected
+func New(info *bencode.Info, root string) (*FS, error) {
+	if info.PieceLength <= 0 {
+		return nil, fmt.Errorf("%w: %d", bencode.ErrorInvalidPieceLength, info.PieceLength)
+	}
+
+	fsys := &FS{
+		info:    info,
+		root:    root,
+		offsets: info.FileOffsets(),
+		tree:    &node{name: ".", file_index: -1, children: map[string]*node{}},
+	}
+
+	for index := range info.Files {
+		file := &info.Files[index]
+
+		if file.IsPadding() {
+			continue
+		}
+
+		components, err := bencode.SanitizePath(file.PathComponents(), bencode.PathPolicyReject)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fsys.insert(components, index); err != nil {
+			return nil, err
+		}
+	}
+
+	return fsys, nil
+}
+
+// insert adds a file to the tree, its parent directories are created if needed
+func (fsys *FS) insert(components []string, file_index int) error {
+	current := fsys.tree
+
+	for position, component := range components {
+		child, ok := current.children[component]
+
+		if position == len(components)-1 {
+			if ok {
+				return fmt.Errorf("%w: [%s] declared twice", ErrorPathConflict, strings.Join(components, "/"))
+			}
+
+			current.children[component] = &node{name: component, file_index: file_index}
+
+			return nil
+		}
+
+		if !ok {
+			child = &node{name: component, file_index: -1, children: map[string]*node{}}
+			current.children[component] = child
+		} else if !child.isDir() {
+			return fmt.Errorf("%w: [%s] is a file and a directory", ErrorPathConflict, strings.Join(components[:position+1], "/"))
+		}
+
+		current = child
+	}
+
+	return nil
+}
+
+// lookup returns the node of a path
+func (fsys *FS) lookup(operation string, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: operation, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := fsys.tree
+
+	if name == "." {
+		return current, nil
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		child, ok := current.children[component]
+
+		if !ok {
+			return nil, &fs.PathError{Op: operation, Path: name, Err: fs.ErrNotExist}
+		}
+
+		current = child
+	}
+
+	return current, nil
+}
+
+// Open opens a file or a directory of the torrent
+func (fsys *FS) Open(name string) (fs.File, error) {
+	n, err := fsys.lookup("open", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n.isDir() {
+		return &dir{info: fsys.fileInfo(n), entries: n.sortedChildren(), fsys: fsys}, nil
+	}
+
+	f := &file{
+		name:        name,
+		info:        fsys.fileInfo(n),
+		fsys:        fsys,
+		offset:      fsys.offsets[n.file_index],
+		piece_index: -1,
+	}
+
+	if !fsys.Verify {
+		local_path, err := fsys.info.Files[n.file_index].LocalPath(fsys.root)
+
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		if f.local, err = os.Open(local_path); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+
+	return f, nil
+}
+
+// Stat returns the description of a file or a directory of the torrent
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	n, err := fsys.lookup("stat", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fsys.fileInfo(n), nil
+}
+
+// ReadDir returns the entries of a directory of the torrent sorted by name
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := fsys.lookup("readdir", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries := []fs.DirEntry{}
+
+	for _, child := range n.sortedChildren() {
+		entries = append(entries, fs.FileInfoToDirEntry(fsys.fileInfo(child)))
+	}
+
+	return entries, nil
+}
+
+// fileInfo returns the description of a node
+func (fsys *FS) fileInfo(n *node) *fileInfo {
+	if n.isDir() {
+		return &fileInfo{name: n.name, mode: fs.ModeDir | 0555}
+	}
+
+	file := &fsys.info.Files[n.file_index]
+	mode := fs.FileMode(0444)
+
+	if strings.ContainsRune(file.Attributes, bencode.FileAttributeExecutable) {
+		mode |= 0111
+	}
+
+	return &fileInfo{name: n.name, size: int64(file.Length), mode: mode}
+}
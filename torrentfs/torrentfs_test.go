@@ -0,0 +1,232 @@
+package torrentfs
+
+import (
+	"crypto/sha1"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+type testFile struct {
+	path       []string
+	content    string
+	attributes string
+}
+
+// newTestInfo returns the info of a multi file torrent, the files are written in a directory
+func newTestInfo(t *testing.T, root string, piece_length int, files []testFile) *bencode.Info {
+	info := &bencode.Info{
+		DirectoryName: "dir",
+		PieceLength:   piece_length,
+	}
+
+	content := ""
+
+	for _, test_file := range files {
+		file := bencode.File{
+			Length:         len(test_file.content),
+			Path:           strings.Join(test_file.path, "/"),
+			DecomposedPath: test_file.path,
+			RawPath:        test_file.path,
+			CompletePath:   "dir/" + strings.Join(test_file.path, "/"),
+			Attributes:     test_file.attributes,
+		}
+
+		info.Files = append(info.Files, file)
+		content += test_file.content
+
+		if file.IsPadding() {
+			continue
+		}
+
+		local_path := filepath.Join(append([]string{root, "dir"}, test_file.path...)...)
+
+		if err := os.MkdirAll(filepath.Dir(local_path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(local_path, []byte(test_file.content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	for start := 0; start < len(content); start += piece_length {
+		end := start + piece_length
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		info.Pieces = append(info.Pieces, bencode.Piece(sha1.Sum([]byte(content[start:end]))))
+	}
+
+	return info
+}
+
+var test_files = []testFile{
+	{path: []string{"a.txt"}, content: "aaaaaaaaaa"},
+	{path: []string{".pad", "2"}, content: "\x00\x00", attributes: "p"},
+	{path: []string{"sub", "b.txt"}, content: "bbbbbbb"},
+	{path: []string{"sub", "empty"}, content: ""},
+	{path: []string{"sub", "deep", "run.sh"}, content: "#!/bin/sh", attributes: "x"},
+}
+
+func TestFS(t *testing.T) {
+	root := t.TempDir()
+	info := newTestInfo(t, root, 4, test_files)
+
+	for _, verify := range []bool{false, true} {
+		fsys, err := New(info, root)
+
+		if err != nil {
+			t.Fatalf("failed to create fs: %v", err)
+		}
+
+		fsys.Verify = verify
+
+		if err := fstest.TestFS(fsys, "dir/a.txt", "dir/sub/b.txt", "dir/sub/empty", "dir/sub/deep/run.sh"); err != nil {
+			t.Errorf("verify %t: %v", verify, err)
+		}
+
+		// the padding files are not part of the tree
+		if _, err := fsys.Stat("dir/.pad"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("verify %t: expected [%v] | [%v] output", verify, fs.ErrNotExist, err)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := New(newTestInfo(t, root, 4, test_files), root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		size     int64
+		mode     fs.FileMode
+		expected error
+	}{
+		{".", 0, fs.ModeDir | 0555, nil},
+		{"dir/a.txt", 10, 0444, nil},
+		{"dir/sub", 0, fs.ModeDir | 0555, nil},
+		{"dir/sub/deep/run.sh", 9, 0555, nil},
+		{"dir/missing", 0, 0, fs.ErrNotExist},
+		{"dir/../dir", 0, 0, fs.ErrInvalid},
+	}
+
+	for index, test := range tests {
+		stat, err := fsys.Stat(test.name)
+
+		if !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+			continue
+		}
+
+		if err == nil && (stat.Size() != test.size || stat.Mode() != test.mode) {
+			t.Errorf("test %d: expected [%d %v] | [%d %v] output", index, test.size, test.mode, stat.Size(), stat.Mode())
+		}
+	}
+
+	entries, err := fsys.ReadDir("dir/sub")
+	names := []string{}
+
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	if expected := "b.txt deep empty"; err != nil || strings.Join(names, " ") != expected {
+		t.Errorf("expected [%s] | [%s] output: %v", expected, strings.Join(names, " "), err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		files    []testFile
+		expected error
+	}{
+		{[]testFile{{path: []string{"a"}}, {path: []string{"a"}}}, ErrorPathConflict},
+		{[]testFile{{path: []string{"a"}}, {path: []string{"a", "b"}}}, ErrorPathConflict},
+		{[]testFile{{path: []string{"a", "b"}}, {path: []string{"a"}}}, ErrorPathConflict},
+		{[]testFile{{path: []string{"..", "b"}}}, bencode.ErrorUnsafePath},
+		{[]testFile{{path: []string{"a", "b"}}, {path: []string{"a", "c"}}}, nil},
+	}
+
+	for index, test := range tests {
+		info := &bencode.Info{DirectoryName: "dir", PieceLength: 4}
+
+		for _, test_file := range test.files {
+			info.Files = append(info.Files, bencode.File{
+				Path:           strings.Join(test_file.path, "/"),
+				DecomposedPath: test_file.path,
+				CompletePath:   "dir/" + strings.Join(test_file.path, "/"),
+			})
+		}
+
+		if _, err := New(info, t.TempDir()); !errors.Is(err, test.expected) {
+			t.Errorf("test %d: expected [%v] | [%v] output", index, test.expected, err)
+		}
+	}
+
+	if _, err := New(&bencode.Info{}, t.TempDir()); !errors.Is(err, bencode.ErrorInvalidPieceLength) {
+		t.Errorf("expected [%v] | [%v] output", bencode.ErrorInvalidPieceLength, err)
+	}
+}
+
+func TestSingleFile(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(root, "file.bin"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	hash := sha1.Sum([]byte("content"))
+	info := &bencode.Info{
+		DirectoryName: "file.bin",
+		PieceLength:   16,
+		Pieces:        []bencode.Piece{hash},
+		Files:         []bencode.File{{Length: 7, Path: "file.bin", CompletePath: "file.bin"}},
+	}
+
+	fsys, err := New(info, root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	fsys.Verify = true
+
+	if content, err := fs.ReadFile(fsys, "file.bin"); err != nil || string(content) != "content" {
+		t.Errorf("expected [content] | [%s] output: %v", content, err)
+	}
+}
+
+func TestFileServer(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := New(newTestInfo(t, root, 4, test_files), root)
+
+	if err != nil {
+		t.Fatalf("failed to create fs: %v", err)
+	}
+
+	fsys.Verify = true
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/dir/sub/b.txt", nil)
+	request.Header.Set("Range", "bytes=2-4")
+	http.FileServer(http.FS(fsys)).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusPartialContent || recorder.Body.String() != "bbb" {
+		t.Errorf("expected [206] [bbb] | [%d] [%s] output", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,154 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/trixky/gobencode/parser"
+)
+
+const hex_digits = "0123456789ABCDEF"
+
+// httpAnnouncer announces to http and https trackers
+//
+// http://www.bittorrent.org/beps/bep_0003.html
+type httpAnnouncer struct {
+	client *Client
+}
+
+// escapeBytes percent-encodes raw bytes, keeping only the unreserved characters
+//
+// https://www.rfc-editor.org/rfc/rfc3986#section-2.3
+func escapeBytes(data []byte) string {
+	var builder strings.Builder
+
+	for _, b := range data {
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			builder.WriteByte(b)
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(hex_digits[b>>4])
+			builder.WriteByte(hex_digits[b&0x0f])
+		}
+	}
+
+	return builder.String()
+}
+
+// buildAnnounceUrl adds the announce parameters to the tracker url
+//
+// the url is built by hand because url.Values would escape info_hash and peer_id a second time
+func buildAnnounceUrl(tracker string, request AnnounceRequest) string {
+	parameters := []string{
+		"info_hash=" + escapeBytes(request.InfoHash[:]),
+		"peer_id=" + escapeBytes(request.PeerID[:]),
+		"port=" + strconv.Itoa(request.Port),
+		"uploaded=" + strconv.Itoa(request.Uploaded),
+		"downloaded=" + strconv.Itoa(request.Downloaded),
+		"left=" + strconv.Itoa(request.Left),
+		"compact=1",
+		"key=" + strconv.FormatUint(uint64(request.Key), 16),
+	}
+
+	if request.Event != EventNone {
+		parameters = append(parameters, "event="+string(request.Event))
+	}
+	if request.NumWant > 0 {
+		parameters = append(parameters, "numwant="+strconv.Itoa(request.NumWant))
+	}
+	if len(request.TrackerID) > 0 {
+		parameters = append(parameters, "trackerid="+escapeBytes([]byte(request.TrackerID)))
+	}
+
+	separator := "?"
+
+	if strings.Contains(tracker, "?") {
+		separator = "&"
+	}
+
+	return tracker + separator + strings.Join(parameters, "&")
+}
+
+// announce sends an announce request to an http tracker
+func (h httpAnnouncer) announce(ctx context.Context, tracker string, request AnnounceRequest) (AnnounceResponse, error) {
+	http_request, err := http.NewRequestWithContext(ctx, http.MethodGet, buildAnnounceUrl(tracker, request), nil)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	http_response, err := h.client.HttpClient.Do(http_request)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	defer http_response.Body.Close()
+
+	if http_response.StatusCode != http.StatusOK {
+		return AnnounceResponse{}, fmt.Errorf("%w: %d", ErrorUnexpectedHttpCode, http_response.StatusCode)
+	}
+
+	data, err := parser.ParseElement(bufio.NewReader(http_response.Body))
+
+	if err != nil {
+		return AnnounceResponse{}, fmt.Errorf("%w: %v", ErrorResponseCorrupted, err)
+	}
+
+	return decodeAnnounceResponse(ctx, data)
+}
+
+// decodeAnnounceResponse decodes the bencoded response of an http tracker
+func decodeAnnounceResponse(ctx context.Context, data interface{}) (response AnnounceResponse, err error) {
+	dictionary, ok := data.(map[string]interface{})
+
+	if !ok {
+		return response, fmt.Errorf("%w: need to be a dictionary", ErrorResponseCorrupted)
+	}
+
+	if failure_reason, ok := dictionary[DictionaryKeyFailureReason].(string); ok {
+		return response, fmt.Errorf("%w: %s", ErrorTrackerFailure, failure_reason)
+	}
+
+	interval, ok := dictionary[DictionaryKeyInterval].(int)
+
+	if !ok {
+		return response, fmt.Errorf("%w: missing %s", ErrorResponseCorrupted, DictionaryKeyInterval)
+	}
+
+	response.Interval = interval
+	response.MinInterval, _ = dictionary[DictionaryKeyMinInterval].(int)
+	response.TrackerID, _ = dictionary[DictionaryKeyTrackerID].(string)
+	response.Complete, _ = dictionary[DictionaryKeyComplete].(int)
+	response.Incomplete, _ = dictionary[DictionaryKeyIncomplete].(int)
+	response.WarningMessage, _ = dictionary[DictionaryKeyWarningMessage].(string)
+
+	switch peers := dictionary[DictionaryKeyPeers].(type) {
+	case string:
+		if response.Peers, err = DecodeCompactPeers(peers, CompactPeerLengthIPv4); err != nil {
+			return response, err
+		}
+	case []interface{}:
+		response.Peers = decodeDictionaryPeers(peers)
+	case nil:
+	default:
+		return response, fmt.Errorf("%w: bad type [%T] for %s", ErrorResponseCorrupted, peers, DictionaryKeyPeers)
+	}
+
+	if peers6, ok := dictionary[DictionaryKeyPeers6].(string); ok {
+		peers, err := DecodeCompactPeers(peers6, CompactPeerLengthIPv6)
+
+		if err != nil {
+			return response, err
+		}
+
+		response.Peers = append(response.Peers, peers...)
+	}
+
+	return response, nil
+}
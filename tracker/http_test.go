@@ -0,0 +1,176 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestEscapeBytes(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		{
+			input:    []byte{},
+			expected: "",
+		},
+		{
+			input:    []byte("abcXYZ019-._~"),
+			expected: "abcXYZ019-._~",
+		},
+		{
+			input:    []byte(" +/?&="),
+			expected: "%20%2B%2F%3F%26%3D",
+		},
+		{
+			input:    []byte{0x00, 0x12, 0xff},
+			expected: "%00%12%FF",
+		},
+	}
+
+	for index, test := range tests {
+		output := escapeBytes(test.input)
+
+		if test.expected != output {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestBuildAnnounceUrl(t *testing.T) {
+	request := AnnounceRequest{
+		InfoHash: [20]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf1, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x12, 0x34, 0x56, 0x78, 0x9a},
+		PeerID:   [20]byte{'-', 'G', 'B', '0', '0', '0', '1', '-', 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l'},
+		Port:     6881,
+		Left:     100,
+		Event:    EventStarted,
+		Key:      255,
+	}
+
+	tests := []struct {
+		tracker  string
+		expected string
+	}{
+		{
+			tracker:  "http://tracker.example.com/announce",
+			expected: "http://tracker.example.com/announce?info_hash=%124Vx%9A%BC%DE%F1%23Eg%89%AB%CD%EF%124Vx%9A&peer_id=-GB0001-abcdefghijkl&port=6881&uploaded=0&downloaded=0&left=100&compact=1&key=ff&event=started",
+		},
+		{
+			tracker:  "http://tracker.example.com/announce?pid=1",
+			expected: "http://tracker.example.com/announce?pid=1&info_hash=%124Vx%9A%BC%DE%F1%23Eg%89%AB%CD%EF%124Vx%9A&peer_id=-GB0001-abcdefghijkl&port=6881&uploaded=0&downloaded=0&left=100&compact=1&key=ff&event=started",
+		},
+	}
+
+	for index, test := range tests {
+		output := buildAnnounceUrl(test.tracker, request)
+
+		if test.expected != output {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+}
+
+func TestDecodeAnnounceResponse(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected AnnounceResponse
+		err      error
+	}{
+		{
+			input: map[string]interface{}{
+				"interval":     1800,
+				"min interval": 900,
+				"complete":     3,
+				"incomplete":   4,
+				"peers":        "\x7f\x00\x00\x01\x1a\xe1",
+				"peers6":       "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe2",
+			},
+			expected: AnnounceResponse{
+				Interval:    1800,
+				MinInterval: 900,
+				Complete:    3,
+				Incomplete:  4,
+				Peers: []Peer{
+					{IP: net.IP{127, 0, 0, 1}, Port: 6881},
+					{IP: net.IPv6loopback, Port: 6882},
+				},
+			},
+		},
+		{
+			input: map[string]interface{}{
+				"interval":   60,
+				"tracker id": "abc",
+				"peers": []interface{}{
+					map[string]interface{}{"peer id": "-GB0001-abcdefghijkl", "ip": "10.0.0.1", "port": 51413},
+				},
+			},
+			expected: AnnounceResponse{
+				Interval:  60,
+				TrackerID: "abc",
+				Peers: []Peer{
+					{ID: "-GB0001-abcdefghijkl", IP: net.ParseIP("10.0.0.1"), Port: 51413},
+				},
+			},
+		},
+		{
+			input: map[string]interface{}{"failure reason": "unregistered torrent"},
+			err:   ErrorTrackerFailure,
+		},
+		{
+			input: map[string]interface{}{"peers": ""},
+			err:   ErrorResponseCorrupted,
+		},
+		{
+			input: map[string]interface{}{"interval": 60, "peers": "\x7f"},
+			err:   ErrorCompactPeersCorrupted,
+		},
+		{
+			input: []interface{}{},
+			err:   ErrorResponseCorrupted,
+		},
+	}
+
+	for index, test := range tests {
+		output, err := decodeAnnounceResponse(context.Background(), test.input)
+
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d: expected error [%v] | [%v] output", index, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: failed to decode response: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %+v | %+v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestDecodeDictionaryPeersSkipped(t *testing.T) {
+	// the dns names are not resolved, the bad entries are skipped without failing the announce
+	peers := []interface{}{
+		map[string]interface{}{"ip": "peer.example.com", "port": 6881},
+		map[string]interface{}{"ip": "10.0.0.1", "port": 6881, "peer id": "a"},
+		map[string]interface{}{"ip": "10.0.0.2"},
+		map[string]interface{}{"ip": "10.0.0.3", "port": 70000},
+		"10.0.0.4",
+		map[string]interface{}{"ip": "::1", "port": 1},
+	}
+
+	expected := []Peer{
+		{ID: "a", IP: net.ParseIP("10.0.0.1"), Port: 6881},
+		{IP: net.ParseIP("::1"), Port: 1},
+	}
+
+	if output := decodeDictionaryPeers(peers); !reflect.DeepEqual(output, expected) {
+		t.Errorf("expected %v | %v output", expected, output)
+	}
+}
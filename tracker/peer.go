@@ -0,0 +1,107 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	CompactPeerLengthIPv4 = 6  // 4 bytes of ip + 2 bytes of port
+	CompactPeerLengthIPv6 = 18 // 16 bytes of ip + 2 bytes of port
+)
+
+var (
+	ErrorCompactPeersCorrupted = errors.New("compact peers corrupted")
+	ErrorPeerCorrupted         = errors.New("peer corrupted")
+)
+
+type Peer struct {
+	ID   string
+	IP   net.IP
+	Port int
+}
+
+// String returns the address of the peer in the host:port format
+func (p Peer) String() string {
+	return net.JoinHostPort(p.IP.String(), strconv.Itoa(p.Port))
+}
+
+// DecodeCompactPeers decodes a compact peer list where each peer takes peer_length bytes
+//
+// http://www.bittorrent.org/beps/bep_0023.html
+// http://www.bittorrent.org/beps/bep_0007.html
+func DecodeCompactPeers(data string, peer_length int) ([]Peer, error) {
+	if peer_length != CompactPeerLengthIPv4 && peer_length != CompactPeerLengthIPv6 {
+		return nil, fmt.Errorf("%w: invalid peer length %d", ErrorCompactPeersCorrupted, peer_length)
+	}
+
+	if len(data)%peer_length != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", ErrorCompactPeersCorrupted, len(data), peer_length)
+	}
+
+	ip_length := peer_length - 2
+	peers := make([]Peer, 0, len(data)/peer_length)
+
+	for start := 0; start < len(data); start += peer_length {
+		ip := make(net.IP, ip_length)
+		copy(ip, data[start:start+ip_length])
+
+		peers = append(peers, Peer{
+			IP:   ip,
+			Port: int(binary.BigEndian.Uint16([]byte(data[start+ip_length : start+peer_length]))),
+		})
+	}
+
+	return peers, nil
+}
+
+// EncodeCompactPeers encodes peers in the compact format, IPv4 and IPv6 peers are returned separately
+func EncodeCompactPeers(peers []Peer) (peers4 string, peers6 string) {
+	buffer4 := []byte{}
+	buffer6 := []byte{}
+	port := make([]byte, 2)
+
+	for _, peer := range peers {
+		binary.BigEndian.PutUint16(port, uint16(peer.Port))
+
+		if ip4 := peer.IP.To4(); ip4 != nil {
+			buffer4 = append(append(buffer4, ip4...), port...)
+		} else if ip6 := peer.IP.To16(); ip6 != nil {
+			buffer6 = append(append(buffer6, ip6...), port...)
+		}
+	}
+
+	return string(buffer4), string(buffer6)
+}
+
+// decodeDictionaryPeers decodes the original (non compact) peer list made of dictionaries
+//
+// The ip of a peer can be a dns name chosen by the tracker, it is not resolved:
+// the peers without a literal ip are skipped like the corrupted ones, a bad entry does not fail the announce
+func decodeDictionaryPeers(data []interface{}) []Peer {
+	peers := make([]Peer, 0, len(data))
+
+	for _, element := range data {
+		dictionary, _ := element.(map[string]interface{})
+		ip_string, _ := dictionary[DictionaryKeyIP].(string)
+		port, ok := dictionary[DictionaryKeyPort].(int)
+		ip := net.ParseIP(ip_string)
+
+		if !ok || ip == nil || port < 0 || port > 65535 {
+			continue
+		}
+
+		peer_id, _ := dictionary[DictionaryKeyPeerID].(string)
+
+		peers = append(peers, Peer{
+			ID:   peer_id,
+			IP:   ip,
+			Port: port,
+		})
+	}
+
+	return peers
+}
@@ -0,0 +1,84 @@
+package tracker
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCompactPeers(t *testing.T) {
+	tests := []struct {
+		input       string
+		peer_length int
+		expected    []Peer
+		fail        bool
+	}{
+		{
+			input:       "",
+			peer_length: CompactPeerLengthIPv4,
+			expected:    []Peer{},
+		},
+		{
+			input:       "\x7f\x00\x00\x01\x1a\xe1\x0a\x00\x00\x02\x00\x50",
+			peer_length: CompactPeerLengthIPv4,
+			expected: []Peer{
+				{IP: net.IP{127, 0, 0, 1}, Port: 6881},
+				{IP: net.IP{10, 0, 0, 2}, Port: 80},
+			},
+		},
+		{
+			input:       "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1",
+			peer_length: CompactPeerLengthIPv6,
+			expected: []Peer{
+				{IP: net.IPv6loopback, Port: 6881},
+			},
+		},
+		{
+			input:       "\x7f\x00\x00\x01\x1a",
+			peer_length: CompactPeerLengthIPv4,
+			fail:        true,
+		},
+		{
+			input:       "\x7f\x00\x00\x01\x1a\xe1",
+			peer_length: 4,
+			fail:        true,
+		},
+	}
+
+	for index, test := range tests {
+		output, err := DecodeCompactPeers(test.input, test.peer_length)
+
+		if test.fail {
+			if err == nil {
+				t.Errorf("test %d: expected an error", index)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d: failed to decode peers: %v", index, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.expected, output) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
+
+func TestEncodeCompactPeers(t *testing.T) {
+	peers := []Peer{
+		{IP: net.ParseIP("127.0.0.1"), Port: 6881},
+		{IP: net.IPv6loopback, Port: 6881},
+		{IP: net.IP{10, 0, 0, 2}, Port: 80},
+	}
+
+	peers4, peers6 := EncodeCompactPeers(peers)
+
+	if expected := "\x7f\x00\x00\x01\x1a\xe1\x0a\x00\x00\x02\x00\x50"; peers4 != expected {
+		t.Errorf("expected [%x] | [%x] output", expected, peers4)
+	}
+	if expected := "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1"; peers6 != expected {
+		t.Errorf("expected [%x] | [%x] output", expected, peers6)
+	}
+}
@@ -0,0 +1,96 @@
+// Code generated by bencodegen. DO NOT EDIT.
+
+package tracker
+
+import (
+	"github.com/trixky/gobencode/codec"
+)
+
+// MarshalBencode encodes a ScrapeFile as a bencoded dictionary
+func (x *ScrapeFile) MarshalBencode() ([]byte, error) {
+	return x.AppendBencode(nil), nil
+}
+
+// AppendBencode appends the bencoded dictionary of a ScrapeFile
+func (x *ScrapeFile) AppendBencode(dst []byte) []byte {
+	dst = codec.AppendDictionaryStart(dst)
+
+	dst = codec.AppendString(dst, "complete")
+	dst = codec.AppendInt(dst, int64(x.Complete))
+
+	dst = codec.AppendString(dst, "downloaded")
+	dst = codec.AppendInt(dst, int64(x.Downloaded))
+
+	dst = codec.AppendString(dst, "incomplete")
+	dst = codec.AppendInt(dst, int64(x.Incomplete))
+
+	return codec.AppendEnd(dst)
+}
+
+// UnmarshalBencode decodes a ScrapeFile from a bencoded dictionary
+func (x *ScrapeFile) UnmarshalBencode(data []byte) error {
+	d := codec.NewDecoder(data)
+
+	if err := x.DecodeBencode(d); err != nil {
+		return err
+	}
+
+	return d.Finish()
+}
+
+// DecodeBencode reads a ScrapeFile from the dictionary at the position of a decoder
+//
+// The unknown keys are skipped, the fields without a bencode tag are kept
+func (x *ScrapeFile) DecodeBencode(d *codec.Decoder) error {
+	if err := d.ReadDictionaryStart(); err != nil {
+		return err
+	}
+
+	var previous []byte
+
+	for d.More() {
+		key, err := d.ReadKey(previous)
+
+		if err != nil {
+			return err
+		}
+
+		previous = key
+
+		switch string(key) {
+		case "complete":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int(r0)
+			x.Complete = v0
+		case "downloaded":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int(r0)
+			x.Downloaded = v0
+		case "incomplete":
+			r0, err := d.ReadInt(codec.IntSize)
+
+			if err != nil {
+				return err
+			}
+
+			v0 := int(r0)
+			x.Incomplete = v0
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return d.ReadEnd()
+}
@@ -0,0 +1,529 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+const (
+	DictionaryKeyFiles      = "files"
+	DictionaryKeyDownloaded = "downloaded"
+
+	ServerDefaultInterval = 1800
+	ServerDefaultNumWant  = 50
+	ServerMaxNumWant      = 200
+)
+
+var (
+	ErrorInfoHashNotAllowed = errors.New("info hash not allowed")
+	ErrorInvalidParameter   = errors.New("invalid parameter")
+	ErrorInvalidInfoHash    = errors.New("invalid info hash")
+	ErrorInvalidPeerID      = errors.New("invalid peer id")
+)
+
+type storedPeer struct {
+	Peer
+	left    int
+	expires time.Time
+}
+
+type swarm struct {
+	peers      map[string]*storedPeer // by peer id
+	downloaded int
+}
+
+// Server is an in-memory tracker answering http announces and scrapes
+//
+// http://www.bittorrent.org/beps/bep_0003.html
+// http://www.bittorrent.org/beps/bep_0048.html
+//
+// The ip parameter of the http announces is only honoured for the requests coming from
+// a loopback or private address (a peer behind the same nat as the tracker), or for
+// all the requests with TrustIPParameter
+type Server struct {
+	Interval         int           // seconds between regular announces
+	PeerLifetime     time.Duration // peers that did not announce for PeerLifetime are forgotten
+	TrustIPParameter bool          // the ip parameter is honoured whatever the source of the request
+
+	allowed map[[20]byte]bool // nil allows every info hash
+	swarms  map[[20]byte]*swarm
+	mutex   sync.Mutex
+}
+
+// NewServer creates a tracker server accepting every info hash
+func NewServer() *Server {
+	return &Server{
+		Interval:     ServerDefaultInterval,
+		PeerLifetime: 2 * ServerDefaultInterval * time.Second,
+		swarms:       map[[20]byte]*swarm{},
+	}
+}
+
+// Allow restricts the server to the given info hashes (and the ones previously allowed)
+func (s *Server) Allow(info_hashes ...[20]byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.allowed == nil {
+		s.allowed = map[[20]byte]bool{}
+	}
+
+	for _, info_hash := range info_hashes {
+		s.allowed[info_hash] = true
+	}
+}
+
+// AllowTorrentFiles restricts the server to the info hashes of some .torrent files
+func (s *Server) AllowTorrentFiles(paths ...string) error {
+	info_hashes := [][20]byte{}
+
+	for _, torrent_path := range paths {
+		f, err := os.Open(torrent_path)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := parser.ParseElement(bufio.NewReader(f))
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to parse [%s]: %w", torrent_path, err)
+		}
+
+		bc := bencode.Bencode{
+			Data: data,
+		}
+
+		if err := bc.UnmarshallInfo(); err != nil {
+			return fmt.Errorf("failed to unmarshall info of [%s]: %w", torrent_path, err)
+		}
+
+		if err := bc.GetInfoHash(); err != nil {
+			return fmt.Errorf("failed to get the info hash of [%s]: %w", torrent_path, err)
+		}
+
+		info_hashes = append(info_hashes, bc.InfoHash)
+	}
+
+	s.Allow(info_hashes...)
+
+	return nil
+}
+
+// isAllowed checks the allow list, the mutex must be held
+func (s *Server) isAllowed(info_hash [20]byte) bool {
+	return s.allowed == nil || s.allowed[info_hash]
+}
+
+// expire forgets the peers of a swarm that did not announce in time, the mutex must be held
+func (s *Server) expire(sw *swarm, now time.Time) {
+	for peer_id, peer := range sw.peers {
+		if now.After(peer.expires) {
+			delete(sw.peers, peer_id)
+		}
+	}
+}
+
+// forget deletes a swarm without peer, unless its downloaded count must be kept, the mutex must be held
+func (s *Server) forget(info_hash [20]byte, sw *swarm) {
+	if len(sw.peers) == 0 && sw.downloaded == 0 {
+		delete(s.swarms, info_hash)
+	}
+}
+
+// Expire forgets the peers that did not announce in time in all the swarms
+func (s *Server) Expire() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	for info_hash, sw := range s.swarms {
+		s.expire(sw, now)
+		s.forget(info_hash, sw)
+	}
+}
+
+// RunExpiry calls Expire every interval until the context is canceled, it returns the context error
+func (s *Server) RunExpiry(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Expire()
+		}
+	}
+}
+
+// count returns the number of seeders and leechers of a swarm, the mutex must be held
+func (sw *swarm) count() (complete int, incomplete int) {
+	for _, peer := range sw.peers {
+		if peer.left == 0 {
+			complete++
+		} else {
+			incomplete++
+		}
+	}
+
+	return
+}
+
+// Announce registers a peer in its swarm and returns other peers of the swarm
+func (s *Server) Announce(request AnnounceRequest, ip net.IP) (AnnounceResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.isAllowed(request.InfoHash) {
+		return AnnounceResponse{}, ErrorInfoHashNotAllowed
+	}
+
+	if request.Port <= 0 || request.Port > 65535 {
+		return AnnounceResponse{}, fmt.Errorf("%w: %d", ErrorInvalidPort, request.Port)
+	}
+
+	now := time.Now()
+	sw, ok := s.swarms[request.InfoHash]
+
+	if !ok {
+		sw = &swarm{peers: map[string]*storedPeer{}}
+		s.swarms[request.InfoHash] = sw
+	}
+
+	s.expire(sw, now)
+
+	peer_id := string(request.PeerID[:])
+
+	if request.Event == EventStopped {
+		delete(sw.peers, peer_id)
+	} else {
+		if request.Event == EventCompleted {
+			sw.downloaded++
+		}
+
+		sw.peers[peer_id] = &storedPeer{
+			Peer: Peer{
+				ID:   peer_id,
+				IP:   ip,
+				Port: request.Port,
+			},
+			left:    request.Left,
+			expires: now.Add(s.PeerLifetime),
+		}
+	}
+
+	num_want := request.NumWant
+
+	if num_want <= 0 {
+		num_want = ServerDefaultNumWant
+	} else if num_want > ServerMaxNumWant {
+		num_want = ServerMaxNumWant
+	}
+
+	response := AnnounceResponse{
+		Interval: s.Interval,
+		Peers:    []Peer{},
+	}
+
+	response.Complete, response.Incomplete = sw.count()
+
+	// the map iteration order is random, which spreads the peers given to each client
+	for id, peer := range sw.peers {
+		if len(response.Peers) >= num_want {
+			break
+		}
+
+		// seeders do not need other seeders
+		if id == peer_id || (request.Left == 0 && peer.left == 0) {
+			continue
+		}
+
+		response.Peers = append(response.Peers, peer.Peer)
+	}
+
+	s.forget(request.InfoHash, sw)
+
+	return response, nil
+}
+
+// Scrape returns the statistics of some torrents, all the known torrents are returned when info_hashes is empty
+func (s *Server) Scrape(info_hashes [][20]byte) ([]ScrapeFile, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	files := make([]ScrapeFile, len(info_hashes))
+
+	for index, info_hash := range info_hashes {
+		if !s.isAllowed(info_hash) {
+			return nil, fmt.Errorf("%w: %x", ErrorInfoHashNotAllowed, info_hash)
+		}
+
+		if sw, ok := s.swarms[info_hash]; ok {
+			s.expire(sw, now)
+
+			files[index].Complete, files[index].Incomplete = sw.count()
+			files[index].Downloaded = sw.downloaded
+		}
+	}
+
+	return files, nil
+}
+
+// infoHashes returns the info hashes of all the known swarms
+func (s *Server) infoHashes() [][20]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	info_hashes := make([][20]byte, 0, len(s.swarms))
+
+	for info_hash := range s.swarms {
+		info_hashes = append(info_hashes, info_hash)
+	}
+
+	return info_hashes
+}
+
+// AttachUDP makes an udp server answer with the swarms of the server
+func (s *Server) AttachUDP(udp_server *UDPServer) {
+	udp_server.AnnounceHandler = func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+		return s.Announce(request, address.IP)
+	}
+	udp_server.ScrapeHandler = s.Scrape
+}
+
+// writeBencode writes a bencoded dictionary as http response
+func writeBencode(w http.ResponseWriter, dictionary map[string]interface{}) {
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(encoded))
+}
+
+// writeFailure writes a failure reason as http response
+//
+// trackers answer failures with a 200 status so clients read the reason
+func writeFailure(w http.ResponseWriter, err error) {
+	writeBencode(w, map[string]interface{}{
+		DictionaryKeyFailureReason: err.Error(),
+	})
+}
+
+// integerParameter reads an optional integer parameter of a query
+func integerParameter(query url.Values, key string) (int, error) {
+	value := query.Get(key)
+
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	integer, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrorInvalidParameter, key)
+	}
+
+	return integer, nil
+}
+
+// parseAnnounceRequest reads an announce request from its query, the ip of the peer is returned separately
+//
+// The ip parameter is honoured with trust_ip or when the request comes from a loopback or private address
+func parseAnnounceRequest(r *http.Request, trust_ip bool) (request AnnounceRequest, ip net.IP, err error) {
+	query := r.URL.Query()
+
+	info_hash := query.Get("info_hash")
+
+	if len(info_hash) != 20 {
+		return request, nil, ErrorInvalidInfoHash
+	}
+
+	peer_id := query.Get("peer_id")
+
+	if len(peer_id) != 20 {
+		return request, nil, ErrorInvalidPeerID
+	}
+
+	copy(request.InfoHash[:], info_hash)
+	copy(request.PeerID[:], peer_id)
+
+	integers := []struct {
+		key   string
+		value *int
+	}{
+		{"port", &request.Port},
+		{"uploaded", &request.Uploaded},
+		{"downloaded", &request.Downloaded},
+		{"left", &request.Left},
+		{"numwant", &request.NumWant},
+	}
+
+	for _, integer := range integers {
+		if *integer.value, err = integerParameter(query, integer.key); err != nil {
+			return request, nil, err
+		}
+	}
+
+	switch event := Event(query.Get("event")); event {
+	case EventNone, EventStarted, EventStopped, EventCompleted:
+		request.Event = event
+	default:
+		return request, nil, fmt.Errorf("%w: event", ErrorInvalidParameter)
+	}
+
+	request.TrackerID = query.Get("trackerid")
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip = net.ParseIP(host)
+
+	// a public peer could register any address with the ip parameter
+	trust_ip = trust_ip || ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+
+	if query_ip := net.ParseIP(query.Get("ip")); query_ip != nil && trust_ip {
+		ip = query_ip
+	}
+
+	if ip == nil {
+		return request, nil, fmt.Errorf("%w: ip", ErrorInvalidParameter)
+	}
+
+	return request, ip, nil
+}
+
+// serveAnnounce answers an http announce
+func (s *Server) serveAnnounce(w http.ResponseWriter, r *http.Request) {
+	request, ip, err := parseAnnounceRequest(r, s.TrustIPParameter)
+
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	response, err := s.Announce(request, ip)
+
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	dictionary := map[string]interface{}{
+		DictionaryKeyInterval:   response.Interval,
+		DictionaryKeyComplete:   response.Complete,
+		DictionaryKeyIncomplete: response.Incomplete,
+	}
+
+	query := r.URL.Query()
+
+	if query.Get("compact") == "0" {
+		// http://www.bittorrent.org/beps/bep_0003.html
+		peers := []interface{}{}
+
+		for _, peer := range response.Peers {
+			peer_dictionary := map[string]interface{}{
+				DictionaryKeyIP:   peer.IP.String(),
+				DictionaryKeyPort: peer.Port,
+			}
+
+			if query.Get("no_peer_id") != "1" {
+				peer_dictionary[DictionaryKeyPeerID] = peer.ID
+			}
+
+			peers = append(peers, peer_dictionary)
+		}
+
+		dictionary[DictionaryKeyPeers] = peers
+	} else {
+		// http://www.bittorrent.org/beps/bep_0023.html
+		// http://www.bittorrent.org/beps/bep_0007.html
+		peers4, peers6 := EncodeCompactPeers(response.Peers)
+
+		dictionary[DictionaryKeyPeers] = peers4
+
+		if len(peers6) > 0 {
+			dictionary[DictionaryKeyPeers6] = peers6
+		}
+	}
+
+	writeBencode(w, dictionary)
+}
+
+// serveScrape answers an http scrape
+//
+// http://www.bittorrent.org/beps/bep_0048.html
+func (s *Server) serveScrape(w http.ResponseWriter, r *http.Request) {
+	info_hashes := [][20]byte{}
+
+	for _, info_hash := range r.URL.Query()["info_hash"] {
+		if len(info_hash) != 20 {
+			writeFailure(w, ErrorInvalidInfoHash)
+			return
+		}
+
+		info_hashes = append(info_hashes, [20]byte{})
+		copy(info_hashes[len(info_hashes)-1][:], info_hash)
+	}
+
+	if len(info_hashes) == 0 {
+		info_hashes = s.infoHashes()
+	}
+
+	files, err := s.Scrape(info_hashes)
+
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	files_dictionary := map[string]interface{}{}
+
+	for index, file := range files {
+		files_dictionary[string(info_hashes[index][:])] = map[string]interface{}{
+			DictionaryKeyComplete:   file.Complete,
+			DictionaryKeyDownloaded: file.Downloaded,
+			DictionaryKeyIncomplete: file.Incomplete,
+		}
+	}
+
+	writeBencode(w, map[string]interface{}{
+		DictionaryKeyFiles: files_dictionary,
+	})
+}
+
+// ServeHTTP answers the announce and scrape requests, the last element of the path selects the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch path.Base(r.URL.Path) {
+	case "announce":
+		s.serveAnnounce(w, r)
+	case "scrape":
+		s.serveScrape(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
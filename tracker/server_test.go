@@ -0,0 +1,378 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+// getBencode sends a get request and parses the bencoded response
+func getBencode(t *testing.T, url string) map[string]interface{} {
+	response, err := http.Get(url)
+
+	if err != nil {
+		t.Fatalf("failed to get [%s]: %v", url, err)
+	}
+
+	defer response.Body.Close()
+
+	data, err := parser.ParseElement(bufio.NewReader(response.Body))
+
+	if err != nil {
+		t.Fatalf("failed to parse the response of [%s]: %v", url, err)
+	}
+
+	dictionary, ok := data.(map[string]interface{})
+
+	if !ok {
+		t.Fatalf("response of [%s] is not a dictionary", url)
+	}
+
+	return dictionary
+}
+
+// announceUrl builds the announce url of a test peer
+func announceUrl(base string, info_hash [20]byte, peer_id byte, left int, extra string) string {
+	return buildAnnounceUrl(base+"/announce", AnnounceRequest{
+		InfoHash: info_hash,
+		PeerID:   [20]byte{peer_id},
+		Port:     6881 + int(peer_id),
+		Left:     left,
+	}) + extra
+}
+
+func TestServerAnnounce(t *testing.T) {
+	server := NewServer()
+	http_server := httptest.NewServer(server)
+	defer http_server.Close()
+
+	info_hash := [20]byte{1, 2, 3}
+
+	getBencode(t, announceUrl(http_server.URL, info_hash, 'a', 0, "&event=started"))
+	getBencode(t, announceUrl(http_server.URL, info_hash, 'b', 10, "&ip=2001:db8::1"))
+
+	// compact
+	response, err := decodeAnnounceResponse(context.Background(), getBencode(t, announceUrl(http_server.URL, info_hash, 'c', 10, "")))
+
+	if err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+
+	if response.Interval != ServerDefaultInterval || response.Complete != 1 || response.Incomplete != 2 {
+		t.Errorf("bad response: %+v", response)
+	}
+	if len(response.Peers) != 2 {
+		t.Fatalf("expected 2 peers | %v output", response.Peers)
+	}
+
+	ports := map[int]net.IP{}
+
+	for _, peer := range response.Peers {
+		ports[peer.Port] = peer.IP
+	}
+
+	if ip := ports[6881+'a']; !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("bad ipv4 peer: %v", response.Peers)
+	}
+	if ip := ports[6881+'b']; !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("bad ipv6 peer: %v", response.Peers)
+	}
+
+	// non compact, seeders do not receive other seeders
+	dictionary := getBencode(t, strings.Replace(announceUrl(http_server.URL, info_hash, 'd', 0, ""), "compact=1", "compact=0", 1))
+	peers, ok := dictionary[DictionaryKeyPeers].([]interface{})
+
+	if !ok || len(peers) != 2 {
+		t.Fatalf("expected 2 peers | %v output", dictionary[DictionaryKeyPeers])
+	}
+
+	for _, peer := range peers {
+		peer_dictionary := peer.(map[string]interface{})
+
+		if id, _ := peer_dictionary[DictionaryKeyPeerID].(string); id[0] == 'a' || len(id) != 20 {
+			t.Errorf("bad peer: %v", peer_dictionary)
+		}
+	}
+
+	// stopped peers are removed
+	getBencode(t, announceUrl(http_server.URL, info_hash, 'b', 10, "&event=stopped"))
+
+	if files, _ := server.Scrape([][20]byte{info_hash}); files[0].Incomplete != 1 || files[0].Complete != 2 {
+		t.Errorf("bad scrape after stop: %v", files)
+	}
+}
+
+func TestServerFailure(t *testing.T) {
+	server := NewServer()
+	server.Allow([20]byte{1})
+	http_server := httptest.NewServer(server)
+	defer http_server.Close()
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			url:      announceUrl(http_server.URL, [20]byte{2}, 'a', 0, ""),
+			expected: ErrorInfoHashNotAllowed.Error(),
+		},
+		{
+			url:      http_server.URL + "/announce?info_hash=abc",
+			expected: ErrorInvalidInfoHash.Error(),
+		},
+		{
+			url:      announceUrl(http_server.URL, [20]byte{1}, 'a', 0, "&event=paused"),
+			expected: ErrorInvalidParameter.Error() + ": event",
+		},
+		{
+			url:      announceUrl(http_server.URL, [20]byte{1}, 'a', 0, "&numwant=many"),
+			expected: ErrorInvalidParameter.Error() + ": numwant",
+		},
+	}
+
+	for index, test := range tests {
+		dictionary := getBencode(t, test.url)
+
+		if output := dictionary[DictionaryKeyFailureReason]; output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%v] output", index, test.expected, output)
+		}
+	}
+
+	response, err := http.Get(http_server.URL + "/unknown")
+
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d | %d output", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestServerScrape(t *testing.T) {
+	server := NewServer()
+	http_server := httptest.NewServer(server)
+	defer http_server.Close()
+
+	first, second := [20]byte{1}, [20]byte{'&', '='}
+
+	getBencode(t, announceUrl(http_server.URL, first, 'a', 0, "&event=completed"))
+	getBencode(t, announceUrl(http_server.URL, first, 'b', 10, ""))
+	getBencode(t, announceUrl(http_server.URL, second, 'a', 10, ""))
+
+	tests := []struct {
+		query    string
+		expected map[string]interface{}
+	}{
+		{
+			query: "?info_hash=" + escapeBytes(first[:]),
+			expected: map[string]interface{}{
+				string(first[:]): map[string]interface{}{"complete": 1, "downloaded": 1, "incomplete": 1},
+			},
+		},
+		{
+			query: "?info_hash=" + escapeBytes(first[:]) + "&info_hash=" + escapeBytes(second[:]) + "&info_hash=" + escapeBytes([]byte("01234567890123456789")),
+			expected: map[string]interface{}{
+				string(first[:]):       map[string]interface{}{"complete": 1, "downloaded": 1, "incomplete": 1},
+				string(second[:]):      map[string]interface{}{"complete": 0, "downloaded": 0, "incomplete": 1},
+				"01234567890123456789": map[string]interface{}{"complete": 0, "downloaded": 0, "incomplete": 0},
+			},
+		},
+		{
+			query: "",
+			expected: map[string]interface{}{
+				string(first[:]):  map[string]interface{}{"complete": 1, "downloaded": 1, "incomplete": 1},
+				string(second[:]): map[string]interface{}{"complete": 0, "downloaded": 0, "incomplete": 1},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		dictionary := getBencode(t, http_server.URL+"/scrape"+test.query)
+
+		if !reflect.DeepEqual(test.expected, dictionary[DictionaryKeyFiles]) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, dictionary[DictionaryKeyFiles])
+		}
+	}
+}
+
+func TestServerExpiry(t *testing.T) {
+	server := NewServer()
+	server.PeerLifetime = 20 * time.Millisecond
+
+	info_hash := [20]byte{1}
+
+	if _, err := server.Announce(AnnounceRequest{InfoHash: info_hash, PeerID: [20]byte{'a'}, Port: 1, Left: 1}, net.IPv4(10, 0, 0, 1)); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	response, err := server.Announce(AnnounceRequest{InfoHash: info_hash, PeerID: [20]byte{'b'}, Port: 2, Left: 1}, net.IPv4(10, 0, 0, 2))
+
+	if err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+
+	if len(response.Peers) != 0 || response.Incomplete != 1 {
+		t.Errorf("expired peer returned: %+v", response)
+	}
+}
+
+func TestServerExpireKeepsDownloaded(t *testing.T) {
+	server := NewServer()
+	server.PeerLifetime = 20 * time.Millisecond
+
+	completed, started := [20]byte{1}, [20]byte{2}
+
+	server.Announce(AnnounceRequest{InfoHash: completed, PeerID: [20]byte{'a'}, Port: 1, Event: EventCompleted}, net.IPv4(10, 0, 0, 1))
+	server.Announce(AnnounceRequest{InfoHash: started, PeerID: [20]byte{'a'}, Port: 1, Left: 1}, net.IPv4(10, 0, 0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- server.RunExpiry(ctx, 10*time.Millisecond)
+	}()
+
+	time.Sleep(60 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected [%v] | [%v] output", context.Canceled, err)
+	}
+
+	// the empty swarms are forgotten, except their downloaded count
+	if info_hashes := server.infoHashes(); !reflect.DeepEqual(info_hashes, [][20]byte{completed}) {
+		t.Errorf("expected [%x] | %x output", completed, info_hashes)
+	}
+
+	files, _ := server.Scrape([][20]byte{completed})
+
+	if expected := (ScrapeFile{Downloaded: 1}); files[0] != expected {
+		t.Errorf("expected %+v | %+v output", expected, files[0])
+	}
+
+	// a stopped peer does not reset the count either
+	server.Announce(AnnounceRequest{InfoHash: completed, PeerID: [20]byte{'b'}, Port: 1, Event: EventStopped}, net.IPv4(10, 0, 0, 2))
+
+	if files, _ := server.Scrape([][20]byte{completed}); files[0].Downloaded != 1 {
+		t.Errorf("expected 1 | %d downloaded output", files[0].Downloaded)
+	}
+}
+
+func TestParseAnnounceRequestIP(t *testing.T) {
+	tests := []struct {
+		remote_address string
+		trust_ip       bool
+		expected       string
+	}{
+		{"192.0.2.1:1234", false, "192.0.2.1"},
+		{"192.0.2.1:1234", true, "2001:db8::1"},
+		{"127.0.0.1:1234", false, "2001:db8::1"},
+		{"10.0.0.2:1234", false, "2001:db8::1"},
+		{"[2001:db8::2]:1234", false, "2001:db8::2"},
+	}
+
+	for index, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, announceUrl("http://tracker", [20]byte{1}, 'a', 0, "&ip=2001:db8::1"), nil)
+		r.RemoteAddr = test.remote_address
+
+		_, ip, err := parseAnnounceRequest(r, test.trust_ip)
+
+		if err != nil || !ip.Equal(net.ParseIP(test.expected)) {
+			t.Errorf("test %d: expected [%s] | [%v] output: %v", index, test.expected, ip, err)
+		}
+	}
+}
+
+func TestServerAllowTorrentFiles(t *testing.T) {
+	server := NewServer()
+
+	if err := server.AllowTorrentFiles("../.test_files/ubuntu.torrent"); err != nil {
+		t.Fatalf("failed to allow torrent files: %v", err)
+	}
+
+	ubuntu := [20]byte{44, 107, 104, 88, 214, 29, 169, 84, 61, 66, 49, 167, 29, 180, 177, 201, 38, 75, 6, 133}
+
+	if _, err := server.Announce(AnnounceRequest{InfoHash: ubuntu, Port: 1}, net.IPv4(10, 0, 0, 1)); err != nil {
+		t.Errorf("allowed info hash refused: %v", err)
+	}
+	if _, err := server.Announce(AnnounceRequest{InfoHash: [20]byte{1}, Port: 1}, net.IPv4(10, 0, 0, 1)); !errors.Is(err, ErrorInfoHashNotAllowed) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInfoHashNotAllowed, err)
+	}
+	if err := server.AllowTorrentFiles("../.test_files/missing.torrent"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestServerWithClients(t *testing.T) {
+	server := NewServer()
+	http_server := httptest.NewServer(server)
+	defer http_server.Close()
+
+	udp_server, err := ListenUDP("127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer udp_server.Close()
+
+	server.AttachUDP(udp_server)
+	go udp_server.Serve()
+
+	bc := bencode.Bencode{InfoHash: [20]byte{9}}
+
+	bc.Announce = http_server.URL + "/announce"
+	http_client, _ := NewClient(&bc, [20]byte{'h'}, 6881)
+
+	bc.Announce = udp_server.URL()
+	udp_client, _ := NewClient(&bc, [20]byte{'u'}, 6882)
+
+	if _, err := http_client.Announce(context.Background(), 0, 0, 0, EventStarted); err != nil {
+		t.Fatalf("failed to announce over http: %v", err)
+	}
+
+	response, err := udp_client.Announce(context.Background(), 0, 0, 10, EventStarted)
+
+	if err != nil {
+		t.Fatalf("failed to announce over udp: %v", err)
+	}
+
+	if len(response.Peers) != 1 || response.Peers[0].Port != 6881 || response.Complete != 1 || response.Incomplete != 1 {
+		t.Errorf("bad response: %+v", response)
+	}
+}
+
+func TestServerResponseIsBencode(t *testing.T) {
+	http_server := httptest.NewServer(NewServer())
+	defer http_server.Close()
+
+	response, err := http.Get(announceUrl(http_server.URL, [20]byte{1}, 'a', 0, ""))
+
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+
+	if expected := "d8:completei1e10:incompletei0e8:intervali1800e5:peers0:e"; expected != string(body) {
+		t.Errorf("expected [%s] | [%s] output", expected, body)
+	}
+}
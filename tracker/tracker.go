@@ -0,0 +1,302 @@
+// Package tracker provide clients to announce to BitTorrent trackers
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+const (
+	DictionaryKeyFailureReason  = "failure reason"
+	DictionaryKeyWarningMessage = "warning message"
+	DictionaryKeyInterval       = "interval"
+	DictionaryKeyMinInterval    = "min interval"
+	DictionaryKeyTrackerID      = "tracker id"
+	DictionaryKeyComplete       = "complete"
+	DictionaryKeyIncomplete     = "incomplete"
+	DictionaryKeyPeers          = "peers"
+	DictionaryKeyPeers6         = "peers6"
+	DictionaryKeyPeerID         = "peer id"
+	DictionaryKeyIP             = "ip"
+	DictionaryKeyPort           = "port"
+)
+
+type Event string
+
+const (
+	EventNone      Event = ""
+	EventStarted   Event = "started"
+	EventStopped   Event = "stopped"
+	EventCompleted Event = "completed"
+)
+
+var (
+	ErrorNoTrackerFound     = errors.New("no tracker found")
+	ErrorAllTrackersFailed  = errors.New("all trackers failed")
+	ErrorUnsupportedScheme  = errors.New("unsupported tracker scheme")
+	ErrorTrackerFailure     = errors.New("tracker failure")
+	ErrorResponseCorrupted  = errors.New("tracker response corrupted")
+	ErrorInvalidPort        = errors.New("invalid port")
+	ErrorUnexpectedHttpCode = errors.New("unexpected http status code")
+)
+
+type AnnounceRequest struct {
+	InfoHash   [20]byte
+	PeerID     [20]byte
+	Port       int
+	Uploaded   int
+	Downloaded int
+	Left       int
+	Event      Event
+	NumWant    int // 0 lets the tracker choose
+	Key        uint32
+	TrackerID  string
+}
+
+type AnnounceResponse struct {
+	Tracker        string
+	Interval       int
+	MinInterval    int
+	TrackerID      string
+	Complete       int
+	Incomplete     int
+	WarningMessage string
+	Peers          []Peer
+}
+
+//go:generate go run github.com/trixky/gobencode/cmd/bencodegen -type ScrapeFile -output scrape_bencode.go
+type ScrapeFile struct {
+	Complete   int `bencode:"complete"`
+	Downloaded int `bencode:"downloaded"`
+	Incomplete int `bencode:"incomplete"`
+}
+
+// announcer is implemented by each supported tracker protocol
+type announcer interface {
+	announce(ctx context.Context, tracker string, request AnnounceRequest) (AnnounceResponse, error)
+}
+
+// Client announces a torrent to its trackers
+//
+// http://www.bittorrent.org/beps/bep_0012.html
+// "If the client is unable to connect to any of the trackers in the first tier,
+// it tries the trackers in the second tier and so on, a successful tracker
+// is moved to the front of its tier"
+//
+// The udp trackers are retried UDPAnnounceMaxRetries times instead of the 8 retries
+// (more than an hour) of BEP 15, so that a dead tracker does not stall the next ones.
+// UDPClient can be replaced to change it
+type Client struct {
+	HttpClient *http.Client
+	UDPClient  *UDPClient
+	PeerID     [20]byte
+	Port       int
+
+	info_hash     [20]byte
+	tiers         [][]string
+	announcers    map[string]announcer
+	key           uint32
+	tracker_id    string
+	next_announce time.Time
+	mutex         sync.Mutex
+}
+
+// NewClient creates a client for the trackers of a bencode
+func NewClient(bc *bencode.Bencode, peer_id [20]byte, port int) (*Client, error) {
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("%w: %d", ErrorInvalidPort, port)
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	tiers := [][]string{}
+
+	for _, tier := range bc.AnnounceList {
+		if len(tier) > 0 {
+			tiers = append(tiers, shuffleTier(random, tier))
+		}
+	}
+
+	// http://www.bittorrent.org/beps/bep_0012.html
+	// "If the announce-list key is present, the client will ignore the announce key"
+	if len(tiers) == 0 && len(bc.Announce) > 0 {
+		tiers = append(tiers, []string{bc.Announce})
+	}
+
+	if len(tiers) == 0 {
+		return nil, ErrorNoTrackerFound
+	}
+
+	udp_client := NewUDPClient()
+	udp_client.MaxRetries = UDPAnnounceMaxRetries
+
+	c := &Client{
+		HttpClient: http.DefaultClient,
+		UDPClient:  udp_client,
+		PeerID:     peer_id,
+		Port:       port,
+		info_hash:  bc.InfoHash,
+		tiers:      tiers,
+		key:        random.Uint32(),
+	}
+
+	c.announcers = map[string]announcer{
+		"http":  httpAnnouncer{client: c},
+		"https": httpAnnouncer{client: c},
+		"udp":   udpAnnouncer{client: c},
+	}
+
+	return c, nil
+}
+
+// shuffleTier returns a shuffled copy of a tier
+func shuffleTier(random *rand.Rand, tier []string) []string {
+	shuffled_tier := append([]string{}, tier...)
+
+	random.Shuffle(len(shuffled_tier), func(i, j int) {
+		shuffled_tier[i], shuffled_tier[j] = shuffled_tier[j], shuffled_tier[i]
+	})
+
+	return shuffled_tier
+}
+
+// Tiers returns a copy of the tiers in their current order
+func (c *Client) Tiers() [][]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tiers := make([][]string, len(c.tiers))
+
+	for index, tier := range c.tiers {
+		tiers[index] = append([]string{}, tier...)
+	}
+
+	return tiers
+}
+
+// NextAnnounce returns the time before which the trackers asked not to be contacted
+func (c *Client) NextAnnounce() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.next_announce
+}
+
+// promote moves a tracker to the front of its tier, the mutex must be held
+func (c *Client) promote(tier_index int, tracker string) {
+	tier := c.tiers[tier_index]
+
+	for tracker_index := range tier {
+		if tier[tracker_index] == tracker {
+			copy(tier[1:tracker_index+1], tier[:tracker_index])
+			tier[0] = tracker
+			return
+		}
+	}
+}
+
+// Announce walks the tiers in order and returns the response of the first tracker that succeeded
+//
+// Regular announces (without event) wait for the interval given by the previous response,
+// the wait can be interrupted with the context. The state of the client is only locked
+// while it is read and updated, not during the wait and the network calls
+func (c *Client) Announce(ctx context.Context, uploaded int, downloaded int, left int, event Event) (AnnounceResponse, error) {
+	c.mutex.Lock()
+	next_announce := c.next_announce
+	tracker_id := c.tracker_id
+	c.mutex.Unlock()
+
+	if event == EventNone {
+		if wait := time.Until(next_announce); wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return AnnounceResponse{}, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	request := AnnounceRequest{
+		InfoHash:   c.info_hash,
+		PeerID:     c.PeerID,
+		Port:       c.Port,
+		Uploaded:   uploaded,
+		Downloaded: downloaded,
+		Left:       left,
+		Event:      event,
+		Key:        c.key,
+		TrackerID:  tracker_id,
+	}
+
+	tracker_errors := []error{}
+
+	for tier_index, tier := range c.Tiers() {
+		for _, tracker := range tier {
+			if err := ctx.Err(); err != nil {
+				return AnnounceResponse{}, err
+			}
+
+			response, err := c.announceTo(ctx, tracker, request)
+
+			if err != nil {
+				tracker_errors = append(tracker_errors, fmt.Errorf("%s: %w", tracker, err))
+				continue
+			}
+
+			interval := response.Interval
+
+			if response.MinInterval > interval {
+				interval = response.MinInterval
+			}
+
+			c.mutex.Lock()
+			c.promote(tier_index, tracker)
+
+			if len(response.TrackerID) > 0 {
+				c.tracker_id = response.TrackerID
+			}
+
+			c.next_announce = time.Now().Add(time.Duration(interval) * time.Second)
+			c.mutex.Unlock()
+
+			return response, nil
+		}
+	}
+
+	return AnnounceResponse{}, fmt.Errorf("%w: %v", ErrorAllTrackersFailed, tracker_errors)
+}
+
+// announceTo announces to a single tracker with the protocol given by its scheme
+func (c *Client) announceTo(ctx context.Context, tracker string, request AnnounceRequest) (AnnounceResponse, error) {
+	tracker_url, err := url.Parse(tracker)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	a, ok := c.announcers[tracker_url.Scheme]
+
+	if !ok {
+		return AnnounceResponse{}, fmt.Errorf("%w: [%s]", ErrorUnsupportedScheme, tracker_url.Scheme)
+	}
+
+	response, err := a.announce(ctx, tracker, request)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	response.Tracker = tracker
+
+	return response, nil
+}
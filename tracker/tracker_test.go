@@ -0,0 +1,260 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+// newTestTracker starts an http tracker answering with the given bencoded body and counting its announces
+func newTestTracker(t *testing.T, status int, body string, info_hash [20]byte, announces *int, mutex *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		*announces++
+		mutex.Unlock()
+
+		if r.URL.Query().Get("info_hash") != string(info_hash[:]) {
+			t.Errorf("bad info hash received: [%x]", r.URL.Query().Get("info_hash"))
+		}
+
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+// newDeadUDPTracker starts an udp socket reading the requests without answering them
+func newDeadUDPTracker(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		buffer := make([]byte, udp_max_packet_length)
+
+		for {
+			if _, _, err := conn.ReadFrom(buffer); err != nil {
+				return
+			}
+		}
+	}()
+
+	return conn
+}
+
+func TestClientAnnounce(t *testing.T) {
+	info_hash := [20]byte{' ', '%', '&', '?', 0xff, 0x00, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+	peer_id := [20]byte{'-', 'G', 'B', '0', '0', '0', '1', '-'}
+	mutex := sync.Mutex{}
+	failing_announces, working_announces, fallback_announces := 0, 0, 0
+
+	failing := newTestTracker(t, http.StatusInternalServerError, "", info_hash, &failing_announces, &mutex)
+	defer failing.Close()
+	refusing := newTestTracker(t, http.StatusOK, "d14:failure reason12:unregisterede", info_hash, &failing_announces, &mutex)
+	defer refusing.Close()
+	working := newTestTracker(t, http.StatusOK, "d8:intervali3600e5:peers6:\x7f\x00\x00\x01\x1a\xe1e", info_hash, &working_announces, &mutex)
+	defer working.Close()
+	fallback := newTestTracker(t, http.StatusOK, "d8:intervali3600e5:peers0:e", info_hash, &fallback_announces, &mutex)
+	defer fallback.Close()
+	dead := newDeadUDPTracker(t)
+	defer dead.Close()
+
+	bc := bencode.Bencode{
+		Announce: "http://unused.example.com/announce",
+		AnnounceList: [][]string{
+			{"udp://" + dead.LocalAddr().String(), failing.URL, working.URL, refusing.URL},
+			{fallback.URL},
+		},
+		InfoHash: info_hash,
+	}
+
+	client, err := NewClient(&bc, peer_id, 6881)
+
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+
+	if client.UDPClient.MaxRetries != UDPAnnounceMaxRetries {
+		t.Errorf("expected [%d] | [%d] udp retries", UDPAnnounceMaxRetries, client.UDPClient.MaxRetries)
+	}
+
+	client.UDPClient.BaseTimeout = 10 * time.Millisecond
+
+	response, err := client.Announce(context.Background(), 0, 0, 100, EventStarted)
+
+	if err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+
+	if response.Tracker != working.URL {
+		t.Errorf("expected tracker [%s] | [%s] output", working.URL, response.Tracker)
+	}
+	if len(response.Peers) != 1 || response.Peers[0].Port != 6881 {
+		t.Errorf("bad peers: %v", response.Peers)
+	}
+	if tiers := client.Tiers(); tiers[0][0] != working.URL || len(tiers[0]) != 4 {
+		t.Errorf("tracker not promoted: %v", tiers)
+	}
+	if fallback_announces != 0 {
+		t.Errorf("second tier contacted while the first tier succeeded")
+	}
+
+	// the promoted tracker is contacted first
+	failing_announces, working_announces = 0, 0
+
+	if _, err := client.Announce(context.Background(), 0, 0, 0, EventCompleted); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if failing_announces != 0 || working_announces != 1 {
+		t.Errorf("expected only the promoted tracker to be contacted: %d failing | %d working", failing_announces, working_announces)
+	}
+
+	// regular announces respect the interval
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Announce(ctx, 0, 0, 0, EventNone); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected [%v] | [%v] output", context.DeadlineExceeded, err)
+	}
+	if working_announces != 1 {
+		t.Errorf("tracker contacted before the interval")
+	}
+}
+
+func TestClientAnnounceUnlocked(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("d8:intervali1800e5:peers0:e"))
+	}))
+	defer slow.Close()
+
+	client, err := NewClient(&bencode.Bencode{Announce: slow.URL}, [20]byte{}, 6881)
+
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		_, err := client.Announce(context.Background(), 0, 0, 0, EventStarted)
+		done <- err
+	}()
+
+	// the state can be read while the tracker is contacted
+	read := make(chan struct{})
+
+	go func() {
+		client.Tiers()
+		client.NextAnnounce()
+		close(read)
+	}()
+
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		t.Errorf("the state of the client is locked during the announce")
+	}
+
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if time.Until(client.NextAnnounce()) <= 0 {
+		t.Errorf("the next announce is not updated")
+	}
+}
+
+func TestClientAnnounceFallback(t *testing.T) {
+	mutex := sync.Mutex{}
+	failing_announces, fallback_announces := 0, 0
+
+	failing := newTestTracker(t, http.StatusNotFound, "", [20]byte{}, &failing_announces, &mutex)
+	defer failing.Close()
+	fallback := newTestTracker(t, http.StatusOK, "d8:intervali1800e5:peers0:e", [20]byte{}, &fallback_announces, &mutex)
+	defer fallback.Close()
+
+	bc := bencode.Bencode{
+		AnnounceList: [][]string{{failing.URL}, {}, {fallback.URL}},
+	}
+
+	client, err := NewClient(&bc, [20]byte{}, 6881)
+
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+
+	response, err := client.Announce(context.Background(), 0, 0, 0, EventStarted)
+
+	if err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if response.Tracker != fallback.URL || response.Interval != 1800 {
+		t.Errorf("bad response: %+v", response)
+	}
+	if failing_announces != 1 || fallback_announces != 1 {
+		t.Errorf("expected one announce per tracker: %d failing | %d fallback", failing_announces, fallback_announces)
+	}
+}
+
+func TestClientAnnounceAllFailed(t *testing.T) {
+	mutex := sync.Mutex{}
+	announces := 0
+
+	failing := newTestTracker(t, http.StatusInternalServerError, "", [20]byte{}, &announces, &mutex)
+	defer failing.Close()
+
+	client, err := NewClient(&bencode.Bencode{Announce: failing.URL}, [20]byte{}, 6881)
+
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+
+	if _, err := client.Announce(context.Background(), 0, 0, 0, EventStarted); !errors.Is(err, ErrorAllTrackersFailed) {
+		t.Errorf("expected [%v] | [%v] output", ErrorAllTrackersFailed, err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(&bencode.Bencode{}, [20]byte{}, 6881); !errors.Is(err, ErrorNoTrackerFound) {
+		t.Errorf("expected [%v] | [%v] output", ErrorNoTrackerFound, err)
+	}
+	if _, err := NewClient(&bencode.Bencode{Announce: "http://tracker"}, [20]byte{}, 0); !errors.Is(err, ErrorInvalidPort) {
+		t.Errorf("expected [%v] | [%v] output", ErrorInvalidPort, err)
+	}
+}
+
+func TestScrapeFileBencode(t *testing.T) {
+	tests := []struct {
+		input    ScrapeFile
+		expected string
+	}{
+		{ScrapeFile{}, "d8:completei0e10:downloadedi0e10:incompletei0ee"},
+		{ScrapeFile{Complete: 12, Downloaded: 340, Incomplete: 5}, "d8:completei12e10:downloadedi340e10:incompletei5ee"},
+	}
+
+	for index, test := range tests {
+		output, err := test.input.MarshalBencode()
+
+		if err != nil || string(output) != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output: %v", index, test.expected, output, err)
+			continue
+		}
+
+		file := ScrapeFile{}
+
+		if err := file.UnmarshalBencode(append(output[:len(output)-1:len(output)-1], "4:name3:abce"...)); err != nil || file != test.input {
+			t.Errorf("test %d: expected [%+v] | [%+v] output: %v", index, test.input, file, err)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// http://www.bittorrent.org/beps/bep_0015.html
+const (
+	udp_protocol_id = 0x41727101980
+
+	udp_action_connect  = 0
+	udp_action_announce = 1
+	udp_action_scrape   = 2
+	udp_action_error    = 3
+
+	udp_connect_length           = 16
+	udp_announce_length          = 98
+	udp_announce_response_length = 20
+	udp_scrape_header_length     = 16
+	udp_scrape_file_length       = 12
+	udp_header_length            = 8
+
+	udp_max_packet_length = 2048
+	udp_max_scrape        = 74 // info hashes per scrape to stay under the usual mtu
+
+	UDPDefaultBaseTimeout = 15 * time.Second
+	UDPDefaultMaxRetries  = 8
+	UDPAnnounceMaxRetries = 2 // retries of the udp client of Client, a dead tracker delays the next one by 105 seconds at most
+	UDPConnectionLifetime = time.Minute
+)
+
+var (
+	ErrorUDPTimeout           = errors.New("udp tracker timeout")
+	ErrorUDPResponseCorrupted = errors.New("udp tracker response corrupted")
+	ErrorTooManyInfoHashes    = errors.New("too many info hashes")
+)
+
+// udpEvents maps the events to their udp value
+var udpEvents = map[Event]uint32{
+	EventNone:      0,
+	EventCompleted: 1,
+	EventStarted:   2,
+	EventStopped:   3,
+}
+
+type udpConnection struct {
+	id      uint64
+	expires time.Time
+}
+
+// UDPClient talks to udp trackers, connection ids are cached by tracker address
+//
+// http://www.bittorrent.org/beps/bep_0015.html
+type UDPClient struct {
+	BaseTimeout time.Duration // a request is retransmitted after BaseTimeout * 2 ^ n
+	MaxRetries  int
+
+	connections map[string]udpConnection
+	random      *rand.Rand
+	mutex       sync.Mutex
+}
+
+// NewUDPClient creates an udp client with the timeouts of the specification
+func NewUDPClient() *UDPClient {
+	return &UDPClient{
+		BaseTimeout: UDPDefaultBaseTimeout,
+		MaxRetries:  UDPDefaultMaxRetries,
+		connections: map[string]udpConnection{},
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// transactionID generates a random transaction id
+func (u *UDPClient) transactionID() uint32 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.random.Uint32()
+}
+
+// cachedConnection returns the connection id of a tracker if it has not expired
+func (u *UDPClient) cachedConnection(address string) (uint64, bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	connection, ok := u.connections[address]
+
+	if !ok || time.Now().After(connection.expires) {
+		delete(u.connections, address)
+		return 0, false
+	}
+
+	return connection.id, true
+}
+
+// dial opens a socket to the tracker of an udp url
+func (u *UDPClient) dial(ctx context.Context, tracker string) (*net.UDPConn, error) {
+	tracker_url, err := url.Parse(tracker)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tracker_url.Scheme != "udp" {
+		return nil, fmt.Errorf("%w: [%s]", ErrorUnsupportedScheme, tracker_url.Scheme)
+	}
+
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "udp", tracker_url.Host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
+
+// exchange sends a packet and waits for the response of the same transaction
+func exchange(ctx context.Context, conn *net.UDPConn, packet []byte, transaction_id uint32, timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+
+	if ctx_deadline, ok := ctx.Deadline(); ok && ctx_deadline.Before(deadline) {
+		deadline = ctx_deadline
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// the context may have been canceled before the deadline was set
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, udp_max_packet_length)
+
+	for {
+		n, err := conn.Read(buffer)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if net_err, ok := err.(net.Error); ok && net_err.Timeout() {
+				return nil, ErrorUDPTimeout
+			}
+			return nil, err
+		}
+
+		// packets of other transactions are ignored
+		if n >= udp_header_length && binary.BigEndian.Uint32(buffer[4:8]) == transaction_id {
+			response := buffer[:n]
+
+			if binary.BigEndian.Uint32(response[:4]) == udp_action_error {
+				return nil, fmt.Errorf("%w: %s", ErrorTrackerFailure, response[udp_header_length:])
+			}
+
+			return response, nil
+		}
+	}
+}
+
+// transact sends a request built with a valid connection id, retransmitting it until a response is received
+func (u *UDPClient) transact(ctx context.Context, conn *net.UDPConn, action uint32, build func(connection_id uint64, transaction_id uint32) []byte) ([]byte, error) {
+	// the socket is unblocked as soon as the context is done
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	address := conn.RemoteAddr().String()
+
+	for n := 0; n <= u.MaxRetries; n++ {
+		timeout := u.BaseTimeout << n
+
+		connection_id, ok := u.cachedConnection(address)
+
+		if !ok {
+			transaction_id := u.transactionID()
+			response, err := exchange(ctx, conn, buildConnect(transaction_id), transaction_id, timeout)
+
+			if err == ErrorUDPTimeout {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if len(response) < udp_connect_length || binary.BigEndian.Uint32(response[:4]) != udp_action_connect {
+				return nil, fmt.Errorf("%w: bad connect response", ErrorUDPResponseCorrupted)
+			}
+
+			connection_id = binary.BigEndian.Uint64(response[8:16])
+
+			u.mutex.Lock()
+			u.connections[address] = udpConnection{
+				id:      connection_id,
+				expires: time.Now().Add(UDPConnectionLifetime),
+			}
+			u.mutex.Unlock()
+		}
+
+		transaction_id := u.transactionID()
+		response, err := exchange(ctx, conn, build(connection_id, transaction_id), transaction_id, timeout)
+
+		if err == ErrorUDPTimeout {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if binary.BigEndian.Uint32(response[:4]) != action {
+			return nil, fmt.Errorf("%w: unexpected action %d", ErrorUDPResponseCorrupted, binary.BigEndian.Uint32(response[:4]))
+		}
+
+		return response, nil
+	}
+
+	return nil, ErrorUDPTimeout
+}
+
+// buildConnect builds a connect request
+func buildConnect(transaction_id uint32) []byte {
+	packet := make([]byte, udp_connect_length)
+
+	binary.BigEndian.PutUint64(packet[0:8], udp_protocol_id)
+	binary.BigEndian.PutUint32(packet[8:12], udp_action_connect)
+	binary.BigEndian.PutUint32(packet[12:16], transaction_id)
+
+	return packet
+}
+
+// buildAnnounce builds an announce request
+func buildAnnounce(connection_id uint64, transaction_id uint32, request AnnounceRequest) []byte {
+	packet := make([]byte, udp_announce_length)
+	num_want := int32(-1) // -1 lets the tracker choose
+
+	if request.NumWant > 0 {
+		num_want = int32(request.NumWant)
+	}
+
+	binary.BigEndian.PutUint64(packet[0:8], connection_id)
+	binary.BigEndian.PutUint32(packet[8:12], udp_action_announce)
+	binary.BigEndian.PutUint32(packet[12:16], transaction_id)
+	copy(packet[16:36], request.InfoHash[:])
+	copy(packet[36:56], request.PeerID[:])
+	binary.BigEndian.PutUint64(packet[56:64], uint64(request.Downloaded))
+	binary.BigEndian.PutUint64(packet[64:72], uint64(request.Left))
+	binary.BigEndian.PutUint64(packet[72:80], uint64(request.Uploaded))
+	binary.BigEndian.PutUint32(packet[80:84], udpEvents[request.Event])
+	binary.BigEndian.PutUint32(packet[84:88], 0) // default ip
+	binary.BigEndian.PutUint32(packet[88:92], request.Key)
+	binary.BigEndian.PutUint32(packet[92:96], uint32(num_want))
+	binary.BigEndian.PutUint16(packet[96:98], uint16(request.Port))
+
+	return packet
+}
+
+// buildScrape builds a scrape request
+func buildScrape(connection_id uint64, transaction_id uint32, info_hashes [][20]byte) []byte {
+	packet := make([]byte, udp_scrape_header_length, udp_scrape_header_length+20*len(info_hashes))
+
+	binary.BigEndian.PutUint64(packet[0:8], connection_id)
+	binary.BigEndian.PutUint32(packet[8:12], udp_action_scrape)
+	binary.BigEndian.PutUint32(packet[12:16], transaction_id)
+
+	for _, info_hash := range info_hashes {
+		packet = append(packet, info_hash[:]...)
+	}
+
+	return packet
+}
+
+// Announce announces to an udp tracker
+//
+// IPv6 trackers answer with 18 bytes peers, IPv4 trackers with 6 bytes peers
+func (u *UDPClient) Announce(ctx context.Context, tracker string, request AnnounceRequest) (AnnounceResponse, error) {
+	conn, err := u.dial(ctx, tracker)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	defer conn.Close()
+
+	response, err := u.transact(ctx, conn, udp_action_announce, func(connection_id uint64, transaction_id uint32) []byte {
+		return buildAnnounce(connection_id, transaction_id, request)
+	})
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	if len(response) < udp_announce_response_length {
+		return AnnounceResponse{}, fmt.Errorf("%w: announce response too short", ErrorUDPResponseCorrupted)
+	}
+
+	peer_length := CompactPeerLengthIPv4
+
+	if conn.RemoteAddr().(*net.UDPAddr).IP.To4() == nil {
+		peer_length = CompactPeerLengthIPv6
+	}
+
+	peers, err := DecodeCompactPeers(string(response[udp_announce_response_length:]), peer_length)
+
+	if err != nil {
+		return AnnounceResponse{}, err
+	}
+
+	return AnnounceResponse{
+		Tracker:    tracker,
+		Interval:   int(binary.BigEndian.Uint32(response[8:12])),
+		Incomplete: int(binary.BigEndian.Uint32(response[12:16])),
+		Complete:   int(binary.BigEndian.Uint32(response[16:20])),
+		Peers:      peers,
+	}, nil
+}
+
+// Scrape asks an udp tracker for the statistics of some torrents, returned in the same order
+func (u *UDPClient) Scrape(ctx context.Context, tracker string, info_hashes [][20]byte) ([]ScrapeFile, error) {
+	if len(info_hashes) > udp_max_scrape {
+		return nil, fmt.Errorf("%w: %d (max %d)", ErrorTooManyInfoHashes, len(info_hashes), udp_max_scrape)
+	}
+
+	conn, err := u.dial(ctx, tracker)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	response, err := u.transact(ctx, conn, udp_action_scrape, func(connection_id uint64, transaction_id uint32) []byte {
+		return buildScrape(connection_id, transaction_id, info_hashes)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) != udp_header_length+udp_scrape_file_length*len(info_hashes) {
+		return nil, fmt.Errorf("%w: bad scrape response length %d", ErrorUDPResponseCorrupted, len(response))
+	}
+
+	files := make([]ScrapeFile, len(info_hashes))
+
+	for index := range files {
+		start := udp_header_length + index*udp_scrape_file_length
+
+		files[index] = ScrapeFile{
+			Complete:   int(binary.BigEndian.Uint32(response[start : start+4])),
+			Downloaded: int(binary.BigEndian.Uint32(response[start+4 : start+8])),
+			Incomplete: int(binary.BigEndian.Uint32(response[start+8 : start+12])),
+		}
+	}
+
+	return files, nil
+}
+
+// udpAnnouncer announces to udp trackers for a Client
+type udpAnnouncer struct {
+	client *Client
+}
+
+// announce sends an announce request to an udp tracker
+func (u udpAnnouncer) announce(ctx context.Context, tracker string, request AnnounceRequest) (AnnounceResponse, error) {
+	return u.client.UDPClient.Announce(ctx, tracker, request)
+}
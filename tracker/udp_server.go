@@ -0,0 +1,263 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	UDPServerConnectionLifetime = 2 * time.Minute
+	UDPServerDefaultInterval    = 1800
+)
+
+var (
+	ErrorNoHandler = errors.New("no handler")
+)
+
+// UDPServer is a minimal udp tracker, announces and scrapes are answered by its handlers
+//
+// It is mostly used as a local stand-in for real udp trackers in tests
+type UDPServer struct {
+	AnnounceHandler func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error)
+	ScrapeHandler   func(info_hashes [][20]byte) ([]ScrapeFile, error)
+
+	conn        *net.UDPConn
+	connections map[uint64]time.Time
+	random      *rand.Rand
+	mutex       sync.Mutex
+}
+
+// ListenUDP creates an udp server listening on address (host:port)
+func ListenUDP(address string) (*UDPServer, error) {
+	udp_address, err := net.ResolveUDPAddr("udp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", udp_address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UDPServer{
+		conn:        conn,
+		connections: map[uint64]time.Time{},
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *UDPServer) Addr() *net.UDPAddr {
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// URL returns the udp url of the server
+func (s *UDPServer) URL() string {
+	return "udp://" + s.Addr().String()
+}
+
+// Close stops the server
+func (s *UDPServer) Close() error {
+	return s.conn.Close()
+}
+
+// Serve answers the requests until the server is closed
+func (s *UDPServer) Serve() error {
+	buffer := make([]byte, udp_max_packet_length)
+
+	for {
+		n, address, err := s.conn.ReadFromUDP(buffer)
+
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if response := s.handle(buffer[:n], address); response != nil {
+			s.conn.WriteToUDP(response, address)
+		}
+	}
+}
+
+// newConnection issues a connection id
+func (s *UDPServer) newConnection() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	for connection_id, expires := range s.connections {
+		if now.After(expires) {
+			delete(s.connections, connection_id)
+		}
+	}
+
+	connection_id := s.random.Uint64()
+	s.connections[connection_id] = now.Add(UDPServerConnectionLifetime)
+
+	return connection_id
+}
+
+// validConnection checks that a connection id has been issued and has not expired
+func (s *UDPServer) validConnection(connection_id uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expires, ok := s.connections[connection_id]
+
+	return ok && time.Now().Before(expires)
+}
+
+// handle answers a single request, nil is returned for packets that must be ignored
+func (s *UDPServer) handle(packet []byte, address *net.UDPAddr) []byte {
+	if len(packet) < udp_connect_length {
+		return nil
+	}
+
+	connection_id := binary.BigEndian.Uint64(packet[0:8])
+	action := binary.BigEndian.Uint32(packet[8:12])
+	transaction_id := binary.BigEndian.Uint32(packet[12:16])
+
+	if action == udp_action_connect {
+		if connection_id != udp_protocol_id {
+			return nil
+		}
+
+		response := make([]byte, udp_connect_length)
+		binary.BigEndian.PutUint32(response[0:4], udp_action_connect)
+		binary.BigEndian.PutUint32(response[4:8], transaction_id)
+		binary.BigEndian.PutUint64(response[8:16], s.newConnection())
+
+		return response
+	}
+
+	if !s.validConnection(connection_id) {
+		return buildError(transaction_id, "invalid connection id")
+	}
+
+	switch action {
+	case udp_action_announce:
+		return s.handleAnnounce(packet, transaction_id, address)
+	case udp_action_scrape:
+		return s.handleScrape(packet, transaction_id)
+	default:
+		return buildError(transaction_id, "unknown action")
+	}
+}
+
+// handleAnnounce answers an announce request
+func (s *UDPServer) handleAnnounce(packet []byte, transaction_id uint32, address *net.UDPAddr) []byte {
+	if len(packet) < udp_announce_length {
+		return buildError(transaction_id, "announce request too short")
+	}
+
+	if s.AnnounceHandler == nil {
+		return buildError(transaction_id, ErrorNoHandler.Error())
+	}
+
+	request := AnnounceRequest{
+		Downloaded: int(binary.BigEndian.Uint64(packet[56:64])),
+		Left:       int(binary.BigEndian.Uint64(packet[64:72])),
+		Uploaded:   int(binary.BigEndian.Uint64(packet[72:80])),
+		Key:        binary.BigEndian.Uint32(packet[88:92]),
+		NumWant:    int(int32(binary.BigEndian.Uint32(packet[92:96]))),
+		Port:       int(binary.BigEndian.Uint16(packet[96:98])),
+	}
+
+	copy(request.InfoHash[:], packet[16:36])
+	copy(request.PeerID[:], packet[36:56])
+
+	for event, value := range udpEvents {
+		if value == binary.BigEndian.Uint32(packet[80:84]) {
+			request.Event = event
+		}
+	}
+
+	announce_response, err := s.AnnounceHandler(request, address)
+
+	if err != nil {
+		return buildError(transaction_id, err.Error())
+	}
+
+	interval := announce_response.Interval
+
+	if interval <= 0 {
+		interval = UDPServerDefaultInterval
+	}
+
+	response := make([]byte, udp_announce_response_length)
+	binary.BigEndian.PutUint32(response[0:4], udp_action_announce)
+	binary.BigEndian.PutUint32(response[4:8], transaction_id)
+	binary.BigEndian.PutUint32(response[8:12], uint32(interval))
+	binary.BigEndian.PutUint32(response[12:16], uint32(announce_response.Incomplete))
+	binary.BigEndian.PutUint32(response[16:20], uint32(announce_response.Complete))
+
+	// the peers are given in the address family of the request
+	peers4, peers6 := EncodeCompactPeers(announce_response.Peers)
+
+	if address.IP.To4() != nil {
+		return append(response, peers4...)
+	}
+
+	return append(response, peers6...)
+}
+
+// handleScrape answers a scrape request
+func (s *UDPServer) handleScrape(packet []byte, transaction_id uint32) []byte {
+	if (len(packet)-udp_scrape_header_length)%20 != 0 {
+		return buildError(transaction_id, "scrape request corrupted")
+	}
+
+	if s.ScrapeHandler == nil {
+		return buildError(transaction_id, ErrorNoHandler.Error())
+	}
+
+	info_hashes := make([][20]byte, (len(packet)-udp_scrape_header_length)/20)
+
+	for index := range info_hashes {
+		start := udp_scrape_header_length + index*20
+		copy(info_hashes[index][:], packet[start:start+20])
+	}
+
+	files, err := s.ScrapeHandler(info_hashes)
+
+	if err != nil {
+		return buildError(transaction_id, err.Error())
+	}
+
+	if len(files) != len(info_hashes) {
+		return buildError(transaction_id, "scrape handler failed")
+	}
+
+	response := make([]byte, udp_header_length+udp_scrape_file_length*len(files))
+	binary.BigEndian.PutUint32(response[0:4], udp_action_scrape)
+	binary.BigEndian.PutUint32(response[4:8], transaction_id)
+
+	for index, file := range files {
+		start := udp_header_length + index*udp_scrape_file_length
+
+		binary.BigEndian.PutUint32(response[start:start+4], uint32(file.Complete))
+		binary.BigEndian.PutUint32(response[start+4:start+8], uint32(file.Downloaded))
+		binary.BigEndian.PutUint32(response[start+8:start+12], uint32(file.Incomplete))
+	}
+
+	return response
+}
+
+// buildError builds an error response
+func buildError(transaction_id uint32, message string) []byte {
+	response := make([]byte, udp_header_length, udp_header_length+len(message))
+
+	binary.BigEndian.PutUint32(response[0:4], udp_action_error)
+	binary.BigEndian.PutUint32(response[4:8], transaction_id)
+
+	return append(response, message...)
+}
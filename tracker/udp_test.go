@@ -0,0 +1,352 @@
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+// newTestUDPServer creates an udp server on a local address, it must be started once the handlers are set
+func newTestUDPServer(t *testing.T, address string) *UDPServer {
+	server, err := ListenUDP(address)
+
+	if err != nil {
+		t.Skipf("failed to listen on [%s]: %v", address, err)
+	}
+
+	return server
+}
+
+// newTestUDPClient creates an udp client with short timeouts
+func newTestUDPClient() *UDPClient {
+	client := NewUDPClient()
+	client.BaseTimeout = 50 * time.Millisecond
+	client.MaxRetries = 2
+
+	return client
+}
+
+func TestBuildAnnounce(t *testing.T) {
+	request := AnnounceRequest{
+		InfoHash:   [20]byte{1, 2, 3},
+		PeerID:     [20]byte{4, 5, 6},
+		Port:       6881,
+		Uploaded:   1,
+		Downloaded: 2,
+		Left:       3,
+		Event:      EventStopped,
+		Key:        7,
+	}
+
+	packet := buildAnnounce(42, 99, request)
+
+	if len(packet) != udp_announce_length {
+		t.Fatalf("expected length %d | %d output", udp_announce_length, len(packet))
+	}
+
+	fields := []struct {
+		name     string
+		output   uint64
+		expected uint64
+	}{
+		{"connection id", binary.BigEndian.Uint64(packet[0:8]), 42},
+		{"action", uint64(binary.BigEndian.Uint32(packet[8:12])), udp_action_announce},
+		{"transaction id", uint64(binary.BigEndian.Uint32(packet[12:16])), 99},
+		{"downloaded", binary.BigEndian.Uint64(packet[56:64]), 2},
+		{"left", binary.BigEndian.Uint64(packet[64:72]), 3},
+		{"uploaded", binary.BigEndian.Uint64(packet[72:80]), 1},
+		{"event", uint64(binary.BigEndian.Uint32(packet[80:84])), 3},
+		{"key", uint64(binary.BigEndian.Uint32(packet[88:92])), 7},
+		{"num want", uint64(binary.BigEndian.Uint32(packet[92:96])), 0xffffffff},
+		{"port", uint64(binary.BigEndian.Uint16(packet[96:98])), 6881},
+	}
+
+	for _, field := range fields {
+		if field.expected != field.output {
+			t.Errorf("%s: expected %d | %d output", field.name, field.expected, field.output)
+		}
+	}
+}
+
+func TestUDPClientAnnounce(t *testing.T) {
+	addresses := []struct {
+		address       string
+		expected_peer Peer
+	}{
+		{address: "127.0.0.1:0", expected_peer: Peer{IP: net.IP{10, 0, 0, 1}, Port: 51413}},
+		{address: "[::1]:0", expected_peer: Peer{IP: net.ParseIP("2001:db8::1"), Port: 51414}},
+	}
+
+	for _, test := range addresses {
+		server := newTestUDPServer(t, test.address)
+		defer server.Close()
+
+		var received AnnounceRequest
+		mutex := sync.Mutex{}
+
+		server.AnnounceHandler = func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+			mutex.Lock()
+			received = request
+			mutex.Unlock()
+
+			return AnnounceResponse{
+				Interval:   900,
+				Complete:   5,
+				Incomplete: 6,
+				Peers: []Peer{
+					{IP: net.IP{10, 0, 0, 1}, Port: 51413},
+					{IP: net.ParseIP("2001:db8::1"), Port: 51414},
+				},
+			}, nil
+		}
+
+		go server.Serve()
+
+		request := AnnounceRequest{
+			InfoHash: [20]byte{1, 2, 3},
+			PeerID:   [20]byte{4, 5, 6},
+			Port:     6881,
+			Left:     10,
+			Event:    EventStarted,
+		}
+
+		response, err := newTestUDPClient().Announce(context.Background(), server.URL(), request)
+
+		if err != nil {
+			t.Fatalf("[%s] failed to announce: %v", test.address, err)
+		}
+
+		mutex.Lock()
+		if received.InfoHash != request.InfoHash || received.PeerID != request.PeerID || received.Event != EventStarted || received.Left != 10 || received.NumWant != -1 {
+			t.Errorf("[%s] bad request received: %+v", test.address, received)
+		}
+		mutex.Unlock()
+		if response.Interval != 900 || response.Complete != 5 || response.Incomplete != 6 {
+			t.Errorf("[%s] bad response: %+v", test.address, response)
+		}
+		if len(response.Peers) != 1 || !response.Peers[0].IP.Equal(test.expected_peer.IP) || response.Peers[0].Port != test.expected_peer.Port {
+			t.Errorf("[%s] expected peers [%v] | %v output", test.address, test.expected_peer, response.Peers)
+		}
+	}
+}
+
+func TestUDPClientScrape(t *testing.T) {
+	server := newTestUDPServer(t, "127.0.0.1:0")
+	defer server.Close()
+
+	server.ScrapeHandler = func(info_hashes [][20]byte) ([]ScrapeFile, error) {
+		files := []ScrapeFile{}
+
+		for _, info_hash := range info_hashes {
+			files = append(files, ScrapeFile{Complete: int(info_hash[0]), Downloaded: 1, Incomplete: 2})
+		}
+
+		return files, nil
+	}
+
+	go server.Serve()
+
+	client := newTestUDPClient()
+
+	files, err := client.Scrape(context.Background(), server.URL(), [][20]byte{{3}, {4}})
+
+	if err != nil {
+		t.Fatalf("failed to scrape: %v", err)
+	}
+
+	if expected := []ScrapeFile{{3, 1, 2}, {4, 1, 2}}; !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v | %v output", expected, files)
+	}
+
+	if _, err := client.Scrape(context.Background(), server.URL(), make([][20]byte, udp_max_scrape+1)); !errors.Is(err, ErrorTooManyInfoHashes) {
+		t.Errorf("expected [%v] | [%v] output", ErrorTooManyInfoHashes, err)
+	}
+}
+
+func TestUDPClientError(t *testing.T) {
+	server := newTestUDPServer(t, "127.0.0.1:0")
+	defer server.Close()
+
+	server.AnnounceHandler = func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+		return AnnounceResponse{}, errors.New("torrent not registered")
+	}
+
+	go server.Serve()
+
+	_, err := newTestUDPClient().Announce(context.Background(), server.URL(), AnnounceRequest{})
+
+	if !errors.Is(err, ErrorTrackerFailure) || err.Error() != ErrorTrackerFailure.Error()+": torrent not registered" {
+		t.Errorf("expected [%v] | [%v] output", ErrorTrackerFailure, err)
+	}
+}
+
+func TestUDPClientConnectionCache(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer conn.Close()
+
+	server := &UDPServer{
+		conn:        conn,
+		connections: map[uint64]time.Time{},
+		random:      newTestUDPClient().random,
+		AnnounceHandler: func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+			return AnnounceResponse{}, nil
+		},
+	}
+
+	go server.Serve()
+
+	client := newTestUDPClient()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Announce(context.Background(), server.URL(), AnnounceRequest{}); err != nil {
+			t.Fatalf("failed to announce: %v", err)
+		}
+	}
+
+	server.mutex.Lock()
+	connections := len(server.connections)
+	server.mutex.Unlock()
+
+	if connections != 1 {
+		t.Errorf("expected 1 connection | %d output", connections)
+	}
+}
+
+func TestUDPClientRetransmission(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer conn.Close()
+
+	server := &UDPServer{conn: conn, connections: map[uint64]time.Time{}, random: newTestUDPClient().random}
+	server.AnnounceHandler = func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+		return AnnounceResponse{Interval: 60}, nil
+	}
+
+	// the first packet of each action is lost
+	go func() {
+		buffer := make([]byte, udp_max_packet_length)
+		seen := map[uint32]bool{}
+
+		for {
+			n, address, err := conn.ReadFromUDP(buffer)
+
+			if err != nil {
+				return
+			}
+
+			action := binary.BigEndian.Uint32(buffer[8:12])
+
+			if !seen[action] {
+				seen[action] = true
+				continue
+			}
+
+			conn.WriteToUDP(server.handle(buffer[:n], address), address)
+		}
+	}()
+
+	response, err := newTestUDPClient().Announce(context.Background(), server.URL(), AnnounceRequest{})
+
+	if err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if response.Interval != 60 {
+		t.Errorf("expected interval 60 | %d output", response.Interval)
+	}
+}
+
+func TestUDPClientCancellation(t *testing.T) {
+	// a socket that never answers
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer conn.Close()
+
+	client := NewUDPClient() // 15 seconds of timeout
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wait := sync.WaitGroup{}
+	wait.Add(1)
+
+	start := time.Now()
+
+	go func() {
+		defer wait.Done()
+
+		if _, err := client.Announce(ctx, "udp://"+conn.LocalAddr().String(), AnnounceRequest{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected [%v] | [%v] output", context.Canceled, err)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wait.Wait()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("announce not interrupted by the context: %v", elapsed)
+	}
+}
+
+func TestUDPClientTimeout(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer conn.Close()
+
+	client := newTestUDPClient()
+	client.MaxRetries = 1
+
+	if _, err := client.Announce(context.Background(), "udp://"+conn.LocalAddr().String(), AnnounceRequest{}); !errors.Is(err, ErrorUDPTimeout) {
+		t.Errorf("expected [%v] | [%v] output", ErrorUDPTimeout, err)
+	}
+}
+
+func TestClientAnnounceUDP(t *testing.T) {
+	server := newTestUDPServer(t, "127.0.0.1:0")
+	defer server.Close()
+
+	server.AnnounceHandler = func(request AnnounceRequest, address *net.UDPAddr) (AnnounceResponse, error) {
+		return AnnounceResponse{Interval: 120}, nil
+	}
+
+	go server.Serve()
+
+	client, err := NewClient(&bencode.Bencode{Announce: server.URL()}, [20]byte{}, 6881)
+
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+
+	client.UDPClient = newTestUDPClient()
+
+	response, err := client.Announce(context.Background(), 0, 0, 0, EventStarted)
+
+	if err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if response.Tracker != server.URL() || response.Interval != 120 {
+		t.Errorf("bad response: %+v", response)
+	}
+}
@@ -0,0 +1,445 @@
+// Package watch provide a polling watcher of a drop directory of torrent files
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+const (
+	torrent_extension    = ".torrent"
+	default_interval     = time.Second
+	default_stable_polls = 1
+)
+
+type EventType int
+
+const (
+	EventAdded   EventType = iota // a new torrent file is ready
+	EventChanged                  // a torrent file already sent has been rewritten
+	EventRemoved                  // a torrent file already sent has been deleted
+	EventInvalid                  // a torrent file can not be parsed or unmarshalled
+)
+
+// String returns the name of the event type
+func (e EventType) String() string {
+	switch e {
+	case EventAdded:
+		return "added"
+	case EventChanged:
+		return "changed"
+	case EventRemoved:
+		return "removed"
+	case EventInvalid:
+		return "invalid"
+	}
+
+	return "unknown"
+}
+
+// Event is a change of a torrent file of the watched directory
+type Event struct {
+	Type    EventType
+	Path    string           // path of the file in the watched directory
+	Torrent *bencode.Bencode // the unmarshalled torrent of an added or changed file
+	Err     error            // why the file is invalid (a *parser.SyntaxError if it is not bencoded), or why an action failed
+	MovedTo string           // path of the file once moved to the processed or failed directory
+}
+
+// Options configures a Watcher, the zero value only sends events
+type Options struct {
+	Interval     time.Duration // time between two polls, 1 second by default
+	StablePolls  int           // polls a file must keep the same size and modification time to be read, 1 by default
+	ProcessedDir string        // directory where the valid torrents are moved, they stay in place if empty
+	FailedDir    string        // directory where the invalid torrents are moved, they stay in place if empty
+	Trackers     [][]string    // tiers replacing the trackers of the valid torrents, the file is rewritten if not nil
+}
+
+// fileState is what the watcher knows about a file
+type fileState struct {
+	size         int64
+	modification time.Time
+	stable       int  // consecutive polls with the same size and modification time
+	sent         bool // an event has been sent for the current content
+	changed      bool // an event has been sent for a former content
+}
+
+// Watcher polls a directory for torrent files, it works on any filesystem
+type Watcher struct {
+	directory string
+	options   Options
+	files     map[string]*fileState
+}
+
+// New returns a watcher of a directory
+func New(directory string, options Options) *Watcher {
+	if options.Interval <= 0 {
+		options.Interval = default_interval
+	}
+
+	if options.StablePolls <= 0 {
+		options.StablePolls = default_stable_polls
+	}
+
+	return &Watcher{
+		directory: directory,
+		options:   options,
+		files:     make(map[string]*fileState),
+	}
+}
+
+// Poll scans the directory once and returns the events, sorted by path
+//
+// A file is read once it kept the same size and modification time for StablePolls polls,
+// so a file being written is not read before it is complete. Poll is not safe for concurrent use.
+func (w *Watcher) Poll() ([]Event, error) {
+	entries, err := os.ReadDir(w.directory)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	present := map[string]bool{}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(entry.Name()), torrent_extension) {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			// removed since the directory was read
+			continue
+		}
+
+		path := filepath.Join(w.directory, entry.Name())
+		present[path] = true
+
+		state, ok := w.files[path]
+
+		if !ok {
+			w.files[path] = &fileState{size: info.Size(), modification: info.ModTime()}
+			continue
+		}
+
+		if state.size != info.Size() || !state.modification.Equal(info.ModTime()) {
+			state.size = info.Size()
+			state.modification = info.ModTime()
+			state.stable = 0
+
+			if state.sent {
+				state.sent = false
+				state.changed = true
+			}
+
+			continue
+		}
+
+		if state.stable < w.options.StablePolls {
+			state.stable++
+		}
+
+		if state.stable < w.options.StablePolls {
+			continue
+		}
+
+		if event, ok := w.process(path, state); ok {
+			events = append(events, event)
+		}
+	}
+
+	for path, state := range w.files {
+		if !present[path] {
+			delete(w.files, path)
+
+			if state.sent {
+				events = append(events, Event{Type: EventRemoved, Path: path})
+			}
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+
+	return events, nil
+}
+
+// process reads a stable file and applies the actions, it returns false if the file has already been sent
+func (w *Watcher) process(path string, state *fileState) (Event, bool) {
+	if state.sent {
+		return Event{}, false
+	}
+
+	event := Event{
+		Type: EventAdded,
+		Path: path,
+	}
+
+	if state.changed {
+		event.Type = EventChanged
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		// retried at the next poll
+		return Event{}, false
+	}
+
+	state.sent = true
+
+	event.Torrent, data, event.Err = w.read(data)
+
+	if event.Err != nil {
+		event.Type = EventInvalid
+		event.Torrent = nil
+		event.MovedTo, err = w.move(path, w.options.FailedDir, nil)
+	} else {
+		event.MovedTo, err = w.move(path, w.options.ProcessedDir, data)
+	}
+
+	if err != nil && event.Err == nil {
+		event.Err = err
+	}
+
+	if len(event.MovedTo) > 0 {
+		delete(w.files, path)
+	} else if info, err := os.Stat(path); err == nil {
+		// a rewritten file is not seen as changed
+		state.size = info.Size()
+		state.modification = info.ModTime()
+	}
+
+	return event, true
+}
+
+// read parses and unmarshalls a torrent, it returns the rewritten bytes if the trackers are replaced
+func (w *Watcher) read(data []byte) (*bencode.Bencode, []byte, error) {
+	// the dictionaries keep their order, a rewritten torrent only differs by its trackers
+	element, err := parser.ParseElementWithOptions(bufio.NewReader(bytes.NewReader(data)), parser.Options{OrderedDictionaries: true, SyntaxErrors: true})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dictionary, ok := element.(*parser.OrderedDict)
+
+	if !ok {
+		return nil, nil, bencode.ErrorDataIsNotADictionary
+	}
+
+	bc := &bencode.Bencode{
+		Data: parser.ToMap(dictionary),
+	}
+
+	if err := bc.UnmarshallAll(); err != nil {
+		return nil, nil, err
+	}
+
+	// the info hash is the hash of the info dictionary as written, even if it is not canonical
+	raw_info, err := parser.RawValue(data, bencode.DictionaryKeyInfo)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bc.InfoHash = sha1.Sum(raw_info)
+
+	if w.options.Trackers == nil {
+		return bc, nil, nil
+	}
+
+	setTrackers(dictionary, w.options.Trackers)
+	dictionary.Set(bencode.DictionaryKeyInfo, bencode.RawElement(raw_info))
+
+	encoded, err := bencode.EncodeElement(dictionary)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bc, []byte(encoded), nil
+}
+
+// setTrackers replaces the announce and the announce list of a torrent
+func setTrackers(dictionary *parser.OrderedDict, tiers [][]string) {
+	dictionary.Delete(bencode.DictionaryKeyAnnounce)
+	dictionary.Delete(bencode.DictionaryKeyAnnounceList)
+
+	announce_list := []interface{}{}
+
+	for _, tier := range tiers {
+		urls := []interface{}{}
+
+		for _, url := range tier {
+			urls = append(urls, url)
+		}
+
+		if len(urls) > 0 {
+			announce_list = append(announce_list, urls)
+		}
+	}
+
+	if len(announce_list) == 0 {
+		return
+	}
+
+	insertSorted(dictionary, bencode.DictionaryKeyAnnounce, announce_list[0].([]interface{})[0])
+
+	if len(announce_list) > 1 || len(announce_list[0].([]interface{})) > 1 {
+		insertSorted(dictionary, bencode.DictionaryKeyAnnounceList, announce_list)
+	}
+}
+
+// insertSorted inserts an entry before the first greater key, the other entries keep their order
+func insertSorted(dictionary *parser.OrderedDict, key string, value interface{}) {
+	index := 0
+
+	for index < len(dictionary.Entries) && dictionary.Entries[index].Key <= key {
+		index++
+	}
+
+	dictionary.Entries = append(dictionary.Entries, parser.DictionaryEntry{})
+	copy(dictionary.Entries[index+1:], dictionary.Entries[index:])
+	dictionary.Entries[index] = parser.DictionaryEntry{Key: key, Value: value}
+}
+
+// linkUnique links a file in a directory under a name not used yet, name.torrent then name.1.torrent...
+//
+// a link never replaces an existing file, the name is claimed even if another process writes to the directory
+func linkUnique(source string, directory string, name string) (string, error) {
+	extension := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, extension)
+	destination := filepath.Join(directory, name)
+
+	for count := 1; ; count++ {
+		if err := os.Link(source, destination); !errors.Is(err, os.ErrExist) {
+			if err != nil {
+				return "", err
+			}
+
+			return destination, nil
+		}
+
+		destination = filepath.Join(directory, fmt.Sprintf("%s.%d%s", stem, count, extension))
+	}
+}
+
+// writeTemporary writes data to a new temporary file of a directory and returns its path
+func writeTemporary(directory string, name string, data []byte) (string, error) {
+	file, err := os.CreateTemp(directory, name+".*.tmp")
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(data)
+
+	if err == nil {
+		err = file.Chmod(0644)
+	}
+
+	if close_err := file.Close(); err == nil {
+		err = close_err
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// move writes a file to a directory and removes it, or rewrites it in place if the directory is empty
+//
+// The data replaces the content of the file if not nil, a file of the directory with the same name is kept
+func (w *Watcher) move(path string, directory string, data []byte) (string, error) {
+	source := path
+
+	if len(directory) > 0 {
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			return "", err
+		}
+	}
+
+	if data != nil {
+		temporary_directory := directory
+
+		if len(directory) == 0 {
+			temporary_directory = filepath.Dir(path)
+		}
+
+		// the file is renamed or linked once written, a reader never sees a partial torrent
+		temporary, err := writeTemporary(temporary_directory, filepath.Base(path), data)
+
+		if err != nil {
+			return "", err
+		}
+
+		defer os.Remove(temporary)
+
+		if len(directory) == 0 {
+			return "", os.Rename(temporary, path)
+		}
+
+		source = temporary
+	}
+
+	if len(directory) == 0 {
+		return "", nil
+	}
+
+	destination, err := linkUnique(source, directory, filepath.Base(path))
+
+	if err != nil {
+		return "", err
+	}
+
+	return destination, os.Remove(path)
+}
+
+// Run polls the directory until the context is canceled and sends the events
+//
+// It returns the context error, or the error of a poll if the directory can not be read
+func (w *Watcher) Run(ctx context.Context, events chan<- Event) error {
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		poll_events, err := w.Poll()
+
+		if err != nil {
+			return err
+		}
+
+		for _, event := range poll_events {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/parser"
+)
+
+// readTestFile returns the bytes of a test torrent
+func readTestFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("..", ".test_files", name))
+
+	if err != nil {
+		t.Fatalf("failed to read file [%s]: %v", name, err)
+	}
+
+	return data
+}
+
+// writeFile writes a file of the watched directory
+func writeFile(t *testing.T, path string, data []byte) {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file [%s]: %v", path, err)
+	}
+}
+
+// poll polls a watcher and returns the type of the events
+func poll(t *testing.T, w *Watcher) ([]Event, []EventType) {
+	events, err := w.Poll()
+
+	if err != nil {
+		t.Fatalf("failed to poll: %v", err)
+	}
+
+	types := []EventType{}
+
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+
+	return events, types
+}
+
+func TestWatcherPoll(t *testing.T) {
+	directory := t.TempDir()
+	w := New(directory, Options{})
+
+	arch_path := filepath.Join(directory, "arch.torrent")
+	broken_path := filepath.Join(directory, "broken.torrent")
+
+	writeFile(t, arch_path, readTestFile(t, "arch.torrent"))
+	writeFile(t, broken_path, []byte("d8:announce"))
+	writeFile(t, filepath.Join(directory, "notes.txt"), []byte("not a torrent"))
+
+	tests := []struct {
+		before   func()
+		expected []EventType
+	}{
+		// the files are read once they are stable
+		{nil, []EventType{}},
+		{nil, []EventType{EventAdded, EventInvalid}},
+		{nil, []EventType{}},
+		// rewritten
+		{func() { writeFile(t, arch_path, readTestFile(t, "minecraft.torrent")) }, []EventType{}},
+		{nil, []EventType{EventChanged}},
+		// deleted
+		{func() { os.Remove(arch_path) }, []EventType{EventRemoved}},
+		{nil, []EventType{}},
+	}
+
+	for index, test := range tests {
+		if test.before != nil {
+			test.before()
+		}
+
+		events, types := poll(t, w)
+
+		if !reflect.DeepEqual(types, test.expected) {
+			t.Fatalf("test %d: expected %v | %v output", index, test.expected, types)
+		}
+
+		for _, event := range events {
+			switch event.Type {
+			case EventAdded, EventChanged:
+				if event.Torrent == nil || event.Err != nil {
+					t.Errorf("test %d: expected a torrent | [%v] output", index, event.Err)
+				}
+			case EventInvalid:
+				syntax_error := &parser.SyntaxError{}
+
+				if event.Path != broken_path || !errors.As(event.Err, &syntax_error) {
+					t.Errorf("test %d: expected a syntax error | [%v] output", index, event.Err)
+				}
+			}
+		}
+	}
+}
+
+func TestWatcherActions(t *testing.T) {
+	directory := t.TempDir()
+	processed := filepath.Join(directory, "processed")
+	failed := filepath.Join(directory, "failed")
+
+	w := New(directory, Options{
+		ProcessedDir: processed,
+		FailedDir:    failed,
+		Trackers:     [][]string{{"http://a/announce"}, {"http://b/announce"}},
+	})
+
+	writeFile(t, filepath.Join(directory, "ubuntu.torrent"), readTestFile(t, "ubuntu.torrent"))
+	writeFile(t, filepath.Join(directory, "broken.torrent"), []byte("i1e"))
+
+	poll(t, w)
+	events, types := poll(t, w)
+
+	if !reflect.DeepEqual(types, []EventType{EventInvalid, EventAdded}) {
+		t.Fatalf("expected [invalid added] | %v output", types)
+	}
+
+	if events[0].MovedTo != filepath.Join(failed, "broken.torrent") || !errors.Is(events[0].Err, bencode.ErrorDataIsNotADictionary) {
+		t.Errorf("expected a move to [%s] | [%s] output: %v", failed, events[0].MovedTo, events[0].Err)
+	}
+
+	if events[1].MovedTo != filepath.Join(processed, "ubuntu.torrent") {
+		t.Errorf("expected a move to [%s] | [%s] output", processed, events[1].MovedTo)
+	}
+
+	if _, err := os.Stat(filepath.Join(directory, "ubuntu.torrent")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the torrent to be moved: %v", err)
+	}
+
+	data, err := os.ReadFile(events[1].MovedTo)
+
+	if err != nil {
+		t.Fatalf("failed to read the processed torrent: %v", err)
+	}
+
+	bc := bencode.Bencode{}
+	bc.Data, _ = parser.ParseElement(bufio.NewReader(bytes.NewReader(data)))
+
+	if err := bc.UnmarshallAll(); err != nil {
+		t.Fatalf("failed to unmarshall the processed torrent: %v", err)
+	}
+
+	if err := bc.GetInfoHash(); err != nil || bc.InfoHash != events[1].Torrent.InfoHash {
+		t.Errorf("expected the info hash to be kept: %v", err)
+	}
+
+	if bc.Announce != "http://a/announce" || !reflect.DeepEqual(bc.AnnounceList, [][]string{{"http://a/announce"}, {"http://b/announce"}}) {
+		t.Errorf("expected the trackers to be replaced | [%s] %v output", bc.Announce, bc.AnnounceList)
+	}
+
+	if _, types := poll(t, w); len(types) != 0 {
+		t.Errorf("expected no event | %v output", types)
+	}
+}
+
+func TestWatcherNonCanonical(t *testing.T) {
+	directory := t.TempDir()
+	processed := filepath.Join(directory, "processed")
+
+	w := New(directory, Options{
+		ProcessedDir: processed,
+		Trackers:     [][]string{{"http://a/announce"}},
+	})
+
+	// the numbers of info have leading zeros and its keys are not sorted, it would change once encoded again
+	raw_info := "d4:name4:test6:lengthi05e12:piece lengthi016384e6:pieces20:" + strings.Repeat("a", 20) + "e"
+	data := []byte("d8:announce13:http://z/test4:info" + raw_info + "e")
+
+	// a file of the processed directory with the same name is kept
+	writeFile(t, filepath.Join(directory, "test.torrent"), data)
+
+	if err := os.MkdirAll(processed, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	writeFile(t, filepath.Join(processed, "test.torrent"), []byte("previous"))
+
+	poll(t, w)
+	events, types := poll(t, w)
+
+	if !reflect.DeepEqual(types, []EventType{EventAdded}) {
+		t.Fatalf("expected [added] | %v output", types)
+	}
+
+	if events[0].Torrent.InfoHash != sha1.Sum([]byte(raw_info)) {
+		t.Errorf("expected the hash of the info as written")
+	}
+
+	if events[0].MovedTo != filepath.Join(processed, "test.1.torrent") {
+		t.Errorf("expected a move to [%s] | [%s] output", filepath.Join(processed, "test.1.torrent"), events[0].MovedTo)
+	}
+
+	if previous, err := os.ReadFile(filepath.Join(processed, "test.torrent")); err != nil || string(previous) != "previous" {
+		t.Errorf("expected the previous file to be kept | [%s] output: %v", previous, err)
+	}
+
+	// no temporary file is left
+	if entries, err := os.ReadDir(processed); err != nil || len(entries) != 2 {
+		t.Errorf("expected [2] files | %v output: %v", entries, err)
+	}
+
+	expected := "d8:announce17:http://a/announce4:info" + raw_info + "e"
+
+	if output, err := os.ReadFile(events[0].MovedTo); err != nil || string(output) != expected {
+		t.Errorf("expected [%s] | [%s] output: %v", expected, output, err)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	directory := t.TempDir()
+	writeFile(t, filepath.Join(directory, "arch.torrent"), readTestFile(t, "arch.torrent"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan Event)
+	done := make(chan error)
+
+	go func() {
+		done <- New(directory, Options{Interval: 10 * time.Millisecond}).Run(ctx, events)
+	}()
+
+	select {
+	case event := <-events:
+		if event.Type != EventAdded {
+			t.Errorf("expected [%s] | [%s] output", EventAdded, event.Type)
+		}
+	case <-ctx.Done():
+		t.Fatalf("no event received")
+	}
+
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected [%v] | [%v] output", context.Canceled, err)
+	}
+
+	if err := New(filepath.Join(directory, "missing"), Options{}).Run(context.Background(), events); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
+
+func TestSetTrackers(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		tiers    [][]string
+		expected []string
+	}{
+		{[]string{"info"}, [][]string{{"a"}}, []string{"announce", "info"}},
+		{[]string{"announce", "info"}, [][]string{{"a"}, {"b"}}, []string{"announce", "announce-list", "info"}},
+		{[]string{"announce-list", "announce", "info"}, [][]string{{"a", "b"}}, []string{"announce", "announce-list", "info"}},
+		// the keys of a non-canonical torrent keep their order
+		{[]string{"info", "comment", "announce"}, [][]string{{"a"}}, []string{"announce", "info", "comment"}},
+		{[]string{"z", "info", "announce"}, [][]string{{"a"}}, []string{"announce", "z", "info"}},
+		{[]string{"info", "announce"}, [][]string{}, []string{"info"}},
+	}
+
+	for index, test := range tests {
+		dictionary := &parser.OrderedDict{}
+
+		for _, key := range test.keys {
+			dictionary.Entries = append(dictionary.Entries, parser.DictionaryEntry{Key: key, Value: 1})
+		}
+
+		setTrackers(dictionary, test.tiers)
+
+		if output := dictionary.Keys(); !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("test %d: expected %v | %v output", index, test.expected, output)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package webseed provide a downloader of torrent content from http web seeds
+//
+// http://www.bittorrent.org/beps/bep_0019.html
+package webseed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/trixky/gobencode/bencode"
+	"github.com/trixky/gobencode/internal/mirror"
+)
+
+var (
+	ErrorNoWebSeedFound     = errors.New("no web seed found")
+	ErrorUnexpectedHttpCode = errors.New("unexpected http code")
+	ErrorPieceUnavailable   = errors.New("piece unavailable from all the web seeds")
+	ErrorRangeIgnored       = errors.New("range ignored by the web seed")
+	ErrorBadContentRange    = errors.New("unexpected content range")
+)
+
+// Client downloads the pieces of a torrent from its web seeds (url-list)
+//
+// A piece failing on a web seed (http error, hash mismatch...) is retried on the next one
+type Client struct {
+	HttpClient *http.Client
+
+	info       *bencode.Info
+	multi_file bool
+	mirrors    *mirror.List
+}
+
+// NewClient creates a client for the web seeds of a torrent
+func NewClient(bc *bencode.Bencode) (*Client, error) {
+	mirrors, err := mirror.NewList(bc.UrlList)
+
+	if err != nil {
+		return nil, ErrorNoWebSeedFound
+	}
+
+	return &Client{
+		HttpClient: http.DefaultClient,
+		info:       &bc.Info,
+		multi_file: bc.IsMultiFile(),
+		mirrors:    mirrors,
+	}, nil
+}
+
+// Mirrors returns the web seeds, the last successful one first
+func (c *Client) Mirrors() []string {
+	return c.mirrors.Urls()
+}
+
+// FileUrl returns the url of a file on a web seed
+//
+// http://www.bittorrent.org/beps/bep_0019.html
+// in the single file case the url is the file itself, or a directory if it ends with a slash (the name is appended),
+// in the multi file case the name and the path of the file are appended, as stored in the torrent (RawName, RawPath)
+func (c *Client) FileUrl(mirror string, file_index int) string {
+	name := c.info.RawName
+
+	if len(name) == 0 {
+		name = c.info.DirectoryName
+	}
+
+	if !c.multi_file {
+		if strings.HasSuffix(mirror, "/") {
+			return mirror + url.PathEscape(name)
+		}
+
+		return mirror
+	}
+
+	path := c.info.Files[file_index].RawPath
+
+	if len(path) == 0 {
+		path = c.info.Files[file_index].DecomposedPath
+	}
+
+	components := append([]string{name}, path...)
+
+	if !strings.HasSuffix(mirror, "/") {
+		mirror += "/"
+	}
+
+	escaped := make([]string, len(components))
+
+	for index, component := range components {
+		escaped[index] = url.PathEscape(component)
+	}
+
+	return mirror + strings.Join(escaped, "/")
+}
+
+// fetchRange requests a byte range of a file
+//
+// A web seed ignoring the range (200) is only accepted for a range at the start of the file,
+// skipping the bytes before the range would download the file up to it for each piece
+func (c *Client) fetchRange(ctx context.Context, file_url string, offset int, length int) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, file_url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Range", "bytes="+strconv.Itoa(offset)+"-"+strconv.Itoa(offset+length-1))
+
+	response, err := c.HttpClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		// bytes <start>-<end>/<total>
+		content_range := response.Header.Get("Content-Range")
+		start := strings.TrimPrefix(strings.SplitN(content_range, "-", 2)[0], "bytes ")
+
+		if start != strconv.Itoa(offset) {
+			return nil, fmt.Errorf("%w: [%s] for the offset %d", ErrorBadContentRange, content_range, offset)
+		}
+	case http.StatusOK:
+		// the range is ignored, the whole file is sent
+		if offset > 0 {
+			return nil, fmt.Errorf("%w: offset %d", ErrorRangeIgnored, offset)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrorUnexpectedHttpCode, response.StatusCode)
+	}
+
+	data := make([]byte, length)
+
+	if _, err := io.ReadFull(response.Body, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// fetchPiece downloads a piece from a web seed, without checking it
+func (c *Client) fetchPiece(ctx context.Context, mirror string, piece_index int) ([]byte, error) {
+	spans, err := c.info.PieceSpans(piece_index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	piece := []byte{}
+
+	for _, span := range spans {
+		// http://www.bittorrent.org/beps/bep_0047.html
+		// the padding files are zeros, they are not requested
+		if c.info.Files[span.FileIndex].IsPadding() {
+			piece = append(piece, make([]byte, span.Length)...)
+			continue
+		}
+
+		data, err := c.fetchRange(ctx, c.FileUrl(mirror, span.FileIndex), span.Offset, span.Length)
+
+		if err != nil {
+			return nil, err
+		}
+
+		piece = append(piece, data...)
+	}
+
+	return piece, nil
+}
+
+// Piece downloads a piece and checks its hash, each web seed is tried until one succeeds
+func (c *Client) Piece(ctx context.Context, piece_index int) ([]byte, error) {
+	if _, err := c.info.PieceLengthAt(piece_index); err != nil {
+		return nil, err
+	}
+
+	data, err := c.mirrors.Try(ctx, func(mirror string) ([]byte, error) {
+		data, err := c.fetchPiece(ctx, mirror, piece_index)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return data, c.info.VerifyPiece(piece_index, data)
+	}, true)
+
+	if errors.Is(err, mirror.ErrorAllFailed) {
+		return nil, fmt.Errorf("%w: piece %d: %v", ErrorPieceUnavailable, piece_index, err)
+	}
+
+	return data, err
+}
+
+// Download downloads all the pieces in a directory, the files are laid out as their LocalPath
+func (c *Client) Download(ctx context.Context, root string) error {
+	return mirror.Download(ctx, c.info, root, c.Piece)
+}
@@ -0,0 +1,293 @@
+package webseed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/trixky/gobencode/bencode"
+)
+
+type testFile struct {
+	path    []string // nil for a single file torrent
+	content []byte
+}
+
+// newTorrent creates a torrent of some files with its web seeds
+func newTorrent(t *testing.T, name string, files []testFile, piece_length int, url_list []string) *bencode.Bencode {
+	content := []byte{}
+	info := map[string]interface{}{
+		bencode.DictionaryKeyName:        name,
+		bencode.DictionaryKeyPieceLength: piece_length,
+	}
+
+	if len(files) == 1 && files[0].path == nil {
+		info[bencode.DictionaryKeyLength] = len(files[0].content)
+		content = files[0].content
+	} else {
+		file_list := []interface{}{}
+
+		for _, file := range files {
+			path := []interface{}{}
+
+			for _, component := range file.path {
+				path = append(path, component)
+			}
+
+			file_list = append(file_list, map[string]interface{}{
+				bencode.DictionaryKeyLength: len(file.content),
+				bencode.DictionaryKeyPath:   path,
+			})
+			content = append(content, file.content...)
+		}
+
+		info[bencode.DictionaryKeyFiles] = file_list
+	}
+
+	pieces := ""
+
+	for start := 0; start < len(content); start += piece_length {
+		end := start + piece_length
+
+		if end > len(content) {
+			end = len(content)
+		}
+
+		hash := sha1.Sum(content[start:end])
+		pieces += string(hash[:])
+	}
+
+	info[bencode.DictionaryKeyPieces] = pieces
+
+	urls := []interface{}{}
+
+	for _, url := range url_list {
+		urls = append(urls, url)
+	}
+
+	bc := &bencode.Bencode{
+		Data: map[string]interface{}{
+			bencode.DictionaryKeyAnnounce: "http://tracker/announce",
+			bencode.DictionaryKeyInfo:     info,
+			bencode.DictionaryKeyUrlList:  urls,
+		},
+	}
+
+	if err := bc.UnmarshallAll(); err != nil {
+		t.Fatalf("failed to unmarshall torrent: %v", err)
+	}
+
+	return bc
+}
+
+// serve serves a directory, the requests are counted
+func serve(t *testing.T, root string, requests *int) *httptest.Server {
+	file_server := http.FileServer(http.Dir(root))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		file_server.ServeHTTP(w, r)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeTestFile writes a file served by a web seed
+func writeTestFile(t *testing.T, path string, content []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestFileUrl(t *testing.T) {
+	single := newTorrent(t, "file name.iso", []testFile{{content: []byte("abc")}}, 2, []string{"http://a/"})
+	multi := newTorrent(t, "dir", []testFile{{path: []string{"sub", "a b.txt"}, content: []byte("abc")}}, 2, []string{"http://a/"})
+
+	// Latin-1 names with UTF-8 alternates, the seeds serve the names as stored
+	legacy := &bencode.Bencode{Data: map[string]interface{}{
+		bencode.DictionaryKeyAnnounce: "http://tracker/announce",
+		bencode.DictionaryKeyUrlList:  []interface{}{"http://a/"},
+		bencode.DictionaryKeyInfo: map[string]interface{}{
+			bencode.DictionaryKeyName:        "caf\xe9",
+			bencode.DictionaryKeyNameUTF8:    "caf\u00e9",
+			bencode.DictionaryKeyPieceLength: 2,
+			bencode.DictionaryKeyPieces:      strings.Repeat("a", 40),
+			bencode.DictionaryKeyFiles: []interface{}{map[string]interface{}{
+				bencode.DictionaryKeyLength:   3,
+				bencode.DictionaryKeyPath:     []interface{}{"\xe9t\xe9.txt"},
+				bencode.DictionaryKeyPathUTF8: []interface{}{"\u00e9t\u00e9.txt"},
+			}},
+		},
+	}}
+
+	if err := legacy.UnmarshallAll(); err != nil {
+		t.Fatalf("failed to unmarshall torrent: %v", err)
+	}
+
+	tests := []struct {
+		bc       *bencode.Bencode
+		mirror   string
+		expected string
+	}{
+		{single, "http://a/files/", "http://a/files/file%20name.iso"},
+		{single, "http://a/files/other.iso", "http://a/files/other.iso"},
+		{multi, "http://a/files/", "http://a/files/dir/sub/a%20b.txt"},
+		{multi, "http://a/files", "http://a/files/dir/sub/a%20b.txt"},
+		{legacy, "http://a/", "http://a/caf%E9/%E9t%E9.txt"},
+	}
+
+	for index, test := range tests {
+		c, err := NewClient(test.bc)
+
+		if err != nil {
+			t.Fatalf("test %d: failed to create client: %v", index, err)
+		}
+
+		if output := c.FileUrl(test.mirror, 0); output != test.expected {
+			t.Errorf("test %d: expected [%s] | [%s] output", index, test.expected, output)
+		}
+	}
+
+	if _, err := NewClient(&bencode.Bencode{UrlList: []string{"ftp://a/"}}); err != ErrorNoWebSeedFound {
+		t.Errorf("expected [%v] | [%v] output", ErrorNoWebSeedFound, err)
+	}
+}
+
+func TestDownloadMultiFile(t *testing.T) {
+	files := []testFile{
+		{path: []string{"a.txt"}, content: []byte(strings.Repeat("a", 10))},
+		{path: []string{"empty"}, content: []byte{}},
+		{path: []string{"sub", "b.txt"}, content: []byte(strings.Repeat("b", 7))},
+	}
+
+	good_root := t.TempDir()
+	bad_root := t.TempDir()
+
+	for _, file := range files {
+		writeTestFile(t, filepath.Join(append([]string{good_root, "dir"}, file.path...)...), file.content)
+		// the bad web seed has a corrupted copy
+		writeTestFile(t, filepath.Join(append([]string{bad_root, "dir"}, file.path...)...), bytes.ToUpper(file.content))
+	}
+
+	good_requests, bad_requests := 0, 0
+	good := serve(t, good_root, &good_requests)
+	bad := serve(t, bad_root, &bad_requests)
+
+	bc := newTorrent(t, "dir", files, 4, []string{bad.URL + "/", "http://127.0.0.1:1/", good.URL})
+	c, err := NewClient(bc)
+
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	root := t.TempDir()
+
+	if err := c.Download(context.Background(), root); err != nil {
+		t.Fatalf("failed to download: %v", err)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(append([]string{root, "dir"}, file.path...)...))
+
+		if err != nil || !bytes.Equal(content, file.content) {
+			t.Errorf("expected [%s] | [%s] output: %v", file.content, content, err)
+		}
+	}
+
+	// the good web seed is tried first once it succeeded
+	if bad_requests != 1 || good_requests != 6 || c.Mirrors()[0] != good.URL {
+		t.Errorf("expected [1] [6] requests | [%d] [%d] output", bad_requests, good_requests)
+	}
+}
+
+func TestDownloadSingleFile(t *testing.T) {
+	content := []byte("single file content")
+	server_root := t.TempDir()
+	writeTestFile(t, filepath.Join(server_root, "file.bin"), content)
+
+	requests := 0
+	server := serve(t, server_root, &requests)
+
+	bc := newTorrent(t, "file.bin", []testFile{{content: content}}, 8, []string{server.URL + "/"})
+	c, err := NewClient(bc)
+
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	data, err := c.Piece(context.Background(), 2)
+
+	if err != nil || string(data) != "ent" {
+		t.Errorf("expected [ent] | [%s] output: %v", data, err)
+	}
+
+	root := t.TempDir()
+
+	if err := c.Download(context.Background(), root); err != nil {
+		t.Fatalf("failed to download: %v", err)
+	}
+
+	if output, err := os.ReadFile(filepath.Join(root, "file.bin")); err != nil || !bytes.Equal(output, content) {
+		t.Errorf("expected [%s] | [%s] output: %v", content, output, err)
+	}
+
+	// the web seed does not have the file
+	bc = newTorrent(t, "missing.bin", []testFile{{content: content}}, 8, []string{server.URL + "/"})
+	c, _ = NewClient(bc)
+
+	if _, err := c.Piece(context.Background(), 0); !errors.Is(err, ErrorPieceUnavailable) {
+		t.Errorf("expected [%v] | [%v] output", ErrorPieceUnavailable, err)
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	content := "0123456789"
+
+	tests := []struct {
+		status        int
+		content_range string
+		body          string
+		offset        int
+		expected      string
+		err           error
+	}{
+		{http.StatusPartialContent, "bytes 4-6/10", "456", 4, "456", nil},
+		{http.StatusPartialContent, "bytes 0-2/10", "012", 4, "", ErrorBadContentRange},
+		{http.StatusPartialContent, "", "456", 4, "", ErrorBadContentRange},
+		{http.StatusOK, "", content, 0, "012", nil},
+		{http.StatusOK, "", content, 4, "", ErrorRangeIgnored},
+		{http.StatusNotFound, "", "", 0, "", ErrorUnexpectedHttpCode},
+	}
+
+	for index, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(test.content_range) > 0 {
+				w.Header().Set("Content-Range", test.content_range)
+			}
+
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+
+		c := &Client{HttpClient: server.Client()}
+		output, err := c.fetchRange(context.Background(), server.URL, test.offset, 3)
+		server.Close()
+
+		if !errors.Is(err, test.err) || string(output) != test.expected {
+			t.Errorf("test %d: expected [%s] [%v] | [%s] [%v] output", index, test.expected, test.err, output, err)
+		}
+	}
+}